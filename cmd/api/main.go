@@ -1,94 +1,378 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"task-flow/internal/config"
 	"task-flow/internal/handler"
+	"task-flow/internal/httpx"
+	"task-flow/internal/logging"
+	"task-flow/internal/metrics"
 	"task-flow/internal/middleware"
+	"task-flow/internal/pkg/cookie"
 	"task-flow/internal/pkg/jwt"
+	"task-flow/internal/pkg/mailer"
+	"task-flow/internal/pkg/oidc"
+	"task-flow/internal/pkg/password"
+	"task-flow/internal/ratelimit"
+	"task-flow/internal/repository"
+	"task-flow/internal/repository/memory"
 	"task-flow/internal/repository/mysql"
+	"task-flow/internal/requestid"
 	"task-flow/internal/router"
 	"task-flow/internal/service"
+	"task-flow/internal/service/account"
+	"task-flow/internal/service/admin"
 	authservice "task-flow/internal/service/auth"
+	"task-flow/internal/service/oauth"
+	"task-flow/internal/service/workspace"
+	"task-flow/internal/tracing"
 
 	"github.com/joho/godotenv"
-	"github.com/rs/cors"
 )
 
 func main() {
 	// Load .env
 	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found")
+		slog.Info("no .env file found")
 	}
 
 	cfg := config.MustLoad()
 
+	logger, err := logging.New(os.Stdout, cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		fatal("configure logging", "err", err)
+	}
+	// Code without a request context, and the log package, use it too
+	slog.SetDefault(logger)
+
+	var spanExporter tracing.Exporter
+	switch cfg.TracesExporter {
+	case "none":
+		spanExporter = tracing.NoopExporter{}
+	case "otlp":
+		spanExporter = tracing.NewOTLPExporter(tracing.OTLPConfig{
+			Endpoint:    cfg.OTLPEndpoint,
+			Headers:     tracing.ParseHeaders(cfg.OTLPHeaders),
+			ServiceName: cfg.ServiceName,
+		})
+		logger.Info("exporting traces", "endpoint", cfg.OTLPEndpoint)
+	default:
+		fatal("unknown OTEL_TRACES_EXPORTER", "value", cfg.TracesExporter)
+	}
+	tracing.SetDefault(tracing.NewTracer(spanExporter))
+
 	// Connect database
 	db := config.ConnectDB()
-	defer db.Close()
+	metrics.RegisterDBStats(metrics.Default, db)
 
 	// Initialize repositories
 	userRepo := mysql.NewUserRepo(db)
 	refreshRepo := mysql.NewRefreshTokenRepo(db)
 	taskRepo := mysql.NewTaskRepo(db)
+	userTokenRepo := mysql.NewUserTokenRepo(db)
+	mfaRepo := mysql.NewMFARepo(db)
+	loginAttemptRepo := mysql.NewLoginAttemptRepo(db)
+	patRepo := mysql.NewPersonalAccessTokenRepo(db)
+	oidcStateRepo := mysql.NewOIDCStateRepo(db)
+	identityRepo := mysql.NewUserIdentityRepo(db)
+	oauthClientRepo := mysql.NewOAuthClientRepo(db)
+	oauthCodeRepo := mysql.NewOAuthCodeRepo(db)
+	oauthRefreshRepo := mysql.NewOAuthRefreshTokenRepo(db)
+	workspaceRepo := mysql.NewWorkspaceRepo(db)
+	projectRepo := mysql.NewProjectRepo(db)
+	invitationRepo := mysql.NewInvitationRepo(db)
+	shareRepo := mysql.NewShareRepo(db)
+	taskLinkRepo := mysql.NewTaskLinkRepo(db)
+	taskCommentRepo := mysql.NewTaskCommentRepo(db)
+	idempotencyRepo := mysql.NewIdempotencyRepo(db)
+
+	var denylist repository.AccessTokenDenylist
+	switch cfg.DenylistDriver {
+	case "memory":
+		denylist = memory.NewAccessTokenDenylist()
+	case "mysql":
+		denylist = mysql.NewAccessTokenDenylist(db)
+	default:
+		fatal("unknown DENYLIST_DRIVER", "value", cfg.DenylistDriver)
+	}
+
+	// Initialize mailer
+	var mail mailer.Mailer
+	switch cfg.MailDriver {
+	case "smtp":
+		mail = mailer.NewSMTP(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	case "log":
+		out := os.Stdout
+		if cfg.MailLogFile != "" {
+			f, err := os.OpenFile(cfg.MailLogFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+			if err != nil {
+				fatal("open mail log", "err", err)
+			}
+			defer f.Close()
+			out = f
+		}
+		mail = mailer.NewLog(out, cfg.MailFrom)
+	default:
+		fatal("unknown MAIL_DRIVER", "value", cfg.MailDriver)
+	}
+	// Mail sent while serving a request can be traced back to it
+	mail = mailer.WithHeaders(mail, func(ctx context.Context) map[string]string {
+		if id := requestid.FromContext(ctx); id != "" {
+			return map[string]string{requestid.Header: id}
+		}
+		return nil
+	})
 
 	// Initialize JWT
 	jwtInstance := jwt.New([]byte(cfg.JWTSecret))
 
+	// Initialize services
+	authSvc := authservice.NewService(userRepo, refreshRepo, denylist, userTokenRepo, mail, mfaRepo, loginAttemptRepo, patRepo, jwtInstance, cfg.AccessTTL, cfg.RefreshTTL)
+	authSvc.PasswordResetTTL = cfg.PasswordResetTTL
+	authSvc.EmailVerificationTTL = cfg.EmailVerificationTTL
+	authSvc.RequireVerifiedEmail = cfg.RequireVerifiedEmail
+	authSvc.AppBaseURL = cfg.AppBaseURL
+	authSvc.PasswordPolicy = password.Policy{
+		MinLength:     cfg.PasswordMinLength,
+		MaxBytes:      password.DefaultPolicy.MaxBytes,
+		RequireUpper:  cfg.PasswordRequireUpper,
+		RequireLower:  cfg.PasswordRequireLower,
+		RequireDigit:  cfg.PasswordRequireDigit,
+		RequireSymbol: cfg.PasswordRequireSymbol,
+		ForbidEmail:   true,
+	}
+	argon := password.NewArgon2id(password.Argon2Params{
+		Memory:  uint32(cfg.Argon2Memory),
+		Time:    uint32(cfg.Argon2Time),
+		Threads: uint8(cfg.Argon2Threads),
+		SaltLen: password.DefaultArgon2Params.SaltLen,
+		KeyLen:  password.DefaultArgon2Params.KeyLen,
+	})
+	bcryptHasher := password.NewBcrypt(cfg.BcryptCost)
+	switch cfg.PasswordHasher {
+	case "argon2id":
+		authSvc.Passwords = password.NewChain(argon, bcryptHasher)
+	case "bcrypt":
+		authSvc.Passwords = password.NewChain(bcryptHasher, argon)
+	default:
+		fatal("unknown PASSWORD_HASHER", "value", cfg.PasswordHasher)
+	}
+	if cfg.BreachedPasswordsFile != "" {
+		breached, err := password.LoadBreachedList(cfg.BreachedPasswordsFile)
+		if err != nil {
+			fatal("load breached passwords", "err", err)
+		}
+		logger.Info("loaded breached password hashes", "count", breached.Len())
+		authSvc.PasswordPolicy.Breached = breached
+	}
+	authSvc.LoginThrottle = authservice.LoginThrottle{
+		MaxAccountFailures: cfg.LoginMaxAccountFailures,
+		MaxIPFailures:      cfg.LoginMaxIPFailures,
+		Window:             cfg.LoginFailureWindow,
+		BaseLockout:        cfg.LoginBaseLockout,
+		MaxLockout:         cfg.LoginMaxLockout,
+	}
+	authSvc.OIDCStateRepo = oidcStateRepo
+	authSvc.IdentityRepo = identityRepo
+	authSvc.OIDCProviders = make(map[string]*oidc.Provider)
+	for _, p := range cfg.OIDCProviders {
+		authSvc.OIDCProviders[p.Name] = oidc.NewProvider(oidc.Config{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		}, &http.Client{Timeout: 10 * time.Second, Transport: tracing.Transport(nil)})
+		logger.Info("oidc provider enabled", "provider", p.Name, "issuer", p.Issuer)
+	}
+
+	taskSvc := service.NewServiceTask(taskRepo, projectRepo, shareRepo, taskLinkRepo, taskCommentRepo, userRepo)
+	adminSvc := admin.NewService(userRepo, authSvc)
+	workspaceSvc := workspace.NewService(workspaceRepo, projectRepo, userRepo)
+	workspaceSvc.ShareRepo = shareRepo
+	workspaceSvc.InvitationRepo = invitationRepo
+	workspaceSvc.Registrar = authSvc
+	workspaceSvc.Mailer = mail
+	workspaceSvc.JWT = jwtInstance
+	workspaceSvc.AppBaseURL = cfg.AppBaseURL
+	workspaceSvc.InvitationTTL = cfg.InvitationTTL
+	accountSvc := account.NewService(userRepo, workspaceRepo, taskRepo, shareRepo, taskCommentRepo, patRepo, identityRepo, authSvc)
+	oauthSvc := oauth.NewService(oauthClientRepo, oauthCodeRepo, oauthRefreshRepo, denylist, jwtInstance, cfg.AccessTTL, cfg.OAuthRefreshTTL)
+
 	// Initialize middleware
-	authMid := middleware.NewAuthMiddleware(jwtInstance)
+	authMid := middleware.NewAuthMiddleware(jwtInstance, denylist, authSvc, userRepo)
 
-	// Initialize services
-	authSvc := authservice.NewService(userRepo, refreshRepo, jwtInstance, cfg.AccessTTL, cfg.RefreshTTL)
-	taskSvc := service.NewServiceTask(taskRepo)
+	var rateLimiter *middleware.RateLimiter
+	rateLimits := middleware.RateLimitPolicies{
+		Strict: cfg.RateLimitStrict,
+		Read:   cfg.RateLimitRead,
+		Write:  cfg.RateLimitWrite,
+		Routes: cfg.RateLimitRoutes,
+	}
+	switch cfg.RateLimitDriver {
+	case "none":
+	case "memory":
+		rateLimiter = middleware.NewRateLimiter(ratelimit.NewMemoryStore(), rateLimits)
+	case "mysql":
+		rateLimiter = middleware.NewRateLimiter(mysql.NewRateLimitStore(db), rateLimits)
+	default:
+		fatal("unknown RATE_LIMIT_DRIVER", "value", cfg.RateLimitDriver)
+	}
+	idempotency := middleware.NewIdempotency(idempotencyRepo, cfg.IdempotencyTTL)
+	trustedProxies, err := httpx.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		fatal("invalid TRUSTED_PROXIES", "err", err)
+	}
 
 	// Initialize handlers
-	authHandler := handler.NewAuthHandler(authSvc)
-	userHandler := handler.NewUserHandler(userRepo)
+	cookies := cookie.NewCookie(cfg.CookieDomain, cfg.CookieSecure, cfg.RefreshTTL)
+	authHandler := handler.NewAuthHandler(authSvc, cookies)
+	userHandler := handler.NewUserHandler(accountSvc)
 	taskHandler := handler.NewTaskHandler(taskSvc)
+	oauthHandler := handler.NewOAuthHandler(oauthSvc)
+	adminHandler := handler.NewAdminHandler(adminSvc)
+	workspaceHandler := handler.NewWorkspaceHandler(workspaceSvc)
+
+	// Metrics go on their own listener when configured, otherwise on the
+	// main one behind a bearer token
+	var publicMetrics http.Handler
+	var metricsSrv *http.Server
+	switch {
+	case cfg.MetricsAddr != "":
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("GET /metrics", metrics.Default.Handler())
+		metricsSrv = &http.Server{
+			Addr:              cfg.MetricsAddr,
+			Handler:           metricsMux,
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+	case cfg.MetricsToken != "":
+		publicMetrics = middleware.RequireStaticToken(cfg.MetricsToken)(metrics.Default.Handler())
+	}
 
 	// Setup router
 	mux := router.New(router.Deps{
-		AuthHandler: authHandler,
-		TaskHandler: taskHandler,
-		UserHandler: userHandler,
-		AuthMid:     authMid,
+		AuthHandler:      authHandler,
+		TaskHandler:      taskHandler,
+		UserHandler:      userHandler,
+		OAuthHandler:     oauthHandler,
+		AdminHandler:     adminHandler,
+		WorkspaceHandler: workspaceHandler,
+		AuthMid:          authMid,
+		Workspaces:       workspaceRepo,
+		Metrics:          publicMetrics,
+		RateLimiter:      rateLimiter,
+		Idempotency:      idempotency,
 	})
 
-	c := cors.New(cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization"},
-		AllowCredentials: true,
+	corsHandler, err := middleware.CORS(middleware.CORSOptions{
+		AllowedOrigins:   cfg.CORSAllowedOrigins,
+		AllowedMethods:   cfg.CORSAllowedMethods,
+		AllowedHeaders:   cfg.CORSAllowedHeaders,
+		ExposedHeaders:   cfg.CORSExposedHeaders,
+		AllowCredentials: cfg.CORSAllowCredentials,
+		MaxAge:           cfg.CORSMaxAge,
 	})
+	if err != nil {
+		fatal("invalid CORS configuration", "err", err)
+	}
+
+	// Built inside out. Recover, SecurityHeaders and Metrics wrap the mux
+	// directly and Tracing comes right after, so they see the matched route.
+	var h http.Handler = mux
+	h = middleware.Recover(h)
+	h = middleware.SecurityHeaders(middleware.SecurityHeaderOptions{
+		Headers: map[string]string{
+			"Strict-Transport-Security": cfg.HSTS,
+			"Content-Security-Policy":   cfg.ContentSecurityPolicy,
+			"X-Content-Type-Options":    "nosniff",
+			"X-Frame-Options":           cfg.FrameOptions,
+			"Referrer-Policy":           cfg.ReferrerPolicy,
+		},
+		Routes: cfg.SecurityHeadersRoutes,
+	})(h)
+	h = middleware.Metrics(h)
+	h = middleware.Tracing(h)
+	h = middleware.Logger(logger)(h)
+	h = middleware.Timeout(cfg.HandlerTimeout)(h)
+	h = middleware.RealIP(trustedProxies)(h)
+	h = middleware.RequestID(h)
+	h = corsHandler(h)
 
 	srv := &http.Server{
-		Addr: cfg.Addr,
-		// Handler: middleware.CORS(middleware.Logger(mux)), // If using manual CORS
-		Handler:           c.Handler(middleware.Logger(mux)),
-		ReadHeaderTimeout: 5 * time.Second,
+		Addr:              cfg.Addr,
+		Handler:           h,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+	servers := []*http.Server{srv}
+	if metricsSrv != nil {
+		servers = append(servers, metricsSrv)
 	}
 
 	// Start server
 	go func() {
-		log.Printf("listening on %s", cfg.Addr)
+		logger.Info("listening", "addr", cfg.Addr)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("server error: %v", err)
+			fatal("server error", "err", err)
 		}
 	}()
 
+	if metricsSrv != nil {
+		go func() {
+			logger.Info("metrics listening", "addr", cfg.MetricsAddr)
+			if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fatal("metrics server error", "err", err)
+			}
+		}()
+	}
+
 	// Graceful shutdown
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 	<-stop
 
-	log.Println("shutting down...")
-	_ = srv.Close()
-	log.Println("shutdown complete")
+	logger.Info("shutting down", "timeout", cfg.ShutdownTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	// Stop accepting connections and let in-flight requests finish; those
+	// still running at the deadline are cut off
+	var wg sync.WaitGroup
+	for _, s := range servers {
+		wg.Go(func() {
+			if err := s.Shutdown(ctx); err != nil {
+				logger.Warn("requests still running at shutdown deadline", "addr", s.Addr, "err", err)
+				_ = s.Close()
+			}
+		})
+	}
+	wg.Wait()
+
+	// Then what the requests were using
+	if err := spanExporter.Shutdown(ctx); err != nil {
+		logger.Warn("flush traces", "err", err)
+	}
+	if err := db.Close(); err != nil {
+		logger.Warn("close database", "err", err)
+	}
+	logger.Info("shutdown complete")
+}
+
+// fatal logs msg with the default logger and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
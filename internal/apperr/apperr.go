@@ -0,0 +1,85 @@
+// Package apperr holds the typed errors services and repositories return
+// for failures a client can act on. The HTTP layer maps them by kind (see
+// httpx.WriteError); any other error is treated as internal and never shown
+// to clients.
+package apperr
+
+import "errors"
+
+// Kind classifies an error by what the client did wrong.
+type Kind string
+
+const (
+	KindValidation   Kind = "validation"
+	KindUnauthorized Kind = "unauthorized"
+	KindForbidden    Kind = "forbidden"
+	KindNotFound     Kind = "not_found"
+	KindConflict     Kind = "conflict"
+	// KindUnprocessable is a well-formed request the server refuses on
+	// semantic grounds, such as a password that breaks the policy.
+	KindUnprocessable Kind = "unprocessable"
+	KindTooLarge      Kind = "too_large"
+	KindRateLimited   Kind = "rate_limited"
+	// KindTimeout is a request the server gave up on because it took too
+	// long.
+	KindTimeout Kind = "timeout"
+	// KindUnavailable is a failure of an upstream the server depends on,
+	// such as an identity provider.
+	KindUnavailable Kind = "unavailable"
+)
+
+// Error is a client-facing error. Code is stable and meant for programs;
+// Message is for people and may change.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	// Details are extra members for the response body.
+	Details map[string]any
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// With returns a copy of e carrying an extra detail. Sentinels stay
+// untouched, but the copy no longer matches them with errors.Is.
+func (e *Error) With(key string, value any) *Error {
+	c := *e
+	c.Details = make(map[string]any, len(e.Details)+1)
+	for k, v := range e.Details {
+		c.Details[k] = v
+	}
+	c.Details[key] = value
+	return &c
+}
+
+func New(kind Kind, code, msg string) *Error {
+	return &Error{Kind: kind, Code: code, Message: msg}
+}
+
+func Validation(code, msg string) *Error    { return New(KindValidation, code, msg) }
+func Unauthorized(code, msg string) *Error  { return New(KindUnauthorized, code, msg) }
+func Forbidden(code, msg string) *Error     { return New(KindForbidden, code, msg) }
+func NotFound(code, msg string) *Error      { return New(KindNotFound, code, msg) }
+func Conflict(code, msg string) *Error      { return New(KindConflict, code, msg) }
+func Unprocessable(code, msg string) *Error { return New(KindUnprocessable, code, msg) }
+func TooLarge(code, msg string) *Error      { return New(KindTooLarge, code, msg) }
+func RateLimited(code, msg string) *Error   { return New(KindRateLimited, code, msg) }
+func Unavailable(code, msg string) *Error   { return New(KindUnavailable, code, msg) }
+func Timeout(code, msg string) *Error       { return New(KindTimeout, code, msg) }
+
+// As finds the first *Error in err's chain.
+func As(err error) (*Error, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
+
+// IsKind reports whether err carries an *Error of the given kind.
+func IsKind(err error, kind Kind) bool {
+	e, ok := As(err)
+	return ok && e.Kind == kind
+}
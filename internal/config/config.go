@@ -1,9 +1,15 @@
 package config
 
 import (
-	"log"
+	"encoding/json"
+	"log/slog"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
+
+	"task-flow/internal/ratelimit"
 )
 
 type Config struct {
@@ -11,11 +17,134 @@ type Config struct {
 	DatabaseURL string
 	JWTSecret   string
 
+	// HTTP server limits. HandlerTimeout is the deadline handlers get
+	// through their context; WriteTimeout should exceed it so a timed-out
+	// request can still be answered. ShutdownTimeout bounds how long
+	// in-flight requests may finish after SIGTERM.
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	HandlerTimeout    time.Duration
+	ShutdownTimeout   time.Duration
+
 	AccessTTL  time.Duration
 	RefreshTTL time.Duration
 
 	CookieDomain string
 	CookieSecure bool
+
+	// LogFormat is "json", "text" or "dev" (colored, for terminals).
+	LogFormat string
+	LogLevel  string
+
+	// MetricsAddr serves /metrics on a separate listener, meant to be
+	// reachable only from inside the network. Without it /metrics is on
+	// the main listener and only when MetricsToken is set, as a bearer
+	// token scrapers must send.
+	MetricsAddr  string
+	MetricsToken string
+
+	// TracesExporter is "none" (spans are only used for IDs in logs) or
+	// "otlp". The OTLP settings use the standard OpenTelemetry variables.
+	TracesExporter string
+	OTLPEndpoint   string
+	OTLPHeaders    string
+	ServiceName    string
+
+	// RateLimitDriver selects where rate limit buckets are kept: "memory"
+	// (per instance), "mysql" (shared across instances) or "none" to turn
+	// rate limiting off. Policies are "limit/period" or "off";
+	// RateLimitRoutes overrides them for single route patterns.
+	RateLimitDriver string
+	RateLimitStrict ratelimit.Policy
+	RateLimitRead   ratelimit.Policy
+	RateLimitWrite  ratelimit.Policy
+	RateLimitRoutes map[string]ratelimit.Policy
+	// TrustedProxies are addresses or CIDR ranges of reverse proxies whose
+	// X-Forwarded-For header is believed.
+	TrustedProxies []string
+
+	// CORS settings. Origins are exact or "https://*.example.com" for any
+	// subdomain; "*" only works without credentials.
+	CORSAllowedOrigins   []string
+	CORSAllowedMethods   []string
+	CORSAllowedHeaders   []string
+	CORSExposedHeaders   []string
+	CORSAllowCredentials bool
+	CORSMaxAge           time.Duration
+
+	// Security headers sent on every response; empty values are not sent.
+	// SecurityHeadersRoutes overrides them per route pattern, read as JSON
+	// like {"GET /public/tasks/{token}": {"Referrer-Policy": "same-origin"}}.
+	HSTS                  string
+	ContentSecurityPolicy string
+	FrameOptions          string
+	ReferrerPolicy        string
+	SecurityHeadersRoutes map[string]map[string]string
+
+	// IdempotencyTTL is how long responses to requests with an
+	// Idempotency-Key are kept for replay.
+	IdempotencyTTL time.Duration
+
+	// DenylistDriver selects where revoked access tokens are kept:
+	// "mysql" (shared across instances) or "memory" (single instance).
+	DenylistDriver string
+
+	LoginMaxAccountFailures int
+	LoginMaxIPFailures      int
+	LoginFailureWindow      time.Duration
+	LoginBaseLockout        time.Duration
+	LoginMaxLockout         time.Duration
+
+	PasswordMinLength     int
+	PasswordRequireUpper  bool
+	PasswordRequireLower  bool
+	PasswordRequireDigit  bool
+	PasswordRequireSymbol bool
+	// PasswordHasher picks the algorithm for new hashes: "argon2id" or
+	// "bcrypt". Hashes from the other one are upgraded on login.
+	PasswordHasher string
+	Argon2Memory   int // KiB
+	Argon2Time     int
+	Argon2Threads  int
+	BcryptCost     int
+	// BreachedPasswordsFile is an optional list of breached SHA-1 hashes.
+	BreachedPasswordsFile string
+
+	// AppBaseURL is the public frontend URL used to build links in emails.
+	AppBaseURL       string
+	PasswordResetTTL time.Duration
+
+	EmailVerificationTTL time.Duration
+	RequireVerifiedEmail bool
+
+	MailDriver   string // "smtp" or "log"
+	MailFrom     string
+	MailLogFile  string // used by the log driver; empty means stdout
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+
+	// OAuthRefreshTTL is the lifetime of refresh tokens issued to
+	// third-party OAuth clients.
+	OAuthRefreshTTL time.Duration
+	// InvitationTTL is how long workspace invitation links stay valid.
+	InvitationTTL time.Duration
+
+	// OIDCProviders are the external identity providers listed in
+	// OIDC_PROVIDERS, each configured by OIDC_<NAME>_* variables.
+	OIDCProviders []OIDCProvider
+}
+
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
 }
 
 func MustLoad() Config {
@@ -25,18 +154,162 @@ func MustLoad() Config {
 
 	accessTTL := mustDuration("ACCESS_TTL", 15*time.Minute)
 	refreshTTL := mustDuration("REFRESH_TTL", 7*24*time.Hour)
+	appBaseURL := getenv("APP_BASE_URL", "http://localhost:3000")
 
 	return Config{
-		Addr:         addr,
-		DatabaseURL:  dbURL,
-		JWTSecret:    secret,
+		Addr:        addr,
+		DatabaseURL: dbURL,
+		JWTSecret:   secret,
+
+		ReadHeaderTimeout: mustDuration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+		ReadTimeout:       mustDuration("HTTP_READ_TIMEOUT", 15*time.Second),
+		WriteTimeout:      mustDuration("HTTP_WRITE_TIMEOUT", 35*time.Second),
+		IdleTimeout:       mustDuration("HTTP_IDLE_TIMEOUT", 60*time.Second),
+		HandlerTimeout:    mustDuration("HTTP_HANDLER_TIMEOUT", 30*time.Second),
+		ShutdownTimeout:   mustDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+
 		AccessTTL:    accessTTL,
 		RefreshTTL:   refreshTTL,
 		CookieDomain: getenv("COOKIE_DOMAIN", "localhost"),
 		CookieSecure: getenv("COOKIE_SECURE", "false") == "true",
+
+		LogFormat: getenv("LOG_FORMAT", "json"),
+		LogLevel:  getenv("LOG_LEVEL", "info"),
+
+		MetricsAddr:  os.Getenv("METRICS_ADDR"),
+		MetricsToken: os.Getenv("METRICS_TOKEN"),
+
+		TracesExporter: getenv("OTEL_TRACES_EXPORTER", "none"),
+		OTLPEndpoint:   getenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318"),
+		OTLPHeaders:    os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"),
+		ServiceName:    getenv("OTEL_SERVICE_NAME", "task-flow"),
+
+		RateLimitDriver: getenv("RATE_LIMIT_DRIVER", "memory"),
+		RateLimitStrict: mustPolicy("RATE_LIMIT_STRICT", "strict", "10/1m"),
+		RateLimitRead:   mustPolicy("RATE_LIMIT_READ", "read", "300/1m"),
+		RateLimitWrite:  mustPolicy("RATE_LIMIT_WRITE", "write", "60/1m"),
+		RateLimitRoutes: loadRoutePolicies(),
+		TrustedProxies:  splitList(os.Getenv("TRUSTED_PROXIES")),
+
+		CORSAllowedOrigins:   splitList(getenv("CORS_ALLOWED_ORIGINS", origin(appBaseURL))),
+		CORSAllowedMethods:   splitList(getenv("CORS_ALLOWED_METHODS", "GET,POST,PUT,PATCH,DELETE")),
+		CORSAllowedHeaders:   splitList(getenv("CORS_ALLOWED_HEADERS", "Content-Type,Authorization,X-Workspace-ID,X-Request-ID,traceparent,Idempotency-Key")),
+		CORSExposedHeaders:   splitList(getenv("CORS_EXPOSED_HEADERS", "X-Request-ID,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,RateLimit-Policy,Retry-After,Idempotent-Replayed")),
+		CORSAllowCredentials: getenv("CORS_ALLOW_CREDENTIALS", "true") == "true",
+		CORSMaxAge:           mustDuration("CORS_MAX_AGE", 10*time.Minute),
+
+		HSTS:                  getenvOrEmpty("SECURITY_HSTS", "max-age=63072000; includeSubDomains"),
+		ContentSecurityPolicy: getenvOrEmpty("SECURITY_CSP", "default-src 'none'; frame-ancestors 'none'"),
+		FrameOptions:          getenvOrEmpty("SECURITY_FRAME_OPTIONS", "DENY"),
+		ReferrerPolicy:        getenvOrEmpty("SECURITY_REFERRER_POLICY", "no-referrer"),
+		SecurityHeadersRoutes: mustJSON[map[string]map[string]string]("SECURITY_HEADERS_ROUTES"),
+
+		IdempotencyTTL: mustDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+
+		DenylistDriver: getenv("DENYLIST_DRIVER", "mysql"),
+
+		LoginMaxAccountFailures: mustInt("LOGIN_MAX_ACCOUNT_FAILURES", 5),
+		LoginMaxIPFailures:      mustInt("LOGIN_MAX_IP_FAILURES", 20),
+		LoginFailureWindow:      mustDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		LoginBaseLockout:        mustDuration("LOGIN_BASE_LOCKOUT", time.Minute),
+		LoginMaxLockout:         mustDuration("LOGIN_MAX_LOCKOUT", time.Hour),
+
+		PasswordMinLength:     mustInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordRequireUpper:  getenv("PASSWORD_REQUIRE_UPPER", "false") == "true",
+		PasswordRequireLower:  getenv("PASSWORD_REQUIRE_LOWER", "false") == "true",
+		PasswordRequireDigit:  getenv("PASSWORD_REQUIRE_DIGIT", "false") == "true",
+		PasswordRequireSymbol: getenv("PASSWORD_REQUIRE_SYMBOL", "false") == "true",
+		BreachedPasswordsFile: os.Getenv("BREACHED_PASSWORDS_FILE"),
+
+		PasswordHasher: getenv("PASSWORD_HASHER", "argon2id"),
+		Argon2Memory:   mustInt("ARGON2_MEMORY_KIB", 64*1024),
+		Argon2Time:     mustInt("ARGON2_TIME", 3),
+		Argon2Threads:  mustInt("ARGON2_THREADS", 2),
+		BcryptCost:     mustInt("BCRYPT_COST", 10),
+
+		AppBaseURL:       appBaseURL,
+		PasswordResetTTL: mustDuration("PASSWORD_RESET_TTL", time.Hour),
+
+		EmailVerificationTTL: mustDuration("EMAIL_VERIFICATION_TTL", 24*time.Hour),
+		RequireVerifiedEmail: getenv("REQUIRE_VERIFIED_EMAIL", "false") == "true",
+
+		MailDriver:   getenv("MAIL_DRIVER", "log"),
+		MailFrom:     getenv("MAIL_FROM", "Task Flow <no-reply@localhost>"),
+		MailLogFile:  os.Getenv("MAIL_LOG_FILE"),
+		SMTPHost:     getenv("SMTP_HOST", "localhost"),
+		SMTPPort:     mustInt("SMTP_PORT", 587),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+
+		OAuthRefreshTTL: mustDuration("OAUTH_REFRESH_TTL", 30*24*time.Hour),
+		InvitationTTL:   mustDuration("INVITATION_TTL", 7*24*time.Hour),
+
+		OIDCProviders: loadOIDCProviders(),
 	}
 }
 
+func loadOIDCProviders() []OIDCProvider {
+	var providers []OIDCProvider
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providers = append(providers, OIDCProvider{
+			Name:         name,
+			Issuer:       mustEnv(prefix + "ISSUER"),
+			ClientID:     mustEnv(prefix + "CLIENT_ID"),
+			ClientSecret: mustEnv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  mustEnv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(getenv(prefix+"SCOPES", "openid email profile")),
+		})
+	}
+	return providers
+}
+
+// loadRoutePolicies reads RATE_LIMIT_ROUTES, such as
+// "POST /auth/login=5/1m,GET /tasks=600/1m".
+func loadRoutePolicies() map[string]ratelimit.Policy {
+	policies := make(map[string]ratelimit.Policy)
+	for _, entry := range splitList(os.Getenv("RATE_LIMIT_ROUTES")) {
+		pattern, v, ok := strings.Cut(entry, "=")
+		if !ok {
+			fatal("invalid rate limit route", "key", "RATE_LIMIT_ROUTES", "value", entry)
+		}
+		pattern = strings.TrimSpace(pattern)
+		policies[pattern] = parsePolicy("RATE_LIMIT_ROUTES", pattern, strings.TrimSpace(v))
+	}
+	return policies
+}
+
+func mustPolicy(k, name, def string) ratelimit.Policy {
+	return parsePolicy(k, name, getenv(k, def))
+}
+
+func parsePolicy(k, name, v string) ratelimit.Policy {
+	if v == "off" {
+		return ratelimit.Policy{Name: name}
+	}
+	p, err := ratelimit.ParsePolicy(name, v)
+	if err != nil {
+		fatal("invalid rate limit", "key", k, "err", err)
+	}
+	return p
+}
+
+// splitList splits a comma-separated value, dropping empty entries.
+func splitList(v string) []string {
+	var list []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
 func getenv(k, def string) string {
 	v := os.Getenv(k)
 	if v == "" {
@@ -45,10 +318,19 @@ func getenv(k, def string) string {
 	return v
 }
 
+// getenvOrEmpty is getenv for settings where an empty value turns
+// something off rather than asking for the default.
+func getenvOrEmpty(k, def string) string {
+	if v, ok := os.LookupEnv(k); ok {
+		return v
+	}
+	return def
+}
+
 func mustEnv(k string) string {
 	v := os.Getenv(k)
 	if v == "" {
-		log.Fatalf("missing env %s", k)
+		fatal("missing env", "key", k)
 	}
 	return v
 }
@@ -60,7 +342,46 @@ func mustDuration(k string, def time.Duration) time.Duration {
 	}
 	d, err := time.ParseDuration(v)
 	if err != nil {
-		log.Fatalf("invalid duration %s=%q: %v", k, v, err)
+		fatal("invalid duration", "key", k, "value", v, "err", err)
 	}
 	return d
 }
+
+// origin is the scheme and host of rawURL, the frontend being the one
+// origin allowed by default.
+func origin(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		fatal("invalid URL", "value", rawURL)
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// mustJSON decodes k as JSON; unset leaves the zero value.
+func mustJSON[T any](k string) T {
+	var v T
+	if s := os.Getenv(k); s != "" {
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			fatal("invalid JSON", "key", k, "err", err)
+		}
+	}
+	return v
+}
+
+func mustInt(k string, def int) int {
+	v := os.Getenv(k)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		fatal("invalid integer", "key", k, "value", v, "err", err)
+	}
+	return n
+}
+
+// fatal logs msg and exits; configuration errors leave nothing to run.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"task-flow/internal/apperr"
+	"task-flow/internal/httpx"
+	"task-flow/internal/middleware"
+	"task-flow/internal/model"
+	"task-flow/internal/service/admin"
+)
+
+type AdminHandler struct {
+	service *admin.Service
+}
+
+func NewAdminHandler(service *admin.Service) *AdminHandler {
+	return &AdminHandler{service: service}
+}
+
+type adminUserResponse struct {
+	ID         string     `json:"id"`
+	Email      string     `json:"email"`
+	Role       model.Role `json:"role"`
+	VerifiedAt *time.Time `json:"verified_at"`
+	DisabledAt *time.Time `json:"disabled_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func newAdminUserResponse(u model.User) adminUserResponse {
+	return adminUserResponse{
+		ID:         u.ID,
+		Email:      u.Email,
+		Role:       u.Role,
+		VerifiedAt: u.VerifiedAt,
+		DisabledAt: u.DisabledAt,
+		CreatedAt:  u.CreatedAt,
+	}
+}
+
+var (
+	errInvalidStatus = apperr.Validation("invalid_status", "status must be active or disabled")
+	errInvalidLimit  = apperr.Validation("invalid_limit", "invalid limit")
+	errInvalidOffset = apperr.Validation("invalid_offset", "invalid offset")
+)
+
+// ListUsers supports ?q= (email search), ?role=, ?status=active|disabled,
+// ?limit= and ?offset=.
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := model.UserFilter{
+		Query: q.Get("q"),
+		Role:  model.Role(q.Get("role")),
+	}
+
+	switch q.Get("status") {
+	case "":
+	case "active":
+		disabled := false
+		filter.Disabled = &disabled
+	case "disabled":
+		disabled := true
+		filter.Disabled = &disabled
+	default:
+		httpx.WriteError(w, r, errInvalidStatus)
+		return
+	}
+
+	var err error
+	if v := q.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			httpx.WriteError(w, r, errInvalidLimit)
+			return
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if filter.Offset, err = strconv.Atoi(v); err != nil {
+			httpx.WriteError(w, r, errInvalidOffset)
+			return
+		}
+	}
+
+	users, err := h.service.ListUsers(r.Context(), middleware.UserID(r.Context()), filter)
+	if writeError(w, r, err) {
+		return
+	}
+
+	res := make([]adminUserResponse, 0, len(users))
+	for _, u := range users {
+		res = append(res, newAdminUserResponse(u))
+	}
+	httpx.JSON(w, http.StatusOK, res)
+}
+
+func (h *AdminHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.service.GetUser(r.Context(), middleware.UserID(r.Context()), r.PathValue("id"))
+	if writeError(w, r, err) {
+		return
+	}
+	httpx.JSON(w, http.StatusOK, newAdminUserResponse(user))
+}
+
+func (h *AdminHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	err := h.service.DisableUser(r.Context(), middleware.UserID(r.Context()), r.PathValue("id"))
+	if writeError(w, r, err) {
+		return
+	}
+	httpx.JSON(w, http.StatusOK, map[string]string{"message": "user disabled"})
+}
+
+func (h *AdminHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	err := h.service.EnableUser(r.Context(), middleware.UserID(r.Context()), r.PathValue("id"))
+	if writeError(w, r, err) {
+		return
+	}
+	httpx.JSON(w, http.StatusOK, map[string]string{"message": "user enabled"})
+}
+
+func (h *AdminHandler) ForceLogout(w http.ResponseWriter, r *http.Request) {
+	err := h.service.ForceLogout(r.Context(), middleware.UserID(r.Context()), r.PathValue("id"))
+	if writeError(w, r, err) {
+		return
+	}
+	httpx.JSON(w, http.StatusOK, map[string]string{"message": "user logged out from all sessions"})
+}
+
+type changeRoleRequest struct {
+	Role model.Role `json:"role" validate:"required,oneof=admin member viewer"`
+}
+
+func (h *AdminHandler) ChangeRole(w http.ResponseWriter, r *http.Request) {
+	var req changeRoleRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	err := h.service.ChangeRole(r.Context(), middleware.UserID(r.Context()), r.PathValue("id"), req.Role)
+	if writeError(w, r, err) {
+		return
+	}
+	httpx.JSON(w, http.StatusOK, map[string]string{"message": "role updated"})
+}
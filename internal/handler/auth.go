@@ -1,23 +1,32 @@
 package handler
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
+	"task-flow/internal/apperr"
 	"task-flow/internal/httpx"
+	"task-flow/internal/logging"
+	"task-flow/internal/middleware"
+	"task-flow/internal/pkg/cookie"
+	"task-flow/internal/pkg/password"
 	authservice "task-flow/internal/service/auth"
 )
 
 type AuthHandler struct {
 	service *authservice.Service
+	cookies *cookie.CookieManager
 }
 
-func NewAuthHandler(service *authservice.Service) *AuthHandler {
-	return &AuthHandler{service: service}
+func NewAuthHandler(service *authservice.Service, cookies *cookie.CookieManager) *AuthHandler {
+	return &AuthHandler{service: service, cookies: cookies}
 }
 
 type loginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
 }
 
 type tokenResponse struct {
@@ -31,20 +40,49 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	access, refresh, err := h.service.Login(r.Context(), req.Email, req.Password)
-	if err != nil {
-		httpx.Error(w, http.StatusUnauthorized, err.Error())
+	res, err := h.service.Login(r.Context(), req.Email, req.Password, httpx.ClientIP(r))
+	if writeAuthError(w, r, err) {
+		return
+	}
+
+	writeLoginResult(w, res)
+}
+
+// writeLoginResult answers a successful first factor with either the MFA
+// challenge or the token pair.
+func writeLoginResult(w http.ResponseWriter, res authservice.LoginResult) {
+	if res.MFAToken != "" {
+		httpx.JSON(w, http.StatusOK, mfaChallengeResponse{
+			MFARequired: true,
+			MFAToken:    res.MFAToken,
+		})
 		return
 	}
 
 	httpx.JSON(w, http.StatusOK, tokenResponse{
-		AccessToken:  access,
-		RefreshToken: refresh,
+		AccessToken:  res.AccessToken,
+		RefreshToken: res.RefreshToken,
 	})
 }
 
+var errPasswordPolicy = apperr.Unprocessable("password_policy", "password does not meet policy")
+
+// writeAuthError answers err like writeError, adding Retry-After to login
+// lockouts and every broken rule to password policy failures.
+func writeAuthError(w http.ResponseWriter, r *http.Request, err error) bool {
+	var locked *authservice.LockedError
+	var policyErr *password.PolicyError
+	switch {
+	case errors.As(err, &locked):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+	case errors.As(err, &policyErr):
+		err = errPasswordPolicy.With("violations", policyErr.Violations)
+	}
+	return writeError(w, r, err)
+}
+
 type refreshRequest struct {
-	RefreshToken string `json:"refresh_token"`
+	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
 func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
@@ -54,8 +92,7 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 	}
 
 	access, refresh, err := h.service.Refresh(r.Context(), req.RefreshToken)
-	if err != nil {
-		httpx.Error(w, http.StatusUnauthorized, err.Error())
+	if writeError(w, r, err) {
 		return
 	}
 
@@ -65,23 +102,39 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// logoutRequest names the refresh token to revoke. Clients that only hold
+// an access token send it as a bearer token and may leave the body empty.
+type logoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
-	var req refreshRequest
-	if !httpx.DecodeJSON(w, r, &req) {
+	var req logoutRequest
+	if r.ContentLength != 0 && !httpx.DecodeJSON(w, r, &req) {
 		return
 	}
 
-	if err := h.service.Logout(r.Context(), req.RefreshToken); err != nil {
-		httpx.Error(w, http.StatusInternalServerError, "logout failed")
+	access, _ := httpx.BearerToken(r)
+	if writeError(w, r, h.service.Logout(r.Context(), req.RefreshToken, access)) {
 		return
 	}
 
 	httpx.JSON(w, http.StatusOK, map[string]string{"message": "logged out"})
 }
 
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserID(r.Context())
+	if writeError(w, r, h.service.LogoutAll(r.Context(), userID)) {
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, map[string]string{"message": "logged out from all sessions"})
+}
+
 type registerRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email string `json:"email" validate:"required,email,max=255"`
+	// The password policy reports its own rules
+	Password string `json:"password" validate:"required"`
 }
 
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
@@ -90,10 +143,144 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.Register(r.Context(), req.Email, req.Password); err != nil {
-		httpx.Error(w, http.StatusBadRequest, err.Error())
+	err := h.service.Register(r.Context(), req.Email, req.Password)
+	if writeAuthError(w, r, err) {
 		return
 	}
 
 	httpx.JSON(w, http.StatusCreated, map[string]string{"message": "registered"})
 }
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req forgotPasswordRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	// Always answer the same way so the endpoint can't be used to discover
+	// which emails are registered.
+	if err := h.service.ForgotPassword(r.Context(), req.Email); err != nil {
+		logging.FromContext(r.Context()).Error("forgot password failed", "err", err)
+	}
+
+	httpx.JSON(w, http.StatusAccepted, map[string]string{
+		"message": "if the email is registered, a reset link has been sent",
+	})
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req resetPasswordRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	err := h.service.ResetPassword(r.Context(), req.Token, req.Password)
+	if writeAuthError(w, r, err) {
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, map[string]string{"message": "password has been reset"})
+}
+
+type verifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req verifyEmailRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	err := h.service.VerifyEmail(r.Context(), req.Token)
+	if writeError(w, r, err) {
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, map[string]string{"message": "email verified"})
+}
+
+type resendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req resendVerificationRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := h.service.ResendVerification(r.Context(), req.Email); err != nil {
+		logging.FromContext(r.Context()).Error("resend verification failed", "err", err)
+	}
+
+	httpx.JSON(w, http.StatusAccepted, map[string]string{
+		"message": "if the email is registered and unverified, a verification link has been sent",
+	})
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+// ChangePassword ends every session and answers with a fresh token pair
+// for the caller.
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var req changePasswordRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	access, refresh, err := h.service.ChangePassword(r.Context(), middleware.UserID(r.Context()), req.CurrentPassword, req.NewPassword)
+	if writeAuthError(w, r, err) {
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	httpx.JSON(w, http.StatusOK, tokenResponse{AccessToken: access, RefreshToken: refresh})
+}
+
+type changeEmailRequest struct {
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required"`
+}
+
+func (h *AuthHandler) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	var req changeEmailRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	err := h.service.RequestEmailChange(r.Context(), middleware.UserID(r.Context()), req.Password, req.Email)
+	if writeError(w, r, err) {
+		return
+	}
+
+	httpx.JSON(w, http.StatusAccepted, map[string]string{
+		"message": "a confirmation link has been sent to the new address",
+	})
+}
+
+func (h *AuthHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var req verifyEmailRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	err := h.service.ConfirmEmailChange(r.Context(), req.Token)
+	if writeError(w, r, err) {
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, map[string]string{"message": "email changed"})
+}
@@ -0,0 +1,17 @@
+package handler
+
+import (
+	"net/http"
+
+	"task-flow/internal/httpx"
+)
+
+// writeError answers err as a problem response; it reports whether there
+// was an error to answer.
+func writeError(w http.ResponseWriter, r *http.Request, err error) bool {
+	if err == nil {
+		return false
+	}
+	httpx.WriteError(w, r, err)
+	return true
+}
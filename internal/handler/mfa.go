@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"net/http"
+
+	"task-flow/internal/httpx"
+	"task-flow/internal/middleware"
+)
+
+type mfaChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+type loginMFARequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+func (h *AuthHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req loginMFARequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	access, refresh, err := h.service.LoginMFA(r.Context(), req.MFAToken, req.Code, httpx.ClientIP(r))
+	if writeAuthError(w, r, err) {
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, tokenResponse{
+		AccessToken:  access,
+		RefreshToken: refresh,
+	})
+}
+
+type mfaSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+func (h *AuthHandler) SetupMFA(w http.ResponseWriter, r *http.Request) {
+	secret, uri, err := h.service.SetupMFA(r.Context(), middleware.UserID(r.Context()))
+	if writeError(w, r, err) {
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	httpx.JSON(w, http.StatusOK, mfaSetupResponse{
+		Secret:     secret,
+		OTPAuthURI: uri,
+	})
+}
+
+type mfaConfirmRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type mfaConfirmResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func (h *AuthHandler) ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+	var req mfaConfirmRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	codes, err := h.service.ConfirmMFA(r.Context(), middleware.UserID(r.Context()), req.Code)
+	if writeError(w, r, err) {
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	httpx.JSON(w, http.StatusOK, mfaConfirmResponse{RecoveryCodes: codes})
+}
+
+type mfaDisableRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+func (h *AuthHandler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	var req mfaDisableRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	err := h.service.DisableMFA(r.Context(), middleware.UserID(r.Context()), req.Password)
+	if writeError(w, r, err) {
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, map[string]string{"message": "2fa disabled"})
+}
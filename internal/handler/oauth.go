@@ -0,0 +1,295 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"task-flow/internal/httpx"
+	"task-flow/internal/logging"
+	"task-flow/internal/middleware"
+	"task-flow/internal/model"
+	"task-flow/internal/service/oauth"
+)
+
+type OAuthHandler struct {
+	service *oauth.Service
+}
+
+func NewOAuthHandler(service *oauth.Service) *OAuthHandler {
+	return &OAuthHandler{service: service}
+}
+
+type createClientRequest struct {
+	Name         string   `json:"name" validate:"required,max=100"`
+	RedirectURIs []string `json:"redirect_uris" validate:"required"`
+	Scopes       []string `json:"scopes"`
+	// Confidential clients (server-side apps) get a secret; public ones
+	// (SPAs, mobile apps) don't.
+	Confidential bool `json:"confidential"`
+}
+
+type clientResponse struct {
+	ClientID     string    `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type createClientResponse struct {
+	clientResponse
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+func newClientResponse(c model.OAuthClient) clientResponse {
+	return clientResponse{
+		ClientID:     c.ID,
+		Name:         c.Name,
+		RedirectURIs: c.RedirectURIs,
+		Scopes:       c.Scopes,
+		Confidential: c.Confidential(),
+		CreatedAt:    c.CreatedAt,
+	}
+}
+
+func (h *OAuthHandler) CreateClient(w http.ResponseWriter, r *http.Request) {
+	var req createClientRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	client, secret, err := h.service.RegisterClient(r.Context(), middleware.UserID(r.Context()), req.Name, req.RedirectURIs, req.Scopes, req.Confidential)
+	if writeError(w, r, err) {
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	httpx.JSON(w, http.StatusCreated, createClientResponse{
+		clientResponse: newClientResponse(client),
+		ClientSecret:   secret,
+	})
+}
+
+func (h *OAuthHandler) ListClients(w http.ResponseWriter, r *http.Request) {
+	clients, err := h.service.ListClients(r.Context(), middleware.UserID(r.Context()))
+	if writeError(w, r, err) {
+		return
+	}
+
+	res := make([]clientResponse, 0, len(clients))
+	for _, c := range clients {
+		res = append(res, newClientResponse(c))
+	}
+	httpx.JSON(w, http.StatusOK, res)
+}
+
+func (h *OAuthHandler) DeleteClient(w http.ResponseWriter, r *http.Request) {
+	err := h.service.DeleteClient(r.Context(), middleware.UserID(r.Context()), r.PathValue("id"))
+	if writeError(w, r, err) {
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, map[string]string{"message": "client deleted"})
+}
+
+type authorizeRequest struct {
+	ResponseType        string `json:"response_type"`
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+	Approve             bool   `json:"approve"`
+}
+
+func (req authorizeRequest) toService() oauth.AuthorizeRequest {
+	return oauth.AuthorizeRequest{
+		ResponseType:        req.ResponseType,
+		ClientID:            req.ClientID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               req.Scope,
+		State:               req.State,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+	}
+}
+
+type consentResponse struct {
+	ClientID    string   `json:"client_id"`
+	ClientName  string   `json:"client_name"`
+	Scopes      []string `json:"scopes"`
+	RedirectURI string   `json:"redirect_uri"`
+}
+
+type redirectResponse struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// AuthorizeInfo backs the consent screen: the frontend passes on the query
+// string it was opened with and shows the user what the app asks for.
+func (h *OAuthHandler) AuthorizeInfo(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	req := authorizeRequest{
+		ResponseType:        q.Get("response_type"),
+		ClientID:            q.Get("client_id"),
+		RedirectURI:         q.Get("redirect_uri"),
+		Scope:               q.Get("scope"),
+		State:               q.Get("state"),
+		CodeChallenge:       q.Get("code_challenge"),
+		CodeChallengeMethod: q.Get("code_challenge_method"),
+	}
+
+	consent, err := h.service.ValidateAuthorize(r.Context(), req.toService())
+	var oerr *oauth.Error
+	if errors.As(err, &oerr) {
+		// Nothing to consent to; the frontend sends the user back with the error
+		httpx.JSON(w, http.StatusBadRequest, redirectResponse{
+			RedirectTo: oauth.ErrorRedirect(consent.RedirectURI, req.State, oerr),
+		})
+		return
+	}
+	if writeError(w, r, err) {
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, consentResponse{
+		ClientID:    consent.Client.ID,
+		ClientName:  consent.Client.Name,
+		Scopes:      consent.Scopes,
+		RedirectURI: consent.RedirectURI,
+	})
+}
+
+// Authorize records the user's decision and returns where to send the
+// browser next.
+func (h *OAuthHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	var req authorizeRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	redirectTo, err := h.service.Authorize(r.Context(), middleware.UserID(r.Context()), req.toService(), req.Approve)
+	if writeError(w, r, err) {
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, redirectResponse{RedirectTo: redirectTo})
+}
+
+// Token is the RFC 6749 token endpoint. It takes form parameters and
+// answers with OAuth-style JSON errors.
+func (h *OAuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	res, err := h.service.Token(r.Context(), client, oauth.TokenRequest{
+		GrantType:    r.PostForm.Get("grant_type"),
+		Code:         r.PostForm.Get("code"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+		RefreshToken: r.PostForm.Get("refresh_token"),
+		Scope:        r.PostForm.Get("scope"),
+	})
+	if err != nil {
+		writeOAuthError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	httpx.JSON(w, http.StatusOK, res)
+}
+
+// Introspect implements RFC 7662 for confidential clients.
+func (h *OAuthHandler) Introspect(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+	if !client.Confidential() {
+		writeOAuthError(w, r, &oauth.Error{Code: oauth.CodeUnauthorizedClient, Description: "introspection requires a confidential client"})
+		return
+	}
+
+	res, err := h.service.Introspect(r.Context(), client, r.PostForm.Get("token"))
+	if err != nil {
+		writeOAuthError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	httpx.JSON(w, http.StatusOK, res)
+}
+
+// Revoke implements RFC 7009.
+func (h *OAuthHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Revoke(r.Context(), client, r.PostForm.Get("token")); err != nil {
+		writeOAuthError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// authenticateClient parses the form and checks client credentials sent
+// via HTTP Basic or as client_id/client_secret form fields.
+func (h *OAuthHandler) authenticateClient(w http.ResponseWriter, r *http.Request) (model.OAuthClient, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, r, &oauth.Error{Code: oauth.CodeInvalidRequest, Description: "invalid form body"})
+		return model.OAuthClient{}, false
+	}
+
+	clientID, secret, basic := r.BasicAuth()
+	if basic {
+		// RFC 6749 2.3.1: credentials are form-encoded before Basic encoding
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+
+	client, err := h.service.AuthenticateClient(r.Context(), clientID, secret)
+	if err != nil {
+		if basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		writeOAuthError(w, r, err)
+		return model.OAuthClient{}, false
+	}
+	return client, true
+}
+
+type oauthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+func writeOAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	var oerr *oauth.Error
+	if !errors.As(err, &oerr) {
+		logging.FromContext(r.Context()).Error("oauth request failed", "err", err)
+		httpx.JSON(w, http.StatusInternalServerError, oauthErrorResponse{Error: "server_error"})
+		return
+	}
+
+	status := http.StatusBadRequest
+	if oerr.Code == oauth.CodeInvalidClient {
+		status = http.StatusUnauthorized
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	httpx.JSON(w, status, oauthErrorResponse{
+		Error:            oerr.Code,
+		ErrorDescription: oerr.Description,
+	})
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http"
+
+	"task-flow/internal/apperr"
+	"task-flow/internal/httpx"
+	"task-flow/internal/logging"
+	authservice "task-flow/internal/service/auth"
+)
+
+var (
+	errProviderUnavailable = apperr.Unavailable("provider_unavailable", "identity provider unavailable")
+	errProviderLoginFailed = apperr.Unavailable("provider_login_failed", "login with identity provider failed")
+	errMissingStateOrCode  = apperr.Validation("missing_state_or_code", "missing state or code")
+)
+
+// OIDCLogin redirects the browser to the identity provider.
+func (h *AuthHandler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	authURL, state, err := h.service.OIDCLoginURL(r.Context(), r.PathValue("provider"))
+	if _, ok := apperr.As(err); err != nil && !ok {
+		logging.FromContext(r.Context()).Error("oidc login failed", "provider", r.PathValue("provider"), "err", err)
+		err = errProviderUnavailable
+	}
+	if writeError(w, r, err) {
+		return
+	}
+
+	h.cookies.SetOIDCStateCookie(w, state, authservice.OIDCStateTTL)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback is the redirect URI registered at the provider.
+func (h *AuthHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		msg := "login cancelled or denied: " + e
+		if d := q.Get("error_description"); d != "" {
+			msg += " (" + d + ")"
+		}
+		httpx.WriteError(w, r, apperr.Validation("provider_denied", msg))
+		return
+	}
+	if q.Get("state") == "" || q.Get("code") == "" {
+		httpx.WriteError(w, r, errMissingStateOrCode)
+		return
+	}
+
+	// The state is single use whatever the outcome
+	browserState := h.cookies.OIDCState(r)
+	h.cookies.ClearOIDCStateCookie(w)
+
+	res, err := h.service.OIDCCallback(r.Context(), r.PathValue("provider"), q.Get("state"), browserState, q.Get("code"), httpx.ClientIP(r))
+	if _, ok := apperr.As(err); err != nil && !ok {
+		// Failures talking to the provider are not the client's fault
+		logging.FromContext(r.Context()).Error("oidc callback failed", "provider", r.PathValue("provider"), "err", err)
+		err = errProviderLoginFailed
+	}
+	if writeAuthError(w, r, err) {
+		return
+	}
+
+	writeLoginResult(w, res)
+}
@@ -1,9 +1,13 @@
 package handler
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"task-flow/internal/httpx"
+	"task-flow/internal/middleware"
+	"task-flow/internal/model"
 	taskservice "task-flow/internal/service"
 )
 
@@ -18,14 +22,82 @@ func NewTaskHandler(task *taskservice.Service) *TaskHandler {
 }
 
 type taskRequest struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
+	Title       string  `json:"title" validate:"required,max=255"`
+	Description string  `json:"description" validate:"max=255"`
+	ProjectID   *string `json:"project_id" validate:"min=1"`
+}
+
+type shareRequest struct {
+	Email string            `json:"email" validate:"required,email"`
+	Level model.AccessLevel `json:"level" validate:"required,oneof=view comment edit"`
+}
+
+type shareResponse struct {
+	UserID    string            `json:"user_id"`
+	Email     string            `json:"email"`
+	Level     model.AccessLevel `json:"level"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+type linkRequest struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type linkResponse struct {
+	ID        string     `json:"id"`
+	Token     string     `json:"token,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type commentRequest struct {
+	Body string `json:"body" validate:"required,max=5000"`
+}
+
+type commentResponse struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email,omitempty"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newShareResponse(s model.Share) shareResponse {
+	return shareResponse{UserID: s.UserID, Email: s.Email, Level: s.Level, CreatedAt: s.CreatedAt}
+}
+
+func newLinkResponse(l model.TaskLink) linkResponse {
+	return linkResponse{ID: l.ID, ExpiresAt: l.ExpiresAt, CreatedAt: l.CreatedAt}
+}
+
+func newCommentResponse(c model.TaskComment) commentResponse {
+	return commentResponse{ID: c.ID, UserID: c.UserID, Email: c.Email, Body: c.Body, CreatedAt: c.CreatedAt}
+}
+
+// authorize runs the service access check for the request's user. Every
+// task handler goes through it; on failure the response is written and ok
+// is false.
+func (h *TaskHandler) authorize(w http.ResponseWriter, r *http.Request, action taskservice.Action) (ctx context.Context, task model.Task, ok bool) {
+	actor := taskservice.Actor{
+		UserID:        middleware.UserID(r.Context()),
+		WorkspaceRole: middleware.WorkspaceRole(r.Context()),
+	}
+
+	ctx, task, err := h.Service.Authorize(r.Context(), actor, r.PathValue("id"), action)
+	if writeError(w, r, err) {
+		return nil, model.Task{}, false
+	}
+	return ctx, task, true
 }
 
 func (h *TaskHandler) GetTasks(w http.ResponseWriter, r *http.Request) {
-	tasks, err := h.Service.GetTasks(r.Context())
-	if err != nil {
-		httpx.Error(w, http.StatusInternalServerError, err.Error())
+	ctx, _, ok := h.authorize(w, r, taskservice.ActionList)
+	if !ok {
+		return
+	}
+
+	tasks, err := h.Service.GetTasks(ctx)
+	if writeError(w, r, err) {
 		return
 	}
 
@@ -33,15 +105,19 @@ func (h *TaskHandler) GetTasks(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *TaskHandler) AddTask(w http.ResponseWriter, r *http.Request) {
+	ctx, _, ok := h.authorize(w, r, taskservice.ActionCreate)
+	if !ok {
+		return
+	}
+
 	var req taskRequest
 
 	if !httpx.DecodeJSON(w, r, &req) {
 		return
 	}
 
-	err := h.Service.AddTask(r.Context(), req.Title, req.Description)
-	if err != nil {
-		httpx.Error(w, http.StatusBadRequest, err.Error())
+	err := h.Service.AddTask(ctx, req.Title, req.Description, req.ProjectID)
+	if writeError(w, r, err) {
 		return
 	}
 
@@ -49,50 +125,234 @@ func (h *TaskHandler) AddTask(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
-	id := r.PathValue("id")
+	ctx, task, ok := h.authorize(w, r, taskservice.ActionDelete)
+	if !ok {
+		return
+	}
+
+	err := h.Service.DeleteTask(ctx, task.ID)
+	if writeError(w, r, err) {
+		return
+	}
 
-	if id == "" {
-		httpx.Error(w, http.StatusBadRequest, "id is required")
+	httpx.JSON(w, http.StatusOK, map[string]string{"message": "task deleted successfully"})
+}
+
+func (h *TaskHandler) GetTasksByID(w http.ResponseWriter, r *http.Request) {
+	_, task, ok := h.authorize(w, r, taskservice.ActionView)
+	if !ok {
 		return
 	}
 
-	task, err := h.Service.FindByID(r.Context(), id)
-	if err != nil {
-		httpx.Error(w, http.StatusInternalServerError, err.Error())
+	httpx.JSON(w, http.StatusOK, task)
+}
+
+type updateTaskRequest struct {
+	Title       *string `json:"title" validate:"notblank,max=255"`
+	Description *string `json:"description" validate:"max=255"`
+	Completed   *bool   `json:"completed"`
+}
+
+func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
+	ctx, task, ok := h.authorize(w, r, taskservice.ActionEdit)
+	if !ok {
 		return
 	}
 
-	if task.ID == "" {
-		httpx.Error(w, http.StatusNotFound, "task not found")
+	var req updateTaskRequest
+	if !httpx.DecodeJSON(w, r, &req) {
 		return
 	}
 
-	err = h.Service.DeleteTask(r.Context(), id)
-	if err != nil {
-		httpx.Error(w, http.StatusInternalServerError, err.Error())
+	title, description, completed := task.Title, task.Description, task.CompletedAt != nil
+	if req.Title != nil {
+		title = *req.Title
+	}
+	if req.Description != nil {
+		description = *req.Description
+	}
+	if req.Completed != nil {
+		completed = *req.Completed
+	}
+
+	task, err := h.Service.UpdateTask(ctx, task, title, description, completed)
+	if writeError(w, r, err) {
 		return
 	}
 
-	httpx.JSON(w, http.StatusOK, map[string]string{"message": "task deleted successfully"})
+	httpx.JSON(w, http.StatusOK, task)
 }
 
-func (h *TaskHandler) GetTasksByID(w http.ResponseWriter, r *http.Request) {
-	id := r.PathValue("id")
-	if id == "" {
-		httpx.Error(w, http.StatusBadRequest, "id is required")
+// ListShared returns the tasks other workspaces shared with the user.
+func (h *TaskHandler) ListShared(w http.ResponseWriter, r *http.Request) {
+	tasks, err := h.Service.ListShared(r.Context(), middleware.UserID(r.Context()))
+	if writeError(w, r, err) {
 		return
 	}
 
-	task, err := h.Service.FindByID(r.Context(), id)
-	if err != nil {
-		httpx.Error(w, http.StatusInternalServerError, err.Error())
+	if tasks == nil {
+		tasks = []model.SharedTask{}
+	}
+	httpx.JSON(w, http.StatusOK, tasks)
+}
+
+func (h *TaskHandler) ListComments(w http.ResponseWriter, r *http.Request) {
+	ctx, task, ok := h.authorize(w, r, taskservice.ActionView)
+	if !ok {
 		return
 	}
 
-	if task.ID == "" {
-		httpx.Error(w, http.StatusNotFound, "task not found")
+	comments, err := h.Service.ListComments(ctx, task)
+	if writeError(w, r, err) {
 		return
 	}
 
-	httpx.JSON(w, http.StatusOK, task)
+	res := make([]commentResponse, 0, len(comments))
+	for _, c := range comments {
+		res = append(res, newCommentResponse(c))
+	}
+	httpx.JSON(w, http.StatusOK, res)
+}
+
+func (h *TaskHandler) AddComment(w http.ResponseWriter, r *http.Request) {
+	ctx, task, ok := h.authorize(w, r, taskservice.ActionComment)
+	if !ok {
+		return
+	}
+
+	var req commentRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	comment, err := h.Service.AddComment(ctx, middleware.UserID(r.Context()), task, req.Body)
+	if writeError(w, r, err) {
+		return
+	}
+
+	httpx.JSON(w, http.StatusCreated, newCommentResponse(comment))
+}
+
+func (h *TaskHandler) ListShares(w http.ResponseWriter, r *http.Request) {
+	ctx, task, ok := h.authorize(w, r, taskservice.ActionShare)
+	if !ok {
+		return
+	}
+
+	shares, err := h.Service.ListTaskShares(ctx, task)
+	if writeError(w, r, err) {
+		return
+	}
+
+	res := make([]shareResponse, 0, len(shares))
+	for _, s := range shares {
+		res = append(res, newShareResponse(s))
+	}
+	httpx.JSON(w, http.StatusOK, res)
+}
+
+func (h *TaskHandler) Share(w http.ResponseWriter, r *http.Request) {
+	ctx, task, ok := h.authorize(w, r, taskservice.ActionShare)
+	if !ok {
+		return
+	}
+
+	var req shareRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	share, err := h.Service.ShareTask(ctx, middleware.UserID(r.Context()), task, req.Email, req.Level)
+	if writeError(w, r, err) {
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, newShareResponse(share))
+}
+
+func (h *TaskHandler) Unshare(w http.ResponseWriter, r *http.Request) {
+	ctx, task, ok := h.authorize(w, r, taskservice.ActionShare)
+	if !ok {
+		return
+	}
+
+	if writeError(w, r, h.Service.UnshareTask(ctx, task, r.PathValue("user_id"))) {
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, map[string]string{"message": "share removed"})
+}
+
+func (h *TaskHandler) CreateLink(w http.ResponseWriter, r *http.Request) {
+	ctx, task, ok := h.authorize(w, r, taskservice.ActionShare)
+	if !ok {
+		return
+	}
+
+	// The body is optional: an empty one makes a link that never expires
+	var req linkRequest
+	if r.ContentLength != 0 && !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	link, token, err := h.Service.CreateLink(ctx, middleware.UserID(r.Context()), task, req.ExpiresAt)
+	if writeError(w, r, err) {
+		return
+	}
+
+	// The token is shown only once
+	res := newLinkResponse(link)
+	w.Header().Set("Cache-Control", "no-store")
+	res.Token = token
+	httpx.JSON(w, http.StatusCreated, res)
+}
+
+func (h *TaskHandler) ListLinks(w http.ResponseWriter, r *http.Request) {
+	ctx, task, ok := h.authorize(w, r, taskservice.ActionShare)
+	if !ok {
+		return
+	}
+
+	links, err := h.Service.ListLinks(ctx, task)
+	if writeError(w, r, err) {
+		return
+	}
+
+	res := make([]linkResponse, 0, len(links))
+	for _, l := range links {
+		res = append(res, newLinkResponse(l))
+	}
+	httpx.JSON(w, http.StatusOK, res)
+}
+
+func (h *TaskHandler) RevokeLink(w http.ResponseWriter, r *http.Request) {
+	ctx, task, ok := h.authorize(w, r, taskservice.ActionShare)
+	if !ok {
+		return
+	}
+
+	if writeError(w, r, h.Service.RevokeLink(ctx, task, r.PathValue("link_id"))) {
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, map[string]string{"message": "link revoked"})
+}
+
+// PublicTask serves a task through a public link, without authentication.
+func (h *TaskHandler) PublicTask(w http.ResponseWriter, r *http.Request) {
+	// The token is in the URL; keep it out of caches and Referer headers
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+
+	task, err := h.Service.PublicTask(r.Context(), r.PathValue("token"))
+	if writeError(w, r, err) {
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, map[string]any{
+		"title":        task.Title,
+		"description":  task.Description,
+		"created_at":   task.Created_At,
+		"completed_at": task.CompletedAt,
+	})
 }
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"task-flow/internal/httpx"
+	"task-flow/internal/middleware"
+	"task-flow/internal/model"
+)
+
+type createTokenRequest struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required"`
+	// ExpiresInDays is optional; zero means the token never expires.
+	ExpiresInDays int `json:"expires_in_days" validate:"min=0"`
+}
+
+type tokenInfoResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type createTokenResponse struct {
+	tokenInfoResponse
+	Token string `json:"token"`
+}
+
+func newTokenInfoResponse(t model.PersonalAccessToken) tokenInfoResponse {
+	return tokenInfoResponse{
+		ID:         t.ID,
+		Name:       t.Name,
+		Scopes:     t.Scopes,
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt,
+		CreatedAt:  t.CreatedAt,
+	}
+}
+
+func (h *AuthHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	var req createTokenRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+
+	plain, token, err := h.service.CreatePersonalAccessToken(r.Context(), middleware.UserID(r.Context()), req.Name, req.Scopes, ttl)
+	if writeError(w, r, err) {
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	httpx.JSON(w, http.StatusCreated, createTokenResponse{
+		tokenInfoResponse: newTokenInfoResponse(token),
+		Token:             plain,
+	})
+}
+
+func (h *AuthHandler) ListTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := h.service.ListPersonalAccessTokens(r.Context(), middleware.UserID(r.Context()))
+	if writeError(w, r, err) {
+		return
+	}
+
+	res := make([]tokenInfoResponse, 0, len(tokens))
+	for _, t := range tokens {
+		res = append(res, newTokenInfoResponse(t))
+	}
+	httpx.JSON(w, http.StatusOK, res)
+}
+
+func (h *AuthHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	err := h.service.RevokePersonalAccessToken(r.Context(), middleware.UserID(r.Context()), r.PathValue("id"))
+	if writeError(w, r, err) {
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, map[string]string{"message": "token revoked"})
+}
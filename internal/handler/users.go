@@ -2,35 +2,118 @@ package handler
 
 import (
 	"net/http"
+	"time"
 
 	"task-flow/internal/httpx"
 	"task-flow/internal/middleware"
-	"task-flow/internal/repository"
+	"task-flow/internal/model"
+	"task-flow/internal/service/account"
 )
 
 type UserHandler struct {
-	Repo repository.UserRepo
+	service *account.Service
 }
 
-func NewUserHandler(repo repository.UserRepo) *UserHandler {
-	return &UserHandler{Repo: repo}
+func NewUserHandler(service *account.Service) *UserHandler {
+	return &UserHandler{service: service}
+}
+
+type userResponse struct {
+	ID           string     `json:"id"`
+	Email        string     `json:"email"`
+	Role         string     `json:"role"`
+	DisplayName  string     `json:"display_name"`
+	AvatarURL    string     `json:"avatar_url"`
+	Timezone     string     `json:"timezone"`
+	Locale       string     `json:"locale"`
+	PendingEmail string     `json:"pending_email,omitempty"`
+	VerifiedAt   *time.Time `json:"verified_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func newUserResponse(u model.User) userResponse {
+	return userResponse{
+		ID:           u.ID,
+		Email:        u.Email,
+		Role:         string(u.Role),
+		DisplayName:  u.DisplayName,
+		AvatarURL:    u.AvatarURL,
+		Timezone:     u.Timezone,
+		Locale:       u.Locale,
+		PendingEmail: u.PendingEmail,
+		VerifiedAt:   u.VerifiedAt,
+		CreatedAt:    u.CreatedAt,
+	}
 }
 
 func (h *UserHandler) Me(w http.ResponseWriter, r *http.Request) {
-	userID := middleware.UserID(r.Context())
-	u, ok, err := h.Repo.FindByID(r.Context(), userID)
-	if err != nil {
-		httpx.Error(w, http.StatusInternalServerError, "server error")
+	u, err := h.service.Profile(r.Context(), middleware.UserID(r.Context()))
+	if writeError(w, r, err) {
 		return
 	}
 
-	if !ok {
-		httpx.Error(w, http.StatusNotFound, "user not found")
+	httpx.JSON(w, http.StatusOK, newUserResponse(u))
+}
+
+type updateProfileRequest struct {
+	DisplayName *string `json:"display_name" validate:"max=100"`
+	AvatarURL   *string `json:"avatar_url" validate:"max=500"`
+	Timezone    *string `json:"timezone" validate:"max=64"`
+	Locale      *string `json:"locale" validate:"max=35"`
+}
+
+func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	var req updateProfileRequest
+	if !httpx.DecodeJSON(w, r, &req) {
 		return
 	}
 
-	httpx.JSON(w, http.StatusOK, map[string]string{
-		"id":    u.ID,
-		"email": u.Email,
+	u, err := h.service.UpdateProfile(r.Context(), middleware.UserID(r.Context()), account.ProfileUpdate{
+		DisplayName: req.DisplayName,
+		AvatarURL:   req.AvatarURL,
+		Timezone:    req.Timezone,
+		Locale:      req.Locale,
 	})
+	if writeError(w, r, err) {
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, newUserResponse(u))
+}
+
+// writeExport sends the archive as a file download.
+func writeExport(w http.ResponseWriter, export account.Export) {
+	filename := "task-flow-" + export.Profile.ID + "-" + export.ExportedAt.Format("20060102T150405Z") + ".json"
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("Cache-Control", "no-store")
+	httpx.JSON(w, http.StatusOK, export)
+}
+
+func (h *UserHandler) Export(w http.ResponseWriter, r *http.Request) {
+	export, err := h.service.Export(r.Context(), middleware.UserID(r.Context()))
+	if writeError(w, r, err) {
+		return
+	}
+
+	writeExport(w, export)
+}
+
+type deleteAccountRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// DeleteMe deletes the account and answers with its data export, which is
+// the last chance to download it.
+func (h *UserHandler) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	var req deleteAccountRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	export, err := h.service.Delete(r.Context(), middleware.UserID(r.Context()), req.Password)
+	if writeError(w, r, err) {
+		return
+	}
+
+	writeExport(w, export)
 }
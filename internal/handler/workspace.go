@@ -0,0 +1,382 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"task-flow/internal/apperr"
+	"task-flow/internal/httpx"
+	"task-flow/internal/middleware"
+	"task-flow/internal/model"
+	"task-flow/internal/service/workspace"
+)
+
+type WorkspaceHandler struct {
+	service *workspace.Service
+}
+
+func NewWorkspaceHandler(service *workspace.Service) *WorkspaceHandler {
+	return &WorkspaceHandler{service: service}
+}
+
+type workspaceResponse struct {
+	ID        string              `json:"id"`
+	Name      string              `json:"name"`
+	Role      model.WorkspaceRole `json:"role,omitempty"`
+	CreatedAt time.Time           `json:"created_at"`
+}
+
+type memberResponse struct {
+	UserID    string              `json:"user_id"`
+	Email     string              `json:"email"`
+	Role      model.WorkspaceRole `json:"role"`
+	CreatedAt time.Time           `json:"created_at"`
+}
+
+type projectResponse struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func newMemberResponse(m model.WorkspaceMember) memberResponse {
+	return memberResponse{UserID: m.UserID, Email: m.Email, Role: m.Role, CreatedAt: m.CreatedAt}
+}
+
+func newProjectResponse(p model.Project) projectResponse {
+	return projectResponse{ID: p.ID, Name: p.Name, Description: p.Description, CreatedAt: p.CreatedAt}
+}
+
+type workspaceRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+func (h *WorkspaceHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req workspaceRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	ws, err := h.service.Create(r.Context(), middleware.UserID(r.Context()), req.Name)
+	if writeError(w, r, err) {
+		return
+	}
+	httpx.JSON(w, http.StatusCreated, workspaceResponse{
+		ID:        ws.ID,
+		Name:      ws.Name,
+		Role:      model.WorkspaceRoleOwner,
+		CreatedAt: time.Now(),
+	})
+}
+
+func (h *WorkspaceHandler) List(w http.ResponseWriter, r *http.Request) {
+	memberships, err := h.service.List(r.Context(), middleware.UserID(r.Context()))
+	if writeError(w, r, err) {
+		return
+	}
+
+	res := make([]workspaceResponse, 0, len(memberships))
+	for _, m := range memberships {
+		res = append(res, workspaceResponse{ID: m.ID, Name: m.Name, Role: m.Role, CreatedAt: m.CreatedAt})
+	}
+	httpx.JSON(w, http.StatusOK, res)
+}
+
+func (h *WorkspaceHandler) Get(w http.ResponseWriter, r *http.Request) {
+	ws, err := h.service.Get(r.Context())
+	if writeError(w, r, err) {
+		return
+	}
+	httpx.JSON(w, http.StatusOK, workspaceResponse{
+		ID:        ws.ID,
+		Name:      ws.Name,
+		Role:      middleware.WorkspaceRole(r.Context()),
+		CreatedAt: ws.CreatedAt,
+	})
+}
+
+func (h *WorkspaceHandler) Rename(w http.ResponseWriter, r *http.Request) {
+	var req workspaceRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	err := h.service.Rename(r.Context(), middleware.WorkspaceRole(r.Context()), req.Name)
+	if writeError(w, r, err) {
+		return
+	}
+	httpx.JSON(w, http.StatusOK, map[string]string{"message": "workspace updated"})
+}
+
+func (h *WorkspaceHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	members, err := h.service.ListMembers(r.Context())
+	if writeError(w, r, err) {
+		return
+	}
+
+	res := make([]memberResponse, 0, len(members))
+	for _, m := range members {
+		res = append(res, newMemberResponse(m))
+	}
+	httpx.JSON(w, http.StatusOK, res)
+}
+
+type addMemberRequest struct {
+	Email string              `json:"email" validate:"required,email"`
+	Role  model.WorkspaceRole `json:"role" validate:"required,oneof=owner admin member viewer"`
+}
+
+func (h *WorkspaceHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	var req addMemberRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	member, err := h.service.AddMember(r.Context(), middleware.WorkspaceRole(r.Context()), req.Email, req.Role)
+	if writeError(w, r, err) {
+		return
+	}
+	httpx.JSON(w, http.StatusCreated, newMemberResponse(member))
+}
+
+type memberRoleRequest struct {
+	Role model.WorkspaceRole `json:"role" validate:"required,oneof=owner admin member viewer"`
+}
+
+func (h *WorkspaceHandler) ChangeMemberRole(w http.ResponseWriter, r *http.Request) {
+	var req memberRoleRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	err := h.service.ChangeRole(r.Context(), middleware.WorkspaceRole(r.Context()), r.PathValue("user_id"), req.Role)
+	if writeError(w, r, err) {
+		return
+	}
+	httpx.JSON(w, http.StatusOK, map[string]string{"message": "role updated"})
+}
+
+func (h *WorkspaceHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	err := h.service.RemoveMember(ctx, middleware.UserID(ctx), middleware.WorkspaceRole(ctx), r.PathValue("user_id"))
+	if writeError(w, r, err) {
+		return
+	}
+	httpx.JSON(w, http.StatusOK, map[string]string{"message": "member removed"})
+}
+
+type projectRequest struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description" validate:"max=255"`
+}
+
+func (h *WorkspaceHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
+	var req projectRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	project, err := h.service.CreateProject(r.Context(), middleware.WorkspaceRole(r.Context()), req.Name, req.Description)
+	if writeError(w, r, err) {
+		return
+	}
+	project.CreatedAt = time.Now()
+	httpx.JSON(w, http.StatusCreated, newProjectResponse(project))
+}
+
+func (h *WorkspaceHandler) ListProjects(w http.ResponseWriter, r *http.Request) {
+	projects, err := h.service.ListProjects(r.Context())
+	if writeError(w, r, err) {
+		return
+	}
+
+	res := make([]projectResponse, 0, len(projects))
+	for _, p := range projects {
+		res = append(res, newProjectResponse(p))
+	}
+	httpx.JSON(w, http.StatusOK, res)
+}
+
+func (h *WorkspaceHandler) GetProject(w http.ResponseWriter, r *http.Request) {
+	project, err := h.service.GetProject(r.Context(), r.PathValue("id"))
+	if writeError(w, r, err) {
+		return
+	}
+	httpx.JSON(w, http.StatusOK, newProjectResponse(project))
+}
+
+func (h *WorkspaceHandler) DeleteProject(w http.ResponseWriter, r *http.Request) {
+	err := h.service.DeleteProject(r.Context(), middleware.WorkspaceRole(r.Context()), r.PathValue("id"))
+	if writeError(w, r, err) {
+		return
+	}
+	httpx.JSON(w, http.StatusOK, map[string]string{"message": "project deleted"})
+}
+
+func (h *WorkspaceHandler) ListProjectShares(w http.ResponseWriter, r *http.Request) {
+	shares, err := h.service.ListProjectShares(r.Context(), r.PathValue("id"))
+	if writeError(w, r, err) {
+		return
+	}
+
+	res := make([]shareResponse, 0, len(shares))
+	for _, s := range shares {
+		res = append(res, newShareResponse(s))
+	}
+	httpx.JSON(w, http.StatusOK, res)
+}
+
+func (h *WorkspaceHandler) ShareProject(w http.ResponseWriter, r *http.Request) {
+	var req shareRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	ctx := r.Context()
+	share, err := h.service.ShareProject(ctx, middleware.UserID(ctx), middleware.WorkspaceRole(ctx), r.PathValue("id"), req.Email, req.Level)
+	if writeError(w, r, err) {
+		return
+	}
+	httpx.JSON(w, http.StatusOK, newShareResponse(share))
+}
+
+func (h *WorkspaceHandler) UnshareProject(w http.ResponseWriter, r *http.Request) {
+	err := h.service.UnshareProject(r.Context(), middleware.WorkspaceRole(r.Context()), r.PathValue("id"), r.PathValue("user_id"))
+	if writeError(w, r, err) {
+		return
+	}
+	httpx.JSON(w, http.StatusOK, map[string]string{"message": "share removed"})
+}
+
+type inviteRequest struct {
+	Email string              `json:"email" validate:"required,email,max=255"`
+	Role  model.WorkspaceRole `json:"role" validate:"required,oneof=owner admin member viewer"`
+}
+
+type invitationResponse struct {
+	ID        string              `json:"id"`
+	Email     string              `json:"email"`
+	Role      model.WorkspaceRole `json:"role"`
+	InvitedBy string              `json:"invited_by,omitempty"`
+	ExpiresAt time.Time           `json:"expires_at"`
+	CreatedAt time.Time           `json:"created_at"`
+}
+
+func newInvitationResponse(inv model.WorkspaceInvitation) invitationResponse {
+	return invitationResponse{
+		ID:        inv.ID,
+		Email:     inv.Email,
+		Role:      inv.Role,
+		InvitedBy: inv.InvitedBy,
+		ExpiresAt: inv.ExpiresAt,
+		CreatedAt: inv.CreatedAt,
+	}
+}
+
+var errInvitationNotFound = apperr.NotFound("invitation_not_found", "invitation not found")
+
+// Invite emails the invitation link; the token itself is never returned.
+func (h *WorkspaceHandler) Invite(w http.ResponseWriter, r *http.Request) {
+	var req inviteRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	ctx := r.Context()
+	inv, err := h.service.Invite(ctx, middleware.UserID(ctx), middleware.WorkspaceRole(ctx), req.Email, req.Role)
+	if writeError(w, r, err) {
+		return
+	}
+	httpx.JSON(w, http.StatusCreated, newInvitationResponse(inv))
+}
+
+func (h *WorkspaceHandler) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	invitations, err := h.service.ListInvitations(r.Context(), middleware.WorkspaceRole(r.Context()))
+	if writeError(w, r, err) {
+		return
+	}
+
+	res := make([]invitationResponse, 0, len(invitations))
+	for _, inv := range invitations {
+		res = append(res, newInvitationResponse(inv))
+	}
+	httpx.JSON(w, http.StatusOK, res)
+}
+
+func (h *WorkspaceHandler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	err := h.service.RevokeInvitation(r.Context(), middleware.WorkspaceRole(r.Context()), r.PathValue("id"))
+	if errors.Is(err, workspace.ErrInvalidInvitation) {
+		err = errInvitationNotFound
+	}
+	if writeError(w, r, err) {
+		return
+	}
+	httpx.JSON(w, http.StatusOK, map[string]string{"message": "invitation revoked"})
+}
+
+type invitationTokenRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password,omitempty"`
+}
+
+type invitationLookupResponse struct {
+	WorkspaceName string              `json:"workspace_name"`
+	Email         string              `json:"email"`
+	Role          model.WorkspaceRole `json:"role"`
+	ExpiresAt     time.Time           `json:"expires_at"`
+	// AccountExists tells the frontend whether to offer login or sign-up.
+	AccountExists bool `json:"account_exists"`
+}
+
+func (h *WorkspaceHandler) LookupInvitation(w http.ResponseWriter, r *http.Request) {
+	var req invitationTokenRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	inv, err := h.service.LookupInvitation(r.Context(), req.Token)
+	if writeError(w, r, err) {
+		return
+	}
+	httpx.JSON(w, http.StatusOK, invitationLookupResponse{
+		WorkspaceName: inv.WorkspaceName,
+		Email:         inv.Email,
+		Role:          inv.Role,
+		ExpiresAt:     inv.ExpiresAt,
+		AccountExists: inv.AccountExists,
+	})
+}
+
+func writeMembership(w http.ResponseWriter, status int, m model.WorkspaceMembership) {
+	httpx.JSON(w, status, workspaceResponse{ID: m.ID, Name: m.Name, Role: m.Role, CreatedAt: m.CreatedAt})
+}
+
+func (h *WorkspaceHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	var req invitationTokenRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	membership, err := h.service.AcceptInvitation(r.Context(), middleware.UserID(r.Context()), req.Token)
+	if writeError(w, r, err) {
+		return
+	}
+	writeMembership(w, http.StatusOK, membership)
+}
+
+// RegisterWithInvitation signs up the invitee; they log in afterwards as
+// usual.
+func (h *WorkspaceHandler) RegisterWithInvitation(w http.ResponseWriter, r *http.Request) {
+	var req invitationTokenRequest
+	if !httpx.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	membership, err := h.service.RegisterWithInvitation(r.Context(), req.Token, req.Password)
+	if writeAuthError(w, r, err) {
+		return
+	}
+	writeMembership(w, http.StatusCreated, membership)
+}
@@ -0,0 +1,111 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"task-flow/internal/apperr"
+	"task-flow/internal/logging"
+	"task-flow/internal/requestid"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 9457 problem details body. Code is the stable,
+// machine-readable identifier clients should switch on.
+type Problem struct {
+	Type   string
+	Title  string
+	Status int
+	Detail string
+	Code   string
+	// Extensions become extra top-level members.
+	Extensions map[string]any
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	m := make(map[string]any, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	m["type"] = p.Type
+	m["title"] = p.Title
+	m["status"] = p.Status
+	m["code"] = p.Code
+	if p.Detail != "" {
+		m["detail"] = p.Detail
+	}
+	return json.Marshal(m)
+}
+
+// WriteProblem writes p, adding the request ID so a client reporting the
+// error can point at the matching log lines.
+func WriteProblem(w http.ResponseWriter, r *http.Request, p Problem) {
+	if id := requestid.FromContext(r.Context()); id != "" {
+		ext := make(map[string]any, len(p.Extensions)+1)
+		for k, v := range p.Extensions {
+			ext[k] = v
+		}
+		ext["request_id"] = id
+		p.Extensions = ext
+	}
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+var kindStatus = map[apperr.Kind]int{
+	apperr.KindValidation:    http.StatusBadRequest,
+	apperr.KindUnauthorized:  http.StatusUnauthorized,
+	apperr.KindForbidden:     http.StatusForbidden,
+	apperr.KindNotFound:      http.StatusNotFound,
+	apperr.KindConflict:      http.StatusConflict,
+	apperr.KindUnprocessable: http.StatusUnprocessableEntity,
+	apperr.KindTooLarge:      http.StatusRequestEntityTooLarge,
+	apperr.KindRateLimited:   http.StatusTooManyRequests,
+	apperr.KindUnavailable:   http.StatusBadGateway,
+	apperr.KindTimeout:       http.StatusServiceUnavailable,
+}
+
+var errRequestTimeout = apperr.Timeout("request_timeout", "the request took too long and was abandoned")
+
+// WriteError is the single place errors become responses. An *apperr.Error
+// is answered with its kind's status and its message; anything else is
+// logged and answered with a bare 500, so internals never reach clients.
+// Code that wraps an *apperr.Error must keep the message fit for clients.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	// Only the request's own deadline; an upstream's timeout is its error
+	if errors.Is(err, context.DeadlineExceeded) && errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		err = errRequestTimeout
+	}
+
+	var status int
+	e, ok := apperr.As(err)
+	if ok {
+		status = kindStatus[e.Kind]
+	}
+	if status == 0 {
+		logging.FromContext(r.Context()).Error("request failed", "method", r.Method, "path", r.URL.Path, "err", err)
+		WriteProblem(w, r, Problem{
+			Status: http.StatusInternalServerError,
+			Detail: "an internal error occurred",
+			Code:   "internal_error",
+		})
+		return
+	}
+
+	WriteProblem(w, r, Problem{
+		Status:     status,
+		Detail:     err.Error(),
+		Code:       e.Code,
+		Extensions: e.Details,
+	})
+}
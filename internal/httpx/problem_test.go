@@ -0,0 +1,102 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"task-flow/internal/apperr"
+	"task-flow/internal/requestid"
+)
+
+func writeErrorBody(t *testing.T, err error) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	WriteError(w, httptest.NewRequest(http.MethodGet, "/tasks", nil), err)
+
+	if ct := w.Header().Get("Content-Type"); ct != ProblemContentType {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	var body map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return w, body
+}
+
+func TestWriteError_Typed(t *testing.T) {
+	errMissing := apperr.NotFound("task_not_found", "task not found")
+
+	w, body := writeErrorBody(t, fmt.Errorf("%w (abc)", errMissing))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d", w.Code)
+	}
+	if body["code"] != "task_not_found" || body["status"] != float64(404) || body["title"] != "Not Found" {
+		t.Errorf("body = %v", body)
+	}
+	if body["detail"] != "task not found (abc)" {
+		t.Errorf("detail = %v", body["detail"])
+	}
+}
+
+func TestWriteError_Details(t *testing.T) {
+	base := apperr.Unprocessable("password_policy", "password does not meet policy")
+
+	_, body := writeErrorBody(t, base.With("violations", []string{"min_length"}))
+	if v, _ := body["violations"].([]any); len(v) != 1 {
+		t.Errorf("violations = %v", body["violations"])
+	}
+	if base.Details != nil {
+		t.Error("With modified the sentinel")
+	}
+}
+
+func TestWriteError_InternalNotEchoed(t *testing.T) {
+	w, body := writeErrorBody(t, errors.New("dial tcp 10.0.0.5:3306: connection refused"))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d", w.Code)
+	}
+	if body["code"] != "internal_error" {
+		t.Errorf("code = %v", body["code"])
+	}
+	if strings.Contains(fmt.Sprint(body), "10.0.0.5") {
+		t.Errorf("internal error leaked: %v", body)
+	}
+}
+
+func TestWriteError_RequestID(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+	r = r.WithContext(requestid.With(r.Context(), "req-123"))
+	w := httptest.NewRecorder()
+	WriteError(w, r, apperr.NotFound("task_not_found", "task not found"))
+
+	var body map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body["request_id"] != "req-123" {
+		t.Errorf("request_id = %v", body["request_id"])
+	}
+}
+
+func TestWriteError_RequestTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	r := httptest.NewRequestWithContext(ctx, http.MethodGet, "/tasks", nil)
+	w := httptest.NewRecorder()
+	WriteError(w, r, fmt.Errorf("list tasks: %w", ctx.Err()))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d", w.Code)
+	}
+
+	// A deadline that isn't the request's is an ordinary failure
+	w, _ = writeErrorBody(t, context.DeadlineExceeded)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d", w.Code)
+	}
+}
@@ -0,0 +1,46 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTrustedProxies_ClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		peer   string
+		header string
+		want   string
+	}{
+		{"direct", "203.0.113.9:1234", "", "203.0.113.9"},
+		{"untrusted peer ignores header", "203.0.113.9:1234", "1.2.3.4", "203.0.113.9"},
+		{"one proxy", "10.0.0.2:80", "198.51.100.7", "198.51.100.7"},
+		{"proxy chain", "10.0.0.2:80", "198.51.100.7, 192.168.1.1", "198.51.100.7"},
+		{"spoofed entry", "10.0.0.2:80", "1.2.3.4, 198.51.100.7", "198.51.100.7"},
+		{"garbage", "10.0.0.2:80", "nonsense, 198.51.100.7", "198.51.100.7"},
+		{"only proxies", "10.0.0.2:80", "10.1.1.1", "10.1.1.1"},
+		{"no header", "10.0.0.2:80", "", "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.peer
+			if tt.header != "" {
+				r.Header.Set("X-Forwarded-For", tt.header)
+			}
+			if got := proxies.ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("accepted an invalid range")
+	}
+}
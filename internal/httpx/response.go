@@ -2,7 +2,29 @@ package httpx
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"task-flow/internal/apperr"
+	"task-flow/internal/validate"
+)
+
+// MaxBodyBytes caps JSON request bodies.
+const MaxBodyBytes = 1 << 20
+
+var (
+	errEmptyBody     = apperr.Validation("empty_body", "request body is empty")
+	errMalformedJSON = apperr.Validation("malformed_json", "malformed JSON")
+	errTrailingData  = apperr.Validation("malformed_json", "unexpected data after the JSON body")
+	errUnknownField  = apperr.Validation("unknown_field", "unknown field")
+	errInvalidValue  = apperr.Validation("invalid_value", "a field has a value of the wrong format")
+	errTypeMismatch  = apperr.Validation("type_mismatch", "wrong JSON type")
+	errBodyTooLarge  = apperr.TooLarge("body_too_large", "request body must be at most "+strconv.Itoa(MaxBodyBytes)+" bytes")
 )
 
 func JSON(w http.ResponseWriter, status int, v any) {
@@ -11,20 +33,97 @@ func JSON(w http.ResponseWriter, status int, v any) {
 	_ = json.NewEncoder(w).Encode(v)
 }
 
+// DecodeJSON reads one JSON object into dst and checks it with
+// validate.Struct. On failure the problem response is written and it
+// returns false.
 func DecodeJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
-	r.Body = http.MaxBytesReader(w, r.Body, 1 << 20)
+	r.Body = http.MaxBytesReader(w, r.Body, MaxBodyBytes)
 	dec := json.NewDecoder(r.Body)
 	dec.DisallowUnknownFields()
 
-	if err := dec.Decode(dst); err != nil {
-		Error(w, http.StatusBadRequest, "invalid json")
-		
+	err := dec.Decode(dst)
+	if err == nil && dec.Decode(&struct{}{}) != io.EOF {
+		err = errTrailingData
+	}
+	if err == nil {
+		err = validate.Struct(dst)
+	}
+	if err != nil {
+		WriteError(w, r, decodeError(err))
 		return false
 	}
 
 	return true
 }
 
-func Error(w http.ResponseWriter, status int, msg string) {
-	JSON(w, status, map[string] string {"error": msg})
-}
\ No newline at end of file
+// decodeError tells apart the ways a body can fail to decode, so clients
+// learn which field to fix instead of just "invalid json".
+func decodeError(err error) error {
+	if _, ok := apperr.As(err); ok {
+		return err
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var tooLarge *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &tooLarge):
+		return errBodyTooLarge
+	case errors.Is(err, io.EOF):
+		return errEmptyBody
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return fmt.Errorf("%w: unexpected end of input", errMalformedJSON)
+	case errors.As(err, &syntaxErr):
+		return fmt.Errorf("%w at offset %d", errMalformedJSON, syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		pointer := validate.Pointer(typeErr.Field)
+		detail := fieldName(typeErr.Field) + " must be " + jsonType(typeErr.Type)
+		return fmt.Errorf("%w: %s", errTypeMismatch.With("errors", []validate.Violation{
+			{Pointer: pointer, Rule: "type", Detail: detail},
+		}), detail)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no type for this one
+		name, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		detail := strconv.Quote(name) + " is not accepted"
+		return fmt.Errorf("%w: %s", errUnknownField.With("errors", []validate.Violation{
+			{Pointer: validate.Pointer(name), Rule: "unknown", Detail: detail},
+		}), detail)
+	}
+	// Anything else is a value a field's UnmarshalJSON rejected, such as a
+	// malformed time; its message names Go types, so it stays out
+	return errInvalidValue
+}
+
+func fieldName(path string) string {
+	if i := strings.LastIndexByte(path, '.'); i >= 0 {
+		return path[i+1:]
+	}
+	if path == "" {
+		return "body"
+	}
+	return path
+}
+
+// jsonType names what a Go type looks like in JSON.
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	}
+	return "a different type"
+}
@@ -0,0 +1,92 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type decodeTarget struct {
+	Title string `json:"title" validate:"required,max=10"`
+	Count int    `json:"count" validate:"min=1"`
+}
+
+func decode(t *testing.T, body string) (bool, *httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(body))
+
+	var dst decodeTarget
+	ok := DecodeJSON(w, r, &dst)
+	if ok {
+		return true, w, nil
+	}
+	var problem map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+		t.Fatal(err)
+	}
+	return false, w, problem
+}
+
+func TestDecodeJSON_Valid(t *testing.T) {
+	if ok, w, _ := decode(t, `{"title":"buy milk","count":2}`); !ok {
+		t.Fatalf("rejected valid body: %s", w.Body)
+	}
+}
+
+func TestDecodeJSON_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		code    string
+		pointer string
+	}{
+		{"empty", ``, http.StatusBadRequest, "empty_body", ""},
+		{"malformed", `{"title":`, http.StatusBadRequest, "malformed_json", ""},
+		{"syntax", `{"title" "x"}`, http.StatusBadRequest, "malformed_json", ""},
+		{"trailing", `{"title":"a","count":1} {}`, http.StatusBadRequest, "malformed_json", ""},
+		{"unknown field", `{"title":"a","count":1,"owner":"x"}`, http.StatusBadRequest, "unknown_field", "/owner"},
+		{"type mismatch", `{"title":"a","count":"two"}`, http.StatusBadRequest, "type_mismatch", "/count"},
+		{"validation", `{"title":"far too long a title","count":1}`, http.StatusBadRequest, "invalid_request", "/title"},
+		{"too large", `{"title":"` + strings.Repeat("a", MaxBodyBytes) + `"}`, http.StatusRequestEntityTooLarge, "body_too_large", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, w, problem := decode(t, tt.body)
+			if ok {
+				t.Fatal("expected rejection")
+			}
+			if w.Code != tt.status || problem["code"] != tt.code {
+				t.Fatalf("status = %d, code = %v", w.Code, problem["code"])
+			}
+			if tt.pointer == "" {
+				return
+			}
+			errs, _ := problem["errors"].([]any)
+			if len(errs) != 1 {
+				t.Fatalf("errors = %v", problem["errors"])
+			}
+			if got := errs[0].(map[string]any)["pointer"]; got != tt.pointer {
+				t.Errorf("pointer = %v, want %s", got, tt.pointer)
+			}
+		})
+	}
+}
+
+func TestDecodeJSON_ReportsAllViolations(t *testing.T) {
+	_, _, problem := decode(t, `{"title":"","count":0}`)
+	errs, _ := problem["errors"].([]any)
+	// count is zero and so treated as not sent; only title is reported
+	if len(errs) != 1 {
+		t.Errorf("errors = %v", problem["errors"])
+	}
+
+	_, _, problem = decode(t, `{"title":"far too long a title","count":-1}`)
+	if errs, _ := problem["errors"].([]any); len(errs) != 2 {
+		t.Errorf("errors = %v", problem["errors"])
+	}
+}
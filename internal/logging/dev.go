@@ -0,0 +1,159 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ANSI colors
+const (
+	reset  = "\033[0m"
+	green  = "\033[32m"
+	yellow = "\033[33m"
+	red    = "\033[31m"
+	cyan   = "\033[36m"
+	gray   = "\033[90m"
+)
+
+// DevHandler writes one colored line per record, meant for a terminal:
+//
+//	15:04:05.000 INFO  request method=GET route="GET /tasks" status=200
+//
+// It is not meant to be parsed; use the JSON or text handler for that.
+type DevHandler struct {
+	w     io.Writer
+	mu    *sync.Mutex
+	level slog.Leveler
+	// attrs are the WithAttrs attributes, already formatted
+	attrs []byte
+	group string
+}
+
+func NewDevHandler(w io.Writer, opts *slog.HandlerOptions) *DevHandler {
+	h := &DevHandler{w: w, mu: &sync.Mutex{}, level: slog.LevelInfo}
+	if opts != nil && opts.Level != nil {
+		h.level = opts.Level
+	}
+	return h
+}
+
+func (h *DevHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *DevHandler) Handle(_ context.Context, r slog.Record) error {
+	buf := make([]byte, 0, 256)
+	if !r.Time.IsZero() {
+		buf = append(buf, gray...)
+		buf = r.Time.AppendFormat(buf, "15:04:05.000")
+		buf = append(buf, reset...)
+		buf = append(buf, ' ')
+	}
+	buf = append(buf, levelColor(r.Level)...)
+	buf = append(buf, padLevel(r.Level)...)
+	buf = append(buf, reset...)
+	buf = append(buf, ' ')
+	buf = append(buf, r.Message...)
+	buf = append(buf, h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		buf = appendAttr(buf, h.group, a)
+		return true
+	})
+	buf = append(buf, '\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf)
+	return err
+}
+
+func (h *DevHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.attrs = slices.Clip(c.attrs)
+	for _, a := range attrs {
+		c.attrs = appendAttr(c.attrs, h.group, a)
+	}
+	return &c
+}
+
+func (h *DevHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.group += name + "."
+	return &c
+}
+
+func appendAttr(buf []byte, prefix string, a slog.Attr) []byte {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return buf
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			buf = appendAttr(buf, prefix, ga)
+		}
+		return buf
+	}
+
+	buf = append(buf, ' ')
+	buf = append(buf, gray...)
+	buf = append(buf, prefix...)
+	buf = append(buf, a.Key...)
+	buf = append(buf, '=')
+	buf = append(buf, reset...)
+
+	val := a.Value.String()
+	if val == "" || strings.ContainsAny(val, " \"=\n") {
+		val = strconv.Quote(val)
+	}
+	if a.Key == "status" && a.Value.Kind() == slog.KindInt64 {
+		buf = append(buf, statusColor(a.Value.Int64())...)
+		buf = append(buf, val...)
+		return append(buf, reset...)
+	}
+	return append(buf, val...)
+}
+
+func padLevel(l slog.Level) string {
+	s := l.String()
+	if len(s) < 5 {
+		s += strings.Repeat(" ", 5-len(s))
+	}
+	return s
+}
+
+func levelColor(l slog.Level) string {
+	switch {
+	case l >= slog.LevelError:
+		return red
+	case l >= slog.LevelWarn:
+		return yellow
+	case l >= slog.LevelInfo:
+		return cyan
+	default:
+		return gray
+	}
+}
+
+func statusColor(code int64) string {
+	switch {
+	case code >= 200 && code < 300:
+		return green
+	case code >= 300 && code < 400:
+		return cyan
+	case code >= 400 && code < 500:
+		return yellow
+	default:
+		return red
+	}
+}
@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(&buf, "json", "info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Debug("hidden")
+	l.Info("request", "status", 200)
+
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("not one JSON record: %q", buf.String())
+	}
+	if rec["msg"] != "request" || rec["status"] != float64(200) {
+		t.Errorf("record = %v", rec)
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "xml", "info"); err == nil {
+		t.Error("unknown format accepted")
+	}
+	if _, err := New(&bytes.Buffer{}, "json", "loud"); err == nil {
+		t.Error("unknown level accepted")
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Error("empty context should give the default logger")
+	}
+
+	var buf bytes.Buffer
+	base := slog.New(slog.NewTextHandler(&buf, nil))
+	ctx := With(WithLogger(context.Background(), base), "request_id", "abc")
+	FromContext(ctx).Info("hello")
+
+	if !strings.Contains(buf.String(), "request_id=abc") {
+		t.Errorf("output = %q", buf.String())
+	}
+}
+
+func TestDevHandler(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(NewDevHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	l.Info("dropped")
+	l.With("request_id", "abc").WithGroup("db").Warn("slow query",
+		"took", 1500*time.Millisecond, "sql", "SELECT 1", "status", 503)
+
+	out := buf.String()
+	if strings.Contains(out, "dropped") || strings.Count(out, "\n") != 1 {
+		t.Fatalf("output = %q", out)
+	}
+	for _, want := range []string{"WARN", "slow query", "request_id=", "abc", "db.took=", "1.5s", `"SELECT 1"`, red + "503"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output %q lacks %q", out, want)
+		}
+	}
+}
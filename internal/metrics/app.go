@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"database/sql"
+)
+
+// Default is the registry the application's metrics live in and the one
+// served at /metrics.
+var Default = NewRegistry()
+
+// HTTP metrics, recorded by middleware.Metrics and RateLimiter. route is the ServeMux
+// pattern, so paths with IDs don't each get their own series.
+var (
+	HTTPRequests = Default.NewCounter("http_requests_total",
+		"HTTP requests served, by route pattern and status.", "method", "route", "status")
+	HTTPDuration = Default.NewHistogram("http_request_duration_seconds",
+		"HTTP request latency, by route pattern and status.", DefBuckets, "method", "route", "status")
+	HTTPInFlight = Default.NewGauge("http_requests_in_flight",
+		"HTTP requests currently being served.")
+	RateLimited = Default.NewCounter("http_rate_limited_total",
+		"Requests rejected by rate limiting, by policy.", "policy")
+)
+
+// Auth outcomes. outcome is a short reason such as "success",
+// "invalid_credentials" or "locked".
+var (
+	Logins = Default.NewCounter("auth_logins_total",
+		"Password and second-factor login attempts, by step and outcome.", "step", "outcome")
+	Refreshes = Default.NewCounter("auth_refreshes_total",
+		"Refresh token exchanges, by outcome.", "outcome")
+	RefreshReuse = Default.NewCounter("auth_refresh_reuse_total",
+		"Rotated refresh tokens presented again, each ending the user's sessions.")
+)
+
+var (
+	TasksCreated   = Default.NewCounter("tasks_created_total", "Tasks created.")
+	TasksCompleted = Default.NewCounter("tasks_completed_total", "Tasks marked as done.")
+)
+
+// RegisterDBStats exposes db's connection pool statistics in r.
+func RegisterDBStats(r *Registry, db *sql.DB) {
+	r.NewGaugeFunc("db_max_open_connections", "Maximum number of open connections to the database.",
+		func() float64 { return float64(db.Stats().MaxOpenConnections) })
+	r.NewGaugeFunc("db_open_connections", "Established connections, both in use and idle.",
+		func() float64 { return float64(db.Stats().OpenConnections) })
+	r.NewGaugeFunc("db_in_use_connections", "Connections currently in use.",
+		func() float64 { return float64(db.Stats().InUse) })
+	r.NewGaugeFunc("db_idle_connections", "Idle connections.",
+		func() float64 { return float64(db.Stats().Idle) })
+	r.NewCounterFunc("db_wait_count_total", "Connections waited for.",
+		func() float64 { return float64(db.Stats().WaitCount) })
+	r.NewCounterFunc("db_wait_duration_seconds_total", "Time spent waiting for a connection.",
+		func() float64 { return db.Stats().WaitDuration.Seconds() })
+	r.NewCounterFunc("db_max_idle_closed_total", "Connections closed due to SetMaxIdleConns.",
+		func() float64 { return float64(db.Stats().MaxIdleClosed) })
+	r.NewCounterFunc("db_max_idle_time_closed_total", "Connections closed due to SetConnMaxIdleTime.",
+		func() float64 { return float64(db.Stats().MaxIdleTimeClosed) })
+	r.NewCounterFunc("db_max_lifetime_closed_total", "Connections closed due to SetConnMaxLifetime.",
+		func() float64 { return float64(db.Stats().MaxLifetimeClosed) })
+}
@@ -0,0 +1,312 @@
+// Package metrics keeps counters, gauges and histograms and exposes them in
+// the Prometheus text exposition format (version 0.0.4). It covers what
+// this service needs and nothing more; there is no client library to pull
+// in.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the media type of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefBuckets suit request latencies in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+// Registry holds metrics by name and writes them in registration order.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+	names   map[string]bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+func (r *Registry) register(name string, m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[name] {
+		panic("metrics: duplicate metric " + name)
+	}
+	r.names[name] = true
+	r.metrics = append(r.metrics, m)
+}
+
+// Write writes every metric to w.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	metrics := slices.Clone(r.metrics)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler serves the registry for scraping.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_ = r.Write(w)
+	})
+}
+
+// family is the state shared by metrics with labels: one series per
+// distinct combination of label values.
+type family struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	values []string
+	value  float64
+	// Histograms only; buckets are not cumulative here
+	buckets []uint64
+	sum     float64
+	count   uint64
+}
+
+func newFamily(name, help, typ string, labels []string) *family {
+	return &family{name: name, help: help, typ: typ, labels: labels, series: make(map[string]*series)}
+}
+
+// get returns the series for values; f.mu must be held.
+func (f *family) get(values []string) *series {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{values: slices.Clone(values)}
+		f.series[key] = s
+	}
+	return s
+}
+
+// sorted returns the series ordered by label values, so output is stable.
+func (f *family) sorted() []*series {
+	out := make([]*series, 0, len(f.series))
+	for _, s := range f.series {
+		out = append(out, s)
+	}
+	slices.SortFunc(out, func(a, b *series) int {
+		return slices.Compare(a.values, b.values)
+	})
+	return out
+}
+
+func (f *family) writeHeader(w *bufio.Writer) {
+	writeHeader(w, f.name, f.help, f.typ)
+}
+
+func (f *family) writeScalars(w *bufio.Writer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.writeHeader(w)
+	for _, s := range f.sorted() {
+		writeSample(w, f.name, f.labels, s.values, "", "", s.value)
+	}
+}
+
+// Counter only goes up. Create one with Registry.NewCounter.
+type Counter struct{ f *family }
+
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{f: newFamily(name, help, "counter", labels)}
+	r.register(name, c)
+	return c
+}
+
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add increases the counter by v, which must not be negative.
+func (c *Counter) Add(v float64, values ...string) {
+	if v < 0 {
+		panic("metrics: counter " + c.f.name + " cannot decrease")
+	}
+	c.f.mu.Lock()
+	c.f.get(values).value += v
+	c.f.mu.Unlock()
+}
+
+// Value returns the count for values, which is 0 until first added to.
+func (c *Counter) Value(values ...string) float64 {
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	if s, ok := c.f.series[strings.Join(values, "\xff")]; ok {
+		return s.value
+	}
+	return 0
+}
+
+func (c *Counter) write(w *bufio.Writer) { c.f.writeScalars(w) }
+
+// Gauge goes up and down. Create one with Registry.NewGauge.
+type Gauge struct{ f *family }
+
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{f: newFamily(name, help, "gauge", labels)}
+	r.register(name, g)
+	return g
+}
+
+func (g *Gauge) Inc(values ...string) { g.Add(1, values...) }
+func (g *Gauge) Dec(values ...string) { g.Add(-1, values...) }
+
+func (g *Gauge) Add(v float64, values ...string) {
+	g.f.mu.Lock()
+	g.f.get(values).value += v
+	g.f.mu.Unlock()
+}
+
+func (g *Gauge) Set(v float64, values ...string) {
+	g.f.mu.Lock()
+	g.f.get(values).value = v
+	g.f.mu.Unlock()
+}
+
+func (g *Gauge) write(w *bufio.Writer) { g.f.writeScalars(w) }
+
+// Histogram counts observations into buckets. Create one with
+// Registry.NewHistogram.
+type Histogram struct {
+	f      *family
+	bounds []float64
+}
+
+// NewHistogram registers a histogram with the given upper bucket bounds,
+// which must be sorted; the +Inf bucket is implied.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if !slices.IsSorted(buckets) {
+		panic("metrics: buckets of " + name + " are not sorted")
+	}
+	h := &Histogram{f: newFamily(name, help, "histogram", labels), bounds: slices.Clone(buckets)}
+	r.register(name, h)
+	return h
+}
+
+func (h *Histogram) Observe(v float64, values ...string) {
+	i, _ := slices.BinarySearch(h.bounds, v)
+
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+	s := h.f.get(values)
+	if s.buckets == nil {
+		s.buckets = make([]uint64, len(h.bounds)+1)
+	}
+	s.buckets[i]++
+	s.sum += v
+	s.count++
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+
+	h.f.writeHeader(w)
+	for _, s := range h.f.sorted() {
+		var cumulative uint64
+		for i, bound := range h.bounds {
+			cumulative += s.buckets[i]
+			writeSample(w, h.f.name+"_bucket", h.f.labels, s.values, "le", formatFloat(bound), float64(cumulative))
+		}
+		writeSample(w, h.f.name+"_bucket", h.f.labels, s.values, "le", "+Inf", float64(s.count))
+		writeSample(w, h.f.name+"_sum", h.f.labels, s.values, "", "", s.sum)
+		writeSample(w, h.f.name+"_count", h.f.labels, s.values, "", "", float64(s.count))
+	}
+}
+
+// funcMetric reads its value when scraped, for state owned elsewhere such
+// as a connection pool.
+type funcMetric struct {
+	name, help, typ string
+	fn              func() float64
+}
+
+// NewGaugeFunc registers a gauge whose value is fn's result at scrape time.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(name, &funcMetric{name: name, help: help, typ: "gauge", fn: fn})
+}
+
+// NewCounterFunc registers a counter whose value is fn's result at scrape
+// time; fn must never return less than before.
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(name, &funcMetric{name: name, help: help, typ: "counter", fn: fn})
+}
+
+func (m *funcMetric) write(w *bufio.Writer) {
+	writeHeader(w, m.name, m.help, m.typ)
+	writeSample(w, m.name, nil, nil, "", "", m.fn())
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func writeHeader(w *bufio.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, helpEscaper.Replace(help), name, typ)
+}
+
+// writeSample writes one line; extraName/extraValue add a label such as le.
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraName, extraValue string, v float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(l + `="` + labelEscaper.Replace(values[i]) + `"`)
+		}
+		if extraName != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extraName + `="` + extraValue + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func scrape(t *testing.T, r *Registry) string {
+	t.Helper()
+	var b strings.Builder
+	if err := r.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestCounter(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("logins_total", "Login attempts.\nBy outcome.", "outcome")
+	c.Inc("success")
+	c.Inc("success")
+	c.Add(3, `bad "quote"`)
+
+	want := `# HELP logins_total Login attempts.\nBy outcome.
+# TYPE logins_total counter
+logins_total{outcome="bad \"quote\""} 3
+logins_total{outcome="success"} 2
+`
+	if got := scrape(t, r); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCounter_Value(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("logins_total", "Login attempts.", "outcome")
+	c.Inc("success")
+
+	if got := c.Value("success"); got != 1 {
+		t.Errorf("expected 1, got %v", got)
+	}
+	if got := c.Value("locked"); got != 0 {
+		t.Errorf("expected 0 for an unseen series, got %v", got)
+	}
+	if strings.Contains(scrape(t, r), "locked") {
+		t.Error("expected Value not to create a series")
+	}
+}
+
+func TestGauge(t *testing.T) {
+	r := NewRegistry()
+	g := r.NewGauge("in_flight", "In flight.")
+	g.Inc()
+	g.Inc()
+	g.Dec()
+
+	if got := scrape(t, r); !strings.Contains(got, "\nin_flight 1\n") {
+		t.Errorf("got:\n%s", got)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	h.Observe(0.05, "GET /tasks")
+	h.Observe(0.1, "GET /tasks")
+	h.Observe(0.5, "GET /tasks")
+	h.Observe(7, "GET /tasks")
+
+	want := `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="GET /tasks",le="0.1"} 2
+latency_seconds_bucket{route="GET /tasks",le="1"} 3
+latency_seconds_bucket{route="GET /tasks",le="+Inf"} 4
+latency_seconds_sum{route="GET /tasks"} 7.65
+latency_seconds_count{route="GET /tasks"} 4
+`
+	if got := scrape(t, r); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFuncMetrics(t *testing.T) {
+	r := NewRegistry()
+	n := 0.0
+	r.NewGaugeFunc("pool_idle", "Idle.", func() float64 { return n })
+	n = 4
+
+	if got := scrape(t, r); !strings.Contains(got, "# TYPE pool_idle gauge\npool_idle 4\n") {
+		t.Errorf("got:\n%s", got)
+	}
+}
+
+func TestRegistry_Duplicate(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("x_total", "X.")
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	r.NewGauge("x_total", "X again.")
+}
+
+func TestCounter_WrongLabelCount(t *testing.T) {
+	c := NewRegistry().NewCounter("x_total", "X.", "a", "b")
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	c.Inc("only-one")
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("x_total", "X.").Inc()
+
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := w.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(w.Body.String(), "x_total 1") {
+		t.Errorf("body = %q", w.Body.String())
+	}
+}
@@ -3,44 +3,171 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"slices"
 	"strings"
 
+	"task-flow/internal/apperr"
 	"task-flow/internal/httpx"
+	"task-flow/internal/model"
 	"task-flow/internal/pkg/jwt"
+	"task-flow/internal/repository"
 )
 
 type ctxKey string
 
+// PersonalTokenVerifier resolves personal access tokens. It is implemented
+// by the auth service, which answers unknown, expired and revoked tokens
+// with an unauthorized *apperr.Error.
+type PersonalTokenVerifier interface {
+	VerifyPersonalAccessToken(ctx context.Context, token string) (model.PersonalAccessToken, error)
+}
+
 type AuthMiddleware struct {
-	JWT *jwt.JWT
+	JWT      *jwt.JWT
+	Denylist repository.AccessTokenDenylist
+	PATs     PersonalTokenVerifier
+	// Users is consulted on every request so that disabled accounts and
+	// role changes take effect immediately.
+	Users repository.UserRepo
 }
 
-func NewAuthMiddleware(j *jwt.JWT) *AuthMiddleware {
+func NewAuthMiddleware(j *jwt.JWT, denylist repository.AccessTokenDenylist, pats PersonalTokenVerifier, users repository.UserRepo) *AuthMiddleware {
 	return &AuthMiddleware{
-		JWT: j,
+		JWT:      j,
+		Denylist: denylist,
+		PATs:     pats,
+		Users:    users,
 	}
 }
 
-const userIDKey ctxKey = "user_id"
+const (
+	userIDKey ctxKey = "user_id"
+	claimsKey ctxKey = "claims"
+	scopesKey ctxKey = "scopes"
+	roleKey   ctxKey = "role"
+)
+
+var (
+	errMissingToken      = apperr.Unauthorized("missing_token", "missing/invalid authorization")
+	errInvalidToken      = apperr.Unauthorized("invalid_token", "invalid token")
+	errTokenRevoked      = apperr.Unauthorized("token_revoked", "token revoked")
+	errAccountDisabled   = apperr.Forbidden("account_disabled", "account disabled")
+	errInsufficientScope = apperr.Forbidden("insufficient_scope", "insufficient scope")
+	errPermissionDenied  = apperr.Forbidden("permission_denied", "permission denied")
+)
+
+// personalTokenPrefix mirrors auth.PersonalAccessTokenPrefix; the service
+// package can't be imported here without a cycle.
+const personalTokenPrefix = "tfpat_"
 
+// RequireAccessJWT authenticates the request with either an access JWT or
+// a personal access token.
 func RequireAccessJWT(authSvc *AuthMiddleware) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			h := r.Header.Get("Authorization")
-			parts := strings.SplitN(h, " ", 2)
-			if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-				httpx.Error(w, http.StatusUnauthorized, "missing/invalid authorization")
+			token, ok := httpx.BearerToken(r)
+			if !ok {
+				httpx.WriteError(w, r, errMissingToken)
+				return
+			}
+
+			if strings.HasPrefix(token, personalTokenPrefix) && authSvc.PATs != nil {
+				pat, err := authSvc.PATs.VerifyPersonalAccessToken(r.Context(), token)
+				if err != nil {
+					// Anything but a bad token is our failure, not the client's
+					if e, ok := apperr.As(err); ok && e.Kind == apperr.KindUnauthorized {
+						err = errInvalidToken
+					}
+					httpx.WriteError(w, r, err)
+					return
+				}
+
+				ctx := context.WithValue(r.Context(), userIDKey, pat.UserID)
+				ctx = context.WithValue(ctx, scopesKey, pat.Scopes)
+				authSvc.serveUser(w, r.WithContext(ctx), next, pat.UserID)
+				return
+			}
+
+			claims, err := authSvc.JWT.Parse(token)
+			if err != nil || claims.Purpose != "" {
+				httpx.WriteError(w, r, errInvalidToken)
 				return
 			}
 
-			userID, err := authSvc.JWT.Verify(parts[1])
-			if err != nil {
-				httpx.Error(w, http.StatusUnauthorized, "invalid token")
+			if authSvc.Denylist != nil {
+				revoked, err := authSvc.Denylist.IsRevoked(r.Context(), claims.ID, claims.Subject, claims.IssuedAtUnixMicro())
+				if err != nil {
+					httpx.WriteError(w, r, err)
+					return
+				}
+				if revoked {
+					httpx.WriteError(w, r, errTokenRevoked)
+					return
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), userIDKey, claims.Subject)
+			ctx = context.WithValue(ctx, claimsKey, claims)
+			if claims.ClientID != "" {
+				// Issued to an OAuth client: only the granted scopes apply
+				ctx = context.WithValue(ctx, scopesKey, strings.Fields(claims.Scope))
+			}
+			authSvc.serveUser(w, r.WithContext(ctx), next, claims.Subject)
+		})
+	}
+}
+
+// serveUser loads the authenticated account, rejects it when disabled and
+// records its role for RequirePermission.
+func (m *AuthMiddleware) serveUser(w http.ResponseWriter, r *http.Request, next http.Handler, userID string) {
+	r = r.WithContext(withLogUser(r.Context(), userID))
+	if m.Users == nil {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	user, found, err := m.Users.FindByID(r.Context(), userID)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	if !found || user.Deleted() {
+		httpx.WriteError(w, r, errInvalidToken)
+		return
+	}
+	if user.Disabled() {
+		httpx.WriteError(w, r, errAccountDisabled)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), roleKey, user.Role)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// RequireScope rejects scope-restricted credentials lacking scope. Plain
+// JWT sessions carry no scopes and always pass.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if scopes, restricted := Scopes(r.Context()); restricted && !slices.Contains(scopes, scope) {
+				httpx.WriteError(w, r, errInsufficientScope)
 				return
 			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
 
-			ctx := context.WithValue(r.Context(), userIDKey, userID)
-			next.ServeHTTP(w, r.WithContext(ctx))
+// RequirePermission rejects users whose role lacks perm. It must run after
+// RequireAccessJWT.
+func RequirePermission(perm model.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !Role(r.Context()).Can(perm) {
+				httpx.WriteError(w, r, errPermissionDenied)
+				return
+			}
+			next.ServeHTTP(w, r)
 		})
 	}
 }
@@ -49,3 +176,22 @@ func UserID(ctx context.Context) string {
 	v, _ := ctx.Value(userIDKey).(string)
 	return v
 }
+
+// Claims returns the verified access token claims of the current request.
+func Claims(ctx context.Context) (jwt.Claims, bool) {
+	c, ok := ctx.Value(claimsKey).(jwt.Claims)
+	return c, ok
+}
+
+// Scopes returns the scopes of a delegated credential. restricted is false
+// for interactive sessions, which may do anything the user can.
+func Scopes(ctx context.Context) (scopes []string, restricted bool) {
+	scopes, restricted = ctx.Value(scopesKey).([]string)
+	return scopes, restricted
+}
+
+// Role returns the role of the authenticated user, or "" if unknown.
+func Role(ctx context.Context) model.Role {
+	v, _ := ctx.Value(roleKey).(model.Role)
+	return v
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"task-flow/internal/apperr"
+	"task-flow/internal/model"
+)
+
+// ==========================================
+// MOCK
+// ==========================================
+
+type mockPATVerifier struct {
+	err error
+}
+
+func (m mockPATVerifier) VerifyPersonalAccessToken(ctx context.Context, token string) (model.PersonalAccessToken, error) {
+	return model.PersonalAccessToken{}, m.err
+}
+
+// ==========================================
+// TESTS
+// ==========================================
+
+func TestRequireAccessJWT_PersonalTokenErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"unknown, expired or revoked", apperr.Unauthorized("invalid_token", "invalid personal access token"), http.StatusUnauthorized},
+		{"store failure", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := NewAuthMiddleware(nil, nil, mockPATVerifier{err: tt.err}, nil)
+			h := RequireAccessJWT(auth)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Error("handler must not run")
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+			r.Header.Set("Authorization", "Bearer "+personalTokenPrefix+"secret")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("expected %d, got %d: %s", tt.want, w.Code, w.Body)
+			}
+		})
+	}
+}
@@ -1,18 +1,105 @@
 package middleware
 
-import "net/http"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
-func CORS(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, DELETE, POST, PATCH, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	"github.com/rs/cors"
+)
 
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
+// CORSOptions says which browser origins may call the API. Origins are
+// exact, like "https://app.example.com", or cover every subdomain, like
+// "https://*.example.com". "*" allows any origin, but not together with
+// credentials, which would let any site act as the signed-in user.
+type CORSOptions struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response.
+	MaxAge time.Duration
+}
+
+// CORS answers preflight requests and adds CORS headers for allowed
+// origins. Requests from other origins are served without them, so
+// browsers don't expose the response.
+func CORS(opts CORSOptions) (func(http.Handler) http.Handler, error) {
+	allowAll := false
+	var patterns []originPattern
+	for _, o := range opts.AllowedOrigins {
+		if o == "*" {
+			allowAll = true
+			continue
+		}
+		p, err := parseOriginPattern(o)
+		if err != nil {
+			return nil, err
 		}
+		patterns = append(patterns, p)
+	}
+	if allowAll && opts.AllowCredentials {
+		return nil, errors.New("cors: origin \"*\" cannot be combined with credentials")
+	}
 
-		next.ServeHTTP(w, r)
+	c := cors.New(cors.Options{
+		AllowOriginFunc: func(origin string) bool {
+			if allowAll {
+				return true
+			}
+			for _, p := range patterns {
+				if p.match(origin) {
+					return true
+				}
+			}
+			return false
+		},
+		AllowedMethods:   opts.AllowedMethods,
+		AllowedHeaders:   opts.AllowedHeaders,
+		ExposedHeaders:   opts.ExposedHeaders,
+		AllowCredentials: opts.AllowCredentials,
+		MaxAge:           int(opts.MaxAge.Seconds()),
 	})
+	return c.Handler, nil
+}
+
+// originPattern is an origin whose host may start with "*.".
+type originPattern struct {
+	scheme string
+	host   string
+	port   string
+	// wildcard matches any subdomain of host, but not host itself
+	wildcard bool
+}
+
+func parseOriginPattern(s string) (originPattern, error) {
+	u, err := url.Parse(strings.ToLower(s))
+	if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		return originPattern{}, fmt.Errorf("cors: invalid origin %q, want scheme://host[:port]", s)
+	}
+	p := originPattern{scheme: u.Scheme, host: u.Hostname(), port: u.Port()}
+	if rest, ok := strings.CutPrefix(p.host, "*."); ok {
+		p.host, p.wildcard = rest, true
+	}
+	if p.host == "" || strings.Contains(p.host, "*") {
+		return originPattern{}, fmt.Errorf("cors: invalid origin %q, a wildcard may only replace the first label", s)
+	}
+	return p, nil
+}
+
+func (p originPattern) match(origin string) bool {
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil || u.Scheme != p.scheme || u.Port() != p.port || u.Path != "" {
+		return false
+	}
+	host := u.Hostname()
+	if !p.wildcard {
+		return host == p.host
+	}
+	sub, ok := strings.CutSuffix(host, "."+p.host)
+	return ok && sub != "" && !strings.HasPrefix(sub, ".") && !strings.HasSuffix(sub, ".")
 }
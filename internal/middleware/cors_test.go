@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORS_Origins(t *testing.T) {
+	mw, err := CORS(CORSOptions{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org", "http://localhost:3000"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://app.example.com", true},
+		{"https://other.example.com", false},
+		{"http://app.example.com", false},
+		{"https://a.example.org", true},
+		{"https://a.b.example.org", true},
+		{"https://example.org", false},
+		{"https://evilexample.org", false},
+		{"https://a.example.org.evil.com", false},
+		{"https://a.example.org:8443", false},
+		{"http://localhost:3000", true},
+		{"http://localhost:4000", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodOptions, "/tasks", nil)
+		r.Header.Set("Origin", tt.origin)
+		r.Header.Set("Access-Control-Request-Method", "POST")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		got := w.Header().Get("Access-Control-Allow-Origin")
+		if (got == tt.origin) != tt.allowed {
+			t.Errorf("%s: Access-Control-Allow-Origin = %q", tt.origin, got)
+		}
+		if tt.allowed && w.Header().Get("Access-Control-Max-Age") != "600" {
+			t.Errorf("%s: Access-Control-Max-Age = %q", tt.origin, w.Header().Get("Access-Control-Max-Age"))
+		}
+	}
+}
+
+func TestCORS_InvalidOptions(t *testing.T) {
+	invalid := []CORSOptions{
+		{AllowedOrigins: []string{"*"}, AllowCredentials: true},
+		{AllowedOrigins: []string{"example.com"}},
+		{AllowedOrigins: []string{"https://example.com/app"}},
+		{AllowedOrigins: []string{"https://a.*.example.com"}},
+	}
+	for _, opts := range invalid {
+		if _, err := CORS(opts); err == nil {
+			t.Errorf("accepted %+v", opts)
+		}
+	}
+	if _, err := CORS(CORSOptions{AllowedOrigins: []string{"*"}}); err != nil {
+		t.Errorf("rejected any origin without credentials: %v", err)
+	}
+}
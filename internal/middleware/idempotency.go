@@ -0,0 +1,221 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"task-flow/internal/apperr"
+	"task-flow/internal/httpx"
+	"task-flow/internal/logging"
+	"task-flow/internal/model"
+	"task-flow/internal/repository"
+)
+
+const (
+	IdempotencyHeader = "Idempotency-Key"
+	// ReplayedHeader marks a response replayed from an earlier request.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLen = 255
+	// idempotencyLockTTL bounds how long a request that never finished,
+	// because the instance died, keeps its key from being retried. It is
+	// well above the handler timeout.
+	idempotencyLockTTL = 2 * time.Minute
+)
+
+var (
+	errInvalidIdempotencyKey = apperr.Validation("invalid_idempotency_key", "Idempotency-Key must be 1 to 255 printable ASCII characters")
+	errIdempotencyKeyReused  = apperr.Unprocessable("idempotency_key_reused", "Idempotency-Key was already used for a different request")
+	errIdempotencyInProgress = apperr.Conflict("idempotency_in_progress", "a request with this Idempotency-Key is still being processed")
+)
+
+// Idempotency makes POST requests that carry an Idempotency-Key safe to
+// retry. The first request with a key runs and its response is stored;
+// retries within the TTL get that response again, marked with
+// Idempotent-Replayed, instead of running the handler twice. Keys are per
+// user and bound to the request they were first used with. Responses
+// marked Cache-Control: no-store, such as those carrying a new secret, are
+// never stored; a retry runs the handler again. A nil *Idempotency does
+// nothing.
+type Idempotency struct {
+	repo repository.IdempotencyRepo
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+func NewIdempotency(repo repository.IdempotencyRepo, ttl time.Duration) *Idempotency {
+	return &Idempotency{repo: repo, ttl: ttl, now: time.Now}
+}
+
+// Handle wraps an authenticated handler.
+func (m *Idempotency) Handle(next http.Handler) http.Handler {
+	if m == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyHeader)
+		userID := UserID(r.Context())
+		if r.Method != http.MethodPost || key == "" || userID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !validIdempotencyKey(key) {
+			httpx.WriteError(w, r, errInvalidIdempotencyKey)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, httpx.MaxBodyBytes+1))
+		if err != nil {
+			httpx.WriteError(w, r, err)
+			return
+		}
+		if len(body) > httpx.MaxBodyBytes {
+			// The handler rejects it; nothing worth remembering
+			r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+			next.ServeHTTP(w, r)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := requestFingerprint(r, body)
+		existing, reserved, err := m.repo.Reserve(r.Context(), userID, key, fingerprint, m.now().Add(idempotencyLockTTL).Unix())
+		if err != nil {
+			httpx.WriteError(w, r, err)
+			return
+		}
+		if !reserved {
+			m.replay(w, r, existing, fingerprint)
+			return
+		}
+
+		// The key is ours until the response is stored. Release it if
+		// that doesn't happen, even when the client is gone or the
+		// handler panics, so the client can retry.
+		ctx := context.WithoutCancel(r.Context())
+		stored := false
+		defer func() {
+			if !stored {
+				if err := m.repo.Release(ctx, userID, key); err != nil {
+					logging.FromContext(ctx).Warn("release idempotency key", "err", err)
+				}
+			}
+		}()
+
+		before := w.Header().Clone()
+		rec := &recordingWriter{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		if rec.status >= 500 {
+			// Server errors may succeed on retry
+			return
+		}
+		if strings.Contains(w.Header().Get("Cache-Control"), "no-store") {
+			// Secrets are shown once and must not sit in the database
+			return
+		}
+		err = m.repo.Complete(ctx, model.IdempotencyRecord{
+			UserID:     userID,
+			Key:        key,
+			StatusCode: rec.status,
+			Header:     addedHeaders(before, w.Header()),
+			Body:       rec.body.Bytes(),
+		}, m.now().Add(m.ttl).Unix())
+		if err != nil {
+			logging.FromContext(ctx).Error("store idempotent response", "err", err)
+			return
+		}
+		stored = true
+	})
+}
+
+func (m *Idempotency) replay(w http.ResponseWriter, r *http.Request, rec model.IdempotencyRecord, fingerprint []byte) {
+	if subtle.ConstantTimeCompare(rec.Fingerprint, fingerprint) != 1 {
+		httpx.WriteError(w, r, errIdempotencyKeyReused)
+		return
+	}
+	if rec.StatusCode == 0 {
+		httpx.WriteError(w, r, errIdempotencyInProgress)
+		return
+	}
+
+	for k, v := range rec.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(rec.StatusCode)
+	_, _ = w.Write(rec.Body)
+}
+
+// requestFingerprint hashes what makes a request the same request: its
+// target, including the workspace header that selects where tasks go,
+// and its body.
+func requestFingerprint(r *http.Request, body []byte) []byte {
+	h := sha256.New()
+	for _, part := range []string{r.Method, r.URL.RequestURI(), r.Header.Get(WorkspaceHeader)} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+func validIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKeyLen {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// addedHeaders returns the headers the handler set, leaving out those
+// outer middleware had already set for this request only, such as
+// X-Request-ID and the rate limit headers.
+func addedHeaders(before, after http.Header) map[string][]string {
+	added := make(map[string][]string)
+	for k, v := range after {
+		if !slices.Equal(before[k], v) {
+			added[k] = v
+		}
+	}
+	return added
+}
+
+// recordingWriter passes a response through while keeping a copy.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
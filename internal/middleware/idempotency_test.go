@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"task-flow/internal/model"
+)
+
+// ==========================================
+// HELPER
+// ==========================================
+
+func postWithKey(h http.Handler, key, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(body))
+	r = r.WithContext(context.WithValue(r.Context(), userIDKey, "user-1"))
+	r.Header.Set(IdempotencyHeader, key)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// ==========================================
+// TESTS
+// ==========================================
+
+func TestIdempotency_Replay(t *testing.T) {
+	calls := 0
+	h := NewIdempotency(newMockIdempotencyRepo(), time.Hour).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":"t1"}`))
+	}))
+
+	first := postWithKey(h, "k1", `{"title":"a"}`)
+	retry := postWithKey(h, "k1", `{"title":"a"}`)
+	if calls != 1 {
+		t.Fatalf("handler ran %d times", calls)
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry = %d %s", retry.Code, retry.Body)
+	}
+	if retry.Header().Get(ReplayedHeader) != "true" || retry.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("retry headers = %v", retry.Header())
+	}
+
+	if w := postWithKey(h, "k1", `{"title":"b"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("different body: status = %d", w.Code)
+	}
+	if w := postWithKey(h, "k2", `{"title":"a"}`); w.Code != http.StatusCreated || calls != 2 {
+		t.Errorf("new key: status = %d, calls = %d", w.Code, calls)
+	}
+}
+
+func TestIdempotency_ServerErrorReleasesKey(t *testing.T) {
+	fail := true
+	h := NewIdempotency(newMockIdempotencyRepo(), time.Hour).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	postWithKey(h, "k1", `{}`)
+	fail = false
+	if w := postWithKey(h, "k1", `{}`); w.Code != http.StatusCreated || w.Header().Get(ReplayedHeader) != "" {
+		t.Errorf("retry after 500 = %d, replayed %q", w.Code, w.Header().Get(ReplayedHeader))
+	}
+}
+
+func TestIdempotency_SecretIsNotStored(t *testing.T) {
+	// Like creating a personal access token: a new secret on every call
+	repo := newMockIdempotencyRepo()
+	n := 0
+	h := NewIdempotency(repo, time.Hour).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token":"tf_pat_secret%d"}`, n)
+	}))
+
+	first := postWithKey(h, "k1", `{"name":"ci"}`)
+	if len(repo.records) != 0 {
+		t.Errorf("stored %d records, want none", len(repo.records))
+	}
+	retry := postWithKey(h, "k1", `{"name":"ci"}`)
+	if strings.Contains(retry.Body.String(), "secret1") || retry.Header().Get(ReplayedHeader) != "" {
+		t.Errorf("retry replayed the first secret: %s", retry.Body)
+	}
+	if first.Code != http.StatusCreated || retry.Code != http.StatusCreated || n != 2 {
+		t.Errorf("status = %d, %d; handler ran %d times", first.Code, retry.Code, n)
+	}
+}
+
+func TestIdempotency_InProgress(t *testing.T) {
+	repo := newMockIdempotencyRepo()
+	h := NewIdempotency(repo, time.Hour).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	if _, ok, _ := repo.Reserve(context.Background(), "user-1", "k1", requestFingerprint(httptest.NewRequest(http.MethodPost, "/tasks", nil), []byte(`{}`)), 0); !ok {
+		t.Fatal("reserve failed")
+	}
+
+	if w := postWithKey(h, "k1", `{}`); w.Code != http.StatusConflict {
+		t.Errorf("status = %d", w.Code)
+	}
+	if w := postWithKey(h, strings.Repeat("k", 256), `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("long key: status = %d", w.Code)
+	}
+}
+
+// ==========================================
+// MOCK REPOSITORY
+// ==========================================
+
+type mockIdempotencyRepo struct {
+	mu      sync.Mutex
+	records map[string]model.IdempotencyRecord
+}
+
+func newMockIdempotencyRepo() *mockIdempotencyRepo {
+	return &mockIdempotencyRepo{records: make(map[string]model.IdempotencyRecord)}
+}
+
+func (m *mockIdempotencyRepo) Reserve(ctx context.Context, userID, key string, fingerprint []byte, expUnix int64) (model.IdempotencyRecord, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if rec, ok := m.records[userID+"/"+key]; ok {
+		return rec, false, nil
+	}
+	m.records[userID+"/"+key] = model.IdempotencyRecord{UserID: userID, Key: key, Fingerprint: fingerprint}
+	return model.IdempotencyRecord{}, true, nil
+}
+
+func (m *mockIdempotencyRepo) Complete(ctx context.Context, rec model.IdempotencyRecord, expUnix int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec.Fingerprint = m.records[rec.UserID+"/"+rec.Key].Fingerprint
+	m.records[rec.UserID+"/"+rec.Key] = rec
+	return nil
+}
+
+func (m *mockIdempotencyRepo) Release(ctx context.Context, userID, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.records[userID+"/"+key].StatusCode == 0 {
+		delete(m.records, userID+"/"+key)
+	}
+	return nil
+}
@@ -1,56 +1,104 @@
 package middleware
 
 import (
-	"fmt"
+	"context"
+	"log/slog"
 	"net/http"
 	"time"
-)
 
-// ANSI colors
-const (
-	reset  = "\033[0m"
-	green  = "\033[32m"
-	yellow = "\033[33m"
-	red    = "\033[31m"
-	cyan   = "\033[36m"
-	gray   = "\033[90m"
+	"task-flow/internal/logging"
+	"task-flow/internal/requestid"
 )
 
 type responseWriter struct {
 	http.ResponseWriter
-	status int
+	status      int
+	bytes       int
+	wroteHeader bool
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
-	rw.status = code
+	if !rw.wroteHeader {
+		rw.status = code
+		rw.wroteHeader = true
+	}
 	rw.ResponseWriter.WriteHeader(code)
 }
 
-func statusColor(code int) string {
-	switch {
-	case code >= 200 && code < 300:
-		return green
-	case code >= 300 && code < 400:
-		return cyan
-	case code >= 400 && code < 500:
-		return yellow
-	default:
-		return red
-	}
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += n
+	return n, err
 }
 
-func Logger(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+const requestLogKey ctxKey = "request_log"
+
+// requestLog collects what inner middleware learns about a request, such
+// as the user, for the access log line written by Logger.
+type requestLog struct {
+	userID  string
+	traceID string
+	// route is set by middleware that copies the request, which hides the
+	// pattern the ServeMux sets from Logger
+	route string
+}
 
-		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
-		next.ServeHTTP(rw, r)
+// Logger gives every request a context logger carrying its ID (see
+// logging.FromContext) and writes one access log line per request.
+func Logger(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
 
-		fmt.Printf("%s%s%s %s %s%d%s %s%.2fms%s\n",
-			cyan, r.Method, reset,
-			r.URL.Path,
-			statusColor(rw.status), rw.status, reset,
-			gray, float64(time.Since(start).Microseconds())/1000, reset,
-		)
-	})
+			l := logger
+			if id := requestid.FromContext(r.Context()); id != "" {
+				l = l.With("request_id", id)
+			}
+			info := &requestLog{}
+			ctx := context.WithValue(r.Context(), requestLogKey, info)
+			r = r.WithContext(logging.WithLogger(ctx, l))
+
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rw, r)
+
+			route := r.Pattern
+			if route == "" {
+				route = info.route
+			}
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("route", route),
+				slog.Int("status", rw.status),
+				slog.Int("bytes", rw.bytes),
+				slog.Duration("latency", time.Since(start)),
+			}
+			if info.userID != "" {
+				attrs = append(attrs, slog.String("user_id", info.userID))
+			}
+			if info.traceID != "" {
+				attrs = append(attrs, slog.String("trace_id", info.traceID))
+			}
+			level := slog.LevelInfo
+			if rw.status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			l.LogAttrs(r.Context(), level, "request", attrs...)
+		})
+	}
+}
+
+// withLogUser records the authenticated user in the access log and the
+// context logger.
+func withLogUser(ctx context.Context, userID string) context.Context {
+	if info, ok := ctx.Value(requestLogKey).(*requestLog); ok {
+		info.userID = userID
+	}
+	return logging.With(ctx, "user_id", userID)
 }
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"task-flow/internal/httpx"
+	"task-flow/internal/metrics"
+)
+
+// Metrics records request counts, latencies and in-flight requests. It
+// must wrap the ServeMux directly, without handlers in between that copy
+// the request, so it can read the matched route pattern.
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		metrics.HTTPInFlight.Inc()
+		defer metrics.HTTPInFlight.Dec()
+
+		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r)
+
+		route := r.Pattern
+		if route == "" {
+			// Unmatched paths share one series
+			route = "unmatched"
+		}
+		status := strconv.Itoa(rw.status)
+		metrics.HTTPRequests.Inc(r.Method, route, status)
+		metrics.HTTPDuration.Observe(time.Since(start).Seconds(), r.Method, route, status)
+	})
+}
+
+// RequireStaticToken admits requests bearing token, for endpoints such as
+// /metrics that are read by infrastructure rather than users.
+func RequireStaticToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok {
+				httpx.WriteError(w, r, errMissingToken)
+				return
+			}
+			if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				httpx.WriteError(w, r, errInvalidToken)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
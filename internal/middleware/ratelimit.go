@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"task-flow/internal/apperr"
+	"task-flow/internal/httpx"
+	"task-flow/internal/logging"
+	"task-flow/internal/metrics"
+	"task-flow/internal/ratelimit"
+)
+
+var errRateLimited = apperr.RateLimited("rate_limited", "too many requests, try again later")
+
+// RealIP resolves the client address behind trusted proxies once per
+// request, for httpx.ClientIP. Without trusted proxies the connected peer
+// is the client and X-Forwarded-For is ignored.
+func RealIP(proxies httpx.TrustedProxies) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(proxies) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(httpx.WithClientIP(r.Context(), proxies.ClientIP(r))))
+		})
+	}
+}
+
+// RateLimitPolicies say how often each kind of route may be called. A
+// policy with a zero Limit is not enforced.
+type RateLimitPolicies struct {
+	// Strict guards endpoints that take credentials, such as login and
+	// register.
+	Strict ratelimit.Policy
+	// Read applies to GET and HEAD requests, Write to the other methods.
+	Read  ratelimit.Policy
+	Write ratelimit.Policy
+	// Routes overrides the above for ServeMux patterns such as
+	// "GET /tasks". Each such route gets buckets of its own.
+	Routes map[string]ratelimit.Policy
+}
+
+// RateLimiter limits requests per user when authenticated and per client
+// IP otherwise. Requests over the limit get 429 with Retry-After; every
+// limited response carries RateLimit-* headers describing the policy.
+// A nil *RateLimiter limits nothing.
+type RateLimiter struct {
+	store    ratelimit.Store
+	policies RateLimitPolicies
+}
+
+func NewRateLimiter(store ratelimit.Store, policies RateLimitPolicies) *RateLimiter {
+	return &RateLimiter{store: store, policies: policies}
+}
+
+// Strict limits next with the Strict policy. Use it on public endpoints
+// that check credentials.
+func (l *RateLimiter) Strict(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return l.limit(next, func(*http.Request) ratelimit.Policy { return l.policies.Strict })
+}
+
+// Limit limits next with the Read or Write policy, by method. Put it
+// inside authentication so users are told apart by ID rather than IP.
+func (l *RateLimiter) Limit(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return l.limit(next, func(r *http.Request) ratelimit.Policy {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			return l.policies.Read
+		}
+		return l.policies.Write
+	})
+}
+
+func (l *RateLimiter) limit(next http.Handler, policy func(*http.Request) ratelimit.Policy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok := l.policies.Routes[r.Pattern]
+		if ok {
+			p.Name = r.Pattern
+		} else {
+			p = policy(r)
+		}
+		if p.Limit <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		subject := "ip:" + httpx.ClientIP(r)
+		if userID := UserID(r.Context()); userID != "" {
+			subject = "user:" + userID
+		}
+		res, err := l.store.Take(r.Context(), p.Name+":"+subject, p)
+		if err != nil {
+			// Better to serve without limits than not at all
+			logging.FromContext(r.Context()).Warn("rate limit store failed", "policy", p.Name, "err", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", seconds(res.Reset))
+		h.Set("RateLimit-Policy", strconv.Itoa(p.Limit)+";w="+seconds(p.Period))
+		if !res.Allowed {
+			metrics.RateLimited.Inc(p.Name)
+			h.Set("Retry-After", seconds(res.RetryAfter))
+			httpx.WriteError(w, r, errRateLimited)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// seconds formats d as whole seconds, rounded up so clients that wait
+// that long are not turned away again.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
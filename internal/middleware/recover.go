@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"task-flow/internal/httpx"
+	"task-flow/internal/logging"
+)
+
+// Recover turns a panic in a handler into a logged stack trace and a 500
+// problem response, instead of a dropped connection. It should sit right
+// around the ServeMux, inside Logger and Metrics, so they record the 500.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				// The server's way to abort a response silently
+				panic(rec)
+			}
+
+			logging.FromContext(r.Context()).Error("panic serving request",
+				"panic", fmt.Sprint(rec), "stack", string(debug.Stack()))
+			if rw.wroteHeader {
+				// Too late for a clean error; cut the response short
+				panic(http.ErrAbortHandler)
+			}
+			httpx.WriteError(w, r, fmt.Errorf("panic: %v", rec))
+		}()
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// Timeout gives each request a deadline. Handlers, services and queries
+// that honor their context stop at it, and httpx.WriteError answers 503.
+// The server's WriteTimeout stays as the hard limit for code that doesn't.
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
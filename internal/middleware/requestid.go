@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+
+	"task-flow/internal/requestid"
+)
+
+// RequestID adopts the client's X-Request-ID when it is well formed and
+// otherwise generates one, stores it in the context and echoes it on the
+// response. It must run before Logger so the access log carries it.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+
+		w.Header().Set(requestid.Header, id)
+		next.ServeHTTP(w, r.WithContext(requestid.With(r.Context(), id)))
+	})
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import "net/http"
+
+// SecurityHeaderOptions are response headers that harden browsers'
+// handling of the API. A header the handler sets itself is left alone.
+type SecurityHeaderOptions struct {
+	// Headers are sent on every response; empty values are skipped.
+	Headers map[string]string
+	// Routes overrides Headers for ServeMux patterns such as
+	// "GET /public/tasks/{token}". An empty value drops the header.
+	Routes map[string]map[string]string
+}
+
+// SecurityHeaders adds the configured headers as the response is written.
+// Like Metrics it must wrap the ServeMux without handlers in between that
+// copy the request, so the route overrides can see the matched pattern.
+func SecurityHeaders(opts SecurityHeaderOptions) func(http.Handler) http.Handler {
+	defaults := canonicalHeaders(opts.Headers)
+	routes := make(map[string]map[string]string, len(opts.Routes))
+	for pattern, headers := range opts.Routes {
+		routes[pattern] = canonicalHeaders(headers)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sw := &securityHeaderWriter{
+				ResponseWriter: w,
+				apply: func(h http.Header) {
+					override := routes[r.Pattern]
+					for k, v := range defaults {
+						if o, ok := override[k]; ok {
+							v = o
+						}
+						setDefault(h, k, v)
+					}
+					for k, v := range override {
+						setDefault(h, k, v)
+					}
+				},
+			}
+			next.ServeHTTP(sw, r)
+			// Handlers that write nothing leave the status to the server
+			sw.applyOnce()
+		})
+	}
+}
+
+func canonicalHeaders(headers map[string]string) map[string]string {
+	out := make(map[string]string, len(headers))
+	for k, v := range headers {
+		out[http.CanonicalHeaderKey(k)] = v
+	}
+	return out
+}
+
+func setDefault(h http.Header, k, v string) {
+	if _, set := h[k]; !set && v != "" {
+		h.Set(k, v)
+	}
+}
+
+// securityHeaderWriter applies the headers just before they are sent,
+// when the route is known and the handler has set its own.
+type securityHeaderWriter struct {
+	http.ResponseWriter
+	apply   func(http.Header)
+	applied bool
+}
+
+func (w *securityHeaderWriter) applyOnce() {
+	if !w.applied {
+		w.applied = true
+		w.apply(w.Header())
+	}
+}
+
+func (w *securityHeaderWriter) WriteHeader(code int) {
+	w.applyOnce()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *securityHeaderWriter) Write(b []byte) (int, error) {
+	if !w.applied {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *securityHeaderWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tasks", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("GET /public/tasks/{token}", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("GET /own", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", "default-src 'self'")
+	})
+	h := SecurityHeaders(SecurityHeaderOptions{
+		Headers: map[string]string{
+			"content-security-policy":   "default-src 'none'",
+			"X-Frame-Options":           "DENY",
+			"Strict-Transport-Security": "",
+		},
+		Routes: map[string]map[string]string{
+			"GET /public/tasks/{token}": {"X-Frame-Options": "", "Referrer-Policy": "same-origin"},
+		},
+	})(mux)
+
+	get := func(path string) http.Header {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Header()
+	}
+
+	got := get("/tasks")
+	if got.Get("Content-Security-Policy") != "default-src 'none'" || got.Get("X-Frame-Options") != "DENY" {
+		t.Errorf("defaults = %v", got)
+	}
+	if _, ok := got["Strict-Transport-Security"]; ok {
+		t.Error("empty header sent")
+	}
+
+	got = get("/public/tasks/abc")
+	if _, ok := got["X-Frame-Options"]; ok || got.Get("Referrer-Policy") != "same-origin" {
+		t.Errorf("route override = %v", got)
+	}
+
+	if got = get("/own"); got.Get("Content-Security-Policy") != "default-src 'self'" {
+		t.Errorf("handler's header replaced: %v", got)
+	}
+	if got = get("/missing"); got.Get("X-Frame-Options") != "DENY" {
+		t.Errorf("unmatched route = %v", got)
+	}
+}
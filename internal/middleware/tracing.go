@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"task-flow/internal/logging"
+	"task-flow/internal/tracing"
+)
+
+// Tracing starts a server span for each request, continuing the caller's
+// trace when a traceparent header is present, and adds the trace and span
+// IDs to the logs. It must run after Logger and wrap Metrics or the
+// ServeMux directly, so it can name the span after the matched route.
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := tracing.Extract(r.Context(), r.Header)
+		ctx, span := tracing.StartKind(ctx, tracing.KindServer, r.Method,
+			tracing.String("http.request.method", r.Method),
+			tracing.String("url.path", r.URL.Path),
+		)
+		defer span.End()
+
+		r = r.WithContext(withLogTrace(ctx, span.SpanContext()))
+		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r)
+
+		if r.Pattern != "" {
+			if info, ok := r.Context().Value(requestLogKey).(*requestLog); ok {
+				info.route = r.Pattern
+			}
+			span.SetName(r.Pattern)
+			span.SetAttributes(tracing.String("http.route", r.Pattern))
+		}
+		span.SetAttributes(tracing.Int("http.response.status_code", rw.status))
+		if rw.status >= http.StatusInternalServerError {
+			span.SetStatus(tracing.StatusError, http.StatusText(rw.status))
+		}
+	})
+}
+
+// withLogTrace records the trace in the access log and the context logger.
+func withLogTrace(ctx context.Context, sc tracing.SpanContext) context.Context {
+	if info, ok := ctx.Value(requestLogKey).(*requestLog); ok {
+		info.traceID = sc.TraceID.String()
+	}
+	return logging.With(ctx, "trace_id", sc.TraceID.String(), "span_id", sc.SpanID.String())
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"task-flow/internal/apperr"
+	"task-flow/internal/httpx"
+	"task-flow/internal/logging"
+	"task-flow/internal/model"
+	"task-flow/internal/repository"
+)
+
+// WorkspaceHeader selects the workspace on routes without a {workspace}
+// path segment.
+const WorkspaceHeader = "X-Workspace-ID"
+
+const workspaceRoleKey ctxKey = "workspace_role"
+
+var (
+	errWorkspaceRequired = apperr.Validation("workspace_required", "workspace is required")
+	errWorkspaceNotFound = apperr.NotFound("workspace_not_found", "workspace not found")
+)
+
+// RequireWorkspace resolves the workspace from the {workspace} path value
+// or the X-Workspace-ID header, checks that the user is a member and scopes
+// the request context to it. It must run after RequireAccessJWT.
+func RequireWorkspace(workspaces repository.WorkspaceRepo) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			workspaceID := r.PathValue("workspace")
+			if workspaceID == "" {
+				workspaceID = r.Header.Get(WorkspaceHeader)
+			}
+			if workspaceID == "" {
+				httpx.WriteError(w, r, errWorkspaceRequired)
+				return
+			}
+
+			member, found, err := workspaces.FindMember(r.Context(), workspaceID, UserID(r.Context()))
+			if err != nil {
+				httpx.WriteError(w, r, err)
+				return
+			}
+			if !found {
+				// Same answer whether the workspace exists or not
+				httpx.WriteError(w, r, errWorkspaceNotFound)
+				return
+			}
+
+			ctx := repository.WithWorkspace(r.Context(), member.WorkspaceID)
+			ctx = logging.With(ctx, "workspace_id", member.WorkspaceID)
+			ctx = context.WithValue(ctx, workspaceRoleKey, member.Role)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireWorkspacePermission rejects members whose workspace role lacks
+// perm. It must run after RequireWorkspace.
+func RequireWorkspacePermission(perm model.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !WorkspaceRole(r.Context()).Can(perm) {
+				httpx.WriteError(w, r, errPermissionDenied)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WorkspaceRole returns the user's role in the current workspace, or "".
+func WorkspaceRole(ctx context.Context) model.WorkspaceRole {
+	v, _ := ctx.Value(workspaceRoleKey).(model.WorkspaceRole)
+	return v
+}
@@ -0,0 +1,15 @@
+package model
+
+// IdempotencyRecord is a request made with an Idempotency-Key and, once it
+// has finished, the response to replay when the client retries it.
+type IdempotencyRecord struct {
+	UserID string
+	Key    string
+	// Fingerprint is a hash of the request, so a key reused for a
+	// different request can be told apart from a retry.
+	Fingerprint []byte
+	// StatusCode is 0 while the first request is still being handled.
+	StatusCode int
+	Header     map[string][]string
+	Body       []byte
+}
@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+type LoginAttempt struct {
+	Email     string
+	IP        string
+	Success   bool
+	CreatedAt time.Time
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// MFA holds a user's TOTP enrolment. It is pending until EnabledAt is set
+// by confirming a first code.
+type MFA struct {
+	UserID      string
+	Secret      []byte
+	EnabledAt   *time.Time
+	LastCounter int64
+}
+
+func (m MFA) Enabled() bool {
+	return m.EnabledAt != nil
+}
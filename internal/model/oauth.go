@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// OAuthClient is a third-party application registered by a user.
+// Confidential clients hold a secret; public clients (SPAs, native apps)
+// have none and must rely on PKCE.
+type OAuthClient struct {
+	ID           string
+	OwnerID      string
+	Name         string
+	SecretHash   []byte
+	RedirectURIs []string
+	// Scopes is the most the client may ever be granted.
+	Scopes    []string
+	CreatedAt time.Time
+}
+
+func (c OAuthClient) Confidential() bool {
+	return len(c.SecretHash) > 0
+}
+
+type OAuthAuthorizationCode struct {
+	ClientID      string
+	UserID        string
+	RedirectURI   string
+	Scopes        []string
+	CodeChallenge string
+	ExpiresAt     time.Time
+}
+
+type OAuthRefreshToken struct {
+	ClientID  string
+	UserID    string
+	Scopes    []string
+	ExpiresAt time.Time
+}
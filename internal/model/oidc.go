@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// OIDCLoginState is what we remember between redirecting a user to an
+// identity provider and handling its callback.
+type OIDCLoginState struct {
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+// UserIdentity links an account to a subject at an external provider.
+type UserIdentity struct {
+	UserID    string
+	Provider  string
+	Subject   string
+	Email     string
+	CreatedAt time.Time
+}
@@ -0,0 +1,52 @@
+package model
+
+// Role is the platform-wide role of an account. Access to tasks is governed
+// by WorkspaceRole instead.
+type Role string
+
+const (
+	RoleAdmin  Role = "admin"
+	RoleMember Role = "member"
+	RoleViewer Role = "viewer"
+)
+
+// Roles lists every role from most to least privileged.
+var Roles = []Role{RoleAdmin, RoleMember, RoleViewer}
+
+func (r Role) Valid() bool {
+	switch r {
+	case RoleAdmin, RoleMember, RoleViewer:
+		return true
+	}
+	return false
+}
+
+// Permission is an action a role may perform. Unlike scopes, which limit a
+// credential, permissions describe what the account itself is allowed.
+type Permission string
+
+const (
+	PermissionTasksRead       Permission = "tasks.read"
+	PermissionTasksWrite      Permission = "tasks.write"
+	PermissionProjectsManage  Permission = "projects.manage"
+	PermissionMembersManage   Permission = "members.manage"
+	PermissionWorkspaceManage Permission = "workspace.manage"
+	PermissionWorkspaceCreate Permission = "workspace.create"
+	PermissionUsersManage     Permission = "users.manage"
+)
+
+// Viewers may join workspaces but not start their own.
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin:  {PermissionWorkspaceCreate, PermissionUsersManage},
+	RoleMember: {PermissionWorkspaceCreate},
+	RoleViewer: {},
+}
+
+func (r Role) Can(p Permission) bool {
+	for _, granted := range rolePermissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package model
+
+// Scopes limit what a delegated credential (personal access token, OAuth
+// token) may do. Interactive JWT sessions are not scope-restricted.
+const (
+	ScopeTasksRead  = "tasks:read"
+	ScopeTasksWrite = "tasks:write"
+	ScopeUserRead   = "user:read"
+	// ScopeAccountManage guards security-sensitive account operations
+	// (tokens, 2FA, sessions). Only interactive sessions hold it.
+	ScopeAccountManage = "account:manage"
+)
+
+// DelegableScopes can be granted to delegated credentials. account:manage
+// is deliberately absent so a token can never mint other tokens.
+var DelegableScopes = []string{ScopeTasksRead, ScopeTasksWrite, ScopeUserRead}
+
+func IsDelegableScope(scope string) bool {
+	for _, s := range DelegableScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package model
+
+import "time"
+
+// AccessLevel is what a user may do with a single task. Levels are
+// ordered: each one includes the ones before it.
+type AccessLevel string
+
+const (
+	AccessNone    AccessLevel = ""
+	AccessView    AccessLevel = "view"
+	AccessComment AccessLevel = "comment"
+	AccessEdit    AccessLevel = "edit"
+)
+
+var accessRank = map[AccessLevel]int{
+	AccessNone:    0,
+	AccessView:    1,
+	AccessComment: 2,
+	AccessEdit:    3,
+}
+
+// Valid reports whether l can be granted by a share.
+func (l AccessLevel) Valid() bool {
+	return accessRank[l] > 0
+}
+
+// Allows reports whether l includes need.
+func (l AccessLevel) Allows(need AccessLevel) bool {
+	return accessRank[l] >= accessRank[need]
+}
+
+// Max returns the higher of two levels.
+func (l AccessLevel) Max(other AccessLevel) AccessLevel {
+	if accessRank[other] > accessRank[l] {
+		return other
+	}
+	return l
+}
+
+// ShareResource is what a share applies to. A project share covers every
+// task in the project.
+type ShareResource string
+
+const (
+	ShareTask    ShareResource = "task"
+	ShareProject ShareResource = "project"
+)
+
+// Share grants one user access to a task or project, independently of
+// workspace membership.
+type Share struct {
+	WorkspaceID string
+	Resource    ShareResource
+	ResourceID  string
+	UserID      string
+	Email       string
+	Level       AccessLevel
+	CreatedBy   string
+	CreatedAt   time.Time
+}
+
+// SharedTask is a task reachable through a share, with the level granted.
+type SharedTask struct {
+	Task
+	Level AccessLevel
+}
+
+// TaskLink is a public read-only link to a task. Only a hash of its token
+// is stored.
+type TaskLink struct {
+	ID          string
+	WorkspaceID string
+	TaskID      string
+	TokenHash   []byte
+	CreatedBy   string
+	ExpiresAt   *time.Time
+	CreatedAt   time.Time
+}
+
+type TaskComment struct {
+	ID          string
+	WorkspaceID string
+	TaskID      string
+	UserID      string
+	Email       string
+	Body        string
+	CreatedAt   time.Time
+}
@@ -4,7 +4,12 @@ import "time"
 
 type Task struct {
 	ID          string
+	WorkspaceID string
+	// ProjectID is optional; when set the project is in the same workspace.
+	ProjectID   *string
 	Title       string
 	Description string
 	Created_At  time.Time
+	// CompletedAt is set once the task is done.
+	CompletedAt *time.Time
 }
@@ -1,7 +1,58 @@
 package model
 
+import "time"
+
 type User struct {
-	ID       string
-	Email    string
-	PassHash []byte
+	ID         string
+	Email      string
+	PassHash   []byte
+	Role       Role
+	VerifiedAt *time.Time
+	DisabledAt *time.Time
+	CreatedAt  time.Time
+	UserProfile
+	// PendingEmail is the new address of an unconfirmed email change.
+	PendingEmail string
+	// DeletedAt is set once the account has been deleted and anonymised.
+	DeletedAt *time.Time
+}
+
+// UserProfile is what users may edit about themselves.
+type UserProfile struct {
+	DisplayName string
+	AvatarURL   string
+	// Timezone is an IANA name such as "Asia/Jakarta".
+	Timezone string
+	// Locale is a BCP 47 language tag such as "id-ID".
+	Locale string
+}
+
+func (u User) Verified() bool {
+	return u.VerifiedAt != nil
+}
+
+// HasPassword is false for accounts created through an identity provider
+// until the user sets a password (e.g. via password reset).
+func (u User) HasPassword() bool {
+	return len(u.PassHash) > 0
+}
+
+// Disabled accounts can neither sign in nor use existing credentials.
+func (u User) Disabled() bool {
+	return u.DisabledAt != nil
+}
+
+// Deleted accounts are anonymised tombstones and never come back.
+func (u User) Deleted() bool {
+	return u.DeletedAt != nil
+}
+
+// UserFilter narrows an admin user listing. Zero values match everything.
+type UserFilter struct {
+	// Query matches a substring of the email address.
+	Query    string
+	Role     Role
+	Disabled *bool
+	Limit    int
+	Offset   int
 }
@@ -0,0 +1,11 @@
+package model
+
+// TokenPurpose scopes a single-use user token to one flow so a token
+// issued for one purpose can never be redeemed for another.
+type TokenPurpose string
+
+const (
+	TokenPurposePasswordReset     TokenPurpose = "password_reset"
+	TokenPurposeEmailVerification TokenPurpose = "email_verification"
+	TokenPurposeEmailChange       TokenPurpose = "email_change"
+)
@@ -0,0 +1,86 @@
+package model
+
+import "time"
+
+// Workspace is the tenant boundary: projects and tasks belong to exactly
+// one workspace and are only visible to its members.
+type Workspace struct {
+	ID        string
+	Name      string
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+// WorkspaceRole is a member's role inside one workspace. It is independent
+// of the platform-wide User.Role.
+type WorkspaceRole string
+
+const (
+	WorkspaceRoleOwner  WorkspaceRole = "owner"
+	WorkspaceRoleAdmin  WorkspaceRole = "admin"
+	WorkspaceRoleMember WorkspaceRole = "member"
+	WorkspaceRoleViewer WorkspaceRole = "viewer"
+)
+
+func (r WorkspaceRole) Valid() bool {
+	switch r {
+	case WorkspaceRoleOwner, WorkspaceRoleAdmin, WorkspaceRoleMember, WorkspaceRoleViewer:
+		return true
+	}
+	return false
+}
+
+var workspacePermissions = map[WorkspaceRole][]Permission{
+	WorkspaceRoleOwner:  {PermissionTasksRead, PermissionTasksWrite, PermissionProjectsManage, PermissionMembersManage, PermissionWorkspaceManage},
+	WorkspaceRoleAdmin:  {PermissionTasksRead, PermissionTasksWrite, PermissionProjectsManage, PermissionMembersManage},
+	WorkspaceRoleMember: {PermissionTasksRead, PermissionTasksWrite},
+	WorkspaceRoleViewer: {PermissionTasksRead},
+}
+
+func (r WorkspaceRole) Can(p Permission) bool {
+	for _, granted := range workspacePermissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+type WorkspaceMember struct {
+	WorkspaceID string
+	UserID      string
+	Email       string
+	Role        WorkspaceRole
+	CreatedAt   time.Time
+}
+
+// WorkspaceMembership is a workspace as seen by one of its members.
+type WorkspaceMembership struct {
+	Workspace
+	Role WorkspaceRole
+}
+
+type Project struct {
+	ID          string
+	WorkspaceID string
+	Name        string
+	Description string
+	CreatedAt   time.Time
+}
+
+// WorkspaceInvitation lets someone join a workspace by email. It is
+// pending until accepted or expired.
+type WorkspaceInvitation struct {
+	ID          string
+	WorkspaceID string
+	Email       string
+	Role        WorkspaceRole
+	InvitedBy   string
+	ExpiresAt   time.Time
+	AcceptedAt  *time.Time
+	CreatedAt   time.Time
+}
+
+func (i WorkspaceInvitation) Pending(now time.Time) bool {
+	return i.AcceptedAt == nil && now.Before(i.ExpiresAt)
+}
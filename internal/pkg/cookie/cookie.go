@@ -44,3 +44,43 @@ func (m *CookieManager) ClearRefreshCookie(w http.ResponseWriter) {
 		MaxAge:   -1,
 	})
 }
+
+// oidcStateCookie ties an OIDC login to the browser that started it, so a
+// callback URL from someone else's login is rejected.
+const oidcStateCookie = "oidc_state"
+
+func (m *CookieManager) SetOIDCStateCookie(w http.ResponseWriter, state string, ttl time.Duration) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/auth/oidc",
+		Domain:   m.CookieDomain,
+		HttpOnly: true,
+		Secure:   m.CookieSecure,
+		// Lax still sends it on the provider's top-level redirect back
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   int(ttl.Seconds()),
+	})
+}
+
+// OIDCState returns the state set by SetOIDCStateCookie, or "".
+func (m *CookieManager) OIDCState(r *http.Request) string {
+	c, err := r.Cookie(oidcStateCookie)
+	if err != nil {
+		return ""
+	}
+	return c.Value
+}
+
+func (m *CookieManager) ClearOIDCStateCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    "",
+		Path:     "/auth/oidc",
+		Domain:   m.CookieDomain,
+		HttpOnly: true,
+		Secure:   m.CookieSecure,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+	})
+}
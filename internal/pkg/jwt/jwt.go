@@ -2,6 +2,7 @@ package jwt
 
 import (
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
@@ -14,6 +15,33 @@ type JWT struct {
 	Secret []byte
 }
 
+// Claims is the registered claim set carried by every token we issue.
+type Claims struct {
+	Subject   string `json:"sub"`
+	ID        string `json:"jti,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	// IssuedAtMicro is iat to the microsecond, so a token issued right
+	// after a user-wide revocation is not caught by it.
+	IssuedAtMicro int64 `json:"iat_us,omitempty"`
+	// Purpose marks special-use tokens (e.g. an MFA challenge). It is empty
+	// for regular access tokens.
+	Purpose string `json:"purpose,omitempty"`
+	// ClientID and Scope are set on tokens issued to OAuth clients, which
+	// may only act within the space-separated scopes granted to them.
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+}
+
+// IssuedAtUnixMicro returns when the token was issued in Unix
+// microseconds, falling back to iat for tokens without iat_us.
+func (c Claims) IssuedAtUnixMicro() int64 {
+	if c.IssuedAtMicro != 0 {
+		return c.IssuedAtMicro
+	}
+	return c.IssuedAt * 1_000_000
+}
+
 func New(secret []byte) *JWT {
 	return &JWT{Secret: secret}
 }
@@ -32,21 +60,64 @@ func signHMACSHA256(msg, secret []byte) []byte {
 	return m.Sum(nil)
 }
 
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return b64url(b), nil
+}
+
 func (j *JWT) Sign(sub string, ttl time.Duration) (string, error) {
-	header := map[string]any{"alg": "HS256", "typ": "JWT"}
+	return j.SignPurpose(sub, "", ttl)
+}
+
+func (j *JWT) SignPurpose(sub, purpose string, ttl time.Duration) (string, error) {
+	jti, err := newID()
+	if err != nil {
+		return "", err
+	}
+
 	now := time.Now().UTC()
-	payload := map[string]any{
-		"sub": sub,
-		"iat": now.Unix(),
-		"exp": now.Add(ttl).Unix(),
+	return j.SignClaims(Claims{
+		Subject:       sub,
+		ID:            jti,
+		IssuedAt:      now.Unix(),
+		ExpiresAt:     now.Add(ttl).Unix(),
+		IssuedAtMicro: now.UnixMicro(),
+		Purpose:       purpose,
+	})
+}
+
+// SignDelegated issues an access token on behalf of sub to an OAuth client,
+// limited to scopes.
+func (j *JWT) SignDelegated(sub, clientID string, scopes []string, ttl time.Duration) (string, error) {
+	jti, err := newID()
+	if err != nil {
+		return "", err
 	}
 
+	now := time.Now().UTC()
+	return j.SignClaims(Claims{
+		Subject:       sub,
+		ID:            jti,
+		IssuedAt:      now.Unix(),
+		ExpiresAt:     now.Add(ttl).Unix(),
+		IssuedAtMicro: now.UnixMicro(),
+		ClientID:      clientID,
+		Scope:         strings.Join(scopes, " "),
+	})
+}
+
+func (j *JWT) SignClaims(c Claims) (string, error) {
+	header := map[string]any{"alg": "HS256", "typ": "JWT"}
+
 	hb, err := json.Marshal(header)
 	if err != nil {
 		return "", err
 	}
 
-	pb, err := json.Marshal(payload)
+	pb, err := json.Marshal(c)
 	if err != nil {
 		return "", err
 	}
@@ -60,43 +131,47 @@ func (j *JWT) Sign(sub string, ttl time.Duration) (string, error) {
 }
 
 func (j *JWT) Verify(token string) (sub string, err error) {
+	c, err := j.Parse(token)
+	if err != nil {
+		return "", err
+	}
+	return c.Subject, nil
+}
+
+func (j *JWT) Parse(token string) (Claims, error) {
 	parts := strings.Split(token, ".")
 	if len(parts) != 3 {
-		return "", errors.New("invalid token")
+		return Claims{}, errors.New("invalid token")
 	}
 
 	msg := parts[0] + "." + parts[1]
 	want := signHMACSHA256([]byte(msg), j.Secret)
 	got, err := b64urldecode(parts[2])
 	if err != nil {
-		return "", errors.New("invalid token")
+		return Claims{}, errors.New("invalid token")
 	}
 
 	if !hmac.Equal(got, want) {
-		return "", errors.New("invalid signature")
+		return Claims{}, errors.New("invalid signature")
 	}
 
 	payloadBytes, err := b64urldecode(parts[1])
 	if err != nil {
-		return "", errors.New("invalid token")
-	}
-
-	var payload struct {
-		Sub string `json:"sub"`
-		Exp int64  `json:"exp"`
+		return Claims{}, errors.New("invalid token")
 	}
 
-	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
-		return "", errors.New("invalid token")
+	var c Claims
+	if err := json.Unmarshal(payloadBytes, &c); err != nil {
+		return Claims{}, errors.New("invalid token")
 	}
 
-	if payload.Sub == "" {
-		return "", errors.New("missing sub")
+	if c.Subject == "" {
+		return Claims{}, errors.New("missing sub")
 	}
 
-	if time.Now().UTC().Unix() >= payload.Exp {
-		return "", errors.New("expired")
+	if time.Now().UTC().Unix() >= c.ExpiresAt {
+		return Claims{}, errors.New("expired")
 	}
 
-	return payload.Sub, nil
+	return c, nil
 }
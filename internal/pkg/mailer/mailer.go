@@ -0,0 +1,122 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/smtp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	// Headers are extra header fields, such as X-Request-ID.
+	Headers map[string]string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// WithHeaders returns a Mailer that adds the headers derived from the send
+// context to every message before passing it to m. Headers already set on
+// a message win.
+func WithHeaders(m Mailer, headers func(ctx context.Context) map[string]string) Mailer {
+	return headerMailer{next: m, headers: headers}
+}
+
+type headerMailer struct {
+	next    Mailer
+	headers func(ctx context.Context) map[string]string
+}
+
+func (m headerMailer) Send(ctx context.Context, msg Message) error {
+	extra := m.headers(ctx)
+	if len(extra) > 0 {
+		merged := make(map[string]string, len(extra)+len(msg.Headers))
+		for k, v := range extra {
+			merged[k] = v
+		}
+		for k, v := range msg.Headers {
+			merged[k] = v
+		}
+		msg.Headers = merged
+	}
+	return m.next.Send(ctx, msg)
+}
+
+// SMTPMailer delivers plain-text mail through an SMTP relay. Auth is only
+// attempted when a username is configured.
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func NewSMTP(host string, port int, username, password, from string) *SMTPMailer {
+	return &SMTPMailer{
+		Host:     host,
+		Port:     port,
+		Username: username,
+		Password: password,
+		From:     from,
+	}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	addr := net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+	return smtp.SendMail(addr, auth, m.From, []string{msg.To}, format(m.From, msg))
+}
+
+// LogMailer writes messages to w instead of delivering them. It is meant
+// for development, where links can be copied straight from the output.
+type LogMailer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	From string
+}
+
+func NewLog(w io.Writer, from string) *LogMailer {
+	return &LogMailer{w: w, From: from}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := fmt.Fprintf(m.w, "----- mail %s -----\n%s\n", time.Now().Format(time.RFC3339), format(m.From, msg))
+	return err
+}
+
+// headerSanitizer keeps user-influenced values from injecting headers.
+var headerSanitizer = strings.NewReplacer("\r", "", "\n", "")
+
+func format(from string, msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + headerSanitizer.Replace(from) + "\r\n")
+	b.WriteString("To: " + headerSanitizer.Replace(msg.To) + "\r\n")
+	b.WriteString("Subject: " + headerSanitizer.Replace(msg.Subject) + "\r\n")
+	for _, k := range slices.Sorted(maps.Keys(msg.Headers)) {
+		b.WriteString(headerSanitizer.Replace(k) + ": " + headerSanitizer.Replace(msg.Headers[k]) + "\r\n")
+	}
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+	return []byte(b.String())
+}
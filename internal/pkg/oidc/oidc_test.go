@@ -0,0 +1,149 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"task-flow/internal/pkg/oidc/oidctest"
+)
+
+func newTestProvider(t *testing.T) (*Provider, *oidctest.Server) {
+	t.Helper()
+
+	idp := oidctest.NewServer()
+	t.Cleanup(idp.Close)
+
+	p := NewProvider(Config{
+		Name:         "mock",
+		Issuer:       idp.URL,
+		ClientID:     oidctest.ClientID,
+		ClientSecret: oidctest.ClientSecret,
+		RedirectURL:  "http://localhost/auth/oidc/mock/callback",
+	}, idp.Client())
+	return p, idp
+}
+
+func TestCodeFlow(t *testing.T) {
+	p, idp := newTestProvider(t)
+	ctx := context.Background()
+
+	verifier, challenge, err := NewPKCE()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authURL, err := p.AuthCodeURL(ctx, "st", "n-0S6_WzA2Mj", challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(authURL)
+	if q := u.Query(); q.Get("scope") != "openid email profile" || q.Get("redirect_uri") != p.RedirectURL {
+		t.Fatalf("unexpected auth url %s", authURL)
+	}
+
+	state, code, err := idp.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != "st" {
+		t.Fatalf("state not passed through: %q", state)
+	}
+
+	raw, err := p.Exchange(ctx, code, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := p.VerifyIDToken(ctx, raw, "n-0S6_WzA2Mj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Subject != "alice" || c.Email != "alice@example.com" || !c.EmailVerified {
+		t.Fatalf("unexpected claims %+v", c)
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	p, idp := newTestProvider(t)
+	ctx := context.Background()
+
+	_, challenge, _ := NewPKCE()
+	authURL, err := p.AuthCodeURL(ctx, "st", "nonce", challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, code, err := idp.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.Exchange(ctx, code, "other-verifier"); err == nil {
+		t.Fatal("expected exchange to fail")
+	}
+}
+
+func TestVerifyIDTokenRejects(t *testing.T) {
+	p, idp := newTestProvider(t)
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token func() string
+		nonce string
+	}{
+		{"nonce mismatch", func() string { return idp.IDToken("nonce", nil) }, "other"},
+		{"wrong audience", func() string { return idp.IDToken("nonce", map[string]any{"aud": "someone-else"}) }, "nonce"},
+		{"wrong issuer", func() string { return idp.IDToken("nonce", map[string]any{"iss": "https://evil.example"}) }, "nonce"},
+		{"expired", func() string {
+			return idp.IDToken("nonce", map[string]any{"exp": time.Now().Add(-time.Hour).Unix()})
+		}, "nonce"},
+		{"audience list without azp", func() string {
+			return idp.IDToken("nonce", map[string]any{"aud": []string{oidctest.ClientID, "api"}})
+		}, "nonce"},
+		{"unknown key", func() string { return idp.Sign("other-key", map[string]any{"sub": "alice"}) }, "nonce"},
+		{"bad signature", func() string {
+			good := idp.Key
+			idp.Key = other
+			defer func() { idp.Key = good }()
+			return idp.IDToken("nonce", nil)
+		}, "nonce"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.VerifyIDToken(context.Background(), tt.token(), tt.nonce)
+			if !errors.Is(err, ErrInvalidIDToken) {
+				t.Fatalf("expected ErrInvalidIDToken, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAudienceListWithAzp(t *testing.T) {
+	p, idp := newTestProvider(t)
+
+	token := idp.IDToken("nonce", map[string]any{
+		"aud": []string{oidctest.ClientID, "api"},
+		"azp": oidctest.ClientID,
+	})
+	if _, err := p.VerifyIDToken(context.Background(), token, "nonce"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	p, idp := newTestProvider(t)
+	p.Issuer = idp.URL + "/"
+
+	if _, err := p.AuthCodeURL(context.Background(), "s", "n", "c"); err == nil {
+		t.Fatal("expected issuer mismatch")
+	}
+}
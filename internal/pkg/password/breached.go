@@ -0,0 +1,87 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const prefixLen = 5
+
+// BreachedList answers "has this password been breached?" from a local
+// copy of SHA-1 hashes, indexed by their 5-character prefix the same way
+// the Pwned Passwords range API buckets them.
+type BreachedList struct {
+	buckets map[string]map[string]struct{}
+}
+
+// LoadBreachedList reads a file with one upper- or lower-case SHA-1 hex
+// digest per line, optionally followed by ":count" as in the Pwned
+// Passwords downloads. Blank lines and lines starting with # are ignored.
+func LoadBreachedList(path string) (*BreachedList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadBreachedList(f)
+}
+
+func ReadBreachedList(r io.Reader) (*BreachedList, error) {
+	l := &BreachedList{buckets: make(map[string]map[string]struct{})}
+
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(text, ":")
+		hash = strings.ToUpper(hash)
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("line %d: expected sha1 hex digest", line)
+		}
+		if _, err := hex.DecodeString(hash); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		l.add(hash)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (l *BreachedList) add(hash string) {
+	prefix, suffix := hash[:prefixLen], hash[prefixLen:]
+	b, ok := l.buckets[prefix]
+	if !ok {
+		b = make(map[string]struct{})
+		l.buckets[prefix] = b
+	}
+	b[suffix] = struct{}{}
+}
+
+func (l *BreachedList) Contains(pw string) bool {
+	sum := sha1.Sum([]byte(pw))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	_, found := l.buckets[hash[:prefixLen]][hash[prefixLen:]]
+	return found
+}
+
+func (l *BreachedList) Len() int {
+	n := 0
+	for _, b := range l.buckets {
+		n += len(b)
+	}
+	return n
+}
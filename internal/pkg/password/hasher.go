@@ -0,0 +1,200 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Hasher is one password hashing algorithm. Hashes are self-describing
+// strings (PHC format for argon2id, modular crypt for bcrypt) so several
+// algorithms can coexist in users.pass_hash.
+type Hasher interface {
+	// Supports reports whether encoded was produced by this algorithm.
+	Supports(encoded string) bool
+	Hash(pw string) (string, error)
+	Verify(pw, encoded string) (bool, error)
+	// NeedsRehash reports whether encoded uses weaker parameters than
+	// Hash would use today.
+	NeedsRehash(encoded string) bool
+}
+
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+// Chain hashes new passwords with its preferred algorithm and still
+// verifies hashes from the legacy ones, flagging them for upgrade.
+type Chain struct {
+	preferred Hasher
+	all       []Hasher
+}
+
+func NewChain(preferred Hasher, legacy ...Hasher) *Chain {
+	return &Chain{
+		preferred: preferred,
+		all:       append([]Hasher{preferred}, legacy...),
+	}
+}
+
+func (c *Chain) Hash(pw string) (string, error) {
+	return c.preferred.Hash(pw)
+}
+
+// Verify checks pw against encoded. needsRehash is set on a match whose
+// hash should be replaced by c.Hash(pw).
+func (c *Chain) Verify(pw, encoded string) (ok, needsRehash bool, err error) {
+	for _, h := range c.all {
+		if !h.Supports(encoded) {
+			continue
+		}
+
+		ok, err := h.Verify(pw, encoded)
+		if err != nil || !ok {
+			return false, false, err
+		}
+		return true, h != c.preferred || h.NeedsRehash(encoded), nil
+	}
+	return false, false, ErrUnknownHash
+}
+
+// ============================================
+// BCRYPT
+// ============================================
+
+type Bcrypt struct {
+	Cost int
+}
+
+func NewBcrypt(cost int) *Bcrypt {
+	return &Bcrypt{Cost: cost}
+}
+
+func (b *Bcrypt) Supports(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func (b *Bcrypt) Hash(pw string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(pw), b.Cost)
+	return string(hash), err
+}
+
+func (b *Bcrypt) Verify(pw, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(pw))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (b *Bcrypt) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost < b.Cost
+}
+
+// ============================================
+// ARGON2ID
+// ============================================
+
+type Argon2Params struct {
+	Memory  uint32 // KiB
+	Time    uint32
+	Threads uint8
+	SaltLen uint32
+	KeyLen  uint32
+}
+
+// DefaultArgon2Params follow the OWASP baseline for argon2id.
+var DefaultArgon2Params = Argon2Params{
+	Memory:  64 * 1024,
+	Time:    3,
+	Threads: 2,
+	SaltLen: 16,
+	KeyLen:  32,
+}
+
+type Argon2id struct {
+	Params Argon2Params
+}
+
+func NewArgon2id(p Argon2Params) *Argon2id {
+	return &Argon2id{Params: p}
+}
+
+var b64 = base64.RawStdEncoding
+
+func (a *Argon2id) Supports(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+func (a *Argon2id) Hash(pw string) (string, error) {
+	salt := make([]byte, a.Params.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(pw), salt, a.Params.Time, a.Params.Memory, a.Params.Threads, a.Params.KeyLen)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, a.Params.Memory, a.Params.Time, a.Params.Threads,
+		b64.EncodeToString(salt), b64.EncodeToString(key),
+	), nil
+}
+
+func (a *Argon2id) Verify(pw, encoded string) (bool, error) {
+	p, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	got := argon2.IDKey([]byte(pw), salt, p.Time, p.Memory, p.Threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(got, key) == 1, nil
+}
+
+func (a *Argon2id) NeedsRehash(encoded string) bool {
+	p, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+
+	return p.Memory < a.Params.Memory ||
+		p.Time < a.Params.Time ||
+		p.Threads < a.Params.Threads ||
+		uint32(len(salt)) < a.Params.SaltLen ||
+		uint32(len(key)) < a.Params.KeyLen
+}
+
+// decodeArgon2id parses $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>.
+func decodeArgon2id(encoded string) (p Argon2Params, salt, key []byte, err error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, ErrUnknownHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return p, nil, nil, fmt.Errorf("argon2id version: %w", err)
+	}
+	if version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil {
+		return p, nil, nil, fmt.Errorf("argon2id params: %w", err)
+	}
+
+	if salt, err = b64.DecodeString(parts[4]); err != nil {
+		return p, nil, nil, fmt.Errorf("argon2id salt: %w", err)
+	}
+	if key, err = b64.DecodeString(parts[5]); err != nil {
+		return p, nil, nil, fmt.Errorf("argon2id hash: %w", err)
+	}
+
+	p.SaltLen = uint32(len(salt))
+	p.KeyLen = uint32(len(key))
+	return p, salt, key, nil
+}
@@ -0,0 +1,72 @@
+package password
+
+import (
+	"strings"
+	"testing"
+)
+
+// Small parameters keep the tests fast; the format is what matters here.
+var testArgon2Params = Argon2Params{Memory: 1024, Time: 1, Threads: 1, SaltLen: 16, KeyLen: 32}
+
+func TestArgon2id_HashVerify(t *testing.T) {
+	h := NewArgon2id(testArgon2Params)
+
+	encoded, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("unexpected PHC string: %s", encoded)
+	}
+
+	if ok, err := h.Verify("correct horse", encoded); err != nil || !ok {
+		t.Errorf("expected match, got %v, %v", ok, err)
+	}
+	if ok, _ := h.Verify("wrong horse", encoded); ok {
+		t.Error("expected mismatch")
+	}
+}
+
+func TestChain_UpgradesLegacyAndWeakHashes(t *testing.T) {
+	legacy := NewBcrypt(4)
+	weak := NewArgon2id(testArgon2Params)
+
+	stronger := testArgon2Params
+	stronger.Time = 2
+	chain := NewChain(NewArgon2id(stronger), legacy)
+
+	bcryptHash, _ := legacy.Hash("secret-pass")
+	weakHash, _ := weak.Hash("secret-pass")
+	currentHash, _ := chain.Hash("secret-pass")
+
+	cases := []struct {
+		name        string
+		encoded     string
+		needsRehash bool
+	}{
+		{"bcrypt", bcryptHash, true},
+		{"weak argon2id", weakHash, true},
+		{"current argon2id", currentHash, false},
+	}
+
+	for _, c := range cases {
+		ok, rehash, err := chain.Verify("secret-pass", c.encoded)
+		if err != nil || !ok {
+			t.Errorf("%s: expected match, got %v, %v", c.name, ok, err)
+		}
+		if rehash != c.needsRehash {
+			t.Errorf("%s: expected needsRehash=%v, got %v", c.name, c.needsRehash, rehash)
+		}
+
+		if ok, rehash, _ := chain.Verify("other-pass", c.encoded); ok || rehash {
+			t.Errorf("%s: expected mismatch without rehash", c.name)
+		}
+	}
+}
+
+func TestChain_UnknownFormat(t *testing.T) {
+	chain := NewChain(NewArgon2id(testArgon2Params))
+	if _, _, err := chain.Verify("pw", "plaintext"); err != ErrUnknownHash {
+		t.Errorf("expected ErrUnknownHash, got %v", err)
+	}
+}
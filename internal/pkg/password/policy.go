@@ -0,0 +1,122 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Rule identifiers are stable so the frontend can map them to its own copy.
+const (
+	RuleMinLength     = "min_length"
+	RuleMaxLength     = "max_length"
+	RuleUppercase     = "uppercase"
+	RuleLowercase     = "lowercase"
+	RuleDigit         = "digit"
+	RuleSymbol        = "symbol"
+	RuleContainsEmail = "contains_email"
+	RuleBreached      = "breached"
+)
+
+type Policy struct {
+	MinLength int // in characters
+	// MaxBytes guards hashers with an input limit (bcrypt ignores
+	// everything past 72 bytes). Zero disables the check.
+	MaxBytes      int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	ForbidEmail   bool
+	// Breached rejects passwords found in a known-breach list when set.
+	Breached *BreachedList
+}
+
+var DefaultPolicy = Policy{
+	MinLength:   8,
+	MaxBytes:    72,
+	ForbidEmail: true,
+}
+
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PolicyError lists every rule a password broke, not just the first.
+type PolicyError struct {
+	Violations []Violation
+}
+
+func (e *PolicyError) Error() string {
+	return "password does not meet policy"
+}
+
+// Check validates pw against the policy. The email rule is skipped when
+// email is empty.
+func (p Policy) Check(pw, email string) error {
+	var v []Violation
+	add := func(rule, msg string) {
+		v = append(v, Violation{Rule: rule, Message: msg})
+	}
+
+	if utf8.RuneCountInString(pw) < p.MinLength {
+		add(RuleMinLength, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+	if p.MaxBytes > 0 && len(pw) > p.MaxBytes {
+		add(RuleMaxLength, fmt.Sprintf("must be at most %d bytes", p.MaxBytes))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range pw {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		add(RuleUppercase, "must contain an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		add(RuleLowercase, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		add(RuleDigit, "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		add(RuleSymbol, "must contain a symbol")
+	}
+
+	if p.ForbidEmail && email != "" && containsEmail(pw, email) {
+		add(RuleContainsEmail, "must not contain your email address")
+	}
+
+	if p.Breached != nil && p.Breached.Contains(pw) {
+		add(RuleBreached, "has appeared in a data breach, choose another one")
+	}
+
+	if len(v) > 0 {
+		return &PolicyError{Violations: v}
+	}
+	return nil
+}
+
+// containsEmail matches the full address or its local part; local parts
+// shorter than 3 characters are too common to be meaningful.
+func containsEmail(pw, email string) bool {
+	pw = strings.ToLower(pw)
+	email = strings.ToLower(email)
+	if strings.Contains(pw, email) {
+		return true
+	}
+
+	local, _, _ := strings.Cut(email, "@")
+	return len(local) >= 3 && strings.Contains(pw, local)
+}
@@ -0,0 +1,74 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func rules(err error) []string {
+	var pe *PolicyError
+	if !errors.As(err, &pe) {
+		return nil
+	}
+	var out []string
+	for _, v := range pe.Violations {
+		out = append(out, v.Rule)
+	}
+	return out
+}
+
+func TestPolicy_ReportsAllViolations(t *testing.T) {
+	p := Policy{MinLength: 10, RequireUpper: true, RequireDigit: true, RequireSymbol: true}
+
+	got := strings.Join(rules(p.Check("short", "")), ",")
+	want := "min_length,uppercase,digit,symbol"
+	if got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	if err := p.Check("Longer-Passw0rd", ""); err != nil {
+		t.Errorf("expected valid password, got %v", rules(err))
+	}
+}
+
+func TestPolicy_MaxBytes(t *testing.T) {
+	err := DefaultPolicy.Check(strings.Repeat("a", 73), "")
+	if got := rules(err); len(got) != 1 || got[0] != RuleMaxLength {
+		t.Errorf("expected max_length violation, got %v", got)
+	}
+}
+
+func TestPolicy_ContainsEmail(t *testing.T) {
+	err := DefaultPolicy.Check("John.Doe2024!", "john.doe@mail.com")
+	if got := rules(err); len(got) != 1 || got[0] != RuleContainsEmail {
+		t.Errorf("expected contains_email violation, got %v", got)
+	}
+}
+
+func TestBreachedList(t *testing.T) {
+	// SHA-1("password") and SHA-1("123456")
+	list, err := ReadBreachedList(strings.NewReader(
+		"# sample\n5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\n7c4a8d09ca3762af61e59520943dc26494f8941b\n",
+	))
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if list.Len() != 2 {
+		t.Fatalf("expected 2 hashes, got %d", list.Len())
+	}
+
+	p := Policy{MinLength: 1, Breached: list}
+	if got := rules(p.Check("password", "")); len(got) != 1 || got[0] != RuleBreached {
+		t.Errorf("expected breached violation, got %v", got)
+	}
+	if err := p.Check("123457", ""); err != nil {
+		t.Errorf("expected unlisted password to pass, got %v", rules(err))
+	}
+}
+
+func TestReadBreachedList_InvalidLine(t *testing.T) {
+	if _, err := ReadBreachedList(strings.NewReader("not-a-hash\n")); err == nil {
+		t.Error("expected error for malformed line")
+	}
+}
@@ -0,0 +1,56 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test vectors from RFC 6238 Appendix B (SHA1), truncated to 6 digits.
+func TestCode_RFC6238Vectors(t *testing.T) {
+	secret := []byte("12345678901234567890")
+
+	cases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, c := range cases {
+		got := Code(secret, Counter(time.Unix(c.unix, 0)))
+		if got != c.want {
+			t.Errorf("t=%d: expected %s, got %s", c.unix, c.want, got)
+		}
+	}
+}
+
+func TestValidate_Skew(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	now := time.Unix(1234567890, 0)
+
+	prev := Code(secret, Counter(now)-1)
+	if _, ok := Validate(secret, prev, now); !ok {
+		t.Error("expected previous period code to be accepted")
+	}
+
+	old := Code(secret, Counter(now)-2)
+	if _, ok := Validate(secret, old, now); ok {
+		t.Error("expected code two periods old to be rejected")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Task Flow", "test@mail.com", []byte("12345678901234567890"))
+
+	if !strings.HasPrefix(uri, "otpauth://totp/Task%20Flow:test@mail.com?") {
+		t.Errorf("unexpected uri prefix: %s", uri)
+	}
+	if !strings.Contains(uri, "secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ") {
+		t.Errorf("expected base32 secret in uri: %s", uri)
+	}
+}
@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	bucket Bucket
+	// full is when the bucket will have refilled; after that the entry
+	// is the same as none
+	full time.Time
+}
+
+type memoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]memoryEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore returns a process-local store. With several instances
+// each one counts separately, so a client gets the limit once per
+// instance. Full buckets are dropped, so memory stays bounded by the
+// clients seen in the longest policy period.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		buckets: make(map[string]memoryEntry),
+		now:     time.Now,
+	}
+}
+
+func (s *memoryStore) Take(ctx context.Context, key string, p Policy) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+	b, res := p.Take(s.buckets[key].bucket, now)
+	s.buckets[key] = memoryEntry{bucket: b, full: now.Add(res.Reset)}
+	return res, nil
+}
+
+// sweep removes full buckets at most once a minute. Callers hold s.mu.
+func (s *memoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for key, e := range s.buckets {
+		if !e.full.After(now) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Package ratelimit implements token-bucket rate limiting. A Policy says
+// how many requests a key may make in a burst and how fast that allowance
+// comes back; a Store keeps one bucket per key, in memory for a single
+// instance or in a database shared by several.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy allows Limit requests at once, refilled evenly over Period: with
+// 10 per minute a client may make 10 requests straight away and then one
+// every 6 seconds.
+type Policy struct {
+	// Name identifies the policy in bucket keys, headers and metrics.
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+// ParsePolicy reads "limit/period", such as "10/1m".
+func ParsePolicy(name, s string) (Policy, error) {
+	limit, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Policy{}, fmt.Errorf("rate limit %q: want limit/period", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(limit))
+	if err != nil || n <= 0 {
+		return Policy{}, fmt.Errorf("rate limit %q: invalid limit", s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(period))
+	if err != nil || d <= 0 {
+		return Policy{}, fmt.Errorf("rate limit %q: invalid period", s)
+	}
+	return Policy{Name: name, Limit: n, Period: d}, nil
+}
+
+func (p Policy) String() string {
+	return fmt.Sprintf("%d/%s", p.Limit, p.Period)
+}
+
+// rate is the refill speed in tokens per second.
+func (p Policy) rate() float64 {
+	return float64(p.Limit) / p.Period.Seconds()
+}
+
+// Bucket is the state a Store keeps per key. The zero Bucket is full.
+type Bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next token, when not Allowed.
+	RetryAfter time.Duration
+}
+
+// Take refills b for the time elapsed until now and removes one token if
+// there is one. It returns the new state to store.
+func (p Policy) Take(b Bucket, now time.Time) (Bucket, Result) {
+	tokens := float64(p.Limit)
+	if !b.Updated.IsZero() {
+		tokens = min(tokens, b.Tokens+now.Sub(b.Updated).Seconds()*p.rate())
+	}
+
+	res := Result{Limit: p.Limit}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = p.wait(1 - tokens)
+	}
+	res.Remaining = int(tokens)
+	res.Reset = p.wait(float64(p.Limit) - tokens)
+	return Bucket{Tokens: tokens, Updated: now}, res
+}
+
+// wait is how long refilling n tokens takes.
+func (p Policy) wait(n float64) time.Duration {
+	return time.Duration(math.Ceil(n / p.rate() * float64(time.Second)))
+}
+
+// Store takes tokens from the bucket of each key under a policy. It must
+// be safe for concurrent use, and so must shared stores across instances.
+type Store interface {
+	Take(ctx context.Context, key string, p Policy) (Result, error)
+}
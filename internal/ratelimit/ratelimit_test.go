@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// ==========================================
+// TESTS
+// ==========================================
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy("login", "10/1m")
+	if err != nil || p.Limit != 10 || p.Period != time.Minute || p.Name != "login" {
+		t.Fatalf("p = %+v, err = %v", p, err)
+	}
+	for _, s := range []string{"", "10", "x/1m", "0/1m", "10/x", "10/0s", "-1/1m"} {
+		if _, err := ParsePolicy("x", s); err == nil {
+			t.Errorf("accepted %q", s)
+		}
+	}
+}
+
+func TestPolicy_Take(t *testing.T) {
+	p := Policy{Name: "p", Limit: 3, Period: 3 * time.Second}
+	now := time.Unix(1000, 0)
+
+	var b Bucket
+	var res Result
+	for i := range 3 {
+		b, res = p.Take(b, now)
+		if !res.Allowed || res.Remaining != 2-i {
+			t.Fatalf("request %d: %+v", i, res)
+		}
+	}
+	if res.Reset != 3*time.Second {
+		t.Errorf("Reset = %v, want the time to refill 3 tokens", res.Reset)
+	}
+
+	b, res = p.Take(b, now)
+	if res.Allowed || res.RetryAfter != time.Second {
+		t.Fatalf("over the limit: %+v", res)
+	}
+
+	// One token comes back per second
+	b, res = p.Take(b, now.Add(time.Second))
+	if !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("after refill: %+v", res)
+	}
+
+	// and never more than the limit
+	_, res = p.Take(b, now.Add(time.Hour))
+	if !res.Allowed || res.Remaining != 2 {
+		t.Errorf("after a long pause: %+v", res)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore().(*memoryStore)
+	now := time.Unix(1000, 0)
+	s.now = func() time.Time { return now }
+	p := Policy{Name: "p", Limit: 1, Period: time.Minute}
+	ctx := context.Background()
+
+	if res, _ := s.Take(ctx, "a", p); !res.Allowed {
+		t.Fatal("first request refused")
+	}
+	if res, _ := s.Take(ctx, "a", p); res.Allowed {
+		t.Error("second request allowed")
+	}
+	if res, _ := s.Take(ctx, "b", p); !res.Allowed {
+		t.Error("keys share a bucket")
+	}
+
+	// Refilled buckets are swept
+	now = now.Add(2 * time.Minute)
+	if res, _ := s.Take(ctx, "c", p); !res.Allowed {
+		t.Fatal("request refused")
+	}
+	if _, ok := s.buckets["a"]; ok {
+		t.Error("full bucket kept")
+	}
+}
@@ -0,0 +1,13 @@
+package repository
+
+import "context"
+
+// AccessTokenDenylist records access tokens that must be rejected before
+// their natural expiry. Entries only need to outlive the access TTL.
+type AccessTokenDenylist interface {
+	Revoke(ctx context.Context, jti string, expUnix int64) error
+	// RevokeUser rejects every token of userID issued at or before
+	// issuedThroughUnixMicro. The entry may be dropped after expUnix.
+	RevokeUser(ctx context.Context, userID string, issuedThroughUnixMicro, expUnix int64) error
+	IsRevoked(ctx context.Context, jti, userID string, issuedAtUnixMicro int64) (bool, error)
+}
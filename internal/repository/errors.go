@@ -0,0 +1,8 @@
+package repository
+
+import "task-flow/internal/apperr"
+
+// ErrDuplicate is returned when a write breaks a unique constraint, such as
+// two accounts racing for the same email. Services usually translate it
+// into an error that names the conflict.
+var ErrDuplicate = apperr.Conflict("duplicate", "resource already exists")
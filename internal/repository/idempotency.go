@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"task-flow/internal/model"
+)
+
+type IdempotencyRepo interface {
+	// Reserve claims userID's key for a new request until expUnix. If an
+	// unexpired record already holds the key it is returned instead, with
+	// reserved false.
+	Reserve(ctx context.Context, userID, key string, fingerprint []byte, expUnix int64) (existing model.IdempotencyRecord, reserved bool, err error)
+	// Complete stores the response of a reserved request and keeps it for
+	// replay until expUnix.
+	Complete(ctx context.Context, rec model.IdempotencyRecord, expUnix int64) error
+	// Release drops an unfinished reservation so the request can be
+	// retried.
+	Release(ctx context.Context, userID, key string) error
+}
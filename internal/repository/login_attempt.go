@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"task-flow/internal/model"
+)
+
+type LoginAttemptRepo interface {
+	Record(ctx context.Context, attempt model.LoginAttempt) error
+	// AccountFailures counts failed attempts for email since the later of
+	// sinceUnix and its last successful login, and returns how long ago the
+	// newest one was. The age is measured by the store's own clock, so it
+	// holds whatever time zone the store keeps timestamps in.
+	AccountFailures(ctx context.Context, email string, sinceUnix int64) (failures int, sinceLast time.Duration, err error)
+	// IPFailures counts failed attempts from ip since sinceUnix regardless of
+	// successes, so one valid account can't be used to reset the counter.
+	IPFailures(ctx context.Context, ip string, sinceUnix int64) (failures int, sinceLast time.Duration, err error)
+}
@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"task-flow/internal/repository"
+)
+
+type userRevocation struct {
+	issuedThrough int64 // unix micro
+	exp           int64
+}
+
+type accessTokenDenylist struct {
+	mu        sync.Mutex
+	tokens    map[string]int64 // jti -> exp
+	users     map[string]userRevocation
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewAccessTokenDenylist returns a process-local denylist. Entries are
+// dropped once they expire, so memory stays bounded by the access TTL.
+func NewAccessTokenDenylist() repository.AccessTokenDenylist {
+	return &accessTokenDenylist{
+		tokens: make(map[string]int64),
+		users:  make(map[string]userRevocation),
+		now:    time.Now,
+	}
+}
+
+func (d *accessTokenDenylist) Revoke(ctx context.Context, jti string, expUnix int64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.sweep()
+	if expUnix > d.tokens[jti] {
+		d.tokens[jti] = expUnix
+	}
+	return nil
+}
+
+func (d *accessTokenDenylist) RevokeUser(ctx context.Context, userID string, issuedThroughUnixMicro, expUnix int64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.sweep()
+	rev := d.users[userID]
+	rev.issuedThrough = max(rev.issuedThrough, issuedThroughUnixMicro)
+	rev.exp = max(rev.exp, expUnix)
+	d.users[userID] = rev
+	return nil
+}
+
+func (d *accessTokenDenylist) IsRevoked(ctx context.Context, jti, userID string, issuedAtUnixMicro int64) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now().Unix()
+	if exp, ok := d.tokens[jti]; ok && exp > now {
+		return true, nil
+	}
+	if rev, ok := d.users[userID]; ok && rev.exp > now && issuedAtUnixMicro <= rev.issuedThrough {
+		return true, nil
+	}
+	return false, nil
+}
+
+// sweep removes expired entries at most once a minute. Callers hold d.mu.
+func (d *accessTokenDenylist) sweep() {
+	now := d.now()
+	if now.Sub(d.lastSweep) < time.Minute {
+		return
+	}
+	d.lastSweep = now
+
+	for jti, exp := range d.tokens {
+		if exp <= now.Unix() {
+			delete(d.tokens, jti)
+		}
+	}
+	for userID, rev := range d.users {
+		if rev.exp <= now.Unix() {
+			delete(d.users, userID)
+		}
+	}
+}
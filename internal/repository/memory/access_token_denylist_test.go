@@ -0,0 +1,58 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// ============================================
+// TESTS
+// ============================================
+
+func TestAccessTokenDenylist_RevokeUser_SameSecond(t *testing.T) {
+	d := NewAccessTokenDenylist()
+	ctx := context.Background()
+
+	cutoff := time.Now().Truncate(time.Second).Add(500 * time.Millisecond)
+	if err := d.RevokeUser(ctx, "user-1", cutoff.UnixMicro(), cutoff.Add(time.Hour).Unix()); err != nil {
+		t.Fatalf("revoke user: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		issuedAt time.Time
+		want     bool
+	}{
+		{"earlier in the second", cutoff.Add(-time.Microsecond), true},
+		{"at the cutoff", cutoff, true},
+		{"later in the same second", cutoff.Add(time.Microsecond), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revoked, err := d.IsRevoked(ctx, "jti", "user-1", tt.issuedAt.UnixMicro())
+			if err != nil {
+				t.Fatalf("is revoked: %v", err)
+			}
+			if revoked != tt.want {
+				t.Errorf("expected revoked=%v, got %v", tt.want, revoked)
+			}
+		})
+	}
+}
+
+func TestAccessTokenDenylist_RevokeUser_Expired(t *testing.T) {
+	d := NewAccessTokenDenylist().(*accessTokenDenylist)
+	ctx := context.Background()
+
+	now := time.Now()
+	if err := d.RevokeUser(ctx, "user-1", now.UnixMicro(), now.Add(time.Minute).Unix()); err != nil {
+		t.Fatalf("revoke user: %v", err)
+	}
+	d.now = func() time.Time { return now.Add(2 * time.Minute) }
+
+	revoked, _ := d.IsRevoked(ctx, "jti", "user-1", now.Add(-time.Second).UnixMicro())
+	if revoked {
+		t.Error("expected revocation to lapse once the access TTL has passed")
+	}
+}
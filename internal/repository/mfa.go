@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"task-flow/internal/model"
+)
+
+type MFARepo interface {
+	// SaveSecret stores a new pending secret, replacing any previous one.
+	SaveSecret(ctx context.Context, userID string, secret []byte) error
+	Find(ctx context.Context, userID string) (model.MFA, bool, error)
+	// Enable activates the enrolment and stores its recovery code hashes.
+	Enable(ctx context.Context, userID string, counter int64, recoveryHashes [][]byte) error
+	// UseCounter records a redeemed TOTP counter. It reports false if the
+	// counter is not newer than the last one used, i.e. a replayed code.
+	UseCounter(ctx context.Context, userID string, counter int64) (bool, error)
+	ConsumeRecoveryCode(ctx context.Context, userID string, codeHash []byte) (bool, error)
+	Delete(ctx context.Context, userID string) error
+}
@@ -0,0 +1,69 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"task-flow/internal/repository"
+)
+
+type accessTokenDenylist struct {
+	db *sql.DB
+}
+
+func NewAccessTokenDenylist(db *sql.DB) repository.AccessTokenDenylist {
+	return &accessTokenDenylist{db: db}
+}
+
+func (r *accessTokenDenylist) Revoke(ctx context.Context, jti string, expUnix int64) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT IGNORE INTO access_token_denylist (jti, expires_at) VALUES (?, FROM_UNIXTIME(?))",
+		jti, expUnix,
+	)
+	if err != nil {
+		return err
+	}
+
+	// Entries are useless once the token itself has expired
+	_, err = r.db.ExecContext(ctx,
+		"DELETE FROM access_token_denylist WHERE expires_at < NOW() LIMIT 100",
+	)
+	return err
+}
+
+func (r *accessTokenDenylist) RevokeUser(ctx context.Context, userID string, issuedThroughUnixMicro, expUnix int64) error {
+	sec, usec := splitUnixMicro(issuedThroughUnixMicro)
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO user_access_revocations (user_id, revoked_before, expires_at)
+		VALUES (?, FROM_UNIXTIME(?) + INTERVAL ? MICROSECOND, FROM_UNIXTIME(?))
+		ON DUPLICATE KEY UPDATE
+			revoked_before = GREATEST(revoked_before, VALUES(revoked_before)),
+			expires_at = GREATEST(expires_at, VALUES(expires_at))`,
+		userID, sec, usec, expUnix,
+	)
+	return err
+}
+
+func (r *accessTokenDenylist) IsRevoked(ctx context.Context, jti, userID string, issuedAtUnixMicro int64) (bool, error) {
+	sec, usec := splitUnixMicro(issuedAtUnixMicro)
+	var revoked bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT
+			EXISTS(SELECT 1 FROM access_token_denylist WHERE jti = ? AND expires_at > NOW())
+			OR EXISTS(SELECT 1 FROM user_access_revocations
+				WHERE user_id = ? AND revoked_before >= FROM_UNIXTIME(?) + INTERVAL ? MICROSECOND
+				AND expires_at > NOW())`,
+		jti, userID, sec, usec,
+	).Scan(&revoked)
+	if err != nil {
+		return false, err
+	}
+	return revoked, nil
+}
+
+// splitUnixMicro splits a Unix time in microseconds into whole seconds
+// for FROM_UNIXTIME and the microseconds left over, so no precision is
+// lost to a floating-point argument.
+func splitUnixMicro(us int64) (sec, usec int64) {
+	return us / 1_000_000, us % 1_000_000
+}
@@ -0,0 +1,22 @@
+package mysql
+
+import (
+	"errors"
+
+	driver "github.com/go-sql-driver/mysql"
+
+	"task-flow/internal/repository"
+)
+
+// erDupEntry is MySQL's ER_DUP_ENTRY.
+const erDupEntry = 1062
+
+// mapError turns driver errors a client can act on into repository errors.
+// The driver message names tables and keys, so it is dropped.
+func mapError(err error) error {
+	var me *driver.MySQLError
+	if errors.As(err, &me) && me.Number == erDupEntry {
+		return repository.ErrDuplicate
+	}
+	return err
+}
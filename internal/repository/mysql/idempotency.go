@@ -0,0 +1,90 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"task-flow/internal/model"
+	"task-flow/internal/repository"
+)
+
+type idempotencyRepo struct {
+	db *sql.DB
+}
+
+func NewIdempotencyRepo(db *sql.DB) repository.IdempotencyRepo {
+	return &idempotencyRepo{db: db}
+}
+
+func (r *idempotencyRepo) Reserve(ctx context.Context, userID, key string, fingerprint []byte, expUnix int64) (model.IdempotencyRecord, bool, error) {
+	// An expired record is taken over in place, in the same statement, so
+	// two retries racing for it can't both win. expires_at is assigned
+	// last because the conditions read it.
+	res, err := r.db.ExecContext(ctx,
+		`INSERT INTO idempotency_keys (user_id, idempotency_key, fingerprint, expires_at)
+		VALUES (?, ?, ?, FROM_UNIXTIME(?))
+		ON DUPLICATE KEY UPDATE
+			fingerprint = IF(expires_at <= NOW(), VALUES(fingerprint), fingerprint),
+			status_code = IF(expires_at <= NOW(), NULL, status_code),
+			response_headers = IF(expires_at <= NOW(), NULL, response_headers),
+			response_body = IF(expires_at <= NOW(), NULL, response_body),
+			expires_at = IF(expires_at <= NOW(), VALUES(expires_at), expires_at)`,
+		userID, key, fingerprint, expUnix,
+	)
+	if err != nil {
+		return model.IdempotencyRecord{}, false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return model.IdempotencyRecord{}, false, err
+	}
+	if n > 0 {
+		// Clear out some expired responses; the key is ours either way
+		_, _ = r.db.ExecContext(ctx,
+			"DELETE FROM idempotency_keys WHERE expires_at < NOW() LIMIT 100",
+		)
+		return model.IdempotencyRecord{}, true, nil
+	}
+
+	rec := model.IdempotencyRecord{UserID: userID, Key: key}
+	var status sql.NullInt64
+	var header sql.NullString
+	err = r.db.QueryRowContext(ctx,
+		`SELECT fingerprint, status_code, response_headers, response_body
+		FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ?`,
+		userID, key,
+	).Scan(&rec.Fingerprint, &status, &header, &rec.Body)
+	if err != nil {
+		return model.IdempotencyRecord{}, false, err
+	}
+	rec.StatusCode = int(status.Int64)
+	if header.Valid {
+		if err := json.Unmarshal([]byte(header.String), &rec.Header); err != nil {
+			return model.IdempotencyRecord{}, false, err
+		}
+	}
+	return rec, false, nil
+}
+
+func (r *idempotencyRepo) Complete(ctx context.Context, rec model.IdempotencyRecord, expUnix int64) error {
+	header, err := json.Marshal(rec.Header)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx,
+		`UPDATE idempotency_keys
+		SET status_code = ?, response_headers = ?, response_body = ?, expires_at = FROM_UNIXTIME(?)
+		WHERE user_id = ? AND idempotency_key = ?`,
+		rec.StatusCode, string(header), rec.Body, expUnix, rec.UserID, rec.Key,
+	)
+	return err
+}
+
+func (r *idempotencyRepo) Release(ctx context.Context, userID, key string) error {
+	_, err := r.db.ExecContext(ctx,
+		"DELETE FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ? AND status_code IS NULL",
+		userID, key,
+	)
+	return err
+}
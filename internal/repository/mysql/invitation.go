@@ -0,0 +1,130 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"task-flow/internal/model"
+	"task-flow/internal/repository"
+)
+
+type invitationRepo struct {
+	db *sql.DB
+}
+
+func NewInvitationRepo(db *sql.DB) repository.InvitationRepo {
+	return &invitationRepo{db: db}
+}
+
+const invitationColumns = "id, workspace_id, email, role, COALESCE(invited_by, ''), expires_at, accepted_at, created_at"
+
+func scanInvitation(row rowScanner) (model.WorkspaceInvitation, error) {
+	var inv model.WorkspaceInvitation
+	var acceptedAt sql.NullTime
+	if err := row.Scan(&inv.ID, &inv.WorkspaceID, &inv.Email, &inv.Role, &inv.InvitedBy, &inv.ExpiresAt, &acceptedAt, &inv.CreatedAt); err != nil {
+		return model.WorkspaceInvitation{}, err
+	}
+	if acceptedAt.Valid {
+		inv.AcceptedAt = &acceptedAt.Time
+	}
+	return inv, nil
+}
+
+func (r *invitationRepo) Create(ctx context.Context, inv model.WorkspaceInvitation) error {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO workspace_invitations (id, workspace_id, email, role, invited_by, expires_at)
+		VALUES (?, ?, ?, ?, ?, FROM_UNIXTIME(?))`,
+		inv.ID, workspaceID, inv.Email, inv.Role, inv.InvitedBy, inv.ExpiresAt.Unix(),
+	)
+	return err
+}
+
+func (r *invitationRepo) ListPending(ctx context.Context) ([]model.WorkspaceInvitation, error) {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+invitationColumns+` FROM workspace_invitations
+		WHERE workspace_id = ? AND accepted_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC`,
+		workspaceID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invitations []model.WorkspaceInvitation
+	for rows.Next() {
+		inv, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, inv)
+	}
+	return invitations, rows.Err()
+}
+
+func (r *invitationRepo) Revoke(ctx context.Context, id string) (bool, error) {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := r.db.ExecContext(ctx,
+		"DELETE FROM workspace_invitations WHERE id = ? AND workspace_id = ? AND accepted_at IS NULL",
+		id, workspaceID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *invitationRepo) RevokeForEmail(ctx context.Context, email string) error {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		"DELETE FROM workspace_invitations WHERE workspace_id = ? AND email = ? AND accepted_at IS NULL",
+		workspaceID, email,
+	)
+	return err
+}
+
+func (r *invitationRepo) FindByID(ctx context.Context, id string) (model.WorkspaceInvitation, bool, error) {
+	inv, err := scanInvitation(r.db.QueryRowContext(ctx,
+		"SELECT "+invitationColumns+" FROM workspace_invitations WHERE id = ?", id,
+	))
+	if err == sql.ErrNoRows {
+		return model.WorkspaceInvitation{}, false, nil
+	}
+	if err != nil {
+		return model.WorkspaceInvitation{}, false, err
+	}
+	return inv, true, nil
+}
+
+func (r *invitationRepo) Accept(ctx context.Context, id string) (bool, error) {
+	// Only the caller whose update succeeds may use the invitation
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE workspace_invitations SET accepted_at = NOW()
+		WHERE id = ? AND accepted_at IS NULL AND expires_at > NOW()`,
+		id,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
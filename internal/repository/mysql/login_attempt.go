@@ -0,0 +1,52 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"task-flow/internal/model"
+	"task-flow/internal/repository"
+)
+
+type loginAttemptRepo struct {
+	db *sql.DB
+}
+
+func NewLoginAttemptRepo(db *sql.DB) repository.LoginAttemptRepo {
+	return &loginAttemptRepo{db: db}
+}
+
+func (r *loginAttemptRepo) Record(ctx context.Context, attempt model.LoginAttempt) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO login_attempts (email, ip, success) VALUES (?, ?, ?)",
+		attempt.Email, attempt.IP, attempt.Success,
+	)
+	return err
+}
+
+func (r *loginAttemptRepo) AccountFailures(ctx context.Context, email string, sinceUnix int64) (int, time.Duration, error) {
+	return scanFailures(r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*), TIMESTAMPDIFF(MICROSECOND, MAX(created_at), NOW()) FROM login_attempts
+		WHERE email = ? AND success = FALSE AND created_at >= FROM_UNIXTIME(?)
+		AND id > COALESCE((SELECT MAX(id) FROM login_attempts WHERE email = ? AND success = TRUE), 0)`,
+		email, sinceUnix, email,
+	))
+}
+
+func (r *loginAttemptRepo) IPFailures(ctx context.Context, ip string, sinceUnix int64) (int, time.Duration, error) {
+	return scanFailures(r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*), TIMESTAMPDIFF(MICROSECOND, MAX(created_at), NOW()) FROM login_attempts
+		WHERE ip = ? AND success = FALSE AND created_at >= FROM_UNIXTIME(?)`,
+		ip, sinceUnix,
+	))
+}
+
+func scanFailures(row *sql.Row) (int, time.Duration, error) {
+	var n int
+	var sinceLast sql.NullInt64 // microseconds
+	if err := row.Scan(&n, &sinceLast); err != nil {
+		return 0, 0, err
+	}
+	return n, time.Duration(sinceLast.Int64) * time.Microsecond, nil
+}
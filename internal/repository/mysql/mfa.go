@@ -0,0 +1,123 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"task-flow/internal/model"
+	"task-flow/internal/repository"
+)
+
+type mfaRepo struct {
+	db *sql.DB
+}
+
+func NewMFARepo(db *sql.DB) repository.MFARepo {
+	return &mfaRepo{db: db}
+}
+
+func (r *mfaRepo) SaveSecret(ctx context.Context, userID string, secret []byte) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO user_mfa (user_id, secret) VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE secret = VALUES(secret), enabled_at = NULL, last_counter = 0`,
+		userID, secret,
+	)
+	return err
+}
+
+func (r *mfaRepo) Find(ctx context.Context, userID string) (model.MFA, bool, error) {
+	var m model.MFA
+	var enabledAt sql.NullTime
+	err := r.db.QueryRowContext(ctx,
+		"SELECT user_id, secret, enabled_at, last_counter FROM user_mfa WHERE user_id = ?",
+		userID,
+	).Scan(&m.UserID, &m.Secret, &enabledAt, &m.LastCounter)
+
+	if err == sql.ErrNoRows {
+		return model.MFA{}, false, nil
+	}
+	if err != nil {
+		return model.MFA{}, false, err
+	}
+	if enabledAt.Valid {
+		m.EnabledAt = &enabledAt.Time
+	}
+	return m, true, nil
+}
+
+func (r *mfaRepo) Enable(ctx context.Context, userID string, counter int64, recoveryHashes [][]byte) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE user_mfa SET enabled_at = NOW(), last_counter = ? WHERE user_id = ?",
+		counter, userID,
+	); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM mfa_recovery_codes WHERE user_id = ?", userID,
+	); err != nil {
+		return err
+	}
+
+	for _, hash := range recoveryHashes {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES (?, ?)",
+			userID, hash,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *mfaRepo) UseCounter(ctx context.Context, userID string, counter int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE user_mfa SET last_counter = ? WHERE user_id = ? AND last_counter < ?",
+		counter, userID, counter,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *mfaRepo) ConsumeRecoveryCode(ctx context.Context, userID string, codeHash []byte) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE mfa_recovery_codes SET used_at = NOW() WHERE user_id = ? AND code_hash = ? AND used_at IS NULL",
+		userID, codeHash,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *mfaRepo) Delete(ctx context.Context, userID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM mfa_recovery_codes WHERE user_id = ?", userID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM user_mfa WHERE user_id = ?", userID,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,199 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"task-flow/internal/model"
+	"task-flow/internal/repository"
+)
+
+type oauthClientRepo struct {
+	db *sql.DB
+}
+
+func NewOAuthClientRepo(db *sql.DB) repository.OAuthClientRepo {
+	return &oauthClientRepo{db: db}
+}
+
+const oauthClientColumns = "id, owner_id, name, secret_hash, redirect_uris, scopes, created_at"
+
+func scanOAuthClient(row rowScanner) (model.OAuthClient, error) {
+	var c model.OAuthClient
+	var redirectURIs, scopes string
+	if err := row.Scan(&c.ID, &c.OwnerID, &c.Name, &c.SecretHash, &redirectURIs, &scopes, &c.CreatedAt); err != nil {
+		return model.OAuthClient{}, err
+	}
+	// Neither URIs nor scopes can contain spaces
+	c.RedirectURIs = strings.Fields(redirectURIs)
+	c.Scopes = strings.Fields(scopes)
+	return c, nil
+}
+
+func (r *oauthClientRepo) Create(ctx context.Context, client model.OAuthClient) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO oauth_clients (id, owner_id, name, secret_hash, redirect_uris, scopes)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		client.ID, client.OwnerID, client.Name, client.SecretHash,
+		strings.Join(client.RedirectURIs, " "), strings.Join(client.Scopes, " "),
+	)
+	return err
+}
+
+func (r *oauthClientRepo) FindByID(ctx context.Context, id string) (model.OAuthClient, bool, error) {
+	c, err := scanOAuthClient(r.db.QueryRowContext(ctx,
+		"SELECT "+oauthClientColumns+" FROM oauth_clients WHERE id = ?", id,
+	))
+	if err == sql.ErrNoRows {
+		return model.OAuthClient{}, false, nil
+	}
+	if err != nil {
+		return model.OAuthClient{}, false, err
+	}
+	return c, true, nil
+}
+
+func (r *oauthClientRepo) ListByOwner(ctx context.Context, ownerID string) ([]model.OAuthClient, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+oauthClientColumns+" FROM oauth_clients WHERE owner_id = ? ORDER BY created_at DESC",
+		ownerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clients []model.OAuthClient
+	for rows.Next() {
+		c, err := scanOAuthClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, c)
+	}
+	return clients, rows.Err()
+}
+
+func (r *oauthClientRepo) Delete(ctx context.Context, ownerID, id string) (bool, error) {
+	// Codes and refresh tokens go with the client (ON DELETE CASCADE)
+	res, err := r.db.ExecContext(ctx,
+		"DELETE FROM oauth_clients WHERE id = ? AND owner_id = ?",
+		id, ownerID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+type oauthCodeRepo struct {
+	db *sql.DB
+}
+
+func NewOAuthCodeRepo(db *sql.DB) repository.OAuthCodeRepo {
+	return &oauthCodeRepo{db: db}
+}
+
+func (r *oauthCodeRepo) Insert(ctx context.Context, codeHash []byte, code model.OAuthAuthorizationCode) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO oauth_authorization_codes
+		(code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, FROM_UNIXTIME(?))`,
+		codeHash, code.ClientID, code.UserID, code.RedirectURI,
+		strings.Join(code.Scopes, " "), code.CodeChallenge, code.ExpiresAt.Unix(),
+	)
+	return err
+}
+
+func (r *oauthCodeRepo) Consume(ctx context.Context, codeHash []byte) (model.OAuthAuthorizationCode, bool, error) {
+	// Same single-use guarantee as user tokens: only one caller flips used_at
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE oauth_authorization_codes SET used_at = NOW()
+		WHERE code_hash = ? AND used_at IS NULL AND expires_at > NOW()`,
+		codeHash,
+	)
+	if err != nil {
+		return model.OAuthAuthorizationCode{}, false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return model.OAuthAuthorizationCode{}, false, err
+	}
+	if n == 0 {
+		return model.OAuthAuthorizationCode{}, false, nil
+	}
+
+	var c model.OAuthAuthorizationCode
+	var scopes string
+	err = r.db.QueryRowContext(ctx,
+		`SELECT client_id, user_id, redirect_uri, scopes, code_challenge, expires_at
+		FROM oauth_authorization_codes WHERE code_hash = ?`,
+		codeHash,
+	).Scan(&c.ClientID, &c.UserID, &c.RedirectURI, &scopes, &c.CodeChallenge, &c.ExpiresAt)
+	if err != nil {
+		return model.OAuthAuthorizationCode{}, false, err
+	}
+	c.Scopes = strings.Fields(scopes)
+	return c, true, nil
+}
+
+type oauthRefreshTokenRepo struct {
+	db *sql.DB
+}
+
+func NewOAuthRefreshTokenRepo(db *sql.DB) repository.OAuthRefreshTokenRepo {
+	return &oauthRefreshTokenRepo{db: db}
+}
+
+func (r *oauthRefreshTokenRepo) Insert(ctx context.Context, tokenHash []byte, token model.OAuthRefreshToken) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO oauth_refresh_tokens (token_hash, client_id, user_id, scopes, expires_at)
+		VALUES (?, ?, ?, ?, FROM_UNIXTIME(?))`,
+		tokenHash, token.ClientID, token.UserID, strings.Join(token.Scopes, " "), token.ExpiresAt.Unix(),
+	)
+	return err
+}
+
+func (r *oauthRefreshTokenRepo) FindByHash(ctx context.Context, tokenHash []byte) (model.OAuthRefreshToken, bool, error) {
+	var t model.OAuthRefreshToken
+	var scopes string
+	err := r.db.QueryRowContext(ctx,
+		`SELECT client_id, user_id, scopes, expires_at FROM oauth_refresh_tokens
+		WHERE token_hash = ? AND expires_at > NOW() AND revoked = FALSE`,
+		tokenHash,
+	).Scan(&t.ClientID, &t.UserID, &scopes, &t.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return model.OAuthRefreshToken{}, false, nil
+	}
+	if err != nil {
+		return model.OAuthRefreshToken{}, false, err
+	}
+	t.Scopes = strings.Fields(scopes)
+	return t, true, nil
+}
+
+func (r *oauthRefreshTokenRepo) Rotate(ctx context.Context, tokenHash []byte) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE oauth_refresh_tokens SET revoked = TRUE
+		WHERE token_hash = ? AND expires_at > NOW() AND revoked = FALSE`,
+		tokenHash,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *oauthRefreshTokenRepo) RevokeByHash(ctx context.Context, tokenHash []byte) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE oauth_refresh_tokens SET revoked = TRUE WHERE token_hash = ?",
+		tokenHash,
+	)
+	return err
+}
@@ -0,0 +1,112 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"task-flow/internal/model"
+	"task-flow/internal/repository"
+)
+
+type oidcStateRepo struct {
+	db *sql.DB
+}
+
+func NewOIDCStateRepo(db *sql.DB) repository.OIDCStateRepo {
+	return &oidcStateRepo{db: db}
+}
+
+func (r *oidcStateRepo) Insert(ctx context.Context, stateHash []byte, state model.OIDCLoginState) error {
+	// Abandoned logins are cleaned up here rather than by a separate job
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM oidc_login_states WHERE expires_at < NOW()"); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO oidc_login_states (state_hash, provider, nonce, code_verifier, expires_at)
+		VALUES (?, ?, ?, ?, FROM_UNIXTIME(?))`,
+		stateHash, state.Provider, state.Nonce, state.CodeVerifier, state.ExpiresAt.Unix(),
+	)
+	return err
+}
+
+func (r *oidcStateRepo) Consume(ctx context.Context, stateHash []byte) (model.OIDCLoginState, bool, error) {
+	var st model.OIDCLoginState
+	err := r.db.QueryRowContext(ctx,
+		`SELECT provider, nonce, code_verifier, expires_at FROM oidc_login_states
+		WHERE state_hash = ? AND expires_at > NOW()`,
+		stateHash,
+	).Scan(&st.Provider, &st.Nonce, &st.CodeVerifier, &st.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return model.OIDCLoginState{}, false, nil
+	}
+	if err != nil {
+		return model.OIDCLoginState{}, false, err
+	}
+
+	// Only the caller whose delete succeeds may use the state
+	res, err := r.db.ExecContext(ctx, "DELETE FROM oidc_login_states WHERE state_hash = ?", stateHash)
+	if err != nil {
+		return model.OIDCLoginState{}, false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return model.OIDCLoginState{}, false, err
+	}
+	if n == 0 {
+		return model.OIDCLoginState{}, false, nil
+	}
+	return st, true, nil
+}
+
+type userIdentityRepo struct {
+	db *sql.DB
+}
+
+func NewUserIdentityRepo(db *sql.DB) repository.UserIdentityRepo {
+	return &userIdentityRepo{db: db}
+}
+
+func (r *userIdentityRepo) FindUserID(ctx context.Context, provider, subject string) (string, bool, error) {
+	var userID string
+	err := r.db.QueryRowContext(ctx,
+		"SELECT user_id FROM user_identities WHERE provider = ? AND subject = ?",
+		provider, subject,
+	).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return userID, true, nil
+}
+
+func (r *userIdentityRepo) Link(ctx context.Context, identity model.UserIdentity) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO user_identities (provider, subject, user_id, email) VALUES (?, ?, ?, ?)",
+		identity.Provider, identity.Subject, identity.UserID, identity.Email,
+	)
+	return err
+}
+
+func (r *userIdentityRepo) ListByUser(ctx context.Context, userID string) ([]model.UserIdentity, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT user_id, provider, subject, email, created_at FROM user_identities WHERE user_id = ? ORDER BY created_at",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []model.UserIdentity
+	for rows.Next() {
+		var i model.UserIdentity
+		if err := rows.Scan(&i.UserID, &i.Provider, &i.Subject, &i.Email, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, i)
+	}
+	return identities, rows.Err()
+}
@@ -0,0 +1,101 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"task-flow/internal/model"
+	"task-flow/internal/repository"
+)
+
+type projectRepo struct {
+	db *sql.DB
+}
+
+func NewProjectRepo(db *sql.DB) repository.ProjectRepo {
+	return &projectRepo{db: db}
+}
+
+const projectColumns = "id, workspace_id, name, description, created_at"
+
+func scanProject(row rowScanner) (model.Project, error) {
+	var p model.Project
+	err := row.Scan(&p.ID, &p.WorkspaceID, &p.Name, &p.Description, &p.CreatedAt)
+	return p, err
+}
+
+func (r *projectRepo) Create(ctx context.Context, project model.Project) error {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		"INSERT INTO projects (id, workspace_id, name, description) VALUES (?, ?, ?, ?)",
+		project.ID, workspaceID, project.Name, project.Description,
+	)
+	return err
+}
+
+func (r *projectRepo) List(ctx context.Context) ([]model.Project, error) {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+projectColumns+" FROM projects WHERE workspace_id = ? ORDER BY name, id",
+		workspaceID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []model.Project
+	for rows.Next() {
+		p, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, p)
+	}
+	return projects, rows.Err()
+}
+
+func (r *projectRepo) FindByID(ctx context.Context, id string) (model.Project, bool, error) {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return model.Project{}, false, err
+	}
+
+	p, err := scanProject(r.db.QueryRowContext(ctx,
+		"SELECT "+projectColumns+" FROM projects WHERE id = ? AND workspace_id = ?",
+		id, workspaceID,
+	))
+	if err == sql.ErrNoRows {
+		return model.Project{}, false, nil
+	}
+	if err != nil {
+		return model.Project{}, false, err
+	}
+	return p, true, nil
+}
+
+// Delete also removes the project's tasks (ON DELETE CASCADE).
+func (r *projectRepo) Delete(ctx context.Context, id string) (bool, error) {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := r.db.ExecContext(ctx,
+		"DELETE FROM projects WHERE id = ? AND workspace_id = ?",
+		id, workspaceID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
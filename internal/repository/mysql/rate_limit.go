@@ -0,0 +1,72 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"task-flow/internal/ratelimit"
+)
+
+type rateLimitStore struct {
+	db *sql.DB
+}
+
+// NewRateLimitStore returns a rate limit store shared by every instance
+// using db. Buckets are refilled by the database's clock, so instances
+// with skewed clocks still agree.
+func NewRateLimitStore(db *sql.DB) ratelimit.Store {
+	return &rateLimitStore{db: db}
+}
+
+func (r *rateLimitStore) Take(ctx context.Context, key string, p ratelimit.Policy) (ratelimit.Result, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	defer tx.Rollback()
+
+	// Create the bucket full first, so the row lock below never has to
+	// lock a gap that a concurrent insert also wants
+	res, err := tx.ExecContext(ctx,
+		`INSERT IGNORE INTO rate_limit_buckets (bucket_key, tokens, updated_at, expires_at)
+		VALUES (?, ?, NOW(6), NOW())`,
+		key, p.Limit,
+	)
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	created, err := res.RowsAffected()
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	var b ratelimit.Bucket
+	var now time.Time
+	if err := tx.QueryRowContext(ctx,
+		"SELECT tokens, updated_at, NOW(6) FROM rate_limit_buckets WHERE bucket_key = ? FOR UPDATE",
+		key,
+	).Scan(&b.Tokens, &b.Updated, &now); err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	b, result := p.Take(b, now)
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE rate_limit_buckets SET tokens = ?, updated_at = ?, expires_at = ? WHERE bucket_key = ?",
+		b.Tokens, b.Updated, now.Add(result.Reset), key,
+	); err != nil {
+		return ratelimit.Result{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	// A full bucket is the same as none; clear some out as new ones come
+	// in. The request was already counted, so a failure here doesn't matter.
+	if created == 1 {
+		_, _ = r.db.ExecContext(ctx,
+			"DELETE FROM rate_limit_buckets WHERE expires_at < NOW() LIMIT 100",
+		)
+	}
+	return result, nil
+}
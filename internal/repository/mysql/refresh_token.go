@@ -3,6 +3,7 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"task-flow/internal/repository"
 )
@@ -39,6 +40,37 @@ func (r *refreshTokenRepo) FindUserIDByHash(ctx context.Context, tokenHash []byt
 	return userID, true, nil
 }
 
+func (r *refreshTokenRepo) Rotate(ctx context.Context, tokenHash []byte) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked = TRUE, rotated_at = NOW()
+		WHERE token_hash = ? AND expires_at > NOW() AND revoked = FALSE`,
+		tokenHash,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *refreshTokenRepo) FindRotated(ctx context.Context, tokenHash []byte) (string, time.Duration, bool, error) {
+	var userID string
+	var sinceRotated int64 // microseconds
+	err := r.db.QueryRowContext(ctx,
+		`SELECT user_id, TIMESTAMPDIFF(MICROSECOND, rotated_at, NOW()) FROM refresh_tokens
+		WHERE token_hash = ? AND expires_at > NOW() AND rotated_at IS NOT NULL`,
+		tokenHash,
+	).Scan(&userID, &sinceRotated)
+
+	if err == sql.ErrNoRows {
+		return "", 0, false, nil
+	}
+	if err != nil {
+		return "", 0, false, err
+	}
+	return userID, time.Duration(sinceRotated) * time.Microsecond, true, nil
+}
+
 func (r *refreshTokenRepo) RevokeByHash(ctx context.Context, tokenHash []byte) error {
 	_, err := r.db.ExecContext(ctx,
 		"UPDATE refresh_tokens SET revoked = TRUE WHERE token_hash = ?",
@@ -46,3 +78,11 @@ func (r *refreshTokenRepo) RevokeByHash(ctx context.Context, tokenHash []byte) e
 	)
 	return err
 }
+
+func (r *refreshTokenRepo) RevokeAllForUser(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked = TRUE WHERE user_id = ? AND revoked = FALSE",
+		userID,
+	)
+	return err
+}
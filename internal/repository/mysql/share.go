@@ -0,0 +1,186 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"task-flow/internal/model"
+	"task-flow/internal/repository"
+)
+
+type shareRepo struct {
+	db *sql.DB
+}
+
+func NewShareRepo(db *sql.DB) repository.ShareRepo {
+	return &shareRepo{db: db}
+}
+
+// shareColumn maps a resource to the column holding its ID. The result is
+// only ever one of two constants, so it is safe to splice into SQL.
+func shareColumn(resource model.ShareResource) (string, error) {
+	switch resource {
+	case model.ShareTask:
+		return "task_id", nil
+	case model.ShareProject:
+		return "project_id", nil
+	}
+	return "", fmt.Errorf("unknown share resource %q", resource)
+}
+
+func (r *shareRepo) Set(ctx context.Context, share model.Share) error {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+	column, err := shareColumn(share.Resource)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO shares (workspace_id, `+column+`, user_id, level, created_by) VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE level = VALUES(level)`,
+		workspaceID, share.ResourceID, share.UserID, share.Level, share.CreatedBy,
+	)
+	return err
+}
+
+func (r *shareRepo) List(ctx context.Context, resource model.ShareResource, resourceID string) ([]model.Share, error) {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	column, err := shareColumn(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT s.workspace_id, s.user_id, u.email, s.level, COALESCE(s.created_by, ''), s.created_at
+		FROM shares s JOIN users u ON u.id = s.user_id
+		WHERE s.workspace_id = ? AND s.`+column+` = ? ORDER BY s.created_at, u.email`,
+		workspaceID, resourceID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shares []model.Share
+	for rows.Next() {
+		s := model.Share{Resource: resource, ResourceID: resourceID}
+		if err := rows.Scan(&s.WorkspaceID, &s.UserID, &s.Email, &s.Level, &s.CreatedBy, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		shares = append(shares, s)
+	}
+	return shares, rows.Err()
+}
+
+func (r *shareRepo) Delete(ctx context.Context, resource model.ShareResource, resourceID, userID string) (bool, error) {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return false, err
+	}
+	column, err := shareColumn(resource)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := r.db.ExecContext(ctx,
+		"DELETE FROM shares WHERE workspace_id = ? AND "+column+" = ? AND user_id = ?",
+		workspaceID, resourceID, userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// taskGrantsQuery selects every share that reaches a task: on the task
+// itself or on its project. The caller filters by workspace or not.
+const taskGrantsQuery = `SELECT t.workspace_id, s.level
+	FROM tasks t
+	JOIN shares s ON s.workspace_id = t.workspace_id
+		AND (s.task_id = t.id OR (t.project_id IS NOT NULL AND s.project_id = t.project_id))
+	WHERE s.user_id = ? AND t.id = ?`
+
+func (r *shareRepo) taskGrants(ctx context.Context, query string, args ...any) (workspaceID string, level model.AccessLevel, err error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return "", model.AccessNone, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var granted model.AccessLevel
+		if err := rows.Scan(&workspaceID, &granted); err != nil {
+			return "", model.AccessNone, err
+		}
+		level = level.Max(granted)
+	}
+	return workspaceID, level, rows.Err()
+}
+
+func (r *shareRepo) TaskLevel(ctx context.Context, userID, taskID string) (model.AccessLevel, error) {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return model.AccessNone, err
+	}
+
+	_, level, err := r.taskGrants(ctx, taskGrantsQuery+" AND t.workspace_id = ?", userID, taskID, workspaceID)
+	return level, err
+}
+
+func (r *shareRepo) FindTaskGrant(ctx context.Context, userID, taskID string) (string, model.AccessLevel, bool, error) {
+	workspaceID, level, err := r.taskGrants(ctx, taskGrantsQuery, userID, taskID)
+	if err != nil {
+		return "", model.AccessNone, false, err
+	}
+	return workspaceID, level, level != model.AccessNone, nil
+}
+
+func (r *shareRepo) ListTasksForUser(ctx context.Context, userID string) ([]model.SharedTask, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT t.id, t.workspace_id, t.project_id, t.title, t.description, t.created_at, t.completed_at, s.level
+		FROM tasks t
+		JOIN shares s ON s.workspace_id = t.workspace_id
+			AND (s.task_id = t.id OR (t.project_id IS NOT NULL AND s.project_id = t.project_id))
+		WHERE s.user_id = ?
+		ORDER BY t.created_at, t.id`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// A task shared both directly and through its project appears twice;
+	// keep the higher level
+	var tasks []model.SharedTask
+	index := make(map[string]int)
+	for rows.Next() {
+		var t model.SharedTask
+		var projectID sql.NullString
+		var completedAt sql.NullTime
+		if err := rows.Scan(&t.ID, &t.WorkspaceID, &projectID, &t.Title, &t.Description, &t.Created_At, &completedAt, &t.Level); err != nil {
+			return nil, err
+		}
+		if projectID.Valid {
+			t.ProjectID = &projectID.String
+		}
+		if completedAt.Valid {
+			t.CompletedAt = &completedAt.Time
+		}
+		if i, ok := index[t.ID]; ok {
+			tasks[i].Level = tasks[i].Level.Max(t.Level)
+			continue
+		}
+		index[t.ID] = len(tasks)
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
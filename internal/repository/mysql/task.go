@@ -16,17 +16,50 @@ func NewTaskRepo(db *sql.DB) repository.TaskRepo {
 	return &taskRepo{db: db}
 }
 
+const taskColumns = "id, workspace_id, project_id, title, description, created_at, completed_at"
+
+func scanTask(row rowScanner) (model.Task, error) {
+	var t model.Task
+	var projectID sql.NullString
+	var completedAt sql.NullTime
+	if err := row.Scan(&t.ID, &t.WorkspaceID, &projectID, &t.Title, &t.Description, &t.Created_At, &completedAt); err != nil {
+		return model.Task{}, err
+	}
+	if projectID.Valid {
+		t.ProjectID = &projectID.String
+	}
+	if completedAt.Valid {
+		t.CompletedAt = &completedAt.Time
+	}
+	return t, nil
+}
+
+// AddTask always stores the task in the context's workspace, whatever
+// task.WorkspaceID says.
 func (r *taskRepo) AddTask(ctx context.Context, task model.Task) error {
-	_, err := r.db.ExecContext(ctx,
-		"INSERT INTO tasks (id, title, description) VALUES (?, ?, ?)",
-		task.ID, task.Title, task.Description,
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		"INSERT INTO tasks (id, workspace_id, project_id, title, description) VALUES (?, ?, ?, ?, ?)",
+		task.ID, workspaceID, task.ProjectID, task.Title, task.Description,
 	)
 
 	return err
 }
 
 func (r *taskRepo) GetTasks(ctx context.Context) ([]model.Task, error) {
-	rows, err := r.db.QueryContext(ctx, "SELECT * FROM tasks")
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+taskColumns+" FROM tasks WHERE workspace_id = ? ORDER BY created_at",
+		workspaceID,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -35,29 +68,52 @@ func (r *taskRepo) GetTasks(ctx context.Context) ([]model.Task, error) {
 
 	var tasks []model.Task
 	for rows.Next() {
-		var t model.Task
-		if err := rows.Scan(&t.ID, &t.Title, &t.Description, &t.Created_At); err != nil {
+		t, err := scanTask(rows)
+		if err != nil {
 			return nil, err
 		}
 		tasks = append(tasks, t)
 	}
 
-	return tasks, nil
+	return tasks, rows.Err()
+}
+
+func (r *taskRepo) UpdateTask(ctx context.Context, task model.Task) error {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		"UPDATE tasks SET title = ?, description = ?, completed_at = ? WHERE id = ? AND workspace_id = ?",
+		task.Title, task.Description, task.CompletedAt, task.ID, workspaceID,
+	)
+
+	return err
 }
 
 func (r *taskRepo) DeleteTask(ctx context.Context, id string) error {
-	_, err := r.db.ExecContext(ctx,
-		"DELETE FROM tasks WHERE id = ?", id)
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		"DELETE FROM tasks WHERE id = ? AND workspace_id = ?", id, workspaceID)
 
 	return err
 }
 
 func (r *taskRepo) FindByID(ctx context.Context, id string) (model.Task, error) {
-	var t model.Task
-	err := r.db.QueryRowContext(ctx,
-		"SELECT id, title, description FROM tasks WHERE id = ?",
-		id,
-	).Scan(&t.ID, &t.Title, &t.Description)
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	t, err := scanTask(r.db.QueryRowContext(ctx,
+		"SELECT "+taskColumns+" FROM tasks WHERE id = ? AND workspace_id = ?",
+		id, workspaceID,
+	))
 
 	if err == sql.ErrNoRows {
 		return model.Task{}, nil
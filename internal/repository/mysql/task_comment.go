@@ -0,0 +1,77 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"task-flow/internal/model"
+	"task-flow/internal/repository"
+)
+
+type taskCommentRepo struct {
+	db *sql.DB
+}
+
+func NewTaskCommentRepo(db *sql.DB) repository.TaskCommentRepo {
+	return &taskCommentRepo{db: db}
+}
+
+func (r *taskCommentRepo) Add(ctx context.Context, comment model.TaskComment) error {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		"INSERT INTO task_comments (id, workspace_id, task_id, user_id, body) VALUES (?, ?, ?, ?, ?)",
+		comment.ID, workspaceID, comment.TaskID, comment.UserID, comment.Body,
+	)
+	return err
+}
+
+func (r *taskCommentRepo) List(ctx context.Context, taskID string) ([]model.TaskComment, error) {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT c.id, c.workspace_id, c.task_id, c.user_id, u.email, c.body, c.created_at
+		FROM task_comments c JOIN users u ON u.id = c.user_id
+		WHERE c.workspace_id = ? AND c.task_id = ? ORDER BY c.created_at, c.id`,
+		workspaceID, taskID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTaskComments(rows)
+}
+
+func (r *taskCommentRepo) ListByUser(ctx context.Context, userID string) ([]model.TaskComment, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT c.id, c.workspace_id, c.task_id, c.user_id, u.email, c.body, c.created_at
+		FROM task_comments c JOIN users u ON u.id = c.user_id
+		WHERE c.user_id = ? ORDER BY c.created_at, c.id`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTaskComments(rows)
+}
+
+func scanTaskComments(rows *sql.Rows) ([]model.TaskComment, error) {
+	var comments []model.TaskComment
+	for rows.Next() {
+		var c model.TaskComment
+		if err := rows.Scan(&c.ID, &c.WorkspaceID, &c.TaskID, &c.UserID, &c.Email, &c.Body, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
@@ -0,0 +1,105 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"task-flow/internal/model"
+	"task-flow/internal/repository"
+)
+
+type taskLinkRepo struct {
+	db *sql.DB
+}
+
+func NewTaskLinkRepo(db *sql.DB) repository.TaskLinkRepo {
+	return &taskLinkRepo{db: db}
+}
+
+const taskLinkColumns = "id, workspace_id, task_id, token_hash, COALESCE(created_by, ''), expires_at, created_at"
+
+func scanTaskLink(row rowScanner) (model.TaskLink, error) {
+	var l model.TaskLink
+	var expiresAt sql.NullTime
+	if err := row.Scan(&l.ID, &l.WorkspaceID, &l.TaskID, &l.TokenHash, &l.CreatedBy, &expiresAt, &l.CreatedAt); err != nil {
+		return model.TaskLink{}, err
+	}
+	if expiresAt.Valid {
+		l.ExpiresAt = &expiresAt.Time
+	}
+	return l, nil
+}
+
+func (r *taskLinkRepo) Create(ctx context.Context, link model.TaskLink) error {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO task_links (id, workspace_id, task_id, token_hash, created_by, expires_at)
+		VALUES (?, ?, ?, ?, ?, FROM_UNIXTIME(?))`,
+		link.ID, workspaceID, link.TaskID, link.TokenHash, link.CreatedBy, unixOrNil(link.ExpiresAt),
+	)
+	return err
+}
+
+func (r *taskLinkRepo) List(ctx context.Context, taskID string) ([]model.TaskLink, error) {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+taskLinkColumns+` FROM task_links
+		WHERE workspace_id = ? AND task_id = ? AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY created_at DESC`,
+		workspaceID, taskID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []model.TaskLink
+	for rows.Next() {
+		l, err := scanTaskLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+func (r *taskLinkRepo) Revoke(ctx context.Context, taskID, id string) (bool, error) {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := r.db.ExecContext(ctx,
+		"DELETE FROM task_links WHERE id = ? AND task_id = ? AND workspace_id = ?",
+		id, taskID, workspaceID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *taskLinkRepo) FindByTokenHash(ctx context.Context, hash []byte) (model.TaskLink, bool, error) {
+	l, err := scanTaskLink(r.db.QueryRowContext(ctx,
+		"SELECT "+taskLinkColumns+` FROM task_links
+		WHERE token_hash = ? AND (expires_at IS NULL OR expires_at > NOW())`,
+		hash,
+	))
+	if err == sql.ErrNoRows {
+		return model.TaskLink{}, false, nil
+	}
+	if err != nil {
+		return model.TaskLink{}, false, err
+	}
+	return l, true, nil
+}
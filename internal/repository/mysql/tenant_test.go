@@ -0,0 +1,152 @@
+package mysql
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// ============================================
+// TESTS
+// ============================================
+
+// tenantTables hold rows owned by a workspace.
+var tenantTables = regexp.MustCompile(`(?i)\b(FROM|INTO|UPDATE|JOIN)\s+(tasks|projects|workspace_members|workspace_invitations|shares|task_links|task_comments)\b`)
+
+// unscoped lists the methods allowed to query tenant tables without a
+// workspace in the context: they establish that scope in the first place.
+// Keep it short; every entry is reviewed by hand.
+var unscoped = map[string]bool{
+	"workspaceRepo.Create":      true,
+	"workspaceRepo.FindMember":  true,
+	"workspaceRepo.ListForUser": true,
+	// Invitations are redeemed by ID from a signed token
+	"invitationRepo.FindByID": true,
+	"invitationRepo.Accept":   true,
+	// Shares and public links are looked up by grantee or secret
+	"shareRepo.FindTaskGrant":      true,
+	"shareRepo.ListTasksForUser":   true,
+	"taskLinkRepo.FindByTokenHash": true,
+	// Account deletion and export act on one user across workspaces
+	"userRepo.Anonymize":         true,
+	"taskCommentRepo.ListByUser": true,
+}
+
+// TestTenantQueriesAreScoped checks every function in this package that
+// touches a tenant table: it must take the workspace from the context and
+// every such query must filter or insert on workspace_id.
+func TestTenantQueriesAreScoped(t *testing.T) {
+	fset := token.NewFileSet()
+	entries, err := os.ReadDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var files []*ast.File
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".go") || strings.HasSuffix(e.Name(), "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, e.Name(), nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+
+	// Queries kept in package-level constants count where they are used
+	consts := make(map[string]string)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for i, name := range vs.Names {
+					if i >= len(vs.Values) {
+						continue
+					}
+					if lit, ok := vs.Values[i].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+						consts[name.Name], _ = strconv.Unquote(lit.Value)
+					}
+				}
+			}
+		}
+	}
+
+	checked := 0
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			name := funcName(fn)
+
+			var queries []string
+			scoped := false
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.BasicLit:
+					if n.Kind != token.STRING {
+						break
+					}
+					s, err := strconv.Unquote(n.Value)
+					if err == nil && tenantTables.MatchString(s) {
+						queries = append(queries, s)
+					}
+				case *ast.Ident:
+					if s, ok := consts[n.Name]; ok && tenantTables.MatchString(s) {
+						queries = append(queries, s)
+					}
+				case *ast.SelectorExpr:
+					if pkg, ok := n.X.(*ast.Ident); ok && pkg.Name == "repository" && n.Sel.Name == "WorkspaceID" {
+						scoped = true
+					}
+				}
+				return true
+			})
+			if len(queries) == 0 {
+				continue
+			}
+			checked++
+			if unscoped[name] {
+				continue
+			}
+
+			for _, q := range queries {
+				if !strings.Contains(q, "workspace_id") {
+					t.Errorf("%s: query on a tenant table does not mention workspace_id: %q", name, q)
+				}
+			}
+			if !scoped {
+				t.Errorf("%s: queries a tenant table without repository.WorkspaceID(ctx)", name)
+			}
+		}
+	}
+
+	if checked == 0 {
+		t.Fatal("no tenant queries found; is the pattern still right?")
+	}
+}
+
+func funcName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	typ := fn.Recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	if ident, ok := typ.(*ast.Ident); ok {
+		return ident.Name + "." + fn.Name.Name
+	}
+	return fn.Name.Name
+}
@@ -3,6 +3,7 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"strings"
 
 	"task-flow/internal/model"
 	"task-flow/internal/repository"
@@ -16,20 +17,30 @@ func NewUserRepo(db *sql.DB) repository.UserRepo {
 	return &userRepo{db: db}
 }
 
-func (r *userRepo) Create(ctx context.Context, user model.User) error {
-	_, err := r.db.ExecContext(ctx,
-		"INSERT INTO users (id, email, pass_hash) VALUES (?, ?, ?)",
-		user.ID, user.Email, user.PassHash,
-	)
-	return err
-}
+const userColumns = `id, email, pass_hash, role, verified_at, disabled_at, created_at,
+	display_name, avatar_url, timezone, locale, COALESCE(pending_email, ''), deleted_at`
 
-func (r *userRepo) FindByID(ctx context.Context, id string) (model.User, bool, error) {
+func scanUser(row rowScanner) (model.User, error) {
 	var u model.User
-	err := r.db.QueryRowContext(ctx,
-		"SELECT id, email, pass_hash FROM users WHERE id = ?", id,
-	).Scan(&u.ID, &u.Email, &u.PassHash)
+	var verifiedAt, disabledAt, deletedAt sql.NullTime
+	if err := row.Scan(&u.ID, &u.Email, &u.PassHash, &u.Role, &verifiedAt, &disabledAt, &u.CreatedAt,
+		&u.DisplayName, &u.AvatarURL, &u.Timezone, &u.Locale, &u.PendingEmail, &deletedAt); err != nil {
+		return model.User{}, err
+	}
+	if verifiedAt.Valid {
+		u.VerifiedAt = &verifiedAt.Time
+	}
+	if disabledAt.Valid {
+		u.DisabledAt = &disabledAt.Time
+	}
+	if deletedAt.Valid {
+		u.DeletedAt = &deletedAt.Time
+	}
+	return u, nil
+}
 
+func findUser(row *sql.Row) (model.User, bool, error) {
+	u, err := scanUser(row)
 	if err == sql.ErrNoRows {
 		return model.User{}, false, nil
 	}
@@ -39,17 +50,173 @@ func (r *userRepo) FindByID(ctx context.Context, id string) (model.User, bool, e
 	return u, true, nil
 }
 
+func (r *userRepo) Create(ctx context.Context, user model.User) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO users (id, email, pass_hash, role, verified_at) VALUES (?, ?, ?, ?, ?)",
+		user.ID, user.Email, user.PassHash, user.Role, user.VerifiedAt,
+	)
+	return mapError(err)
+}
+
+func (r *userRepo) FindByID(ctx context.Context, id string) (model.User, bool, error) {
+	return findUser(r.db.QueryRowContext(ctx,
+		"SELECT "+userColumns+" FROM users WHERE id = ?", id,
+	))
+}
+
 func (r *userRepo) FindByEmail(ctx context.Context, email string) (model.User, bool, error) {
-	var u model.User
-	err := r.db.QueryRowContext(ctx,
-		"SELECT id, email, pass_hash FROM users WHERE email = ?", email,
-	).Scan(&u.ID, &u.Email, &u.PassHash)
+	return findUser(r.db.QueryRowContext(ctx,
+		"SELECT "+userColumns+" FROM users WHERE email = ?", email,
+	))
+}
 
-	if err == sql.ErrNoRows {
-		return model.User{}, false, nil
+func (r *userRepo) UpdatePassword(ctx context.Context, id string, passHash []byte) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE users SET pass_hash = ? WHERE id = ?",
+		passHash, id,
+	)
+	return err
+}
+
+func (r *userRepo) MarkVerified(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE users SET verified_at = COALESCE(verified_at, NOW()) WHERE id = ?",
+		id,
+	)
+	return err
+}
+
+func (r *userRepo) List(ctx context.Context, filter model.UserFilter) ([]model.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE 1 = 1"
+	var args []any
+
+	if filter.Query != "" {
+		query += " AND email LIKE ?"
+		args = append(args, "%"+escapeLike(filter.Query)+"%")
+	}
+	if filter.Role != "" {
+		query += " AND role = ?"
+		args = append(args, filter.Role)
 	}
+	if filter.Disabled != nil {
+		if *filter.Disabled {
+			query += " AND disabled_at IS NOT NULL"
+		} else {
+			query += " AND disabled_at IS NULL"
+		}
+	}
+
+	query += " ORDER BY created_at DESC, id LIMIT ? OFFSET ?"
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return model.User{}, false, err
+		return nil, err
 	}
-	return u, true, nil
+	defer rows.Close()
+
+	var users []model.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (r *userRepo) SetRole(ctx context.Context, id string, role model.Role) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE users SET role = ? WHERE id = ?",
+		role, id,
+	)
+	return err
+}
+
+func (r *userRepo) SetDisabled(ctx context.Context, id string, disabled bool) error {
+	query := "UPDATE users SET disabled_at = COALESCE(disabled_at, NOW()) WHERE id = ?"
+	if !disabled {
+		query = "UPDATE users SET disabled_at = NULL WHERE id = ?"
+	}
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *userRepo) UpdateProfile(ctx context.Context, id string, profile model.UserProfile) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE users SET display_name = ?, avatar_url = ?, timezone = ?, locale = ? WHERE id = ?",
+		profile.DisplayName, profile.AvatarURL, profile.Timezone, profile.Locale, id,
+	)
+	return err
+}
+
+func (r *userRepo) SetPendingEmail(ctx context.Context, id, email string) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE users SET pending_email = NULLIF(?, '') WHERE id = ?",
+		email, id,
+	)
+	return err
+}
+
+func (r *userRepo) ChangeEmail(ctx context.Context, id, email string) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE users SET email = ?, pending_email = NULL, verified_at = NOW() WHERE id = ?",
+		email, id,
+	)
+	return mapError(err)
+}
+
+// credentialTables hold the sign-in material and sessions of a user, as
+// table and owner column. Anonymize removes them outright.
+var credentialTables = [][2]string{
+	{"refresh_tokens", "user_id"},
+	{"user_tokens", "user_id"},
+	{"mfa_recovery_codes", "user_id"},
+	{"user_mfa", "user_id"},
+	{"personal_access_tokens", "user_id"},
+	{"user_identities", "user_id"},
+	{"oauth_authorization_codes", "user_id"},
+	{"oauth_refresh_tokens", "user_id"},
+	{"idempotency_keys", "user_id"},
+	// Cascades to the codes and tokens issued to the user's clients
+	{"oauth_clients", "owner_id"},
+}
+
+func (r *userRepo) Anonymize(ctx context.Context, id string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, t := range credentialTables {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+t[0]+" WHERE "+t[1]+" = ?", id); err != nil {
+			return err
+		}
+	}
+	// Leaves every workspace and loses access to anything shared
+	if _, err := tx.ExecContext(ctx, "DELETE FROM workspace_members WHERE user_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM shares WHERE user_id = ?", id); err != nil {
+		return err
+	}
+
+	// The placeholder address keeps the email column unique
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE users SET email = CONCAT('deleted-', id, '@deleted.invalid'), pass_hash = '',
+		display_name = '', avatar_url = '', pending_email = NULL, verified_at = NULL,
+		disabled_at = COALESCE(disabled_at, NOW()), deleted_at = NOW()
+		WHERE id = ?`,
+		id,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// escapeLike makes user input match literally inside a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
 }
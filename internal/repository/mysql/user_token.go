@@ -0,0 +1,81 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"task-flow/internal/model"
+	"task-flow/internal/repository"
+)
+
+type userTokenRepo struct {
+	db *sql.DB
+}
+
+func NewUserTokenRepo(db *sql.DB) repository.UserTokenRepo {
+	return &userTokenRepo{db: db}
+}
+
+func (r *userTokenRepo) Insert(ctx context.Context, userID string, purpose model.TokenPurpose, tokenHash []byte, expUnix int64) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at) VALUES (?, ?, ?, FROM_UNIXTIME(?))",
+		userID, purpose, tokenHash, expUnix,
+	)
+	return err
+}
+
+func (r *userTokenRepo) Find(ctx context.Context, purpose model.TokenPurpose, tokenHash []byte) (string, bool, error) {
+	var userID string
+	err := r.db.QueryRowContext(ctx,
+		`SELECT user_id FROM user_tokens
+		WHERE token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > NOW()`,
+		tokenHash, purpose,
+	).Scan(&userID)
+
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return userID, true, nil
+}
+
+func (r *userTokenRepo) Consume(ctx context.Context, purpose model.TokenPurpose, tokenHash []byte) (string, bool, error) {
+	// The conditional update is what makes the token single-use: only one
+	// concurrent caller can flip used_at.
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE user_tokens SET used_at = NOW()
+		WHERE token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > NOW()`,
+		tokenHash, purpose,
+	)
+	if err != nil {
+		return "", false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return "", false, err
+	}
+	if n == 0 {
+		return "", false, nil
+	}
+
+	var userID string
+	err = r.db.QueryRowContext(ctx,
+		"SELECT user_id FROM user_tokens WHERE token_hash = ? AND purpose = ?",
+		tokenHash, purpose,
+	).Scan(&userID)
+	if err != nil {
+		return "", false, err
+	}
+	return userID, true, nil
+}
+
+func (r *userTokenRepo) DeleteByUser(ctx context.Context, userID string, purpose model.TokenPurpose) error {
+	_, err := r.db.ExecContext(ctx,
+		"DELETE FROM user_tokens WHERE user_id = ? AND purpose = ?",
+		userID, purpose,
+	)
+	return err
+}
@@ -0,0 +1,195 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"task-flow/internal/model"
+	"task-flow/internal/repository"
+)
+
+type workspaceRepo struct {
+	db *sql.DB
+}
+
+func NewWorkspaceRepo(db *sql.DB) repository.WorkspaceRepo {
+	return &workspaceRepo{db: db}
+}
+
+func (r *workspaceRepo) Create(ctx context.Context, ws model.Workspace, ownerID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO workspaces (id, name, created_by) VALUES (?, ?, ?)",
+		ws.ID, ws.Name, ownerID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO workspace_members (workspace_id, user_id, role) VALUES (?, ?, ?)",
+		ws.ID, ownerID, model.WorkspaceRoleOwner,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *workspaceRepo) FindMember(ctx context.Context, workspaceID, userID string) (model.WorkspaceMember, bool, error) {
+	m, err := scanWorkspaceMember(r.db.QueryRowContext(ctx,
+		`SELECT m.workspace_id, m.user_id, u.email, m.role, m.created_at
+		FROM workspace_members m JOIN users u ON u.id = m.user_id
+		WHERE m.workspace_id = ? AND m.user_id = ?`,
+		workspaceID, userID,
+	))
+	if err == sql.ErrNoRows {
+		return model.WorkspaceMember{}, false, nil
+	}
+	if err != nil {
+		return model.WorkspaceMember{}, false, err
+	}
+	return m, true, nil
+}
+
+func (r *workspaceRepo) ListForUser(ctx context.Context, userID string) ([]model.WorkspaceMembership, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT w.id, w.name, COALESCE(w.created_by, ''), w.created_at, m.role
+		FROM workspace_members m JOIN workspaces w ON w.id = m.workspace_id
+		WHERE m.user_id = ? ORDER BY w.name, w.id`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var memberships []model.WorkspaceMembership
+	for rows.Next() {
+		var m model.WorkspaceMembership
+		if err := rows.Scan(&m.ID, &m.Name, &m.CreatedBy, &m.CreatedAt, &m.Role); err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, m)
+	}
+	return memberships, rows.Err()
+}
+
+func (r *workspaceRepo) Get(ctx context.Context) (model.Workspace, error) {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return model.Workspace{}, err
+	}
+
+	var ws model.Workspace
+	err = r.db.QueryRowContext(ctx,
+		"SELECT id, name, COALESCE(created_by, ''), created_at FROM workspaces WHERE id = ?",
+		workspaceID,
+	).Scan(&ws.ID, &ws.Name, &ws.CreatedBy, &ws.CreatedAt)
+	return ws, err
+}
+
+func (r *workspaceRepo) Rename(ctx context.Context, name string) error {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		"UPDATE workspaces SET name = ? WHERE id = ?",
+		name, workspaceID,
+	)
+	return err
+}
+
+func scanWorkspaceMember(row rowScanner) (model.WorkspaceMember, error) {
+	var m model.WorkspaceMember
+	err := row.Scan(&m.WorkspaceID, &m.UserID, &m.Email, &m.Role, &m.CreatedAt)
+	return m, err
+}
+
+func (r *workspaceRepo) ListMembers(ctx context.Context) ([]model.WorkspaceMember, error) {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT m.workspace_id, m.user_id, u.email, m.role, m.created_at
+		FROM workspace_members m JOIN users u ON u.id = m.user_id
+		WHERE m.workspace_id = ? ORDER BY m.created_at, u.email`,
+		workspaceID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []model.WorkspaceMember
+	for rows.Next() {
+		m, err := scanWorkspaceMember(rows)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+func (r *workspaceRepo) SetMember(ctx context.Context, userID string, role model.WorkspaceRole) error {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO workspace_members (workspace_id, user_id, role) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE role = VALUES(role)`,
+		workspaceID, userID, role,
+	)
+	return err
+}
+
+func (r *workspaceRepo) RemoveMember(ctx context.Context, userID string) (bool, error) {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := r.db.ExecContext(ctx,
+		"DELETE FROM workspace_members WHERE workspace_id = ? AND user_id = ?",
+		workspaceID, userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *workspaceRepo) CountOwners(ctx context.Context) (int, error) {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	err = r.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM workspace_members WHERE workspace_id = ? AND role = ?",
+		workspaceID, model.WorkspaceRoleOwner,
+	).Scan(&n)
+	return n, err
+}
+
+func (r *workspaceRepo) Delete(ctx context.Context) error {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Members, projects, tasks and everything hanging off them cascade
+	_, err = r.db.ExecContext(ctx, "DELETE FROM workspaces WHERE id = ?", workspaceID)
+	return err
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+
+	"task-flow/internal/model"
+)
+
+type OAuthClientRepo interface {
+	Create(ctx context.Context, client model.OAuthClient) error
+	FindByID(ctx context.Context, id string) (model.OAuthClient, bool, error)
+	ListByOwner(ctx context.Context, ownerID string) ([]model.OAuthClient, error)
+	// Delete removes a client of ownerID along with its codes and refresh
+	// tokens. It reports false if no such client exists.
+	Delete(ctx context.Context, ownerID, id string) (bool, error)
+}
+
+type OAuthCodeRepo interface {
+	Insert(ctx context.Context, codeHash []byte, code model.OAuthAuthorizationCode) error
+	// Consume redeems an unexpired, unused code. It reports false if the
+	// code does not exist or was already redeemed.
+	Consume(ctx context.Context, codeHash []byte) (model.OAuthAuthorizationCode, bool, error)
+}
+
+type OAuthRefreshTokenRepo interface {
+	Insert(ctx context.Context, tokenHash []byte, token model.OAuthRefreshToken) error
+	FindByHash(ctx context.Context, tokenHash []byte) (model.OAuthRefreshToken, bool, error)
+	// Rotate revokes a valid token that is being exchanged for a new one.
+	// It reports false if the token was no longer valid, e.g. because a
+	// concurrent request rotated it first.
+	Rotate(ctx context.Context, tokenHash []byte) (bool, error)
+	RevokeByHash(ctx context.Context, tokenHash []byte) error
+}
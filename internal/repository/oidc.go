@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"task-flow/internal/model"
+)
+
+type OIDCStateRepo interface {
+	Insert(ctx context.Context, stateHash []byte, state model.OIDCLoginState) error
+	// Consume deletes an unexpired state and returns it. It reports false if
+	// the state is unknown, expired or was already used.
+	Consume(ctx context.Context, stateHash []byte) (model.OIDCLoginState, bool, error)
+}
+
+type UserIdentityRepo interface {
+	FindUserID(ctx context.Context, provider, subject string) (string, bool, error)
+	Link(ctx context.Context, identity model.UserIdentity) error
+	ListByUser(ctx context.Context, userID string) ([]model.UserIdentity, error)
+}
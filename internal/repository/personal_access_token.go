@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+
+	"task-flow/internal/model"
+)
+
+type PersonalAccessTokenRepo interface {
+	Create(ctx context.Context, token model.PersonalAccessToken, tokenHash []byte) error
+	// FindActiveByHash only returns tokens that are neither revoked nor expired.
+	FindActiveByHash(ctx context.Context, tokenHash []byte) (model.PersonalAccessToken, bool, error)
+	ListByUser(ctx context.Context, userID string) ([]model.PersonalAccessToken, error)
+	Revoke(ctx context.Context, userID, id string) (bool, error)
+	TouchLastUsed(ctx context.Context, id string) error
+}
@@ -1,9 +1,20 @@
 package repository
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type RefreshTokenRepo interface {
 	Insert(ctx context.Context, userID string, tokenHash []byte, expUnix int64) error
 	FindUserIDByHash(ctx context.Context, tokenHash []byte) (string, bool, error)
+	// Rotate revokes a valid token that is being exchanged for a new one.
+	// It reports false if the token was no longer valid, e.g. because a
+	// concurrent request rotated it first.
+	Rotate(ctx context.Context, tokenHash []byte) (bool, error)
+	// FindRotated returns the owner of an unexpired token that was already
+	// rotated, and how long ago that was by the database clock.
+	FindRotated(ctx context.Context, tokenHash []byte) (userID string, sinceRotated time.Duration, found bool, err error)
 	RevokeByHash(ctx context.Context, tokenHash []byte) error
+	RevokeAllForUser(ctx context.Context, userID string) error
 }
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+
+	"task-flow/internal/model"
+)
+
+// ShareRepo stores task and project shares. FindTaskGrant and
+// ListTasksForUser resolve shares by grantee across workspaces and are not
+// scoped; the rest act on the workspace in the context.
+type ShareRepo interface {
+	// Set adds the share or changes its level.
+	Set(ctx context.Context, share model.Share) error
+	List(ctx context.Context, resource model.ShareResource, resourceID string) ([]model.Share, error)
+	Delete(ctx context.Context, resource model.ShareResource, resourceID, userID string) (bool, error)
+	// TaskLevel returns the highest level userID holds on a task in the
+	// context's workspace, directly or through its project.
+	TaskLevel(ctx context.Context, userID, taskID string) (model.AccessLevel, error)
+
+	// FindTaskGrant returns the workspace of a task shared with userID and
+	// the highest level granted.
+	FindTaskGrant(ctx context.Context, userID, taskID string) (workspaceID string, level model.AccessLevel, found bool, err error)
+	ListTasksForUser(ctx context.Context, userID string) ([]model.SharedTask, error)
+}
+
+// TaskLinkRepo stores public task links. FindByTokenHash resolves a link
+// from its secret and is not scoped.
+type TaskLinkRepo interface {
+	Create(ctx context.Context, link model.TaskLink) error
+	List(ctx context.Context, taskID string) ([]model.TaskLink, error)
+	Revoke(ctx context.Context, taskID, id string) (bool, error)
+
+	// FindByTokenHash returns an unexpired link.
+	FindByTokenHash(ctx context.Context, hash []byte) (model.TaskLink, bool, error)
+}
+
+// TaskCommentRepo is scoped to the workspace in the context, apart from
+// ListByUser.
+type TaskCommentRepo interface {
+	Add(ctx context.Context, comment model.TaskComment) error
+	List(ctx context.Context, taskID string) ([]model.TaskComment, error)
+
+	// ListByUser returns every comment userID wrote, in any workspace.
+	ListByUser(ctx context.Context, userID string) ([]model.TaskComment, error)
+}
@@ -6,9 +6,11 @@ import (
 	"task-flow/internal/model"
 )
 
+// TaskRepo is scoped to the workspace in the context (see WithWorkspace).
 type TaskRepo interface {
 	AddTask(ctx context.Context, task model.Task) error
 	GetTasks(ctx context.Context) ([]model.Task, error)
 	FindByID(ctx context.Context, id string) (model.Task, error)
+	UpdateTask(ctx context.Context, task model.Task) error
 	DeleteTask(ctx context.Context, id string) error
 }
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoWorkspace is returned by tenant-scoped repositories when the context
+// carries no workspace. Failing closed means a forgotten scope is a bug
+// report, not a data leak.
+var ErrNoWorkspace = errors.New("no workspace in context")
+
+type workspaceKey struct{}
+
+// WithWorkspace scopes every tenant-aware repository call made with the
+// returned context to workspaceID.
+func WithWorkspace(ctx context.Context, workspaceID string) context.Context {
+	return context.WithValue(ctx, workspaceKey{}, workspaceID)
+}
+
+// WorkspaceID returns the workspace the context is scoped to.
+func WorkspaceID(ctx context.Context) (string, error) {
+	id, _ := ctx.Value(workspaceKey{}).(string)
+	if id == "" {
+		return "", ErrNoWorkspace
+	}
+	return id, nil
+}
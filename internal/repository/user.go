@@ -7,7 +7,25 @@ import (
 )
 
 type UserRepo interface {
+	// Create returns ErrDuplicate when the email is taken.
 	Create(ctx context.Context, user model.User) error
 	FindByID(ctx context.Context, id string) (model.User, bool, error)
 	FindByEmail(ctx context.Context, email string) (model.User, bool, error)
+	UpdatePassword(ctx context.Context, id string, passHash []byte) error
+	MarkVerified(ctx context.Context, id string) error
+	List(ctx context.Context, filter model.UserFilter) ([]model.User, error)
+	SetRole(ctx context.Context, id string, role model.Role) error
+	// SetDisabled disables or re-enables an account.
+	SetDisabled(ctx context.Context, id string, disabled bool) error
+	UpdateProfile(ctx context.Context, id string, profile model.UserProfile) error
+	// SetPendingEmail records the address of an email change until it is
+	// confirmed; "" clears it.
+	SetPendingEmail(ctx context.Context, id, email string) error
+	// ChangeEmail makes email the account's verified address and clears the
+	// pending change. It returns ErrDuplicate when the email is taken.
+	ChangeEmail(ctx context.Context, id, email string) error
+	// Anonymize deletes the account: it removes its credentials, sessions,
+	// OAuth clients, workspace memberships and shares, and scrubs the user
+	// row, which stays disabled so that comments keep an author.
+	Anonymize(ctx context.Context, id string) error
 }
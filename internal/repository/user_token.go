@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"task-flow/internal/model"
+)
+
+type UserTokenRepo interface {
+	Insert(ctx context.Context, userID string, purpose model.TokenPurpose, tokenHash []byte, expUnix int64) error
+	// Find returns the owner of an unexpired, unused token without
+	// redeeming it.
+	Find(ctx context.Context, purpose model.TokenPurpose, tokenHash []byte) (string, bool, error)
+	// Consume marks an unexpired, unused token as used and returns its owner.
+	// It reports false if the token does not exist or was already redeemed.
+	Consume(ctx context.Context, purpose model.TokenPurpose, tokenHash []byte) (string, bool, error)
+	DeleteByUser(ctx context.Context, userID string, purpose model.TokenPurpose) error
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+
+	"task-flow/internal/model"
+)
+
+// WorkspaceRepo manages workspaces and their members. Apart from Create,
+// FindMember and ListForUser, which establish the scope in the first place,
+// every method acts on the workspace in the context (see WithWorkspace).
+type WorkspaceRepo interface {
+	// Create inserts the workspace with ownerID as its first owner.
+	Create(ctx context.Context, ws model.Workspace, ownerID string) error
+	FindMember(ctx context.Context, workspaceID, userID string) (model.WorkspaceMember, bool, error)
+	ListForUser(ctx context.Context, userID string) ([]model.WorkspaceMembership, error)
+
+	Get(ctx context.Context) (model.Workspace, error)
+	Rename(ctx context.Context, name string) error
+	ListMembers(ctx context.Context) ([]model.WorkspaceMember, error)
+	// SetMember adds userID or changes their role.
+	SetMember(ctx context.Context, userID string, role model.WorkspaceRole) error
+	RemoveMember(ctx context.Context, userID string) (bool, error)
+	CountOwners(ctx context.Context) (int, error)
+	// Delete removes the workspace with everything in it.
+	Delete(ctx context.Context) error
+}
+
+// ProjectRepo is scoped to the workspace in the context.
+type ProjectRepo interface {
+	Create(ctx context.Context, project model.Project) error
+	List(ctx context.Context) ([]model.Project, error)
+	FindByID(ctx context.Context, id string) (model.Project, bool, error)
+	Delete(ctx context.Context, id string) (bool, error)
+}
+
+// InvitationRepo stores workspace invitations. FindByID and Accept look up
+// an invitation by the ID from a signed token and are not scoped; the rest
+// act on the workspace in the context.
+type InvitationRepo interface {
+	Create(ctx context.Context, inv model.WorkspaceInvitation) error
+	ListPending(ctx context.Context) ([]model.WorkspaceInvitation, error)
+	// Revoke deletes a pending invitation.
+	Revoke(ctx context.Context, id string) (bool, error)
+	// RevokeForEmail deletes pending invitations for email, so that only
+	// the latest one works.
+	RevokeForEmail(ctx context.Context, email string) error
+
+	FindByID(ctx context.Context, id string) (model.WorkspaceInvitation, bool, error)
+	// Accept marks a pending invitation accepted. It reports false when the
+	// invitation was already used, revoked or has expired.
+	Accept(ctx context.Context, id string) (bool, error)
+}
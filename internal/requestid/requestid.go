@@ -0,0 +1,57 @@
+// Package requestid carries the ID that correlates a request with its log
+// lines, error responses and the mail it sends.
+package requestid
+
+import (
+	"context"
+
+	"task-flow/internal/utils"
+)
+
+// Header is read from requests and echoed on responses.
+const Header = "X-Request-ID"
+
+// maxLen bounds IDs taken from clients, which end up in every log line.
+const maxLen = 128
+
+type ctxKey struct{}
+
+// New returns a fresh random ID.
+func New() string {
+	id, err := utils.GenerateID()
+	if err != nil {
+		// Only possible when the system's randomness fails; a request
+		// without an ID is better than no request
+		return "unavailable"
+	}
+	return id
+}
+
+// Valid reports whether a client-supplied ID is safe to adopt: non-empty,
+// bounded and limited to characters that need no escaping in logs or
+// headers.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLen {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// With returns a copy of ctx carrying id.
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext returns the request ID carried by ctx, or "".
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
@@ -0,0 +1,32 @@
+package requestid
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	cases := map[string]bool{
+		"":                                 false,
+		"abc-123":                          true,
+		"4bf92f3577b34da6a3ce929d0e0e4736": true,
+		"trace:span.1_x":                   true,
+		"has space":                        false,
+		"line\nbreak":                      false,
+		`quote"`:                           false,
+		strings.Repeat("a", maxLen):        true,
+		strings.Repeat("a", maxLen+1):      false,
+	}
+	for id, want := range cases {
+		if got := Valid(id); got != want {
+			t.Errorf("Valid(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	a, b := New(), New()
+	if !Valid(a) || a == b {
+		t.Errorf("New() = %q, %q", a, b)
+	}
+}
@@ -5,18 +5,59 @@ import (
 
 	"task-flow/internal/handler"
 	"task-flow/internal/middleware"
+	"task-flow/internal/model"
+	"task-flow/internal/repository"
 )
 
 type Deps struct {
-	AuthHandler *handler.AuthHandler
-	TaskHandler *handler.TaskHandler
-	UserHandler *handler.UserHandler
-	AuthMid     *middleware.AuthMiddleware
+	AuthHandler      *handler.AuthHandler
+	TaskHandler      *handler.TaskHandler
+	UserHandler      *handler.UserHandler
+	OAuthHandler     *handler.OAuthHandler
+	AdminHandler     *handler.AdminHandler
+	WorkspaceHandler *handler.WorkspaceHandler
+	AuthMid          *middleware.AuthMiddleware
+	// Workspaces resolves workspace membership for tenant-scoped routes
+	Workspaces repository.WorkspaceRepo
+	// Metrics, when set, is served at GET /metrics and must do its own
+	// access control
+	Metrics http.Handler
+	// RateLimiter limits API routes; nil disables rate limiting
+	RateLimiter *middleware.RateLimiter
+	// Idempotency replays authenticated POSTs retried with the same
+	// Idempotency-Key; nil disables it
+	Idempotency *middleware.Idempotency
 }
 
 func New(d Deps) *http.ServeMux {
 	mux := http.NewServeMux()
 
+	// public and strict rate limit unauthenticated routes by client IP;
+	// strict is for the ones that take credentials
+	public := func(h http.HandlerFunc) http.Handler { return d.RateLimiter.Limit(h) }
+	strict := func(h http.HandlerFunc) http.Handler { return d.RateLimiter.Strict(h) }
+
+	// protected requires an authenticated user whose credential carries
+	// scope, rate limits them by user and honors Idempotency-Key
+	protected := func(scope string, h http.HandlerFunc) http.Handler {
+		return middleware.RequireAccessJWT(d.AuthMid)(d.RateLimiter.Limit(middleware.RequireScope(scope)(d.Idempotency.Handle(h))))
+	}
+	// permitted additionally requires the user's role to grant perm
+	permitted := func(scope string, perm model.Permission, h http.HandlerFunc) http.Handler {
+		return protected(scope, middleware.RequirePermission(perm)(h).ServeHTTP)
+	}
+	// inWorkspace scopes the request to a workspace the user belongs to and
+	// requires their role there to grant perm
+	inWorkspace := func(scope string, perm model.Permission, h http.HandlerFunc) http.Handler {
+		return protected(scope, middleware.RequireWorkspace(d.Workspaces)(middleware.RequireWorkspacePermission(perm)(h)).ServeHTTP)
+	}
+
+	// member scopes the request to a workspace the user belongs to, leaving
+	// finer checks to the handler
+	member := func(scope string, h http.HandlerFunc) http.Handler {
+		return protected(scope, middleware.RequireWorkspace(d.Workspaces)(h).ServeHTTP)
+	}
+
 	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		_, _ = w.Write([]byte(`{"message": "Server Golang is running..."}`))
@@ -28,20 +69,115 @@ func New(d Deps) *http.ServeMux {
 		_, _ = w.Write([]byte(`{"status":"OK"}`))
 	})
 
+	if d.Metrics != nil {
+		mux.Handle("GET /metrics", d.Metrics)
+	}
+
 	// Auth routes (public)
-	mux.HandleFunc("POST /auth/register", d.AuthHandler.Register)
-	mux.HandleFunc("POST /auth/login", d.AuthHandler.Login)
-	mux.HandleFunc("POST /auth/refresh", d.AuthHandler.Refresh)
-	mux.HandleFunc("POST /auth/logout", d.AuthHandler.Logout)
+	mux.Handle("POST /auth/register", strict(d.AuthHandler.Register))
+	mux.Handle("POST /auth/login", strict(d.AuthHandler.Login))
+	mux.Handle("POST /auth/login/mfa", strict(d.AuthHandler.LoginMFA))
+	mux.Handle("POST /auth/refresh", public(d.AuthHandler.Refresh))
+	mux.Handle("POST /auth/logout", public(d.AuthHandler.Logout))
+	mux.Handle("POST /auth/verify-email", public(d.AuthHandler.VerifyEmail))
+	mux.Handle("POST /auth/verify-email/resend", strict(d.AuthHandler.ResendVerification))
+	mux.Handle("POST /auth/password/forgot", strict(d.AuthHandler.ForgotPassword))
+	mux.Handle("POST /auth/password/reset", strict(d.AuthHandler.ResetPassword))
+	mux.Handle("POST /auth/email/confirm", public(d.AuthHandler.ConfirmEmailChange))
+	mux.Handle("GET /auth/oidc/{provider}/login", public(d.AuthHandler.OIDCLogin))
+	mux.Handle("GET /auth/oidc/{provider}/callback", public(d.AuthHandler.OIDCCallback))
+	mux.Handle("POST /auth/logout-all", protected(model.ScopeAccountManage, d.AuthHandler.LogoutAll))
 
 	// User routes (protected)
-	mux.Handle("GET /users/me", middleware.RequireAccessJWT(d.AuthMid)(http.HandlerFunc(d.UserHandler.Me)))
+	mux.Handle("GET /users/me", protected(model.ScopeUserRead, d.UserHandler.Me))
+	mux.Handle("PATCH /users/me", protected(model.ScopeAccountManage, d.UserHandler.UpdateMe))
+	mux.Handle("DELETE /users/me", protected(model.ScopeAccountManage, d.UserHandler.DeleteMe))
+	mux.Handle("GET /users/me/export", protected(model.ScopeAccountManage, d.UserHandler.Export))
+	mux.Handle("POST /users/me/password", protected(model.ScopeAccountManage, d.AuthHandler.ChangePassword))
+	mux.Handle("POST /users/me/email", protected(model.ScopeAccountManage, d.AuthHandler.ChangeEmail))
+	mux.Handle("POST /users/me/2fa/setup", protected(model.ScopeAccountManage, d.AuthHandler.SetupMFA))
+	mux.Handle("POST /users/me/2fa/confirm", protected(model.ScopeAccountManage, d.AuthHandler.ConfirmMFA))
+	mux.Handle("POST /users/me/2fa/disable", protected(model.ScopeAccountManage, d.AuthHandler.DisableMFA))
+	mux.Handle("GET /users/me/tokens", protected(model.ScopeAccountManage, d.AuthHandler.ListTokens))
+	mux.Handle("POST /users/me/tokens", protected(model.ScopeAccountManage, d.AuthHandler.CreateToken))
+	mux.Handle("DELETE /users/me/tokens/{id}", protected(model.ScopeAccountManage, d.AuthHandler.RevokeToken))
+
+	// OAuth 2.0 authorization server. The consent endpoints are called by
+	// our own frontend on behalf of the signed-in user.
+	mux.Handle("GET /oauth/clients", protected(model.ScopeAccountManage, d.OAuthHandler.ListClients))
+	mux.Handle("POST /oauth/clients", protected(model.ScopeAccountManage, d.OAuthHandler.CreateClient))
+	mux.Handle("DELETE /oauth/clients/{id}", protected(model.ScopeAccountManage, d.OAuthHandler.DeleteClient))
+	mux.Handle("GET /oauth/authorize", protected(model.ScopeAccountManage, d.OAuthHandler.AuthorizeInfo))
+	mux.Handle("POST /oauth/authorize", protected(model.ScopeAccountManage, d.OAuthHandler.Authorize))
+	mux.Handle("POST /oauth/token", public(d.OAuthHandler.Token))
+	mux.Handle("POST /oauth/introspect", public(d.OAuthHandler.Introspect))
+	mux.Handle("POST /oauth/revoke", public(d.OAuthHandler.Revoke))
+
+	// Workspace routes
+	mux.Handle("GET /workspaces", protected(model.ScopeTasksRead, d.WorkspaceHandler.List))
+	mux.Handle("POST /workspaces", permitted(model.ScopeAccountManage, model.PermissionWorkspaceCreate, d.WorkspaceHandler.Create))
+	mux.Handle("GET /workspaces/{workspace}", inWorkspace(model.ScopeTasksRead, model.PermissionTasksRead, d.WorkspaceHandler.Get))
+	mux.Handle("PATCH /workspaces/{workspace}", inWorkspace(model.ScopeAccountManage, model.PermissionWorkspaceManage, d.WorkspaceHandler.Rename))
+	mux.Handle("GET /workspaces/{workspace}/members", inWorkspace(model.ScopeTasksRead, model.PermissionTasksRead, d.WorkspaceHandler.ListMembers))
+	mux.Handle("POST /workspaces/{workspace}/members", inWorkspace(model.ScopeAccountManage, model.PermissionMembersManage, d.WorkspaceHandler.AddMember))
+	mux.Handle("PUT /workspaces/{workspace}/members/{user_id}", inWorkspace(model.ScopeAccountManage, model.PermissionMembersManage, d.WorkspaceHandler.ChangeMemberRole))
+	// Any member may leave; the service checks removing others
+	mux.Handle("DELETE /workspaces/{workspace}/members/{user_id}", inWorkspace(model.ScopeAccountManage, model.PermissionTasksRead, d.WorkspaceHandler.RemoveMember))
+	mux.Handle("GET /workspaces/{workspace}/projects", inWorkspace(model.ScopeTasksRead, model.PermissionTasksRead, d.WorkspaceHandler.ListProjects))
+	mux.Handle("POST /workspaces/{workspace}/projects", inWorkspace(model.ScopeAccountManage, model.PermissionProjectsManage, d.WorkspaceHandler.CreateProject))
+	mux.Handle("GET /workspaces/{workspace}/projects/{id}", inWorkspace(model.ScopeTasksRead, model.PermissionTasksRead, d.WorkspaceHandler.GetProject))
+	mux.Handle("DELETE /workspaces/{workspace}/projects/{id}", inWorkspace(model.ScopeAccountManage, model.PermissionProjectsManage, d.WorkspaceHandler.DeleteProject))
+	mux.Handle("GET /workspaces/{workspace}/projects/{id}/shares", inWorkspace(model.ScopeAccountManage, model.PermissionProjectsManage, d.WorkspaceHandler.ListProjectShares))
+	mux.Handle("POST /workspaces/{workspace}/projects/{id}/shares", inWorkspace(model.ScopeAccountManage, model.PermissionProjectsManage, d.WorkspaceHandler.ShareProject))
+	mux.Handle("DELETE /workspaces/{workspace}/projects/{id}/shares/{user_id}", inWorkspace(model.ScopeAccountManage, model.PermissionProjectsManage, d.WorkspaceHandler.UnshareProject))
+
+	mux.Handle("GET /workspaces/{workspace}/invitations", inWorkspace(model.ScopeAccountManage, model.PermissionMembersManage, d.WorkspaceHandler.ListInvitations))
+	mux.Handle("POST /workspaces/{workspace}/invitations", inWorkspace(model.ScopeAccountManage, model.PermissionMembersManage, d.WorkspaceHandler.Invite))
+	mux.Handle("DELETE /workspaces/{workspace}/invitations/{id}", inWorkspace(model.ScopeAccountManage, model.PermissionMembersManage, d.WorkspaceHandler.RevokeInvitation))
+
+	// Invitation links. Lookup and register are public; the token is the
+	// credential.
+	mux.Handle("POST /invitations/lookup", public(d.WorkspaceHandler.LookupInvitation))
+	mux.Handle("POST /invitations/register", strict(d.WorkspaceHandler.RegisterWithInvitation))
+	mux.Handle("POST /invitations/accept", protected(model.ScopeAccountManage, d.WorkspaceHandler.AcceptInvitation))
+
+	// Task routes. The /tasks forms select the workspace with the
+	// X-Workspace-ID header. Membership is checked here; what the member may
+	// do with each task is decided by the task handlers, since shares can
+	// grant more than the workspace role.
+	for _, prefix := range []string{"/workspaces/{workspace}", ""} {
+		mux.Handle("GET "+prefix+"/tasks", member(model.ScopeTasksRead, d.TaskHandler.GetTasks))
+		mux.Handle("POST "+prefix+"/tasks", member(model.ScopeTasksWrite, d.TaskHandler.AddTask))
+		mux.Handle("DELETE "+prefix+"/tasks/{id}", member(model.ScopeTasksWrite, d.TaskHandler.DeleteTask))
+		mux.Handle("GET "+prefix+"/tasks/{id}", member(model.ScopeTasksRead, d.TaskHandler.GetTasksByID))
+		mux.Handle("PATCH "+prefix+"/tasks/{id}", member(model.ScopeTasksWrite, d.TaskHandler.UpdateTask))
+		mux.Handle("GET "+prefix+"/tasks/{id}/comments", member(model.ScopeTasksRead, d.TaskHandler.ListComments))
+		mux.Handle("POST "+prefix+"/tasks/{id}/comments", member(model.ScopeTasksWrite, d.TaskHandler.AddComment))
+		mux.Handle("GET "+prefix+"/tasks/{id}/shares", member(model.ScopeAccountManage, d.TaskHandler.ListShares))
+		mux.Handle("POST "+prefix+"/tasks/{id}/shares", member(model.ScopeAccountManage, d.TaskHandler.Share))
+		mux.Handle("DELETE "+prefix+"/tasks/{id}/shares/{user_id}", member(model.ScopeAccountManage, d.TaskHandler.Unshare))
+		mux.Handle("GET "+prefix+"/tasks/{id}/links", member(model.ScopeAccountManage, d.TaskHandler.ListLinks))
+		mux.Handle("POST "+prefix+"/tasks/{id}/links", member(model.ScopeAccountManage, d.TaskHandler.CreateLink))
+		mux.Handle("DELETE "+prefix+"/tasks/{id}/links/{link_id}", member(model.ScopeAccountManage, d.TaskHandler.RevokeLink))
+	}
+
+	// Tasks shared with the user from workspaces they may not belong to
+	mux.Handle("GET /shared/tasks", protected(model.ScopeTasksRead, d.TaskHandler.ListShared))
+	mux.Handle("GET /shared/tasks/{id}", protected(model.ScopeTasksRead, d.TaskHandler.GetTasksByID))
+	mux.Handle("PATCH /shared/tasks/{id}", protected(model.ScopeTasksWrite, d.TaskHandler.UpdateTask))
+	mux.Handle("GET /shared/tasks/{id}/comments", protected(model.ScopeTasksRead, d.TaskHandler.ListComments))
+	mux.Handle("POST /shared/tasks/{id}/comments", protected(model.ScopeTasksWrite, d.TaskHandler.AddComment))
+
+	// Public read-only task links; the token is the credential
+	mux.Handle("GET /public/tasks/{token}", public(d.TaskHandler.PublicTask))
 
-	// Task routes
-	mux.Handle("GET /tasks", middleware.RequireAccessJWT(d.AuthMid)(http.HandlerFunc(d.TaskHandler.GetTasks)))
-	mux.Handle("POST /tasks", middleware.RequireAccessJWT(d.AuthMid)(http.HandlerFunc(d.TaskHandler.AddTask)))
-	mux.Handle("DELETE /tasks/{id}", middleware.RequireAccessJWT(d.AuthMid)(http.HandlerFunc(d.TaskHandler.DeleteTask)))
-	mux.Handle("GET /tasks/{id}", middleware.RequireAccessJWT(d.AuthMid)(http.HandlerFunc(d.TaskHandler.GetTasksByID)))
+	// Admin routes; never reachable with delegated credentials
+	mux.Handle("GET /admin/users", permitted(model.ScopeAccountManage, model.PermissionUsersManage, d.AdminHandler.ListUsers))
+	mux.Handle("GET /admin/users/{id}", permitted(model.ScopeAccountManage, model.PermissionUsersManage, d.AdminHandler.GetUser))
+	mux.Handle("POST /admin/users/{id}/disable", permitted(model.ScopeAccountManage, model.PermissionUsersManage, d.AdminHandler.DisableUser))
+	mux.Handle("POST /admin/users/{id}/enable", permitted(model.ScopeAccountManage, model.PermissionUsersManage, d.AdminHandler.EnableUser))
+	mux.Handle("POST /admin/users/{id}/logout", permitted(model.ScopeAccountManage, model.PermissionUsersManage, d.AdminHandler.ForceLogout))
+	mux.Handle("PUT /admin/users/{id}/role", permitted(model.ScopeAccountManage, model.PermissionUsersManage, d.AdminHandler.ChangeRole))
 
 	return mux
 }
@@ -0,0 +1,157 @@
+package account
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	// Timezones must validate even on hosts without a zoneinfo database
+	_ "time/tzdata"
+
+	"task-flow/internal/apperr"
+	"task-flow/internal/model"
+	"task-flow/internal/repository"
+	"task-flow/internal/tracing"
+)
+
+// PasswordVerifier re-checks a signed-in user's password. It is implemented
+// by the auth service.
+type PasswordVerifier interface {
+	VerifyPassword(ctx context.Context, userID, pw string) (model.User, error)
+}
+
+// Service lets users manage their own profile, export their data and
+// delete their account.
+type Service struct {
+	UserRepo      repository.UserRepo
+	WorkspaceRepo repository.WorkspaceRepo
+	TaskRepo      repository.TaskRepo
+	ShareRepo     repository.ShareRepo
+	CommentRepo   repository.TaskCommentRepo
+	PATRepo       repository.PersonalAccessTokenRepo
+	IdentityRepo  repository.UserIdentityRepo
+	Passwords     PasswordVerifier
+}
+
+func NewService(
+	userRepo repository.UserRepo,
+	workspaceRepo repository.WorkspaceRepo,
+	taskRepo repository.TaskRepo,
+	shareRepo repository.ShareRepo,
+	commentRepo repository.TaskCommentRepo,
+	patRepo repository.PersonalAccessTokenRepo,
+	identityRepo repository.UserIdentityRepo,
+	passwords PasswordVerifier,
+) *Service {
+	return &Service{
+		UserRepo:      userRepo,
+		WorkspaceRepo: workspaceRepo,
+		TaskRepo:      taskRepo,
+		ShareRepo:     shareRepo,
+		CommentRepo:   commentRepo,
+		PATRepo:       patRepo,
+		IdentityRepo:  identityRepo,
+		Passwords:     passwords,
+	}
+}
+
+const (
+	maxDisplayNameLength = 100
+	maxAvatarURLLength   = 500
+)
+
+var (
+	ErrUserNotFound       = apperr.NotFound("user_not_found", "user not found")
+	ErrInvalidDisplayName = apperr.Validation("invalid_display_name", "display_name must be at most 100 characters")
+	ErrInvalidAvatarURL   = apperr.Validation("invalid_avatar_url", "avatar_url must be an http or https URL of at most 500 characters")
+	ErrInvalidTimezone    = apperr.Validation("invalid_timezone", "timezone must be an IANA name such as Asia/Jakarta")
+	ErrInvalidLocale      = apperr.Validation("invalid_locale", "locale must be a language tag such as id-ID")
+)
+
+// localePattern accepts the common BCP 47 shapes: a language, then
+// optional script, region or variant subtags.
+var localePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+func (s *Service) Profile(ctx context.Context, userID string) (model.User, error) {
+	ctx, span := tracing.Start(ctx, "account.Profile")
+	defer span.End()
+
+	user, found, err := s.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return model.User{}, err
+	}
+	if !found || user.Deleted() {
+		return model.User{}, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// ProfileUpdate holds the fields to change; nil fields are left alone.
+type ProfileUpdate struct {
+	DisplayName *string
+	AvatarURL   *string
+	Timezone    *string
+	Locale      *string
+}
+
+func (s *Service) UpdateProfile(ctx context.Context, userID string, update ProfileUpdate) (model.User, error) {
+	ctx, span := tracing.Start(ctx, "account.UpdateProfile")
+	defer span.End()
+
+	user, err := s.Profile(ctx, userID)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	profile := user.UserProfile
+	if update.DisplayName != nil {
+		name := strings.TrimSpace(*update.DisplayName)
+		if utf8.RuneCountInString(name) > maxDisplayNameLength {
+			return model.User{}, ErrInvalidDisplayName
+		}
+		profile.DisplayName = name
+	}
+	if update.AvatarURL != nil {
+		avatar := strings.TrimSpace(*update.AvatarURL)
+		if avatar != "" && !validAvatarURL(avatar) {
+			return model.User{}, ErrInvalidAvatarURL
+		}
+		profile.AvatarURL = avatar
+	}
+	if update.Timezone != nil {
+		tz := strings.TrimSpace(*update.Timezone)
+		// LoadLocation treats "" as UTC and also opens "Local"; neither is
+		// a name worth storing
+		if tz == "" || tz == "Local" {
+			return model.User{}, ErrInvalidTimezone
+		}
+		if _, err := time.LoadLocation(tz); err != nil {
+			return model.User{}, ErrInvalidTimezone
+		}
+		profile.Timezone = tz
+	}
+	if update.Locale != nil {
+		locale := strings.TrimSpace(*update.Locale)
+		if !localePattern.MatchString(locale) {
+			return model.User{}, ErrInvalidLocale
+		}
+		profile.Locale = locale
+	}
+
+	if err := s.UserRepo.UpdateProfile(ctx, userID, profile); err != nil {
+		return model.User{}, err
+	}
+	user.UserProfile = profile
+	return user, nil
+}
+
+func validAvatarURL(raw string) bool {
+	if len(raw) > maxAvatarURLLength {
+		return false
+	}
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
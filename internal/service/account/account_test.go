@@ -0,0 +1,306 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"task-flow/internal/model"
+	"task-flow/internal/repository"
+)
+
+// ============================================
+// MOCK REPOSITORIES
+// ============================================
+
+type mockUserRepo struct {
+	repository.UserRepo
+	users      map[string]model.User // id -> user
+	anonymized []string
+}
+
+func (m *mockUserRepo) FindByID(ctx context.Context, id string) (model.User, bool, error) {
+	u, ok := m.users[id]
+	return u, ok, nil
+}
+
+func (m *mockUserRepo) UpdateProfile(ctx context.Context, id string, profile model.UserProfile) error {
+	u := m.users[id]
+	u.UserProfile = profile
+	m.users[id] = u
+	return nil
+}
+
+func (m *mockUserRepo) Anonymize(ctx context.Context, id string) error {
+	m.anonymized = append(m.anonymized, id)
+	u := m.users[id]
+	now := time.Now()
+	u.DeletedAt = &now
+	m.users[id] = u
+	return nil
+}
+
+// mockWorkspaceRepo only implements what account deletion needs.
+type mockWorkspaceRepo struct {
+	repository.WorkspaceRepo
+	workspaces map[string]model.Workspace
+	members    map[string]map[string]model.WorkspaceRole // workspace -> user -> role
+}
+
+func (m *mockWorkspaceRepo) ListForUser(ctx context.Context, userID string) ([]model.WorkspaceMembership, error) {
+	var res []model.WorkspaceMembership
+	for id, members := range m.members {
+		if role, ok := members[userID]; ok {
+			res = append(res, model.WorkspaceMembership{Workspace: m.workspaces[id], Role: role})
+		}
+	}
+	return res, nil
+}
+
+func (m *mockWorkspaceRepo) ListMembers(ctx context.Context) ([]model.WorkspaceMember, error) {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var res []model.WorkspaceMember
+	for userID, role := range m.members[id] {
+		res = append(res, model.WorkspaceMember{WorkspaceID: id, UserID: userID, Role: role})
+	}
+	return res, nil
+}
+
+func (m *mockWorkspaceRepo) CountOwners(ctx context.Context) (int, error) {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, role := range m.members[id] {
+		if role == model.WorkspaceRoleOwner {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *mockWorkspaceRepo) Delete(ctx context.Context) error {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+	delete(m.workspaces, id)
+	delete(m.members, id)
+	return nil
+}
+
+type mockTaskRepo struct {
+	repository.TaskRepo
+	tasks []model.Task
+}
+
+func (m *mockTaskRepo) GetTasks(ctx context.Context) ([]model.Task, error) {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var res []model.Task
+	for _, t := range m.tasks {
+		if t.WorkspaceID == id {
+			res = append(res, t)
+		}
+	}
+	return res, nil
+}
+
+type mockShareRepo struct {
+	repository.ShareRepo
+}
+
+func (m *mockShareRepo) ListTasksForUser(ctx context.Context, userID string) ([]model.SharedTask, error) {
+	return nil, nil
+}
+
+type mockCommentRepo struct {
+	repository.TaskCommentRepo
+	comments []model.TaskComment
+}
+
+func (m *mockCommentRepo) ListByUser(ctx context.Context, userID string) ([]model.TaskComment, error) {
+	var res []model.TaskComment
+	for _, c := range m.comments {
+		if c.UserID == userID {
+			res = append(res, c)
+		}
+	}
+	return res, nil
+}
+
+type mockPATRepo struct {
+	repository.PersonalAccessTokenRepo
+}
+
+func (m *mockPATRepo) ListByUser(ctx context.Context, userID string) ([]model.PersonalAccessToken, error) {
+	return nil, nil
+}
+
+type mockIdentityRepo struct {
+	repository.UserIdentityRepo
+}
+
+func (m *mockIdentityRepo) ListByUser(ctx context.Context, userID string) ([]model.UserIdentity, error) {
+	return nil, nil
+}
+
+var errWrongPassword = errors.New("invalid credentials")
+
+// mockPasswords accepts "secret" for every user.
+type mockPasswords struct {
+	users *mockUserRepo
+}
+
+func (m *mockPasswords) VerifyPassword(ctx context.Context, userID, pw string) (model.User, error) {
+	if pw != "secret" {
+		return model.User{}, errWrongPassword
+	}
+	return m.users.users[userID], nil
+}
+
+// ============================================
+// HELPER
+// ============================================
+
+// newTestService sets up user-1 owning "personal" alone and "team" together
+// with user-2, who is a member there.
+func newTestService() (*Service, *mockUserRepo, *mockWorkspaceRepo) {
+	users := &mockUserRepo{users: map[string]model.User{
+		"user-1": {ID: "user-1", Email: "one@example.com", UserProfile: model.UserProfile{Timezone: "UTC", Locale: "en"}},
+		"user-2": {ID: "user-2", Email: "two@example.com"},
+	}}
+	workspaces := &mockWorkspaceRepo{
+		workspaces: map[string]model.Workspace{
+			"personal": {ID: "personal", Name: "Personal"},
+			"team":     {ID: "team", Name: "Team"},
+		},
+		members: map[string]map[string]model.WorkspaceRole{
+			"personal": {"user-1": model.WorkspaceRoleOwner},
+			"team":     {"user-1": model.WorkspaceRoleOwner, "user-2": model.WorkspaceRoleMember},
+		},
+	}
+	tasks := &mockTaskRepo{tasks: []model.Task{
+		{ID: "task-1", WorkspaceID: "personal", Title: "Mine"},
+		{ID: "task-2", WorkspaceID: "team", Title: "Ours"},
+	}}
+	comments := &mockCommentRepo{comments: []model.TaskComment{
+		{ID: "c-1", WorkspaceID: "team", TaskID: "task-2", UserID: "user-1", Body: "hi"},
+	}}
+
+	svc := NewService(users, workspaces, tasks, &mockShareRepo{}, comments, &mockPATRepo{}, &mockIdentityRepo{}, &mockPasswords{users: users})
+	return svc, users, workspaces
+}
+
+func ptr(s string) *string { return &s }
+
+// ============================================
+// TESTS
+// ============================================
+
+func TestUpdateProfile(t *testing.T) {
+	svc, _, _ := newTestService()
+	ctx := context.Background()
+
+	user, err := svc.UpdateProfile(ctx, "user-1", ProfileUpdate{
+		DisplayName: ptr("  Ada  "),
+		Timezone:    ptr("Asia/Jakarta"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.DisplayName != "Ada" || user.Timezone != "Asia/Jakarta" || user.Locale != "en" {
+		t.Fatalf("profile = %+v", user.UserProfile)
+	}
+
+	tests := []struct {
+		name   string
+		update ProfileUpdate
+		want   error
+	}{
+		{"timezone", ProfileUpdate{Timezone: ptr("Mars/Olympus")}, ErrInvalidTimezone},
+		{"empty timezone", ProfileUpdate{Timezone: ptr("")}, ErrInvalidTimezone},
+		{"locale", ProfileUpdate{Locale: ptr("english please")}, ErrInvalidLocale},
+		{"avatar scheme", ProfileUpdate{AvatarURL: ptr("javascript:alert(1)")}, ErrInvalidAvatarURL},
+		{"display name", ProfileUpdate{DisplayName: ptr(string(make([]byte, 101)))}, ErrInvalidDisplayName},
+	}
+	for _, tt := range tests {
+		if _, err := svc.UpdateProfile(ctx, "user-1", tt.update); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	svc, _, _ := newTestService()
+
+	export, err := svc.Export(context.Background(), "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if export.Profile.Email != "one@example.com" || len(export.Workspaces) != 2 || len(export.Comments) != 1 {
+		t.Fatalf("export = %+v", export)
+	}
+	for _, ws := range export.Workspaces {
+		switch ws.ID {
+		case "personal":
+			if len(ws.Tasks) != 1 || ws.Tasks[0].ID != "task-1" {
+				t.Errorf("personal tasks = %+v", ws.Tasks)
+			}
+		case "team":
+			// Shared with others, so not the user's data alone
+			if len(ws.Tasks) != 0 {
+				t.Errorf("team tasks = %+v, want none", ws.Tasks)
+			}
+		}
+	}
+}
+
+func TestDelete_LastOwnerOfSharedWorkspace(t *testing.T) {
+	svc, users, _ := newTestService()
+
+	if _, err := svc.Delete(context.Background(), "user-1", "secret"); !errors.Is(err, ErrLastOwner) {
+		t.Fatalf("err = %v, want ErrLastOwner", err)
+	}
+	if len(users.anonymized) != 0 {
+		t.Fatal("account was deleted")
+	}
+}
+
+func TestDelete(t *testing.T) {
+	svc, users, workspaces := newTestService()
+	ctx := context.Background()
+	workspaces.members["team"]["user-2"] = model.WorkspaceRoleOwner
+
+	if _, err := svc.Delete(ctx, "user-1", "wrong"); !errors.Is(err, errWrongPassword) {
+		t.Fatalf("wrong password: err = %v", err)
+	}
+
+	export, err := svc.Delete(ctx, "user-1", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(export.Workspaces) != 2 || export.Profile.ID != "user-1" {
+		t.Fatalf("export = %+v", export)
+	}
+	if len(users.anonymized) != 1 || users.anonymized[0] != "user-1" {
+		t.Fatalf("anonymized = %v", users.anonymized)
+	}
+	if _, ok := workspaces.workspaces["personal"]; ok {
+		t.Error("workspace the user was alone in survived")
+	}
+	if _, ok := workspaces.workspaces["team"]; !ok {
+		t.Error("shared workspace was deleted")
+	}
+
+	if _, err := svc.Profile(ctx, "user-1"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("profile after delete: err = %v", err)
+	}
+}
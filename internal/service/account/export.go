@@ -0,0 +1,265 @@
+package account
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"task-flow/internal/apperr"
+	"task-flow/internal/model"
+	"task-flow/internal/repository"
+	"task-flow/internal/tracing"
+)
+
+// ErrLastOwner blocks deleting an account that is the only owner of a
+// workspace other people still use.
+var ErrLastOwner = apperr.Conflict("last_owner", "transfer ownership of your shared workspaces before deleting your account")
+
+// Export is the archive of everything stored about a user. It is a
+// download format, so its JSON shape is kept stable on purpose.
+type Export struct {
+	ExportedAt   time.Time           `json:"exported_at"`
+	Profile      ExportProfile       `json:"profile"`
+	Workspaces   []ExportWorkspace   `json:"workspaces"`
+	SharedTasks  []ExportTask        `json:"shared_tasks"`
+	Comments     []ExportComment     `json:"comments"`
+	AccessTokens []ExportAccessToken `json:"personal_access_tokens"`
+	Identities   []ExportIdentity    `json:"linked_identities"`
+}
+
+type ExportProfile struct {
+	ID          string     `json:"id"`
+	Email       string     `json:"email"`
+	Role        model.Role `json:"role"`
+	DisplayName string     `json:"display_name"`
+	AvatarURL   string     `json:"avatar_url"`
+	Timezone    string     `json:"timezone"`
+	Locale      string     `json:"locale"`
+	VerifiedAt  *time.Time `json:"verified_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// ExportWorkspace lists a membership. Tasks are included only for
+// workspaces the user is alone in, since those are theirs alone and are
+// deleted with the account.
+type ExportWorkspace struct {
+	ID    string              `json:"id"`
+	Name  string              `json:"name"`
+	Role  model.WorkspaceRole `json:"role"`
+	Tasks []ExportTask        `json:"tasks,omitempty"`
+}
+
+type ExportTask struct {
+	ID          string            `json:"id"`
+	WorkspaceID string            `json:"workspace_id"`
+	ProjectID   *string           `json:"project_id"`
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	Level       model.AccessLevel `json:"level,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	CompletedAt *time.Time        `json:"completed_at"`
+}
+
+type ExportComment struct {
+	ID          string    `json:"id"`
+	WorkspaceID string    `json:"workspace_id"`
+	TaskID      string    `json:"task_id"`
+	Body        string    `json:"body"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ExportAccessToken struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type ExportIdentity struct {
+	Provider  string    `json:"provider"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newExportTask(t model.Task, level model.AccessLevel) ExportTask {
+	return ExportTask{
+		ID:          t.ID,
+		WorkspaceID: t.WorkspaceID,
+		ProjectID:   t.ProjectID,
+		Title:       t.Title,
+		Description: t.Description,
+		Level:       level,
+		CreatedAt:   t.Created_At,
+		CompletedAt: t.CompletedAt,
+	}
+}
+
+// membership is a workspace of the user together with what deleting the
+// account would do to it.
+type membership struct {
+	model.WorkspaceMembership
+	ctx context.Context
+	// alone is true when the user is the only member
+	alone bool
+}
+
+func (s *Service) memberships(ctx context.Context, userID string) ([]membership, error) {
+	list, err := s.WorkspaceRepo.ListForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]membership, 0, len(list))
+	for _, ws := range list {
+		scoped := repository.WithWorkspace(ctx, ws.ID)
+		members, err := s.WorkspaceRepo.ListMembers(scoped)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, membership{WorkspaceMembership: ws, ctx: scoped, alone: len(members) == 1})
+	}
+	return res, nil
+}
+
+// Export collects everything stored about userID.
+func (s *Service) Export(ctx context.Context, userID string) (Export, error) {
+	ctx, span := tracing.Start(ctx, "account.Export")
+	defer span.End()
+
+	user, err := s.Profile(ctx, userID)
+	if err != nil {
+		return Export{}, err
+	}
+	memberships, err := s.memberships(ctx, userID)
+	if err != nil {
+		return Export{}, err
+	}
+	return s.export(ctx, user, memberships)
+}
+
+func (s *Service) export(ctx context.Context, user model.User, memberships []membership) (Export, error) {
+	res := Export{
+		ExportedAt: time.Now().UTC(),
+		Profile: ExportProfile{
+			ID:          user.ID,
+			Email:       user.Email,
+			Role:        user.Role,
+			DisplayName: user.DisplayName,
+			AvatarURL:   user.AvatarURL,
+			Timezone:    user.Timezone,
+			Locale:      user.Locale,
+			VerifiedAt:  user.VerifiedAt,
+			CreatedAt:   user.CreatedAt,
+		},
+		Workspaces:   []ExportWorkspace{},
+		SharedTasks:  []ExportTask{},
+		Comments:     []ExportComment{},
+		AccessTokens: []ExportAccessToken{},
+		Identities:   []ExportIdentity{},
+	}
+
+	for _, m := range memberships {
+		ws := ExportWorkspace{ID: m.ID, Name: m.Name, Role: m.Role}
+		if m.alone {
+			tasks, err := s.TaskRepo.GetTasks(m.ctx)
+			if err != nil {
+				return Export{}, err
+			}
+			for _, t := range tasks {
+				ws.Tasks = append(ws.Tasks, newExportTask(t, ""))
+			}
+		}
+		res.Workspaces = append(res.Workspaces, ws)
+	}
+
+	shared, err := s.ShareRepo.ListTasksForUser(ctx, user.ID)
+	if err != nil {
+		return Export{}, err
+	}
+	for _, t := range shared {
+		res.SharedTasks = append(res.SharedTasks, newExportTask(t.Task, t.Level))
+	}
+
+	comments, err := s.CommentRepo.ListByUser(ctx, user.ID)
+	if err != nil {
+		return Export{}, err
+	}
+	for _, c := range comments {
+		res.Comments = append(res.Comments, ExportComment{
+			ID: c.ID, WorkspaceID: c.WorkspaceID, TaskID: c.TaskID, Body: c.Body, CreatedAt: c.CreatedAt,
+		})
+	}
+
+	tokens, err := s.PATRepo.ListByUser(ctx, user.ID)
+	if err != nil {
+		return Export{}, err
+	}
+	for _, t := range tokens {
+		res.AccessTokens = append(res.AccessTokens, ExportAccessToken{
+			ID: t.ID, Name: t.Name, Scopes: t.Scopes, ExpiresAt: t.ExpiresAt, LastUsedAt: t.LastUsedAt, CreatedAt: t.CreatedAt,
+		})
+	}
+
+	identities, err := s.IdentityRepo.ListByUser(ctx, user.ID)
+	if err != nil {
+		return Export{}, err
+	}
+	for _, i := range identities {
+		res.Identities = append(res.Identities, ExportIdentity{Provider: i.Provider, Email: i.Email, CreatedAt: i.CreatedAt})
+	}
+
+	return res, nil
+}
+
+// Delete re-checks the password, exports the account and then deletes it:
+// workspaces the user is alone in go with it, and the user row is
+// anonymised so that comments in shared workspaces keep an author. The
+// export is returned for the caller to download; it is not stored.
+func (s *Service) Delete(ctx context.Context, userID, pw string) (Export, error) {
+	ctx, span := tracing.Start(ctx, "account.Delete")
+	defer span.End()
+
+	user, err := s.Passwords.VerifyPassword(ctx, userID, pw)
+	if err != nil {
+		return Export{}, err
+	}
+	if user.Deleted() {
+		return Export{}, ErrUserNotFound
+	}
+
+	memberships, err := s.memberships(ctx, userID)
+	if err != nil {
+		return Export{}, err
+	}
+	for _, m := range memberships {
+		if m.alone || m.Role != model.WorkspaceRoleOwner {
+			continue
+		}
+		owners, err := s.WorkspaceRepo.CountOwners(m.ctx)
+		if err != nil {
+			return Export{}, err
+		}
+		if owners == 1 {
+			return Export{}, fmt.Errorf("%w (%s)", ErrLastOwner, m.Name)
+		}
+	}
+
+	export, err := s.export(ctx, user, memberships)
+	if err != nil {
+		return Export{}, err
+	}
+
+	for _, m := range memberships {
+		if m.alone {
+			if err := s.WorkspaceRepo.Delete(m.ctx); err != nil {
+				return Export{}, err
+			}
+		}
+	}
+	if err := s.UserRepo.Anonymize(ctx, userID); err != nil {
+		return Export{}, err
+	}
+	return export, nil
+}
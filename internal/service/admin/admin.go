@@ -0,0 +1,155 @@
+package admin
+
+import (
+	"context"
+	"strings"
+
+	"task-flow/internal/apperr"
+	"task-flow/internal/model"
+	"task-flow/internal/repository"
+	"task-flow/internal/tracing"
+)
+
+// SessionRevoker ends every session of a user. It is implemented by the
+// auth service.
+type SessionRevoker interface {
+	LogoutAll(ctx context.Context, userID string) error
+}
+
+// Service holds account administration. Every method re-checks that the
+// acting user may manage users, independently of the HTTP middleware.
+type Service struct {
+	UserRepo repository.UserRepo
+	Sessions SessionRevoker
+}
+
+func NewService(userRepo repository.UserRepo, sessions SessionRevoker) *Service {
+	return &Service{
+		UserRepo: userRepo,
+		Sessions: sessions,
+	}
+}
+
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 200
+)
+
+var (
+	ErrForbidden    = apperr.Forbidden("permission_denied", "permission denied")
+	ErrUserNotFound = apperr.NotFound("user_not_found", "user not found")
+	ErrInvalidRole  = apperr.Validation("invalid_role", "invalid role")
+	// ErrSelfAction guards against admins locking themselves out.
+	ErrSelfAction = apperr.Validation("self_action", "admins cannot disable or change the role of their own account")
+)
+
+func (s *Service) require(ctx context.Context, actorID string, perm model.Permission) error {
+	actor, found, err := s.UserRepo.FindByID(ctx, actorID)
+	if err != nil {
+		return err
+	}
+	if !found || actor.Disabled() || !actor.Role.Can(perm) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func (s *Service) target(ctx context.Context, actorID, userID string) (model.User, error) {
+	if err := s.require(ctx, actorID, model.PermissionUsersManage); err != nil {
+		return model.User{}, err
+	}
+
+	user, found, err := s.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return model.User{}, err
+	}
+	if !found || user.Deleted() {
+		return model.User{}, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *Service) ListUsers(ctx context.Context, actorID string, filter model.UserFilter) ([]model.User, error) {
+	ctx, span := tracing.Start(ctx, "admin.ListUsers")
+	defer span.End()
+
+	if err := s.require(ctx, actorID, model.PermissionUsersManage); err != nil {
+		return nil, err
+	}
+
+	if filter.Role != "" && !filter.Role.Valid() {
+		return nil, ErrInvalidRole
+	}
+	filter.Query = strings.ToLower(strings.TrimSpace(filter.Query))
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultListLimit
+	}
+	filter.Limit = min(filter.Limit, MaxListLimit)
+	filter.Offset = max(filter.Offset, 0)
+
+	return s.UserRepo.List(ctx, filter)
+}
+
+func (s *Service) GetUser(ctx context.Context, actorID, userID string) (model.User, error) {
+	ctx, span := tracing.Start(ctx, "admin.GetUser")
+	defer span.End()
+
+	return s.target(ctx, actorID, userID)
+}
+
+// DisableUser blocks the account and ends all of its sessions.
+func (s *Service) DisableUser(ctx context.Context, actorID, userID string) error {
+	ctx, span := tracing.Start(ctx, "admin.DisableUser")
+	defer span.End()
+
+	if actorID == userID {
+		return ErrSelfAction
+	}
+	if _, err := s.target(ctx, actorID, userID); err != nil {
+		return err
+	}
+
+	if err := s.UserRepo.SetDisabled(ctx, userID, true); err != nil {
+		return err
+	}
+	return s.Sessions.LogoutAll(ctx, userID)
+}
+
+func (s *Service) EnableUser(ctx context.Context, actorID, userID string) error {
+	ctx, span := tracing.Start(ctx, "admin.EnableUser")
+	defer span.End()
+
+	if _, err := s.target(ctx, actorID, userID); err != nil {
+		return err
+	}
+	return s.UserRepo.SetDisabled(ctx, userID, false)
+}
+
+// ForceLogout ends all sessions of the user without disabling the account.
+func (s *Service) ForceLogout(ctx context.Context, actorID, userID string) error {
+	ctx, span := tracing.Start(ctx, "admin.ForceLogout")
+	defer span.End()
+
+	if _, err := s.target(ctx, actorID, userID); err != nil {
+		return err
+	}
+	return s.Sessions.LogoutAll(ctx, userID)
+}
+
+// ChangeRole takes effect on the user's next request; roles are not baked
+// into tokens.
+func (s *Service) ChangeRole(ctx context.Context, actorID, userID string, role model.Role) error {
+	ctx, span := tracing.Start(ctx, "admin.ChangeRole")
+	defer span.End()
+
+	if !role.Valid() {
+		return ErrInvalidRole
+	}
+	if actorID == userID {
+		return ErrSelfAction
+	}
+	if _, err := s.target(ctx, actorID, userID); err != nil {
+		return err
+	}
+	return s.UserRepo.SetRole(ctx, userID, role)
+}
@@ -0,0 +1,239 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"task-flow/internal/model"
+)
+
+// ============================================
+// MOCK REPOSITORIES
+// ============================================
+
+type mockUserRepo struct {
+	users      map[string]model.User // id -> user
+	lastFilter model.UserFilter
+}
+
+func (m *mockUserRepo) Create(ctx context.Context, user model.User) error {
+	m.users[user.ID] = user
+	return nil
+}
+
+func (m *mockUserRepo) FindByID(ctx context.Context, id string) (model.User, bool, error) {
+	u, ok := m.users[id]
+	return u, ok, nil
+}
+
+func (m *mockUserRepo) FindByEmail(ctx context.Context, email string) (model.User, bool, error) {
+	for _, u := range m.users {
+		if u.Email == email {
+			return u, true, nil
+		}
+	}
+	return model.User{}, false, nil
+}
+
+func (m *mockUserRepo) UpdatePassword(ctx context.Context, id string, passHash []byte) error {
+	return nil
+}
+
+func (m *mockUserRepo) MarkVerified(ctx context.Context, id string) error {
+	return nil
+}
+
+func (m *mockUserRepo) List(ctx context.Context, filter model.UserFilter) ([]model.User, error) {
+	m.lastFilter = filter
+	var users []model.User
+	for _, u := range m.users {
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+func (m *mockUserRepo) SetRole(ctx context.Context, id string, role model.Role) error {
+	u := m.users[id]
+	u.Role = role
+	m.users[id] = u
+	return nil
+}
+
+func (m *mockUserRepo) SetDisabled(ctx context.Context, id string, disabled bool) error {
+	u := m.users[id]
+	u.DisabledAt = nil
+	if disabled {
+		now := time.Now()
+		u.DisabledAt = &now
+	}
+	m.users[id] = u
+	return nil
+}
+
+func (m *mockUserRepo) UpdateProfile(ctx context.Context, id string, profile model.UserProfile) error {
+	return nil
+}
+
+func (m *mockUserRepo) SetPendingEmail(ctx context.Context, id, email string) error {
+	return nil
+}
+
+func (m *mockUserRepo) ChangeEmail(ctx context.Context, id, email string) error {
+	return nil
+}
+
+func (m *mockUserRepo) Anonymize(ctx context.Context, id string) error {
+	return nil
+}
+
+type mockSessions struct {
+	loggedOut []string
+}
+
+func (m *mockSessions) LogoutAll(ctx context.Context, userID string) error {
+	m.loggedOut = append(m.loggedOut, userID)
+	return nil
+}
+
+// ============================================
+// HELPER
+// ============================================
+
+func newTestService() (*Service, *mockUserRepo, *mockSessions) {
+	repo := &mockUserRepo{users: map[string]model.User{
+		"admin":  {ID: "admin", Email: "admin@mail.com", Role: model.RoleAdmin},
+		"member": {ID: "member", Email: "member@mail.com", Role: model.RoleMember},
+		"viewer": {ID: "viewer", Email: "viewer@mail.com", Role: model.RoleViewer},
+	}}
+	sessions := &mockSessions{}
+	return NewService(repo, sessions), repo, sessions
+}
+
+// ============================================
+// TESTS
+// ============================================
+
+func TestRolePermissions(t *testing.T) {
+	tests := []struct {
+		role model.Role
+		perm model.Permission
+		want bool
+	}{
+		{model.RoleAdmin, model.PermissionUsersManage, true},
+		{model.RoleAdmin, model.PermissionWorkspaceCreate, true},
+		{model.RoleMember, model.PermissionWorkspaceCreate, true},
+		{model.RoleMember, model.PermissionUsersManage, false},
+		{model.RoleViewer, model.PermissionWorkspaceCreate, false},
+		// Task access comes from the workspace role, not the account role
+		{model.RoleAdmin, model.PermissionTasksRead, false},
+		{model.Role("root"), model.PermissionWorkspaceCreate, false},
+	}
+	for _, tt := range tests {
+		if got := tt.role.Can(tt.perm); got != tt.want {
+			t.Errorf("%s.Can(%s) = %v, want %v", tt.role, tt.perm, got, tt.want)
+		}
+	}
+}
+
+func TestNonAdminIsForbidden(t *testing.T) {
+	svc, _, _ := newTestService()
+	ctx := context.Background()
+
+	if _, err := svc.ListUsers(ctx, "member", model.UserFilter{}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected ErrForbidden, got %v", err)
+	}
+	if err := svc.ChangeRole(ctx, "member", "viewer", model.RoleAdmin); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected ErrForbidden, got %v", err)
+	}
+	if err := svc.DisableUser(ctx, "viewer", "member"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected ErrForbidden, got %v", err)
+	}
+}
+
+func TestListUsers_ClampsFilter(t *testing.T) {
+	svc, repo, _ := newTestService()
+
+	if _, err := svc.ListUsers(context.Background(), "admin", model.UserFilter{Query: "  Mail ", Limit: 10000, Offset: -5}); err != nil {
+		t.Fatal(err)
+	}
+	if f := repo.lastFilter; f.Limit != MaxListLimit || f.Offset != 0 || f.Query != "mail" {
+		t.Errorf("unexpected filter %+v", f)
+	}
+
+	if _, err := svc.ListUsers(context.Background(), "admin", model.UserFilter{Role: "root"}); !errors.Is(err, ErrInvalidRole) {
+		t.Errorf("expected ErrInvalidRole, got %v", err)
+	}
+}
+
+func TestDisableAndEnableUser(t *testing.T) {
+	svc, repo, sessions := newTestService()
+	ctx := context.Background()
+
+	if err := svc.DisableUser(ctx, "admin", "member"); err != nil {
+		t.Fatal(err)
+	}
+	if !repo.users["member"].Disabled() {
+		t.Error("expected user to be disabled")
+	}
+	if len(sessions.loggedOut) != 1 || sessions.loggedOut[0] != "member" {
+		t.Errorf("expected sessions of member to be revoked, got %v", sessions.loggedOut)
+	}
+
+	if err := svc.EnableUser(ctx, "admin", "member"); err != nil {
+		t.Fatal(err)
+	}
+	if repo.users["member"].Disabled() {
+		t.Error("expected user to be enabled")
+	}
+
+	if err := svc.DisableUser(ctx, "admin", "admin"); !errors.Is(err, ErrSelfAction) {
+		t.Errorf("expected ErrSelfAction, got %v", err)
+	}
+	if err := svc.DisableUser(ctx, "admin", "ghost"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestDisabledAdminIsForbidden(t *testing.T) {
+	svc, repo, _ := newTestService()
+	now := time.Now()
+	a := repo.users["admin"]
+	a.DisabledAt = &now
+	repo.users["admin"] = a
+
+	if _, err := svc.ListUsers(context.Background(), "admin", model.UserFilter{}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected ErrForbidden, got %v", err)
+	}
+}
+
+func TestChangeRole(t *testing.T) {
+	svc, repo, _ := newTestService()
+	ctx := context.Background()
+
+	if err := svc.ChangeRole(ctx, "admin", "viewer", model.RoleMember); err != nil {
+		t.Fatal(err)
+	}
+	if repo.users["viewer"].Role != model.RoleMember {
+		t.Errorf("expected member, got %s", repo.users["viewer"].Role)
+	}
+
+	if err := svc.ChangeRole(ctx, "admin", "viewer", "owner"); !errors.Is(err, ErrInvalidRole) {
+		t.Errorf("expected ErrInvalidRole, got %v", err)
+	}
+	if err := svc.ChangeRole(ctx, "admin", "admin", model.RoleViewer); !errors.Is(err, ErrSelfAction) {
+		t.Errorf("expected ErrSelfAction, got %v", err)
+	}
+}
+
+func TestForceLogout(t *testing.T) {
+	svc, repo, sessions := newTestService()
+
+	if err := svc.ForceLogout(context.Background(), "admin", "member"); err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions.loggedOut) != 1 || repo.users["member"].Disabled() {
+		t.Errorf("expected sessions revoked without disabling, got %v", sessions.loggedOut)
+	}
+}
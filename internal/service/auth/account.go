@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"task-flow/internal/apperr"
+	"task-flow/internal/logging"
+	"task-flow/internal/model"
+	"task-flow/internal/pkg/mailer"
+	"task-flow/internal/repository"
+	"task-flow/internal/tracing"
+)
+
+var (
+	ErrEmailTaken              = apperr.Conflict("email_taken", "email already registered")
+	ErrSameEmail               = apperr.Validation("same_email", "that is already your email address")
+	ErrInvalidEmailChangeToken = apperr.Validation("invalid_email_change_token", "invalid or expired email change token")
+)
+
+// VerifyPassword re-checks the password of a signed-in user before a
+// sensitive change. Accounts without a password must set one through
+// password reset first.
+func (s *Service) VerifyPassword(ctx context.Context, userID, pw string) (model.User, error) {
+	ctx, span := tracing.Start(ctx, "auth.VerifyPassword")
+	defer span.End()
+
+	user, found, err := s.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return model.User{}, err
+	}
+	if !found || !user.HasPassword() {
+		return model.User{}, ErrInvalidCredentials
+	}
+
+	ok, _, err := s.Passwords.Verify(pw, string(user.PassHash))
+	if err != nil {
+		return model.User{}, err
+	}
+	if !ok {
+		return model.User{}, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// ChangePassword replaces the password after checking the current one. All
+// sessions end, including the caller's, which gets a fresh token pair.
+func (s *Service) ChangePassword(ctx context.Context, userID, current, newPassword string) (access, refresh string, err error) {
+	ctx, span := tracing.Start(ctx, "auth.ChangePassword")
+	defer span.End()
+
+	user, err := s.VerifyPassword(ctx, userID, current)
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.PasswordPolicy.Check(newPassword, user.Email); err != nil {
+		return "", "", err
+	}
+
+	hash, err := s.Passwords.Hash(newPassword)
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.UserRepo.UpdatePassword(ctx, userID, []byte(hash)); err != nil {
+		return "", "", err
+	}
+	// A pending reset link would otherwise still override the new password
+	if err := s.UserTokenRepo.DeleteByUser(ctx, userID, model.TokenPurposePasswordReset); err != nil {
+		return "", "", err
+	}
+
+	if err := s.LogoutAll(ctx, userID); err != nil {
+		return "", "", err
+	}
+	return s.issueTokens(ctx, userID)
+}
+
+// RequestEmailChange emails a confirmation link to the new address. The
+// account keeps its current email until the link is opened.
+func (s *Service) RequestEmailChange(ctx context.Context, userID, pw, newEmail string) error {
+	ctx, span := tracing.Start(ctx, "auth.RequestEmailChange")
+	defer span.End()
+
+	user, err := s.VerifyPassword(ctx, userID, pw)
+	if err != nil {
+		return err
+	}
+
+	newEmail, err = NormalizeEmail(newEmail)
+	if err != nil {
+		return err
+	}
+	if newEmail == user.Email {
+		return ErrSameEmail
+	}
+	if _, taken, err := s.UserRepo.FindByEmail(ctx, newEmail); err != nil {
+		return err
+	} else if taken {
+		return ErrEmailTaken
+	}
+
+	// Only the most recent request stays usable
+	if err := s.UserTokenRepo.DeleteByUser(ctx, userID, model.TokenPurposeEmailChange); err != nil {
+		return err
+	}
+	if err := s.UserRepo.SetPendingEmail(ctx, userID, newEmail); err != nil {
+		return err
+	}
+
+	plain, hash, expUnix, err := newToken(s.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	if err := s.UserTokenRepo.Insert(ctx, userID, model.TokenPurposeEmailChange, hash, expUnix); err != nil {
+		return err
+	}
+
+	link := s.AppBaseURL + "/confirm-email?token=" + url.QueryEscape(plain)
+	if err := s.Mailer.Send(ctx, mailer.Message{
+		To:      newEmail,
+		Subject: "Confirm your new Task Flow email",
+		Body: "Confirm that you want to use this address for your Task Flow account by opening the link below. " +
+			"It expires in " + s.EmailVerificationTTL.String() + ".\n\n" +
+			link + "\n",
+	}); err != nil {
+		return err
+	}
+
+	// Let the owner of the current address know, in case it wasn't them
+	if err := s.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your Task Flow email is being changed",
+		Body: "Someone asked to change the email of your Task Flow account to " + newEmail + ".\n\n" +
+			"If this wasn't you, reset your password right away.\n",
+	}); err != nil {
+		logging.FromContext(ctx).Error("send email change notice failed", "user_id", userID, "err", err)
+	}
+	return nil
+}
+
+// ConfirmEmailChange redeems the link sent by RequestEmailChange. The token
+// is the credential, so no session is needed.
+func (s *Service) ConfirmEmailChange(ctx context.Context, token string) error {
+	ctx, span := tracing.Start(ctx, "auth.ConfirmEmailChange")
+	defer span.End()
+
+	userID, found, err := s.UserTokenRepo.Consume(ctx, model.TokenPurposeEmailChange, hashToken(token))
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrInvalidEmailChangeToken
+	}
+
+	user, found, err := s.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !found || user.PendingEmail == "" {
+		return ErrInvalidEmailChangeToken
+	}
+
+	// Someone may have registered the address in the meantime
+	if _, taken, err := s.UserRepo.FindByEmail(ctx, user.PendingEmail); err != nil {
+		return err
+	} else if taken {
+		return ErrEmailTaken
+	}
+
+	err = s.UserRepo.ChangeEmail(ctx, userID, user.PendingEmail)
+	if errors.Is(err, repository.ErrDuplicate) {
+		return ErrEmailTaken
+	}
+	return err
+}
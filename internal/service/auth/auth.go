@@ -6,11 +6,21 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"errors"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
+	"task-flow/internal/apperr"
+	"task-flow/internal/logging"
+	"task-flow/internal/metrics"
 	"task-flow/internal/model"
 	"task-flow/internal/pkg/jwt"
+	"task-flow/internal/pkg/mailer"
+	"task-flow/internal/pkg/oidc"
+	"task-flow/internal/pkg/password"
 	"task-flow/internal/repository"
+	"task-flow/internal/tracing"
 	"task-flow/internal/utils"
 
 	"golang.org/x/crypto/bcrypt"
@@ -19,72 +29,267 @@ import (
 type Service struct {
 	UserRepo         repository.UserRepo
 	RefreshTokenRepo repository.RefreshTokenRepo
+	Denylist         repository.AccessTokenDenylist
+	UserTokenRepo    repository.UserTokenRepo
+	Mailer           mailer.Mailer
+	MFARepo          repository.MFARepo
+	LoginAttemptRepo repository.LoginAttemptRepo
+	PATRepo          repository.PersonalAccessTokenRepo
+
+	// OIDCProviders are the external identity providers users may sign in
+	// with, keyed by the name used in the login URL. Optional.
+	OIDCProviders map[string]*oidc.Provider
+	OIDCStateRepo repository.OIDCStateRepo
+	IdentityRepo  repository.UserIdentityRepo
 
 	JWT        *jwt.JWT
 	AccessTTL  time.Duration
 	RefreshTTL time.Duration
 
+	PasswordResetTTL     time.Duration
+	EmailVerificationTTL time.Duration
+	// RequireVerifiedEmail blocks login until the address is confirmed.
+	RequireVerifiedEmail bool
+	LoginThrottle        LoginThrottle
+	PasswordPolicy       password.Policy
+	// Passwords hashes new passwords and verifies (and upgrades) stored ones.
+	Passwords *password.Chain
+
+	// MFAIssuer is the account issuer shown in authenticator apps.
+	MFAIssuer string
+	// AppBaseURL is prefixed to links sent by email, e.g. the reset page.
+	AppBaseURL string
+
 	CookieDomain string
 	CookieSecure bool
+
+	// patTouched holds when each personal access token was last marked
+	// used by this instance.
+	patTouched sync.Map // token ID -> time.Time
 }
 
 func NewService(
 	userRepo repository.UserRepo,
 	refreshRepo repository.RefreshTokenRepo,
+	denylist repository.AccessTokenDenylist,
+	userTokenRepo repository.UserTokenRepo,
+	mail mailer.Mailer,
+	mfaRepo repository.MFARepo,
+	loginAttemptRepo repository.LoginAttemptRepo,
+	patRepo repository.PersonalAccessTokenRepo,
 	jwtInstance *jwt.JWT,
 	accessTTL, refreshTTL time.Duration,
 ) *Service {
 	return &Service{
 		UserRepo:         userRepo,
 		RefreshTokenRepo: refreshRepo,
-		JWT:              jwtInstance,
-		AccessTTL:        accessTTL,
-		RefreshTTL:       refreshTTL,
+		Denylist:         denylist,
+		UserTokenRepo:    userTokenRepo,
+		Mailer:           mail,
+		MFARepo:          mfaRepo,
+		LoginAttemptRepo: loginAttemptRepo,
+		PATRepo:          patRepo,
+		LoginThrottle:    DefaultLoginThrottle,
+		PasswordPolicy:   password.DefaultPolicy,
+		Passwords: password.NewChain(
+			password.NewArgon2id(password.DefaultArgon2Params),
+			password.NewBcrypt(bcrypt.DefaultCost),
+		),
+		MFAIssuer:            "Task Flow",
+		JWT:                  jwtInstance,
+		AccessTTL:            accessTTL,
+		RefreshTTL:           refreshTTL,
+		PasswordResetTTL:     time.Hour,
+		EmailVerificationTTL: 24 * time.Hour,
 	}
 }
 
-func newRefreshToken(ttl time.Duration) (plain string, hash []byte, expUnix int64, err error) {
-	b := make([]byte, 32)
-	if _, err := rand.Read(b); err != nil {
+// newToken creates an opaque random token. Only its hash is ever stored.
+func newToken(ttl time.Duration) (plain string, hash []byte, expUnix int64, err error) {
+	plain, err = randomToken()
+	if err != nil {
 		return "", nil, 0, err
 	}
 
-	plain = base64.RawURLEncoding.EncodeToString(b)
 	hash = hashToken(plain)
 	expUnix = time.Now().Add(ttl).Unix()
 
 	return plain, hash, expUnix, nil
 }
 
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+var (
+	ErrInvalidCredentials       = apperr.Unauthorized("invalid_credentials", "invalid credentials")
+	ErrInvalidRefreshToken      = apperr.Unauthorized("invalid_refresh_token", "invalid refresh token")
+	ErrRefreshTokenReused       = apperr.Unauthorized("refresh_token_reused", "refresh token was already used; all sessions have been ended")
+	ErrUserNotFound             = apperr.NotFound("user_not_found", "user not found")
+	ErrInvalidResetToken        = apperr.Validation("invalid_reset_token", "invalid or expired reset token")
+	ErrInvalidVerificationToken = apperr.Validation("invalid_verification_token", "invalid or expired verification token")
+	ErrEmailNotVerified         = apperr.Forbidden("email_not_verified", "email not verified")
+	ErrAccountDisabled          = apperr.Forbidden("account_disabled", "account disabled")
+)
+
 func hashToken(plain string) []byte {
 	sum := sha256.Sum256([]byte(plain))
 	return sum[:]
 }
 
-func (s *Service) Login(ctx context.Context, email, password string) (access, refresh string, err error) {
+// LoginResult carries either a token pair or, for accounts with 2FA, an
+// MFA challenge token to be redeemed at LoginMFA.
+type LoginResult struct {
+	AccessToken  string
+	RefreshToken string
+	MFAToken     string
+}
+
+// Login authenticates by email and password. ip is the client address and
+// feeds the per-IP brute-force throttle.
+func (s *Service) Login(ctx context.Context, email, pw, ip string) (res LoginResult, err error) {
+	ctx, span := tracing.Start(ctx, "auth.Login")
+	defer span.End()
+
+	defer func() {
+		result := outcome(err)
+		if res.MFAToken != "" {
+			result = "mfa_required"
+		}
+		metrics.Logins.Inc("password", result)
+	}()
+
+	normalized, err := NormalizeEmail(email)
+	if err != nil {
+		// Still counts against the client IP
+		normalized = strings.ToLower(strings.TrimSpace(email))
+	}
+
+	if err := s.checkThrottle(ctx, normalized, ip); err != nil {
+		return LoginResult{}, err
+	}
+
+	user, err := s.authenticate(ctx, normalized, pw)
+	if errors.Is(err, ErrInvalidCredentials) {
+		if err := s.recordAttempt(ctx, normalized, ip, false); err != nil {
+			return LoginResult{}, err
+		}
+		return LoginResult{}, err
+	}
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	if err := s.loginAllowed(user); err != nil {
+		return LoginResult{}, err
+	}
+
+	return s.completeLogin(ctx, user, ip)
+}
+
+// loginAllowed checks the account state every login step must see, since
+// it may change between the first factor and the second.
+func (s *Service) loginAllowed(user model.User) error {
+	if user.Disabled() {
+		return ErrAccountDisabled
+	}
+	if s.RequireVerifiedEmail && !user.Verified() {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+// completeLogin runs after the first factor: it either asks for the second
+// factor or records the success and issues a token pair.
+func (s *Service) completeLogin(ctx context.Context, user model.User, ip string) (LoginResult, error) {
+	mfa, found, err := s.MFARepo.Find(ctx, user.ID)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	if found && mfa.Enabled() {
+		// The attempt is only recorded as successful after the second factor
+		token, err := s.JWT.SignPurpose(user.ID, mfaChallengePurpose, mfaChallengeTTL)
+		if err != nil {
+			return LoginResult{}, err
+		}
+		return LoginResult{MFAToken: token}, nil
+	}
+
+	if err := s.recordAttempt(ctx, user.Email, ip, true); err != nil {
+		return LoginResult{}, err
+	}
+
+	access, refresh, err := s.issueTokens(ctx, user.ID)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	return LoginResult{AccessToken: access, RefreshToken: refresh}, nil
+}
+
+// outcome labels a result for metrics by its error code, which keeps the
+// label values few and stable.
+func outcome(err error) string {
+	if err == nil {
+		return "success"
+	}
+	if e, ok := apperr.As(err); ok {
+		return e.Code
+	}
+	return "error"
+}
+
+func (s *Service) authenticate(ctx context.Context, email, pw string) (model.User, error) {
 	user, found, err := s.UserRepo.FindByEmail(ctx, email)
 	if err != nil {
-		return "", "", err
+		return model.User{}, err
 	}
-	if !found {
-		return "", "", errors.New("invalid credentials")
+	if !found || !user.HasPassword() {
+		return model.User{}, ErrInvalidCredentials
+	}
+
+	ok, needsRehash, err := s.Passwords.Verify(pw, string(user.PassHash))
+	if err != nil {
+		return model.User{}, err
+	}
+	if !ok {
+		return model.User{}, ErrInvalidCredentials
+	}
+	// Only revealed to someone who knows the password
+	if user.Disabled() {
+		return model.User{}, ErrAccountDisabled
 	}
 
-	if err := bcrypt.CompareHashAndPassword(user.PassHash, []byte(password)); err != nil {
-		return "", "", errors.New("invalid credentials")
+	// Upgrade outdated hashes while we have the plaintext. A failure here
+	// must not block the login.
+	if needsRehash {
+		if hash, err := s.Passwords.Hash(pw); err != nil {
+			logging.FromContext(ctx).Error("rehash password failed", "user_id", user.ID, "err", err)
+		} else if err := s.UserRepo.UpdatePassword(ctx, user.ID, []byte(hash)); err != nil {
+			logging.FromContext(ctx).Error("store rehashed password failed", "user_id", user.ID, "err", err)
+		} else {
+			user.PassHash = []byte(hash)
+		}
 	}
 
-	access, err = s.JWT.Sign(user.ID, s.AccessTTL)
+	return user, nil
+}
+
+func (s *Service) issueTokens(ctx context.Context, userID string) (access, refresh string, err error) {
+	access, err = s.JWT.Sign(userID, s.AccessTTL)
 	if err != nil {
 		return "", "", err
 	}
 
-	refresh, hash, expUnix, err := newRefreshToken(s.RefreshTTL)
+	refresh, hash, expUnix, err := newToken(s.RefreshTTL)
 	if err != nil {
 		return "", "", err
 	}
 
-	if err := s.RefreshTokenRepo.Insert(ctx, user.ID, hash, expUnix); err != nil {
+	if err := s.RefreshTokenRepo.Insert(ctx, userID, hash, expUnix); err != nil {
 		return "", "", err
 	}
 
@@ -92,6 +297,11 @@ func (s *Service) Login(ctx context.Context, email, password string) (access, re
 }
 
 func (s *Service) Refresh(ctx context.Context, refreshPlain string) (newAccess, newRefresh string, err error) {
+	ctx, span := tracing.Start(ctx, "auth.Refresh")
+	defer span.End()
+
+	defer func() { metrics.Refreshes.Inc(outcome(err)) }()
+
 	hash := hashToken(refreshPlain)
 
 	userID, found, err := s.RefreshTokenRepo.FindUserIDByHash(ctx, hash)
@@ -99,50 +309,115 @@ func (s *Service) Refresh(ctx context.Context, refreshPlain string) (newAccess,
 		return "", "", err
 	}
 	if !found {
-		return "", "", errors.New("invalid refresh token")
+		userID, sinceRotated, rotated, err := s.RefreshTokenRepo.FindRotated(ctx, hash)
+		if err != nil {
+			return "", "", err
+		}
+		// A client that lost its response, or a second tab, may present
+		// the token again right after exchanging it
+		if !rotated || sinceRotated < refreshReuseGrace {
+			return "", "", ErrInvalidRefreshToken
+		}
+		return "", "", s.refreshReused(ctx, userID)
 	}
 
 	// Token rotation: revoke old, issue new
-	if err := s.RefreshTokenRepo.RevokeByHash(ctx, hash); err != nil {
+	rotated, err := s.RefreshTokenRepo.Rotate(ctx, hash)
+	if err != nil {
 		return "", "", err
 	}
+	if !rotated {
+		// A concurrent request exchanged the same token first
+		return "", "", ErrInvalidRefreshToken
+	}
 
-	newAccess, err = s.JWT.Sign(userID, s.AccessTTL)
-	if err != nil {
-		return "", "", err
+	return s.issueTokens(ctx, userID)
+}
+
+// refreshReuseGrace is how long after a refresh token is exchanged it is
+// merely invalid rather than a sign of theft.
+const refreshReuseGrace = 30 * time.Second
+
+// refreshReused handles a refresh token presented well after it was
+// exchanged. Either the client or someone who copied the token holds the
+// newer one, and there is no telling which, so every session of the user
+// ends.
+func (s *Service) refreshReused(ctx context.Context, userID string) error {
+	metrics.RefreshReuse.Inc()
+	logging.FromContext(ctx).Warn("refresh token reuse detected", "user_id", userID)
+	if err := s.LogoutAll(ctx, userID); err != nil {
+		return err
 	}
+	return ErrRefreshTokenReused
+}
 
-	newRefresh, newHash, expUnix, err := newRefreshToken(s.RefreshTTL)
-	if err != nil {
-		return "", "", err
+// Logout revokes the given refresh token and, when it is still valid, the
+// access token presented alongside it. Either may be empty.
+func (s *Service) Logout(ctx context.Context, refreshPlain, accessToken string) error {
+	ctx, span := tracing.Start(ctx, "auth.Logout")
+	defer span.End()
+
+	if refreshPlain != "" {
+		if err := s.RefreshTokenRepo.RevokeByHash(ctx, hashToken(refreshPlain)); err != nil {
+			return err
+		}
 	}
 
-	if err := s.RefreshTokenRepo.Insert(ctx, userID, newHash, expUnix); err != nil {
-		return "", "", err
+	if accessToken == "" {
+		return nil
 	}
 
-	return newAccess, newRefresh, nil
+	claims, err := s.JWT.Parse(accessToken)
+	if err != nil || claims.ID == "" {
+		// Already unusable, nothing to deny
+		return nil
+	}
+	return s.Denylist.Revoke(ctx, claims.ID, claims.ExpiresAt)
 }
 
-func (s *Service) Logout(ctx context.Context, refreshPlain string) error {
-	if refreshPlain == "" {
-		return nil
+// LogoutAll ends every session of the user: all refresh tokens are revoked
+// and every access token issued up to now is denied until it expires.
+// Tokens issued once it returns, such as those of a login that follows,
+// are not affected.
+func (s *Service) LogoutAll(ctx context.Context, userID string) error {
+	ctx, span := tracing.Start(ctx, "auth.LogoutAll")
+	defer span.End()
+
+	if err := s.RefreshTokenRepo.RevokeAllForUser(ctx, userID); err != nil {
+		return err
 	}
-	return s.RefreshTokenRepo.RevokeByHash(ctx, hashToken(refreshPlain))
+
+	// Tokens are stamped to the microsecond, and only those stamped after
+	// the cutoff pass. Writing it takes longer than that, so tokens issued
+	// once this returns are clear.
+	cutoff := time.Now()
+	return s.Denylist.RevokeUser(ctx, userID, cutoff.UnixMicro(), cutoff.Add(s.AccessTTL).Unix())
 }
 
-func (s *Service) Register(ctx context.Context, email, password string) error {
+func (s *Service) Register(ctx context.Context, email, pw string) error {
+	ctx, span := tracing.Start(ctx, "auth.Register")
+	defer span.End()
+
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return err
+	}
+
+	if err := s.PasswordPolicy.Check(pw, email); err != nil {
+		return err
+	}
+
 	// Check if email already exists
 	_, found, err := s.UserRepo.FindByEmail(ctx, email)
 	if err != nil {
 		return err
 	}
 	if found {
-		return errors.New("email already registered")
+		return ErrEmailTaken
 	}
 
 	// Hash password
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hash, err := s.Passwords.Hash(pw)
 	if err != nil {
 		return err
 	}
@@ -157,8 +432,176 @@ func (s *Service) Register(ctx context.Context, email, password string) error {
 	user := model.User{
 		ID:       id,
 		Email:    email,
-		PassHash: hash,
+		PassHash: []byte(hash),
+		Role:     model.RoleMember,
+	}
+
+	if err := s.UserRepo.Create(ctx, user); err != nil {
+		// Lost a race with another sign-up for the same address
+		if errors.Is(err, repository.ErrDuplicate) {
+			return ErrEmailTaken
+		}
+		return err
+	}
+
+	// The account exists at this point; a lost email can be resent
+	if err := s.sendVerification(ctx, user); err != nil {
+		logging.FromContext(ctx).Error("send verification email failed", "user_id", user.ID, "err", err)
+	}
+	return nil
+}
+
+// ForgotPassword emails a single-use reset link when the email belongs to
+// an account. It succeeds silently otherwise so callers cannot probe for
+// registered addresses.
+func (s *Service) ForgotPassword(ctx context.Context, email string) error {
+	ctx, span := tracing.Start(ctx, "auth.ForgotPassword")
+	defer span.End()
+
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return nil
+	}
+
+	user, found, err := s.UserRepo.FindByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return nil
+	}
+
+	// Only the most recent link stays usable
+	if err := s.UserTokenRepo.DeleteByUser(ctx, user.ID, model.TokenPurposePasswordReset); err != nil {
+		return err
+	}
+
+	plain, hash, expUnix, err := newToken(s.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	if err := s.UserTokenRepo.Insert(ctx, user.ID, model.TokenPurposePasswordReset, hash, expUnix); err != nil {
+		return err
+	}
+
+	link := s.AppBaseURL + "/reset-password?token=" + url.QueryEscape(plain)
+	return s.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Task Flow password",
+		Body: "We received a request to reset your password.\n\n" +
+			"Open the link below to choose a new one. It expires in " + s.PasswordResetTTL.String() + ".\n\n" +
+			link + "\n\n" +
+			"If you did not ask for this, you can ignore this email.\n",
+	})
+}
+
+// ResetPassword redeems a reset token, sets the new password and ends all
+// existing sessions of the account.
+func (s *Service) ResetPassword(ctx context.Context, token, newPassword string) error {
+	ctx, span := tracing.Start(ctx, "auth.ResetPassword")
+	defer span.End()
+
+	// The token is only redeemed once the new password is known to be
+	// acceptable, so a rejected one can be corrected with the same link.
+	tokenHash := hashToken(token)
+	userID, found, err := s.UserTokenRepo.Find(ctx, model.TokenPurposePasswordReset, tokenHash)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrInvalidResetToken
+	}
+
+	user, found, err := s.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrInvalidResetToken
+	}
+	if err := s.PasswordPolicy.Check(newPassword, user.Email); err != nil {
+		return err
+	}
+
+	hash, err := s.Passwords.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+
+	// Only one concurrent request may use the token
+	consumedBy, found, err := s.UserTokenRepo.Consume(ctx, model.TokenPurposePasswordReset, tokenHash)
+	if err != nil {
+		return err
+	}
+	if !found || consumedBy != userID {
+		return ErrInvalidResetToken
+	}
+
+	if err := s.UserRepo.UpdatePassword(ctx, userID, []byte(hash)); err != nil {
+		return err
+	}
+
+	return s.LogoutAll(ctx, userID)
+}
+
+func (s *Service) sendVerification(ctx context.Context, user model.User) error {
+	if err := s.UserTokenRepo.DeleteByUser(ctx, user.ID, model.TokenPurposeEmailVerification); err != nil {
+		return err
+	}
+
+	plain, hash, expUnix, err := newToken(s.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	if err := s.UserTokenRepo.Insert(ctx, user.ID, model.TokenPurposeEmailVerification, hash, expUnix); err != nil {
+		return err
+	}
+
+	link := s.AppBaseURL + "/verify-email?token=" + url.QueryEscape(plain)
+	return s.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your Task Flow email",
+		Body: "Welcome to Task Flow!\n\n" +
+			"Confirm your email address by opening the link below. It expires in " + s.EmailVerificationTTL.String() + ".\n\n" +
+			link + "\n",
+	})
+}
+
+// ResendVerification emails a fresh verification link to an unverified
+// account. Like ForgotPassword it never reveals whether the email exists.
+func (s *Service) ResendVerification(ctx context.Context, email string) error {
+	ctx, span := tracing.Start(ctx, "auth.ResendVerification")
+	defer span.End()
+
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return nil
+	}
+
+	user, found, err := s.UserRepo.FindByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if !found || user.Verified() {
+		return nil
+	}
+
+	return s.sendVerification(ctx, user)
+}
+
+func (s *Service) VerifyEmail(ctx context.Context, token string) error {
+	ctx, span := tracing.Start(ctx, "auth.VerifyEmail")
+	defer span.End()
+
+	userID, found, err := s.UserTokenRepo.Consume(ctx, model.TokenPurposeEmailVerification, hashToken(token))
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrInvalidVerificationToken
 	}
 
-	return s.UserRepo.Create(ctx, user)
+	return s.UserRepo.MarkVerified(ctx, userID)
 }
@@ -3,11 +3,21 @@ package auth
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"task-flow/internal/metrics"
 	"task-flow/internal/model"
 	"task-flow/internal/pkg/jwt"
+	"task-flow/internal/pkg/mailer"
+	"task-flow/internal/pkg/oidc"
+	"task-flow/internal/pkg/oidc/oidctest"
+	"task-flow/internal/pkg/password"
+	"task-flow/internal/pkg/totp"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -56,8 +66,109 @@ func (m *mockUserRepo) FindByEmail(ctx context.Context, email string) (model.Use
 	return user, found, nil
 }
 
+func (m *mockUserRepo) MarkVerified(ctx context.Context, id string) error {
+	for email, u := range m.users {
+		if u.ID == id {
+			now := time.Now()
+			u.VerifiedAt = &now
+			m.users[email] = u
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *mockUserRepo) UpdatePassword(ctx context.Context, id string, passHash []byte) error {
+	for email, u := range m.users {
+		if u.ID == id {
+			u.PassHash = passHash
+			m.users[email] = u
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *mockUserRepo) List(ctx context.Context, filter model.UserFilter) ([]model.User, error) {
+	var users []model.User
+	for _, u := range m.users {
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+func (m *mockUserRepo) SetRole(ctx context.Context, id string, role model.Role) error {
+	for email, u := range m.users {
+		if u.ID == id {
+			u.Role = role
+			m.users[email] = u
+		}
+	}
+	return nil
+}
+
+func (m *mockUserRepo) SetDisabled(ctx context.Context, id string, disabled bool) error {
+	for email, u := range m.users {
+		if u.ID == id {
+			u.DisabledAt = nil
+			if disabled {
+				now := time.Now()
+				u.DisabledAt = &now
+			}
+			m.users[email] = u
+		}
+	}
+	return nil
+}
+
+func (m *mockUserRepo) UpdateProfile(ctx context.Context, id string, profile model.UserProfile) error {
+	for email, u := range m.users {
+		if u.ID == id {
+			u.UserProfile = profile
+			m.users[email] = u
+		}
+	}
+	return nil
+}
+
+func (m *mockUserRepo) SetPendingEmail(ctx context.Context, id, pending string) error {
+	for email, u := range m.users {
+		if u.ID == id {
+			u.PendingEmail = pending
+			m.users[email] = u
+		}
+	}
+	return nil
+}
+
+// ChangeEmail re-keys the user, since the mock is indexed by email.
+func (m *mockUserRepo) ChangeEmail(ctx context.Context, id, newEmail string) error {
+	for email, u := range m.users {
+		if u.ID == id {
+			now := time.Now()
+			delete(m.users, email)
+			u.Email, u.PendingEmail, u.VerifiedAt = newEmail, "", &now
+			m.users[newEmail] = u
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *mockUserRepo) Anonymize(ctx context.Context, id string) error {
+	for email, u := range m.users {
+		if u.ID == id {
+			delete(m.users, email)
+		}
+	}
+	return nil
+}
+
 type mockRefreshRepo struct {
-	tokens    map[string]string // hash -> userID
+	mu        sync.Mutex
+	tokens    map[string]string    // hash -> userID
+	rotated   map[string]string    // hash -> userID
+	rotatedAt map[string]time.Time // hash -> when
 	insertErr error
 	findErr   error
 	revokeErr error
@@ -65,11 +176,15 @@ type mockRefreshRepo struct {
 
 func newMockRefreshRepo() *mockRefreshRepo {
 	return &mockRefreshRepo{
-		tokens: make(map[string]string),
+		tokens:    make(map[string]string),
+		rotated:   make(map[string]string),
+		rotatedAt: make(map[string]time.Time),
 	}
 }
 
 func (m *mockRefreshRepo) Insert(ctx context.Context, userID string, tokenHash []byte, expUnix int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.insertErr != nil {
 		return m.insertErr
 	}
@@ -78,6 +193,8 @@ func (m *mockRefreshRepo) Insert(ctx context.Context, userID string, tokenHash [
 }
 
 func (m *mockRefreshRepo) FindUserIDByHash(ctx context.Context, tokenHash []byte) (string, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.findErr != nil {
 		return "", false, m.findErr
 	}
@@ -85,7 +202,35 @@ func (m *mockRefreshRepo) FindUserIDByHash(ctx context.Context, tokenHash []byte
 	return userID, found, nil
 }
 
+func (m *mockRefreshRepo) Rotate(ctx context.Context, tokenHash []byte) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.revokeErr != nil {
+		return false, m.revokeErr
+	}
+	userID, found := m.tokens[string(tokenHash)]
+	if !found {
+		return false, nil
+	}
+	delete(m.tokens, string(tokenHash))
+	m.rotated[string(tokenHash)] = userID
+	m.rotatedAt[string(tokenHash)] = time.Now()
+	return true, nil
+}
+
+func (m *mockRefreshRepo) FindRotated(ctx context.Context, tokenHash []byte) (string, time.Duration, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.findErr != nil {
+		return "", 0, false, m.findErr
+	}
+	userID, found := m.rotated[string(tokenHash)]
+	return userID, time.Since(m.rotatedAt[string(tokenHash)]), found, nil
+}
+
 func (m *mockRefreshRepo) RevokeByHash(ctx context.Context, tokenHash []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.revokeErr != nil {
 		return m.revokeErr
 	}
@@ -93,18 +238,351 @@ func (m *mockRefreshRepo) RevokeByHash(ctx context.Context, tokenHash []byte) er
 	return nil
 }
 
+func (m *mockRefreshRepo) RevokeAllForUser(ctx context.Context, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.revokeErr != nil {
+		return m.revokeErr
+	}
+	for hash, id := range m.tokens {
+		if id == userID {
+			delete(m.tokens, hash)
+		}
+	}
+	return nil
+}
+
+type mockDenylist struct {
+	tokens map[string]int64 // jti -> exp
+	users  map[string]int64 // userID -> issued through, unix micro
+}
+
+func newMockDenylist() *mockDenylist {
+	return &mockDenylist{
+		tokens: make(map[string]int64),
+		users:  make(map[string]int64),
+	}
+}
+
+func (m *mockDenylist) Revoke(ctx context.Context, jti string, expUnix int64) error {
+	m.tokens[jti] = expUnix
+	return nil
+}
+
+func (m *mockDenylist) RevokeUser(ctx context.Context, userID string, issuedThroughUnixMicro, expUnix int64) error {
+	m.users[userID] = issuedThroughUnixMicro
+	// A real store takes longer than a microsecond to answer
+	for time.Now().UnixMicro() <= issuedThroughUnixMicro {
+	}
+	return nil
+}
+
+func (m *mockDenylist) IsRevoked(ctx context.Context, jti, userID string, issuedAtUnixMicro int64) (bool, error) {
+	if _, ok := m.tokens[jti]; ok {
+		return true, nil
+	}
+	through, ok := m.users[userID]
+	return ok && issuedAtUnixMicro <= through, nil
+}
+
+type mockUserToken struct {
+	userID  string
+	purpose model.TokenPurpose
+	used    bool
+}
+
+type mockUserTokenRepo struct {
+	tokens map[string]*mockUserToken // hash -> token
+}
+
+func newMockUserTokenRepo() *mockUserTokenRepo {
+	return &mockUserTokenRepo{
+		tokens: make(map[string]*mockUserToken),
+	}
+}
+
+func (m *mockUserTokenRepo) Insert(ctx context.Context, userID string, purpose model.TokenPurpose, tokenHash []byte, expUnix int64) error {
+	m.tokens[string(tokenHash)] = &mockUserToken{userID: userID, purpose: purpose}
+	return nil
+}
+
+func (m *mockUserTokenRepo) Find(ctx context.Context, purpose model.TokenPurpose, tokenHash []byte) (string, bool, error) {
+	t, found := m.tokens[string(tokenHash)]
+	if !found || t.used || t.purpose != purpose {
+		return "", false, nil
+	}
+	return t.userID, true, nil
+}
+
+func (m *mockUserTokenRepo) Consume(ctx context.Context, purpose model.TokenPurpose, tokenHash []byte) (string, bool, error) {
+	t, found := m.tokens[string(tokenHash)]
+	if !found || t.used || t.purpose != purpose {
+		return "", false, nil
+	}
+	t.used = true
+	return t.userID, true, nil
+}
+
+func (m *mockUserTokenRepo) DeleteByUser(ctx context.Context, userID string, purpose model.TokenPurpose) error {
+	for hash, t := range m.tokens {
+		if t.userID == userID && t.purpose == purpose {
+			delete(m.tokens, hash)
+		}
+	}
+	return nil
+}
+
+type mockMFARepo struct {
+	mfa      map[string]model.MFA
+	recovery map[string]map[string]bool // userID -> hash -> used
+}
+
+func newMockMFARepo() *mockMFARepo {
+	return &mockMFARepo{
+		mfa:      make(map[string]model.MFA),
+		recovery: make(map[string]map[string]bool),
+	}
+}
+
+func (m *mockMFARepo) SaveSecret(ctx context.Context, userID string, secret []byte) error {
+	m.mfa[userID] = model.MFA{UserID: userID, Secret: secret}
+	return nil
+}
+
+func (m *mockMFARepo) Find(ctx context.Context, userID string) (model.MFA, bool, error) {
+	mfa, found := m.mfa[userID]
+	return mfa, found, nil
+}
+
+func (m *mockMFARepo) Enable(ctx context.Context, userID string, counter int64, recoveryHashes [][]byte) error {
+	mfa := m.mfa[userID]
+	now := time.Now()
+	mfa.EnabledAt = &now
+	mfa.LastCounter = counter
+	m.mfa[userID] = mfa
+
+	m.recovery[userID] = make(map[string]bool)
+	for _, h := range recoveryHashes {
+		m.recovery[userID][string(h)] = false
+	}
+	return nil
+}
+
+func (m *mockMFARepo) UseCounter(ctx context.Context, userID string, counter int64) (bool, error) {
+	mfa := m.mfa[userID]
+	if counter <= mfa.LastCounter {
+		return false, nil
+	}
+	mfa.LastCounter = counter
+	m.mfa[userID] = mfa
+	return true, nil
+}
+
+func (m *mockMFARepo) ConsumeRecoveryCode(ctx context.Context, userID string, codeHash []byte) (bool, error) {
+	used, found := m.recovery[userID][string(codeHash)]
+	if !found || used {
+		return false, nil
+	}
+	m.recovery[userID][string(codeHash)] = true
+	return true, nil
+}
+
+func (m *mockMFARepo) Delete(ctx context.Context, userID string) error {
+	delete(m.mfa, userID)
+	delete(m.recovery, userID)
+	return nil
+}
+
+type mockLoginAttemptRepo struct {
+	attempts []model.LoginAttempt
+}
+
+func (m *mockLoginAttemptRepo) Record(ctx context.Context, attempt model.LoginAttempt) error {
+	attempt.CreatedAt = time.Now()
+	m.attempts = append(m.attempts, attempt)
+	return nil
+}
+
+func (m *mockLoginAttemptRepo) AccountFailures(ctx context.Context, email string, sinceUnix int64) (int, time.Duration, error) {
+	var n int
+	var last time.Time
+	for _, a := range m.attempts {
+		if a.Email != email {
+			continue
+		}
+		if a.Success {
+			n, last = 0, time.Time{}
+			continue
+		}
+		if a.CreatedAt.Unix() >= sinceUnix {
+			n, last = n+1, a.CreatedAt
+		}
+	}
+	return n, sinceLast(last), nil
+}
+
+func (m *mockLoginAttemptRepo) IPFailures(ctx context.Context, ip string, sinceUnix int64) (int, time.Duration, error) {
+	var n int
+	var last time.Time
+	for _, a := range m.attempts {
+		if a.IP == ip && !a.Success && a.CreatedAt.Unix() >= sinceUnix {
+			n, last = n+1, a.CreatedAt
+		}
+	}
+	return n, sinceLast(last), nil
+}
+
+func sinceLast(last time.Time) time.Duration {
+	if last.IsZero() {
+		return 0
+	}
+	return time.Since(last)
+}
+
+type mockPATRepo struct {
+	tokens   map[string]model.PersonalAccessToken // hash -> token
+	mu       sync.Mutex
+	touched  map[string]int
+	touchErr error
+}
+
+func newMockPATRepo() *mockPATRepo {
+	return &mockPATRepo{
+		tokens:  make(map[string]model.PersonalAccessToken),
+		touched: make(map[string]int),
+	}
+}
+
+func (m *mockPATRepo) Create(ctx context.Context, token model.PersonalAccessToken, tokenHash []byte) error {
+	m.tokens[string(tokenHash)] = token
+	return nil
+}
+
+func (m *mockPATRepo) FindActiveByHash(ctx context.Context, tokenHash []byte) (model.PersonalAccessToken, bool, error) {
+	t, found := m.tokens[string(tokenHash)]
+	if !found || (t.ExpiresAt != nil && t.ExpiresAt.Before(time.Now())) {
+		return model.PersonalAccessToken{}, false, nil
+	}
+	return t, true, nil
+}
+
+func (m *mockPATRepo) ListByUser(ctx context.Context, userID string) ([]model.PersonalAccessToken, error) {
+	var out []model.PersonalAccessToken
+	for _, t := range m.tokens {
+		if t.UserID == userID {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockPATRepo) Revoke(ctx context.Context, userID, id string) (bool, error) {
+	for hash, t := range m.tokens {
+		if t.ID == id && t.UserID == userID {
+			delete(m.tokens, hash)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockPATRepo) TouchLastUsed(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.touched[id]++
+	return m.touchErr
+}
+
+// touches waits briefly for background touches of id and counts them.
+func (m *mockPATRepo) touches(id string) int {
+	deadline := time.Now().Add(time.Second)
+	for {
+		m.mu.Lock()
+		n := m.touched[id]
+		m.mu.Unlock()
+		if n > 0 || time.Now().After(deadline) {
+			return n
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+type mockOIDCStateRepo struct {
+	states map[string]model.OIDCLoginState
+}
+
+func (m *mockOIDCStateRepo) Insert(ctx context.Context, stateHash []byte, state model.OIDCLoginState) error {
+	m.states[string(stateHash)] = state
+	return nil
+}
+
+func (m *mockOIDCStateRepo) Consume(ctx context.Context, stateHash []byte) (model.OIDCLoginState, bool, error) {
+	st, ok := m.states[string(stateHash)]
+	delete(m.states, string(stateHash))
+	if !ok || time.Now().After(st.ExpiresAt) {
+		return model.OIDCLoginState{}, false, nil
+	}
+	return st, true, nil
+}
+
+type mockIdentityRepo struct {
+	identities map[string]string // provider/subject -> userID
+}
+
+func (m *mockIdentityRepo) FindUserID(ctx context.Context, provider, subject string) (string, bool, error) {
+	userID, ok := m.identities[provider+"/"+subject]
+	return userID, ok, nil
+}
+
+func (m *mockIdentityRepo) Link(ctx context.Context, identity model.UserIdentity) error {
+	m.identities[identity.Provider+"/"+identity.Subject] = identity.UserID
+	return nil
+}
+
+func (m *mockIdentityRepo) ListByUser(ctx context.Context, userID string) ([]model.UserIdentity, error) {
+	var res []model.UserIdentity
+	for key, id := range m.identities {
+		if id == userID {
+			provider, subject, _ := strings.Cut(key, "/")
+			res = append(res, model.UserIdentity{UserID: id, Provider: provider, Subject: subject})
+		}
+	}
+	return res, nil
+}
+
+type mockMailer struct {
+	sent []mailer.Message
+}
+
+func (m *mockMailer) Send(ctx context.Context, msg mailer.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
 // ============================================
 // HELPER
 // ============================================
 
 func newTestService(userRepo *mockUserRepo, refreshRepo *mockRefreshRepo) *Service {
-	return NewService(
+	svc := NewService(
 		userRepo,
 		refreshRepo,
+		newMockDenylist(),
+		newMockUserTokenRepo(),
+		&mockMailer{},
+		newMockMFARepo(),
+		&mockLoginAttemptRepo{},
+		newMockPATRepo(),
 		jwt.New([]byte("test-secret")),
 		15*time.Minute,
 		7*24*time.Hour,
 	)
+	// Cheap parameters keep the suite fast
+	svc.Passwords = password.NewChain(
+		password.NewArgon2id(password.Argon2Params{Memory: 1024, Time: 1, Threads: 1, SaltLen: 16, KeyLen: 32}),
+		password.NewBcrypt(bcrypt.MinCost),
+	)
+	return svc
 }
 
 func hashPassword(password string) []byte {
@@ -126,23 +604,47 @@ func TestLogin_Success(t *testing.T) {
 
 	svc := newTestService(userRepo, newMockRefreshRepo())
 
-	access, refresh, err := svc.Login(context.Background(), "test@mail.com", "password123")
+	res, err := svc.Login(context.Background(), "test@mail.com", "password123", "127.0.0.1")
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
-	if access == "" {
+	if res.AccessToken == "" {
 		t.Error("expected access token, got empty")
 	}
-	if refresh == "" {
+	if res.RefreshToken == "" {
 		t.Error("expected refresh token, got empty")
 	}
 }
 
+func TestLogin_RehashesLegacyBcrypt(t *testing.T) {
+	userRepo := newMockUserRepo()
+	userRepo.users["test@mail.com"] = model.User{
+		ID:       "user-1",
+		Email:    "test@mail.com",
+		PassHash: hashPassword("password123"),
+	}
+
+	svc := newTestService(userRepo, newMockRefreshRepo())
+
+	if _, err := svc.Login(context.Background(), "test@mail.com", "password123", "127.0.0.1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	stored := string(userRepo.users["test@mail.com"].PassHash)
+	if !strings.HasPrefix(stored, "$argon2id$") {
+		t.Fatalf("expected hash upgraded to argon2id, got %q", stored)
+	}
+
+	if _, err := svc.Login(context.Background(), "test@mail.com", "password123", "127.0.0.1"); err != nil {
+		t.Errorf("expected login with upgraded hash, got %v", err)
+	}
+}
+
 func TestLogin_WrongEmail(t *testing.T) {
 	userRepo := newMockUserRepo()
 	svc := newTestService(userRepo, newMockRefreshRepo())
 
-	_, _, err := svc.Login(context.Background(), "notfound@mail.com", "password123")
+	_, err := svc.Login(context.Background(), "notfound@mail.com", "password123", "127.0.0.1")
 
 	if err == nil {
 		t.Fatal("expected error, got nil")
@@ -162,7 +664,7 @@ func TestLogin_WrongPassword(t *testing.T) {
 
 	svc := newTestService(userRepo, newMockRefreshRepo())
 
-	_, _, err := svc.Login(context.Background(), "test@mail.com", "wrongpassword")
+	_, err := svc.Login(context.Background(), "test@mail.com", "wrongpassword", "127.0.0.1")
 
 	if err == nil {
 		t.Fatal("expected error, got nil")
@@ -178,7 +680,7 @@ func TestLogin_DatabaseError(t *testing.T) {
 
 	svc := newTestService(userRepo, newMockRefreshRepo())
 
-	_, _, err := svc.Login(context.Background(), "test@mail.com", "password123")
+	_, err := svc.Login(context.Background(), "test@mail.com", "password123", "127.0.0.1")
 
 	if err == nil {
 		t.Fatal("expected error, got nil")
@@ -211,6 +713,45 @@ func TestRegister_Success(t *testing.T) {
 	}
 }
 
+func TestRegister_NormalizesEmail(t *testing.T) {
+	userRepo := newMockUserRepo()
+	svc := newTestService(userRepo, newMockRefreshRepo())
+
+	if err := svc.Register(context.Background(), "  New@Mail.COM ", "password123"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, found := userRepo.users["new@mail.com"]; !found {
+		t.Error("expected user stored under normalized email")
+	}
+}
+
+func TestRegister_InvalidEmail(t *testing.T) {
+	svc := newTestService(newMockUserRepo(), newMockRefreshRepo())
+
+	for _, email := range []string{"", "not-an-email", "a@b", "Name <a@mail.com>", "a@@mail.com", "a@mail.com."} {
+		err := svc.Register(context.Background(), email, "password123")
+		if !errors.Is(err, ErrInvalidEmail) {
+			t.Errorf("email %q: expected ErrInvalidEmail, got %v", email, err)
+		}
+	}
+}
+
+func TestRegister_WeakPassword(t *testing.T) {
+	userRepo := newMockUserRepo()
+	svc := newTestService(userRepo, newMockRefreshRepo())
+
+	for _, pw := range []string{"", "short", strings.Repeat("x", 73), "newuser123"} {
+		err := svc.Register(context.Background(), "newuser@mail.com", pw)
+		var policyErr *password.PolicyError
+		if !errors.As(err, &policyErr) {
+			t.Errorf("password %q: expected PolicyError, got %v", pw, err)
+		}
+	}
+	if len(userRepo.users) != 0 {
+		t.Error("expected no user to be created")
+	}
+}
+
 func TestRegister_EmailAlreadyExists(t *testing.T) {
 	userRepo := newMockUserRepo()
 	userRepo.users["existing@mail.com"] = model.User{
@@ -288,34 +829,997 @@ func TestRefresh_InvalidToken(t *testing.T) {
 	}
 }
 
-// ============================================
-// TEST LOGOUT
-// ============================================
-
-func TestLogout_Success(t *testing.T) {
+func TestRefresh_ReuseEndsAllSessions(t *testing.T) {
 	refreshRepo := newMockRefreshRepo()
-	tokenPlain := "test-refresh-token"
-	tokenHash := hashToken(tokenPlain)
-	refreshRepo.tokens[string(tokenHash)] = "user-1"
-
+	refreshRepo.tokens[string(hashToken("stolen"))] = "user-1"
 	svc := newTestService(newMockUserRepo(), refreshRepo)
+	denylist := svc.Denylist.(*mockDenylist)
+	ctx := context.Background()
+	before := metrics.RefreshReuse.Value()
 
-	err := svc.Logout(context.Background(), tokenPlain)
+	access, refresh, err := svc.Refresh(ctx, "stolen")
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
-	// Token should be revoked
-	if _, found := refreshRepo.tokens[string(tokenHash)]; found {
-		t.Error("expected token to be revoked")
+	// The old token comes back, from the thief or the client, well after
+	// it was exchanged
+	refreshRepo.rotatedAt[string(hashToken("stolen"))] = time.Now().Add(-2 * refreshReuseGrace)
+	if _, _, err := svc.Refresh(ctx, "stolen"); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("expected ErrRefreshTokenReused, got %v", err)
+	}
+	if got := metrics.RefreshReuse.Value() - before; got != 1 {
+		t.Errorf("expected one reuse counted, got %v", got)
+	}
+	if _, found := refreshRepo.tokens[string(hashToken(refresh))]; found {
+		t.Error("expected the newer refresh token to be revoked")
+	}
+	claims, _ := svc.JWT.Parse(access)
+	if revoked, _ := denylist.IsRevoked(ctx, claims.ID, claims.Subject, claims.IssuedAtUnixMicro()); !revoked {
+		t.Error("expected the newer access token to be revoked")
 	}
 }
 
-func TestLogout_EmptyToken(t *testing.T) {
-	svc := newTestService(newMockUserRepo(), newMockRefreshRepo())
+func TestRefresh_ConcurrentExchangeIsNotReuse(t *testing.T) {
+	refreshRepo := newMockRefreshRepo()
+	refreshRepo.tokens[string(hashToken("session"))] = "user-1"
+	svc := newTestService(newMockUserRepo(), refreshRepo)
+	before := metrics.RefreshReuse.Value()
+
+	// Two tabs, or a retry after a timeout, exchange the same token at once
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, errs[i] = svc.Refresh(context.Background(), "session")
+		}()
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+			if !errors.Is(err, ErrInvalidRefreshToken) {
+				t.Errorf("expected ErrInvalidRefreshToken, got %v", err)
+			}
+		}
+	}
+	if failed != 1 {
+		t.Errorf("expected exactly one exchange to fail, got %d", failed)
+	}
+	if len(refreshRepo.tokens) != 1 {
+		t.Errorf("expected the winner's refresh token to stay valid, got %d tokens", len(refreshRepo.tokens))
+	}
+	if _, revoked := svc.Denylist.(*mockDenylist).users["user-1"]; revoked {
+		t.Error("expected sessions to stay intact")
+	}
+	if got := metrics.RefreshReuse.Value() - before; got != 0 {
+		t.Errorf("expected no reuse counted, got %v", got)
+	}
+}
+
+func TestRefresh_LoggedOutTokenIsNotReuse(t *testing.T) {
+	refreshRepo := newMockRefreshRepo()
+	refreshRepo.tokens[string(hashToken("session"))] = "user-1"
+	svc := newTestService(newMockUserRepo(), refreshRepo)
+	ctx := context.Background()
+
+	if err := svc.Logout(ctx, "session", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := svc.Refresh(ctx, "session"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("expected ErrInvalidRefreshToken, got %v", err)
+	}
+	if _, revoked := svc.Denylist.(*mockDenylist).users["user-1"]; revoked {
+		t.Error("expected no user-wide revocation")
+	}
+}
+
+// ============================================
+// TEST LOGOUT
+// ============================================
+
+func TestLogout_Success(t *testing.T) {
+	refreshRepo := newMockRefreshRepo()
+	tokenPlain := "test-refresh-token"
+	tokenHash := hashToken(tokenPlain)
+	refreshRepo.tokens[string(tokenHash)] = "user-1"
 
-	err := svc.Logout(context.Background(), "")
+	svc := newTestService(newMockUserRepo(), refreshRepo)
+
+	err := svc.Logout(context.Background(), tokenPlain, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Token should be revoked
+	if _, found := refreshRepo.tokens[string(tokenHash)]; found {
+		t.Error("expected token to be revoked")
+	}
+}
+
+func TestLogout_EmptyToken(t *testing.T) {
+	svc := newTestService(newMockUserRepo(), newMockRefreshRepo())
+
+	err := svc.Logout(context.Background(), "", "")
 	if err != nil {
 		t.Fatalf("expected no error for empty token, got %v", err)
 	}
 }
+
+func TestLogout_RevokesAccessToken(t *testing.T) {
+	svc := newTestService(newMockUserRepo(), newMockRefreshRepo())
+	denylist := svc.Denylist.(*mockDenylist)
+
+	access, err := svc.JWT.Sign("user-1", svc.AccessTTL)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	if err := svc.Logout(context.Background(), "", access); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	claims, _ := svc.JWT.Parse(access)
+	revoked, _ := denylist.IsRevoked(context.Background(), claims.ID, claims.Subject, claims.IssuedAtUnixMicro())
+	if !revoked {
+		t.Error("expected access token to be denylisted")
+	}
+}
+
+func TestLogoutAll_Success(t *testing.T) {
+	refreshRepo := newMockRefreshRepo()
+	refreshRepo.tokens[string(hashToken("token-a"))] = "user-1"
+	refreshRepo.tokens[string(hashToken("token-b"))] = "user-1"
+	refreshRepo.tokens[string(hashToken("token-c"))] = "user-2"
+
+	svc := newTestService(newMockUserRepo(), refreshRepo)
+	denylist := svc.Denylist.(*mockDenylist)
+
+	access, _ := svc.JWT.Sign("user-1", svc.AccessTTL)
+
+	if err := svc.LogoutAll(context.Background(), "user-1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(refreshRepo.tokens) != 1 {
+		t.Errorf("expected only other user's token to remain, got %d tokens", len(refreshRepo.tokens))
+	}
+
+	claims, _ := svc.JWT.Parse(access)
+	revoked, _ := denylist.IsRevoked(context.Background(), claims.ID, claims.Subject, claims.IssuedAtUnixMicro())
+	if !revoked {
+		t.Error("expected existing access token to be revoked")
+	}
+}
+
+func TestLogoutAll_SparesTokensIssuedAfter(t *testing.T) {
+	svc := newTestService(newMockUserRepo(), newMockRefreshRepo())
+	denylist := svc.Denylist.(*mockDenylist)
+
+	if err := svc.LogoutAll(context.Background(), "user-1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// Issued within the same second as the revocation
+	access, _ := svc.JWT.Sign("user-1", svc.AccessTTL)
+
+	claims, _ := svc.JWT.Parse(access)
+	revoked, _ := denylist.IsRevoked(context.Background(), claims.ID, claims.Subject, claims.IssuedAtUnixMicro())
+	if revoked {
+		t.Error("expected token issued after LogoutAll to be accepted")
+	}
+}
+
+// ============================================
+// TEST PASSWORD RESET
+// ============================================
+
+// tokenFromMail pulls the token query parameter out of an emailed link.
+func tokenFromMail(t *testing.T, msg mailer.Message) string {
+	t.Helper()
+	i := strings.Index(msg.Body, "token=")
+	if i < 0 {
+		t.Fatalf("no token in mail body: %q", msg.Body)
+	}
+	token, _, _ := strings.Cut(msg.Body[i+len("token="):], "\n")
+	plain, err := url.QueryUnescape(token)
+	if err != nil {
+		t.Fatalf("unescape token: %v", err)
+	}
+	return plain
+}
+
+func TestForgotPassword_UnknownEmail(t *testing.T) {
+	svc := newTestService(newMockUserRepo(), newMockRefreshRepo())
+	mail := svc.Mailer.(*mockMailer)
+
+	if err := svc.ForgotPassword(context.Background(), "nobody@mail.com"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(mail.sent) != 0 {
+		t.Errorf("expected no mail to be sent, got %d", len(mail.sent))
+	}
+}
+
+func TestResetPassword_Success(t *testing.T) {
+	userRepo := newMockUserRepo()
+	userRepo.users["test@mail.com"] = model.User{
+		ID:       "user-1",
+		Email:    "test@mail.com",
+		PassHash: hashPassword("oldpassword"),
+	}
+	refreshRepo := newMockRefreshRepo()
+	refreshRepo.tokens[string(hashToken("session"))] = "user-1"
+
+	svc := newTestService(userRepo, refreshRepo)
+	mail := svc.Mailer.(*mockMailer)
+
+	if err := svc.ForgotPassword(context.Background(), "test@mail.com"); err != nil {
+		t.Fatalf("forgot password: %v", err)
+	}
+	if len(mail.sent) != 1 || mail.sent[0].To != "test@mail.com" {
+		t.Fatalf("expected one reset mail to test@mail.com, got %+v", mail.sent)
+	}
+	token := tokenFromMail(t, mail.sent[0])
+
+	if err := svc.ResetPassword(context.Background(), token, "newpassword"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if _, err := svc.Login(context.Background(), "test@mail.com", "newpassword", "127.0.0.1"); err != nil {
+		t.Errorf("expected login with new password, got %v", err)
+	}
+	if _, found := refreshRepo.tokens[string(hashToken("session"))]; found {
+		t.Error("expected existing refresh tokens to be revoked")
+	}
+
+	// Tokens are single-use
+	err := svc.ResetPassword(context.Background(), token, "anotherpassword")
+	if !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("expected ErrInvalidResetToken on reuse, got %v", err)
+	}
+}
+
+func TestResetPassword_RejectedPasswordKeepsToken(t *testing.T) {
+	userRepo := newMockUserRepo()
+	userRepo.users["test@mail.com"] = model.User{
+		ID:       "user-1",
+		Email:    "test@mail.com",
+		PassHash: hashPassword("oldpassword"),
+	}
+	svc := newTestService(userRepo, newMockRefreshRepo())
+	mail := svc.Mailer.(*mockMailer)
+	ctx := context.Background()
+
+	if err := svc.ForgotPassword(ctx, "test@mail.com"); err != nil {
+		t.Fatalf("forgot password: %v", err)
+	}
+	token := tokenFromMail(t, mail.sent[0])
+
+	// Too short, then containing the email, which needs the account
+	for _, pw := range []string{"short", "test@mail.com!"} {
+		var policyErr *password.PolicyError
+		if err := svc.ResetPassword(ctx, token, pw); !errors.As(err, &policyErr) {
+			t.Fatalf("%q: expected a policy error, got %v", pw, err)
+		}
+	}
+
+	if err := svc.ResetPassword(ctx, token, "newpassword"); err != nil {
+		t.Fatalf("expected the link to still work, got %v", err)
+	}
+}
+
+func TestResetPassword_InvalidToken(t *testing.T) {
+	svc := newTestService(newMockUserRepo(), newMockRefreshRepo())
+
+	err := svc.ResetPassword(context.Background(), "bogus", "newpassword")
+	if !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("expected ErrInvalidResetToken, got %v", err)
+	}
+}
+
+// ============================================
+// TEST EMAIL VERIFICATION
+// ============================================
+
+func TestVerifyEmail_Success(t *testing.T) {
+	userRepo := newMockUserRepo()
+	svc := newTestService(userRepo, newMockRefreshRepo())
+	svc.RequireVerifiedEmail = true
+	mail := svc.Mailer.(*mockMailer)
+
+	if err := svc.Register(context.Background(), "new@mail.com", "password123"); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if len(mail.sent) != 1 {
+		t.Fatalf("expected verification mail, got %d mails", len(mail.sent))
+	}
+
+	_, err := svc.Login(context.Background(), "new@mail.com", "password123", "127.0.0.1")
+	if !errors.Is(err, ErrEmailNotVerified) {
+		t.Fatalf("expected ErrEmailNotVerified before verification, got %v", err)
+	}
+
+	if err := svc.VerifyEmail(context.Background(), tokenFromMail(t, mail.sent[0])); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !userRepo.users["new@mail.com"].Verified() {
+		t.Error("expected user to be verified")
+	}
+
+	if _, err := svc.Login(context.Background(), "new@mail.com", "password123", "127.0.0.1"); err != nil {
+		t.Errorf("expected login after verification, got %v", err)
+	}
+}
+
+func TestVerifyEmail_InvalidToken(t *testing.T) {
+	svc := newTestService(newMockUserRepo(), newMockRefreshRepo())
+
+	err := svc.VerifyEmail(context.Background(), "bogus")
+	if !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Errorf("expected ErrInvalidVerificationToken, got %v", err)
+	}
+}
+
+func TestResendVerification_SkipsVerifiedUser(t *testing.T) {
+	userRepo := newMockUserRepo()
+	now := time.Now()
+	userRepo.users["test@mail.com"] = model.User{ID: "user-1", Email: "test@mail.com", VerifiedAt: &now}
+
+	svc := newTestService(userRepo, newMockRefreshRepo())
+	mail := svc.Mailer.(*mockMailer)
+
+	if err := svc.ResendVerification(context.Background(), "test@mail.com"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(mail.sent) != 0 {
+		t.Errorf("expected no mail for verified user, got %d", len(mail.sent))
+	}
+}
+
+// ============================================
+// TEST 2FA
+// ============================================
+
+// enrollMFA runs setup + confirm for user-1 and returns the raw secret and
+// recovery codes.
+func enrollMFA(t *testing.T, svc *Service) ([]byte, []string) {
+	t.Helper()
+	ctx := context.Background()
+
+	if _, _, err := svc.SetupMFA(ctx, "user-1"); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	secret := svc.MFARepo.(*mockMFARepo).mfa["user-1"].Secret
+
+	// Confirm with the previous period's code so the current one is still
+	// fresh for the login step.
+	code := totp.Code(secret, totp.Counter(time.Now())-1)
+	codes, err := svc.ConfirmMFA(ctx, "user-1", code)
+	if err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("expected %d recovery codes, got %d", recoveryCodeCount, len(codes))
+	}
+	return secret, codes
+}
+
+func newMFATestService() *Service {
+	userRepo := newMockUserRepo()
+	userRepo.users["test@mail.com"] = model.User{
+		ID:       "user-1",
+		Email:    "test@mail.com",
+		PassHash: hashPassword("password123"),
+	}
+	return newTestService(userRepo, newMockRefreshRepo())
+}
+
+func TestLoginMFA_Success(t *testing.T) {
+	svc := newMFATestService()
+	secret, _ := enrollMFA(t, svc)
+	ctx := context.Background()
+
+	res, err := svc.Login(ctx, "test@mail.com", "password123", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if res.MFAToken == "" || res.AccessToken != "" {
+		t.Fatalf("expected only an mfa challenge, got %+v", res)
+	}
+
+	code := totp.Code(secret, totp.Counter(time.Now()))
+	access, refresh, err := svc.LoginMFA(ctx, res.MFAToken, code, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if access == "" || refresh == "" {
+		t.Error("expected tokens after mfa")
+	}
+
+	// Neither the challenge nor the code can be replayed
+	if _, _, err := svc.LoginMFA(ctx, res.MFAToken, code, "127.0.0.1"); !errors.Is(err, ErrInvalidMFAToken) {
+		t.Errorf("expected ErrInvalidMFAToken on challenge reuse, got %v", err)
+	}
+	res, _ = svc.Login(ctx, "test@mail.com", "password123", "127.0.0.1")
+	if _, _, err := svc.LoginMFA(ctx, res.MFAToken, code, "127.0.0.1"); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("expected ErrInvalidMFACode on code reuse, got %v", err)
+	}
+}
+
+func TestLoginMFA_RecoveryCode(t *testing.T) {
+	svc := newMFATestService()
+	_, codes := enrollMFA(t, svc)
+	ctx := context.Background()
+
+	res, _ := svc.Login(ctx, "test@mail.com", "password123", "127.0.0.1")
+	if _, _, err := svc.LoginMFA(ctx, res.MFAToken, strings.ToUpper(codes[0]), "127.0.0.1"); err != nil {
+		t.Fatalf("expected recovery code to work, got %v", err)
+	}
+
+	res, _ = svc.Login(ctx, "test@mail.com", "password123", "127.0.0.1")
+	if _, _, err := svc.LoginMFA(ctx, res.MFAToken, codes[0], "127.0.0.1"); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("expected used recovery code to be rejected, got %v", err)
+	}
+}
+
+func TestLoginMFA_AccessTokenRejected(t *testing.T) {
+	svc := newMFATestService()
+	secret, _ := enrollMFA(t, svc)
+
+	access, _ := svc.JWT.Sign("user-1", svc.AccessTTL)
+	code := totp.Code(secret, totp.Counter(time.Now()))
+	if _, _, err := svc.LoginMFA(context.Background(), access, code, "127.0.0.1"); !errors.Is(err, ErrInvalidMFAToken) {
+		t.Errorf("expected ErrInvalidMFAToken for access token, got %v", err)
+	}
+}
+
+func TestLoginMFA_RechecksAccount(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(svc *Service, user *model.User)
+		want   error
+	}{
+		{"disabled", func(svc *Service, user *model.User) {
+			now := time.Now()
+			user.DisabledAt = &now
+		}, ErrAccountDisabled},
+		{"deleted", func(svc *Service, user *model.User) {
+			now := time.Now()
+			user.DeletedAt = &now
+		}, ErrInvalidMFAToken},
+		{"unverified", func(svc *Service, user *model.User) {
+			svc.RequireVerifiedEmail = true
+		}, ErrEmailNotVerified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newMFATestService()
+			secret, _ := enrollMFA(t, svc)
+			ctx := context.Background()
+
+			res, err := svc.Login(ctx, "test@mail.com", "password123", "127.0.0.1")
+			if err != nil || res.MFAToken == "" {
+				t.Fatalf("expected an mfa challenge, got %+v, %v", res, err)
+			}
+
+			// The account changes while the user looks up their code
+			userRepo := svc.UserRepo.(*mockUserRepo)
+			user := userRepo.users["test@mail.com"]
+			tt.change(svc, &user)
+			userRepo.users["test@mail.com"] = user
+
+			code := totp.Code(secret, totp.Counter(time.Now()))
+			if _, _, err := svc.LoginMFA(ctx, res.MFAToken, code, "127.0.0.1"); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestDisableMFA(t *testing.T) {
+	svc := newMFATestService()
+	enrollMFA(t, svc)
+	ctx := context.Background()
+
+	if err := svc.DisableMFA(ctx, "user-1", "wrongpassword"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+	}
+	if err := svc.DisableMFA(ctx, "user-1", "password123"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	res, err := svc.Login(ctx, "test@mail.com", "password123", "127.0.0.1")
+	if err != nil || res.AccessToken == "" {
+		t.Errorf("expected direct login after disabling 2fa, got %+v, %v", res, err)
+	}
+}
+
+// ============================================
+// TEST LOGIN THROTTLE
+// ============================================
+
+func TestLogin_LocksAccountAfterFailures(t *testing.T) {
+	svc := newMFATestService()
+	ctx := context.Background()
+
+	for i := range svc.LoginThrottle.MaxAccountFailures {
+		// Spread over IPs so only the account limit applies
+		ip := fmt.Sprintf("10.0.0.%d", i+1)
+		if _, err := svc.Login(ctx, "test@mail.com", "wrong", ip); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("attempt %d: expected ErrInvalidCredentials, got %v", i, err)
+		}
+	}
+
+	// Even the right password is refused while locked
+	_, err := svc.Login(ctx, "test@mail.com", "password123", "10.0.1.1")
+	var locked *LockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("expected LockedError, got %v", err)
+	}
+	if locked.RetryAfter <= 0 || locked.RetryAfter > svc.LoginThrottle.BaseLockout {
+		t.Errorf("expected retry after within base lockout, got %v", locked.RetryAfter)
+	}
+}
+
+func TestLogin_LocksIPAfterFailures(t *testing.T) {
+	svc := newMFATestService()
+	svc.LoginThrottle.MaxIPFailures = 3
+	ctx := context.Background()
+
+	for _, email := range []string{"a@mail.com", "b@mail.com", "c@mail.com"} {
+		svc.Login(ctx, email, "guess", "10.0.0.1")
+	}
+
+	_, err := svc.Login(ctx, "test@mail.com", "password123", "10.0.0.1")
+	var locked *LockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("expected LockedError for IP, got %v", err)
+	}
+
+	if _, err := svc.Login(ctx, "test@mail.com", "password123", "10.0.0.2"); err != nil {
+		t.Errorf("expected other IP to be unaffected, got %v", err)
+	}
+}
+
+func TestLogin_NotLockedBelowThreshold(t *testing.T) {
+	svc := newMFATestService()
+	attempts := svc.LoginAttemptRepo.(*mockLoginAttemptRepo)
+	ctx := context.Background()
+
+	// A failure stamped ahead of our clock, as a store in another time
+	// zone would report it, must not lock anyone out on its own
+	attempts.attempts = append(attempts.attempts, model.LoginAttempt{
+		Email:     "test@mail.com",
+		IP:        "10.0.0.1",
+		CreatedAt: time.Now().Add(3 * time.Hour),
+	})
+
+	if _, err := svc.Login(ctx, "test@mail.com", "password123", "10.0.0.1"); err != nil {
+		t.Errorf("expected login below the failure threshold, got %v", err)
+	}
+}
+
+func TestLoginThrottle_ExponentialLockout(t *testing.T) {
+	th := LoginThrottle{BaseLockout: time.Minute, MaxLockout: 10 * time.Minute}
+
+	cases := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{4, 0},
+		{5, time.Minute},
+		{6, 2 * time.Minute},
+		{7, 4 * time.Minute},
+		{8, 8 * time.Minute},
+		{9, 10 * time.Minute},
+		{50, 10 * time.Minute},
+	}
+	for _, c := range cases {
+		if got := th.lockout(c.failures, 5); got != c.want {
+			t.Errorf("failures=%d: expected %v, got %v", c.failures, c.want, got)
+		}
+	}
+}
+
+// ============================================
+// TEST PERSONAL ACCESS TOKENS
+// ============================================
+
+func TestPersonalAccessToken_Lifecycle(t *testing.T) {
+	svc := newTestService(newMockUserRepo(), newMockRefreshRepo())
+	patRepo := svc.PATRepo.(*mockPATRepo)
+	ctx := context.Background()
+
+	plain, token, err := svc.CreatePersonalAccessToken(ctx, "user-1", "ci", []string{model.ScopeTasksRead}, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(plain, PersonalAccessTokenPrefix) {
+		t.Errorf("expected prefixed token, got %q", plain)
+	}
+	if token.ExpiresAt == nil {
+		t.Error("expected expiry to be set")
+	}
+	for hash := range patRepo.tokens {
+		if strings.Contains(hash, plain) {
+			t.Error("plaintext token must not be stored")
+		}
+	}
+
+	got, err := svc.VerifyPersonalAccessToken(ctx, plain)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if got.UserID != "user-1" || patRepo.touches(token.ID) != 1 {
+		t.Errorf("expected token of user-1 with last-used tracking, got %+v", got)
+	}
+
+	if err := svc.RevokePersonalAccessToken(ctx, "user-2", token.ID); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("expected other users unable to revoke, got %v", err)
+	}
+	if err := svc.RevokePersonalAccessToken(ctx, "user-1", token.ID); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if _, err := svc.VerifyPersonalAccessToken(ctx, plain); !errors.Is(err, ErrInvalidPersonalToken) {
+		t.Errorf("expected revoked token to be rejected, got %v", err)
+	}
+}
+
+func TestPersonalAccessToken_TouchIsBestEffort(t *testing.T) {
+	svc := newTestService(newMockUserRepo(), newMockRefreshRepo())
+	patRepo := svc.PATRepo.(*mockPATRepo)
+	patRepo.touchErr = errors.New("db down")
+	ctx := context.Background()
+
+	plain, token, err := svc.CreatePersonalAccessToken(ctx, "user-1", "ci", []string{model.ScopeTasksRead}, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A failed write doesn't fail the request, and isn't retried on every one
+	for range 3 {
+		if _, err := svc.VerifyPersonalAccessToken(ctx, plain); err != nil {
+			t.Fatalf("verify: %v", err)
+		}
+	}
+	if n := patRepo.touches(token.ID); n != 1 {
+		t.Errorf("expected one touch, got %d", n)
+	}
+}
+
+func TestPersonalAccessToken_InvalidScopes(t *testing.T) {
+	svc := newTestService(newMockUserRepo(), newMockRefreshRepo())
+
+	for _, scopes := range [][]string{nil, {"admin"}, {model.ScopeAccountManage}, {model.ScopeTasksRead, model.ScopeTasksRead}} {
+		_, _, err := svc.CreatePersonalAccessToken(context.Background(), "user-1", "ci", scopes, 0)
+		if !errors.Is(err, ErrInvalidScope) {
+			t.Errorf("scopes %v: expected ErrInvalidScope, got %v", scopes, err)
+		}
+	}
+}
+
+func newOIDCTestService(t *testing.T) (*Service, *oidctest.Server) {
+	t.Helper()
+
+	idp := oidctest.NewServer()
+	t.Cleanup(idp.Close)
+
+	svc := newTestService(newMockUserRepo(), newMockRefreshRepo())
+	svc.OIDCStateRepo = &mockOIDCStateRepo{states: make(map[string]model.OIDCLoginState)}
+	svc.IdentityRepo = &mockIdentityRepo{identities: make(map[string]string)}
+	svc.OIDCProviders = map[string]*oidc.Provider{
+		"mock": oidc.NewProvider(oidc.Config{
+			Name:         "mock",
+			Issuer:       idp.URL,
+			ClientID:     oidctest.ClientID,
+			ClientSecret: oidctest.ClientSecret,
+			RedirectURL:  "http://localhost:8080/auth/oidc/mock/callback",
+		}, idp.Client()),
+	}
+	return svc, idp
+}
+
+// oidcLogin runs the whole redirect dance against the mock provider.
+func oidcLogin(t *testing.T, svc *Service, idp *oidctest.Server) (LoginResult, error) {
+	t.Helper()
+	ctx := context.Background()
+
+	authURL, browserState, err := svc.OIDCLoginURL(ctx, "mock")
+	if err != nil {
+		t.Fatalf("login url: %v", err)
+	}
+	state, code, err := idp.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	return svc.OIDCCallback(ctx, "mock", state, browserState, code, "127.0.0.1")
+}
+
+func TestOIDC_CreatesAndReusesAccount(t *testing.T) {
+	svc, idp := newOIDCTestService(t)
+	userRepo := svc.UserRepo.(*mockUserRepo)
+
+	res, err := oidcLogin(t, svc, idp)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if res.AccessToken == "" || res.RefreshToken == "" {
+		t.Fatalf("expected tokens, got %+v", res)
+	}
+
+	user, found := userRepo.users["alice@example.com"]
+	if !found || !user.Verified() || user.HasPassword() {
+		t.Fatalf("expected verified passwordless account, got %+v", user)
+	}
+	if _, err := svc.Login(context.Background(), "alice@example.com", "", "127.0.0.1"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected password login to fail, got %v", err)
+	}
+
+	// The identity is found by subject even if the email changes upstream
+	idp.SetUser(map[string]any{"sub": "alice", "email": "alice@new.example", "email_verified": true})
+	if _, err := oidcLogin(t, svc, idp); err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	if len(userRepo.users) != 1 {
+		t.Errorf("expected a single account, got %d", len(userRepo.users))
+	}
+}
+
+func TestOIDC_LinksVerifiedAccount(t *testing.T) {
+	svc, idp := newOIDCTestService(t)
+	now := time.Now()
+	svc.UserRepo.(*mockUserRepo).users["alice@example.com"] = model.User{
+		ID:         "user-1",
+		Email:      "alice@example.com",
+		PassHash:   hashPassword("password123"),
+		VerifiedAt: &now,
+	}
+
+	if _, err := oidcLogin(t, svc, idp); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if userID := svc.IdentityRepo.(*mockIdentityRepo).identities["mock/alice"]; userID != "user-1" {
+		t.Errorf("expected identity linked to user-1, got %q", userID)
+	}
+	if _, err := svc.Login(context.Background(), "alice@example.com", "password123", "127.0.0.1"); err != nil {
+		t.Errorf("expected password to keep working, got %v", err)
+	}
+}
+
+func TestOIDC_TakesOverUnverifiedAccount(t *testing.T) {
+	svc, idp := newOIDCTestService(t)
+	userRepo := svc.UserRepo.(*mockUserRepo)
+	userRepo.users["alice@example.com"] = model.User{
+		ID:       "user-1",
+		Email:    "alice@example.com",
+		PassHash: hashPassword("squatter-password"),
+	}
+
+	res, err := oidcLogin(t, svc, idp)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	user := userRepo.users["alice@example.com"]
+	if !user.Verified() || user.HasPassword() {
+		t.Errorf("expected verified account without the old password, got %+v", user)
+	}
+	if _, revoked := svc.Denylist.(*mockDenylist).users["user-1"]; !revoked {
+		t.Error("expected previous sessions to be revoked")
+	}
+	// The new session is issued right after the old ones are revoked
+	claims, err := svc.JWT.Parse(res.AccessToken)
+	if err != nil {
+		t.Fatalf("parse access token: %v", err)
+	}
+	if revoked, _ := svc.Denylist.IsRevoked(context.Background(), claims.ID, claims.Subject, claims.IssuedAtUnixMicro()); revoked {
+		t.Error("expected the new access token to be usable")
+	}
+}
+
+func TestOIDC_RejectsUnverifiedEmail(t *testing.T) {
+	svc, idp := newOIDCTestService(t)
+	idp.SetUser(map[string]any{"sub": "mallory", "email": "alice@example.com", "email_verified": false})
+
+	if _, err := oidcLogin(t, svc, idp); !errors.Is(err, ErrOIDCEmailNotVerified) {
+		t.Fatalf("expected ErrOIDCEmailNotVerified, got %v", err)
+	}
+	if len(svc.UserRepo.(*mockUserRepo).users) != 0 {
+		t.Error("expected no account to be created")
+	}
+}
+
+func TestOIDC_StateIsSingleUse(t *testing.T) {
+	svc, idp := newOIDCTestService(t)
+	ctx := context.Background()
+
+	authURL, browserState, err := svc.OIDCLoginURL(ctx, "mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, code, err := idp.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.OIDCCallback(ctx, "mock", state, browserState, code, "127.0.0.1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := svc.OIDCCallback(ctx, "mock", state, browserState, code, "127.0.0.1"); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Errorf("expected ErrInvalidOIDCState on replay, got %v", err)
+	}
+	if _, err := svc.OIDCCallback(ctx, "mock", "forged", "forged", code, "127.0.0.1"); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Errorf("expected ErrInvalidOIDCState for unknown state, got %v", err)
+	}
+	if _, _, err := svc.OIDCLoginURL(ctx, "unknown"); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("expected ErrUnknownProvider, got %v", err)
+	}
+}
+
+func TestOIDC_StateIsBoundToBrowser(t *testing.T) {
+	svc, idp := newOIDCTestService(t)
+	ctx := context.Background()
+
+	// An attacker starts a login and gets the victim's browser to finish it
+	authURL, attackerState, err := svc.OIDCLoginURL(ctx, "mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, code, err := idp.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.OIDCCallback(ctx, "mock", state, "", code, "127.0.0.1"); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Errorf("expected ErrInvalidOIDCState without a browser state, got %v", err)
+	}
+	_, victimState, err := svc.OIDCLoginURL(ctx, "mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.OIDCCallback(ctx, "mock", state, victimState, code, "127.0.0.1"); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Errorf("expected ErrInvalidOIDCState for another browser's state, got %v", err)
+	}
+	if _, err := svc.OIDCCallback(ctx, "mock", state, attackerState, code, "127.0.0.1"); err != nil {
+		t.Errorf("expected the login to still work from the browser that started it, got %v", err)
+	}
+}
+
+func TestOIDC_RequiresSecondFactor(t *testing.T) {
+	svc, idp := newOIDCTestService(t)
+	now := time.Now()
+	svc.UserRepo.(*mockUserRepo).users["test@mail.com"] = model.User{
+		ID:         "user-1",
+		Email:      "test@mail.com",
+		PassHash:   hashPassword("password123"),
+		VerifiedAt: &now,
+	}
+	enrollMFA(t, svc)
+	idp.SetUser(map[string]any{"sub": "tester", "email": "test@mail.com", "email_verified": true})
+
+	res, err := oidcLogin(t, svc, idp)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if res.MFAToken == "" || res.AccessToken != "" {
+		t.Errorf("expected only an mfa challenge, got %+v", res)
+	}
+}
+
+func TestLogin_DisabledAccount(t *testing.T) {
+	userRepo := newMockUserRepo()
+	now := time.Now()
+	userRepo.users["test@mail.com"] = model.User{
+		ID:         "user-1",
+		Email:      "test@mail.com",
+		PassHash:   hashPassword("password123"),
+		DisabledAt: &now,
+	}
+	svc := newTestService(userRepo, newMockRefreshRepo())
+
+	if _, err := svc.Login(context.Background(), "test@mail.com", "password123", "127.0.0.1"); !errors.Is(err, ErrAccountDisabled) {
+		t.Errorf("expected ErrAccountDisabled, got %v", err)
+	}
+	// A wrong password must not reveal the account state
+	if _, err := svc.Login(context.Background(), "test@mail.com", "wrong", "127.0.0.1"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected ErrInvalidCredentials, got %v", err)
+	}
+}
+
+// ============================================
+// TEST PASSWORD AND EMAIL CHANGE
+// ============================================
+
+func TestChangePassword(t *testing.T) {
+	refreshRepo := newMockRefreshRepo()
+	refreshRepo.tokens[string(hashToken("session"))] = "user-1"
+	svc := newMFATestService()
+	svc.RefreshTokenRepo = refreshRepo
+	ctx := context.Background()
+
+	if _, _, err := svc.ChangePassword(ctx, "user-1", "wrong", "newpassword"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials for a wrong current password, got %v", err)
+	}
+
+	access, refresh, err := svc.ChangePassword(ctx, "user-1", "password123", "newpassword")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if access == "" || refresh == "" {
+		t.Fatal("expected a fresh token pair")
+	}
+	// The new pair is issued in the same second as the revocation
+	claims, err := svc.JWT.Parse(access)
+	if err != nil {
+		t.Fatalf("parse access token: %v", err)
+	}
+	revoked, err := svc.Denylist.IsRevoked(ctx, claims.ID, claims.Subject, claims.IssuedAtUnixMicro())
+	if err != nil || revoked {
+		t.Errorf("expected the new access token to be usable, got revoked=%v err=%v", revoked, err)
+	}
+	if _, found := refreshRepo.tokens[string(hashToken("session"))]; found {
+		t.Error("expected existing refresh tokens to be revoked")
+	}
+	if _, err := svc.Login(ctx, "test@mail.com", "newpassword", "127.0.0.1"); err != nil {
+		t.Errorf("expected login with new password, got %v", err)
+	}
+}
+
+func TestChangeEmail(t *testing.T) {
+	svc := newMFATestService()
+	userRepo := svc.UserRepo.(*mockUserRepo)
+	mail := svc.Mailer.(*mockMailer)
+	ctx := context.Background()
+
+	if err := svc.RequestEmailChange(ctx, "user-1", "wrong", "new@mail.com"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+	}
+	if err := svc.RequestEmailChange(ctx, "user-1", "password123", "Test@Mail.com"); !errors.Is(err, ErrSameEmail) {
+		t.Fatalf("expected ErrSameEmail, got %v", err)
+	}
+
+	if err := svc.RequestEmailChange(ctx, "user-1", "password123", "New@Mail.com"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(mail.sent) != 2 || mail.sent[0].To != "new@mail.com" || mail.sent[1].To != "test@mail.com" {
+		t.Fatalf("expected a confirmation to the new address and a notice to the old one, got %+v", mail.sent)
+	}
+	// Nothing changes until the link is opened
+	if _, found := userRepo.users["test@mail.com"]; !found {
+		t.Fatal("expected the old address to stay in use")
+	}
+
+	token := tokenFromMail(t, mail.sent[0])
+	if err := svc.ConfirmEmailChange(ctx, token); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	user, found := userRepo.users["new@mail.com"]
+	if !found || !user.Verified() || user.PendingEmail != "" {
+		t.Fatalf("expected the verified new address, got %+v", user)
+	}
+
+	if err := svc.ConfirmEmailChange(ctx, token); !errors.Is(err, ErrInvalidEmailChangeToken) {
+		t.Errorf("expected ErrInvalidEmailChangeToken on reuse, got %v", err)
+	}
+}
+
+func TestChangeEmail_TakenBeforeConfirm(t *testing.T) {
+	svc := newMFATestService()
+	userRepo := svc.UserRepo.(*mockUserRepo)
+	mail := svc.Mailer.(*mockMailer)
+	ctx := context.Background()
+
+	if err := svc.RequestEmailChange(ctx, "user-1", "password123", "new@mail.com"); err != nil {
+		t.Fatal(err)
+	}
+	userRepo.users["new@mail.com"] = model.User{ID: "user-2", Email: "new@mail.com"}
+
+	if err := svc.ConfirmEmailChange(ctx, tokenFromMail(t, mail.sent[0])); !errors.Is(err, ErrEmailTaken) {
+		t.Fatalf("expected ErrEmailTaken, got %v", err)
+	}
+	if userRepo.users["test@mail.com"].ID != "user-1" {
+		t.Error("expected the account to keep its address")
+	}
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"net/mail"
+	"strings"
+
+	"task-flow/internal/apperr"
+)
+
+var ErrInvalidEmail = apperr.Validation("invalid_email", "invalid email address")
+
+// NormalizeEmail validates a bare address (no display name) and returns
+// the canonical form we store and look up: trimmed and lower-cased.
+func NormalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" || len(email) > 254 {
+		return "", ErrInvalidEmail
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" {
+		return "", ErrInvalidEmail
+	}
+
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" || len(local) > 64 {
+		return "", ErrInvalidEmail
+	}
+	// Require a dotted domain; "user@localhost" is never deliverable for us
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return "", ErrInvalidEmail
+	}
+
+	return email, nil
+}
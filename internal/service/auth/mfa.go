@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	"task-flow/internal/apperr"
+	"task-flow/internal/metrics"
+	"task-flow/internal/pkg/totp"
+	"task-flow/internal/tracing"
+)
+
+const (
+	mfaChallengePurpose = "mfa"
+	mfaChallengeTTL     = 5 * time.Minute
+	recoveryCodeCount   = 10
+)
+
+var (
+	ErrMFAAlreadyEnabled = apperr.Conflict("mfa_already_enabled", "2fa already enabled")
+	ErrMFANotPending     = apperr.Validation("mfa_not_pending", "2fa setup not started")
+	ErrMFANotEnabled     = apperr.Validation("mfa_not_enabled", "2fa not enabled")
+	ErrInvalidMFACode    = apperr.Unauthorized("invalid_mfa_code", "invalid 2fa code")
+	ErrInvalidMFAToken   = apperr.Unauthorized("invalid_mfa_token", "invalid or expired mfa token")
+)
+
+// SetupMFA starts (or restarts) TOTP enrolment and returns the secret in
+// base32 along with an otpauth URI for QR rendering. 2FA only becomes
+// active once ConfirmMFA succeeds.
+func (s *Service) SetupMFA(ctx context.Context, userID string) (secret, uri string, err error) {
+	ctx, span := tracing.Start(ctx, "auth.SetupMFA")
+	defer span.End()
+
+	user, found, err := s.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return "", "", err
+	}
+	if !found {
+		return "", "", ErrUserNotFound
+	}
+
+	existing, found, err := s.MFARepo.Find(ctx, userID)
+	if err != nil {
+		return "", "", err
+	}
+	if found && existing.Enabled() {
+		return "", "", ErrMFAAlreadyEnabled
+	}
+
+	raw, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := s.MFARepo.SaveSecret(ctx, userID, raw); err != nil {
+		return "", "", err
+	}
+
+	return totp.EncodeSecret(raw), totp.URI(s.MFAIssuer, user.Email, raw), nil
+}
+
+// ConfirmMFA activates a pending enrolment with a first valid code and
+// returns freshly generated recovery codes. They are only shown once.
+func (s *Service) ConfirmMFA(ctx context.Context, userID, code string) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "auth.ConfirmMFA")
+	defer span.End()
+
+	mfa, found, err := s.MFARepo.Find(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrMFANotPending
+	}
+	if mfa.Enabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	counter, ok := totp.Validate(mfa.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.MFARepo.Enable(ctx, userID, counter, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// LoginMFA redeems the challenge token returned by Login. code is either a
+// current TOTP code or one of the user's unused recovery codes. Wrong codes
+// count as failed logins for the throttle.
+func (s *Service) LoginMFA(ctx context.Context, mfaToken, code, ip string) (access, refresh string, err error) {
+	ctx, span := tracing.Start(ctx, "auth.LoginMFA")
+	defer span.End()
+
+	defer func() { metrics.Logins.Inc("mfa", outcome(err)) }()
+
+	claims, err := s.JWT.Parse(mfaToken)
+	if err != nil || claims.Purpose != mfaChallengePurpose {
+		return "", "", ErrInvalidMFAToken
+	}
+
+	revoked, err := s.Denylist.IsRevoked(ctx, claims.ID, claims.Subject, claims.IssuedAtUnixMicro())
+	if err != nil {
+		return "", "", err
+	}
+	if revoked {
+		return "", "", ErrInvalidMFAToken
+	}
+
+	user, found, err := s.UserRepo.FindByID(ctx, claims.Subject)
+	if err != nil {
+		return "", "", err
+	}
+	if !found || user.Deleted() {
+		return "", "", ErrInvalidMFAToken
+	}
+	if err := s.loginAllowed(user); err != nil {
+		return "", "", err
+	}
+
+	if err := s.checkThrottle(ctx, user.Email, ip); err != nil {
+		return "", "", err
+	}
+
+	ok, err := s.checkMFACode(ctx, user.ID, code)
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.recordAttempt(ctx, user.Email, ip, ok); err != nil {
+		return "", "", err
+	}
+	if !ok {
+		return "", "", ErrInvalidMFACode
+	}
+
+	// The challenge is single-use
+	if err := s.Denylist.Revoke(ctx, claims.ID, claims.ExpiresAt); err != nil {
+		return "", "", err
+	}
+
+	return s.issueTokens(ctx, claims.Subject)
+}
+
+// DisableMFA turns 2FA off after re-checking the account password.
+func (s *Service) DisableMFA(ctx context.Context, userID, pw string) error {
+	ctx, span := tracing.Start(ctx, "auth.DisableMFA")
+	defer span.End()
+
+	user, found, err := s.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrUserNotFound
+	}
+	if !user.HasPassword() {
+		return ErrInvalidCredentials
+	}
+
+	ok, _, err := s.Passwords.Verify(pw, string(user.PassHash))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidCredentials
+	}
+
+	mfa, found, err := s.MFARepo.Find(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !found || !mfa.Enabled() {
+		return ErrMFANotEnabled
+	}
+
+	return s.MFARepo.Delete(ctx, userID)
+}
+
+func (s *Service) checkMFACode(ctx context.Context, userID, code string) (bool, error) {
+	mfa, found, err := s.MFARepo.Find(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if !found || !mfa.Enabled() {
+		return false, nil
+	}
+
+	code = strings.TrimSpace(code)
+	if counter, ok := totp.Validate(mfa.Secret, code, time.Now()); ok {
+		// Refuse a code that was already used within its window
+		return s.MFARepo.UseCounter(ctx, userID, counter)
+	}
+
+	return s.MFARepo.ConsumeRecoveryCode(ctx, userID, hashToken(normalizeRecoveryCode(code)))
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newRecoveryCodes returns codes formatted as xxxxx-xxxxx together with
+// the hashes to store.
+func newRecoveryCodes() (codes []string, hashes [][]byte, err error) {
+	for range recoveryCodeCount {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(b))[:10]
+
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, hashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
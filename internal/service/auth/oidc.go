@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"time"
+
+	"task-flow/internal/apperr"
+	"task-flow/internal/model"
+	"task-flow/internal/pkg/oidc"
+	"task-flow/internal/tracing"
+	"task-flow/internal/utils"
+)
+
+// OIDCStateTTL bounds how long a user may take at the identity provider.
+const OIDCStateTTL = 10 * time.Minute
+
+var (
+	ErrUnknownProvider      = apperr.NotFound("unknown_provider", "unknown identity provider")
+	ErrInvalidOIDCState     = apperr.Unauthorized("invalid_oidc_state", "invalid or expired login state")
+	ErrOIDCEmailNotVerified = apperr.Forbidden("oidc_email_not_verified", "identity provider did not confirm the email address")
+)
+
+func (s *Service) oidcProvider(name string) (*oidc.Provider, error) {
+	p, ok := s.OIDCProviders[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+// OIDCLoginURL starts an authorization-code login with PKCE and returns the
+// provider URL to redirect the user to. Nonce and code verifier are kept
+// server side until the callback. The returned state must also be kept by
+// the browser, e.g. in a cookie, and handed to OIDCCallback with it.
+func (s *Service) OIDCLoginURL(ctx context.Context, providerName string) (authURL, state string, err error) {
+	ctx, span := tracing.Start(ctx, "auth.OIDCLoginURL")
+	defer span.End()
+
+	p, err := s.oidcProvider(providerName)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err = randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err = p.AuthCodeURL(ctx, state, nonce, challenge)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = s.OIDCStateRepo.Insert(ctx, hashToken(state), model.OIDCLoginState{
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(OIDCStateTTL),
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return authURL, state, nil
+}
+
+// OIDCCallback finishes a provider login: it redeems the state, exchanges
+// the code, verifies the ID token and signs the linked account in like a
+// password login would (including the 2FA step). browserState is the state
+// the browser kept from OIDCLoginURL; without it an attacker could have a
+// victim's browser finish the attacker's login.
+func (s *Service) OIDCCallback(ctx context.Context, providerName, state, browserState, code, ip string) (LoginResult, error) {
+	ctx, span := tracing.Start(ctx, "auth.OIDCCallback")
+	defer span.End()
+
+	p, err := s.oidcProvider(providerName)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	if browserState == "" || subtle.ConstantTimeCompare([]byte(state), []byte(browserState)) != 1 {
+		return LoginResult{}, ErrInvalidOIDCState
+	}
+
+	st, found, err := s.OIDCStateRepo.Consume(ctx, hashToken(state))
+	if err != nil {
+		return LoginResult{}, err
+	}
+	if !found || st.Provider != providerName {
+		return LoginResult{}, ErrInvalidOIDCState
+	}
+
+	rawIDToken, err := p.Exchange(ctx, code, st.CodeVerifier)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	claims, err := p.VerifyIDToken(ctx, rawIDToken, st.Nonce)
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	user, err := s.oidcUser(ctx, providerName, claims)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	if user.Disabled() {
+		return LoginResult{}, ErrAccountDisabled
+	}
+
+	return s.completeLogin(ctx, user, ip)
+}
+
+// oidcUser resolves the account for a verified ID token. A known identity
+// wins; otherwise the identity is linked to the account with the same
+// (provider-verified) email, which is created if needed.
+func (s *Service) oidcUser(ctx context.Context, providerName string, claims oidc.IDClaims) (model.User, error) {
+	userID, found, err := s.IdentityRepo.FindUserID(ctx, providerName, claims.Subject)
+	if err != nil {
+		return model.User{}, err
+	}
+	if found {
+		user, found, err := s.UserRepo.FindByID(ctx, userID)
+		if err != nil {
+			return model.User{}, err
+		}
+		if !found {
+			return model.User{}, fmt.Errorf("identity %s/%s points to missing user %s", providerName, claims.Subject, userID)
+		}
+		return user, nil
+	}
+
+	// Linking by email is only safe when the provider vouches for it
+	if !claims.EmailVerified {
+		return model.User{}, ErrOIDCEmailNotVerified
+	}
+	email, err := NormalizeEmail(claims.Email)
+	if err != nil {
+		return model.User{}, ErrOIDCEmailNotVerified
+	}
+
+	user, found, err := s.UserRepo.FindByEmail(ctx, email)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	switch {
+	case !found:
+		id, err := utils.GenerateID()
+		if err != nil {
+			return model.User{}, err
+		}
+		now := time.Now()
+		// An empty (not NULL) hash marks an account without a password
+		user = model.User{ID: id, Email: email, PassHash: []byte{}, Role: model.RoleMember, VerifiedAt: &now}
+		if err := s.UserRepo.Create(ctx, user); err != nil {
+			return model.User{}, err
+		}
+	case !user.Verified():
+		// Someone registered this address without proving they own it. The
+		// provider just did, so drop whatever password they chose and end
+		// their sessions before handing the account over.
+		if err := s.UserRepo.UpdatePassword(ctx, user.ID, []byte{}); err != nil {
+			return model.User{}, err
+		}
+		if err := s.UserRepo.MarkVerified(ctx, user.ID); err != nil {
+			return model.User{}, err
+		}
+		if err := s.LogoutAll(ctx, user.ID); err != nil {
+			return model.User{}, err
+		}
+		now := time.Now()
+		user.PassHash = []byte{}
+		user.VerifiedAt = &now
+	}
+
+	err = s.IdentityRepo.Link(ctx, model.UserIdentity{
+		UserID:   user.ID,
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    email,
+	})
+	if err != nil {
+		return model.User{}, err
+	}
+
+	return user, nil
+}
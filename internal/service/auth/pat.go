@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"task-flow/internal/apperr"
+	"task-flow/internal/logging"
+	"task-flow/internal/model"
+	"task-flow/internal/tracing"
+	"task-flow/internal/utils"
+)
+
+// PersonalAccessTokenPrefix makes PATs recognisable both to the auth
+// middleware and to secret scanners.
+const PersonalAccessTokenPrefix = "tfpat_"
+
+var (
+	ErrInvalidTokenName     = apperr.Validation("invalid_token_name", "token name is required (max 100 characters)")
+	ErrInvalidScope         = apperr.Validation("invalid_scope", "invalid scope")
+	ErrTokenNotFound        = apperr.NotFound("token_not_found", "token not found")
+	ErrInvalidPersonalToken = apperr.Unauthorized("invalid_token", "invalid personal access token")
+)
+
+// CreatePersonalAccessToken issues a named, scoped token. The plaintext is
+// returned once and only its hash is stored. A zero ttl never expires.
+func (s *Service) CreatePersonalAccessToken(ctx context.Context, userID, name string, scopes []string, ttl time.Duration) (string, model.PersonalAccessToken, error) {
+	ctx, span := tracing.Start(ctx, "auth.CreatePersonalAccessToken")
+	defer span.End()
+
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return "", model.PersonalAccessToken{}, ErrInvalidTokenName
+	}
+
+	if len(scopes) == 0 {
+		return "", model.PersonalAccessToken{}, ErrInvalidScope
+	}
+	seen := make(map[string]bool)
+	for _, scope := range scopes {
+		if !model.IsDelegableScope(scope) || seen[scope] {
+			return "", model.PersonalAccessToken{}, ErrInvalidScope
+		}
+		seen[scope] = true
+	}
+
+	id, err := utils.GenerateID()
+	if err != nil {
+		return "", model.PersonalAccessToken{}, err
+	}
+
+	random, err := randomToken()
+	if err != nil {
+		return "", model.PersonalAccessToken{}, err
+	}
+	plain := PersonalAccessTokenPrefix + random
+
+	token := model.PersonalAccessToken{
+		ID:        id,
+		UserID:    userID,
+		Name:      name,
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}
+	if ttl > 0 {
+		exp := time.Now().Add(ttl)
+		token.ExpiresAt = &exp
+	}
+
+	if err := s.PATRepo.Create(ctx, token, hashToken(plain)); err != nil {
+		return "", model.PersonalAccessToken{}, err
+	}
+
+	return plain, token, nil
+}
+
+func (s *Service) ListPersonalAccessTokens(ctx context.Context, userID string) ([]model.PersonalAccessToken, error) {
+	ctx, span := tracing.Start(ctx, "auth.ListPersonalAccessTokens")
+	defer span.End()
+
+	return s.PATRepo.ListByUser(ctx, userID)
+}
+
+func (s *Service) RevokePersonalAccessToken(ctx context.Context, userID, id string) error {
+	ctx, span := tracing.Start(ctx, "auth.RevokePersonalAccessToken")
+	defer span.End()
+
+	ok, err := s.PATRepo.Revoke(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrTokenNotFound
+	}
+	return nil
+}
+
+// VerifyPersonalAccessToken resolves a presented PAT and records its use.
+func (s *Service) VerifyPersonalAccessToken(ctx context.Context, plain string) (model.PersonalAccessToken, error) {
+	ctx, span := tracing.Start(ctx, "auth.VerifyPersonalAccessToken")
+	defer span.End()
+
+	if !strings.HasPrefix(plain, PersonalAccessTokenPrefix) {
+		return model.PersonalAccessToken{}, ErrInvalidPersonalToken
+	}
+
+	token, found, err := s.PATRepo.FindActiveByHash(ctx, hashToken(plain))
+	if err != nil {
+		return model.PersonalAccessToken{}, err
+	}
+	if !found {
+		return model.PersonalAccessToken{}, ErrInvalidPersonalToken
+	}
+
+	s.touchPersonalAccessToken(ctx, token.ID)
+	return token, nil
+}
+
+// patTouchInterval matches the minute the last-used time is rounded to in
+// storage.
+const patTouchInterval = time.Minute
+
+// touchPersonalAccessToken records that a token was used, in the
+// background and at most once per interval. The time is informational, so
+// a slow or failing write must not hold up or fail the request.
+func (s *Service) touchPersonalAccessToken(ctx context.Context, id string) {
+	now := time.Now()
+	if last, ok := s.patTouched.Load(id); ok && now.Sub(last.(time.Time)) < patTouchInterval {
+		return
+	}
+	s.patTouched.Store(id, now)
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		if err := s.PATRepo.TouchLastUsed(ctx, id); err != nil {
+			logging.FromContext(ctx).Warn("touch personal access token", "token_id", id, "err", err)
+		}
+	}()
+}
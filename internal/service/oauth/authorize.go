@@ -0,0 +1,149 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"slices"
+	"time"
+
+	"task-flow/internal/apperr"
+	"task-flow/internal/model"
+	"task-flow/internal/tracing"
+)
+
+var (
+	// ErrUnknownClient and ErrRedirectURIMismatch are not reported to the
+	// client: without a trusted redirect URI there is nowhere safe to send
+	// the user back to.
+	ErrUnknownClient       = apperr.Validation("unknown_client", "unknown client")
+	ErrRedirectURIMismatch = apperr.Validation("redirect_uri_mismatch", "redirect_uri is not registered for this client")
+)
+
+// AuthorizeRequest holds the authorization endpoint parameters.
+type AuthorizeRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// Consent is what the user is asked to approve.
+type Consent struct {
+	Client      model.OAuthClient
+	Scopes      []string
+	RedirectURI string
+}
+
+// ValidateAuthorize checks an authorization request before consent is
+// asked. Errors other than ErrUnknownClient and ErrRedirectURIMismatch are
+// *Error values meant to be sent back to the redirect URI.
+func (s *Service) ValidateAuthorize(ctx context.Context, req AuthorizeRequest) (Consent, error) {
+	ctx, span := tracing.Start(ctx, "oauth.ValidateAuthorize")
+	defer span.End()
+
+	client, found, err := s.ClientRepo.FindByID(ctx, req.ClientID)
+	if err != nil {
+		return Consent{}, err
+	}
+	if !found {
+		return Consent{}, ErrUnknownClient
+	}
+
+	redirectURI := req.RedirectURI
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+	if !slices.Contains(client.RedirectURIs, redirectURI) {
+		return Consent{}, ErrRedirectURIMismatch
+	}
+
+	consent := Consent{Client: client, RedirectURI: redirectURI}
+
+	if req.ResponseType != "code" {
+		return consent, oauthError(CodeUnsupportedResponseType, "only response_type=code is supported")
+	}
+	// PKCE is mandatory for every client, confidential ones included
+	if req.CodeChallengeMethod != "S256" || len(req.CodeChallenge) < 43 || len(req.CodeChallenge) > 128 {
+		return consent, oauthError(CodeInvalidRequest, "a S256 code_challenge is required")
+	}
+
+	consent.Scopes, err = grantScopes(req.Scope, client.Scopes)
+	if err != nil {
+		return consent, err
+	}
+	return consent, nil
+}
+
+// Authorize records userID's decision and returns the URI to send the
+// browser back to: with a code when approved, access_denied otherwise.
+func (s *Service) Authorize(ctx context.Context, userID string, req AuthorizeRequest, approved bool) (string, error) {
+	ctx, span := tracing.Start(ctx, "oauth.Authorize")
+	defer span.End()
+
+	consent, err := s.ValidateAuthorize(ctx, req)
+	var oerr *Error
+	if errors.As(err, &oerr) {
+		return ErrorRedirect(consent.RedirectURI, req.State, oerr), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if !approved {
+		return ErrorRedirect(consent.RedirectURI, req.State, oauthError(CodeAccessDenied, "the user denied the request")), nil
+	}
+
+	code, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.CodeRepo.Insert(ctx, hashToken(code), model.OAuthAuthorizationCode{
+		ClientID:      consent.Client.ID,
+		UserID:        userID,
+		RedirectURI:   consent.RedirectURI,
+		Scopes:        consent.Scopes,
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     time.Now().Add(s.CodeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return redirectWith(consent.RedirectURI, url.Values{
+		"code":  {code},
+		"state": {req.State},
+	}), nil
+}
+
+// ErrorRedirect builds the redirect carrying an authorization error.
+func ErrorRedirect(redirectURI, state string, e *Error) string {
+	params := url.Values{"error": {e.Code}}
+	if e.Description != "" {
+		params.Set("error_description", e.Description)
+	}
+	params.Set("state", state)
+	return redirectWith(redirectURI, params)
+}
+
+func redirectWith(redirectURI string, params url.Values) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		// Registered URIs are validated, so this can't happen
+		return redirectURI
+	}
+
+	q := u.Query()
+	for k, v := range params {
+		if len(v) == 0 || v[0] == "" {
+			continue
+		}
+		q.Set(k, v[0])
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
@@ -0,0 +1,258 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"task-flow/internal/apperr"
+	"task-flow/internal/model"
+	"task-flow/internal/pkg/jwt"
+	"task-flow/internal/repository"
+	"task-flow/internal/tracing"
+	"task-flow/internal/utils"
+)
+
+// ClientSecretPrefix makes client secrets recognisable to secret scanners.
+const ClientSecretPrefix = "tfcs_"
+
+// Service is the OAuth 2.0 authorization server: client registration, the
+// authorization-code grant with PKCE, refresh and client-credentials grants,
+// introspection (RFC 7662) and revocation (RFC 7009).
+type Service struct {
+	ClientRepo       repository.OAuthClientRepo
+	CodeRepo         repository.OAuthCodeRepo
+	RefreshTokenRepo repository.OAuthRefreshTokenRepo
+	Denylist         repository.AccessTokenDenylist
+
+	JWT        *jwt.JWT
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+	CodeTTL    time.Duration
+}
+
+func NewService(
+	clientRepo repository.OAuthClientRepo,
+	codeRepo repository.OAuthCodeRepo,
+	refreshRepo repository.OAuthRefreshTokenRepo,
+	denylist repository.AccessTokenDenylist,
+	jwtInstance *jwt.JWT,
+	accessTTL, refreshTTL time.Duration,
+) *Service {
+	return &Service{
+		ClientRepo:       clientRepo,
+		CodeRepo:         codeRepo,
+		RefreshTokenRepo: refreshRepo,
+		Denylist:         denylist,
+		JWT:              jwtInstance,
+		AccessTTL:        accessTTL,
+		RefreshTTL:       refreshTTL,
+		CodeTTL:          time.Minute,
+	}
+}
+
+// Error codes from RFC 6749 section 4.1.2.1 and 5.2.
+const (
+	CodeInvalidRequest          = "invalid_request"
+	CodeInvalidClient           = "invalid_client"
+	CodeInvalidGrant            = "invalid_grant"
+	CodeUnauthorizedClient      = "unauthorized_client"
+	CodeUnsupportedGrantType    = "unsupported_grant_type"
+	CodeUnsupportedResponseType = "unsupported_response_type"
+	CodeInvalidScope            = "invalid_scope"
+	CodeAccessDenied            = "access_denied"
+)
+
+// Error is an OAuth error response as defined by RFC 6749.
+type Error struct {
+	Code        string
+	Description string
+}
+
+func (e *Error) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+func oauthError(code, description string) *Error {
+	return &Error{Code: code, Description: description}
+}
+
+var (
+	ErrInvalidClientName  = apperr.Validation("invalid_client_name", "client name is required (max 100 characters)")
+	ErrInvalidRedirectURI = apperr.Validation("invalid_redirect_uri", "invalid redirect uri")
+	ErrInvalidScope       = apperr.Validation("invalid_scope", "invalid scope")
+	ErrClientNotFound     = apperr.NotFound("client_not_found", "client not found")
+)
+
+func hashToken(plain string) []byte {
+	sum := sha256.Sum256([]byte(plain))
+	return sum[:]
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// RegisterClient creates a client owned by ownerID. Confidential clients get
+// a secret, returned once; only its hash is stored.
+func (s *Service) RegisterClient(ctx context.Context, ownerID, name string, redirectURIs, scopes []string, confidential bool) (model.OAuthClient, string, error) {
+	ctx, span := tracing.Start(ctx, "oauth.RegisterClient")
+	defer span.End()
+
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return model.OAuthClient{}, "", ErrInvalidClientName
+	}
+
+	if len(redirectURIs) == 0 || len(redirectURIs) > 10 {
+		return model.OAuthClient{}, "", ErrInvalidRedirectURI
+	}
+	for _, uri := range redirectURIs {
+		if !validRedirectURI(uri) {
+			return model.OAuthClient{}, "", ErrInvalidRedirectURI
+		}
+	}
+
+	if len(scopes) == 0 {
+		return model.OAuthClient{}, "", ErrInvalidScope
+	}
+	for i, scope := range scopes {
+		if !model.IsDelegableScope(scope) || slices.Contains(scopes[:i], scope) {
+			return model.OAuthClient{}, "", ErrInvalidScope
+		}
+	}
+
+	id, err := utils.GenerateID()
+	if err != nil {
+		return model.OAuthClient{}, "", err
+	}
+
+	client := model.OAuthClient{
+		ID:           id,
+		OwnerID:      ownerID,
+		Name:         name,
+		RedirectURIs: redirectURIs,
+		Scopes:       scopes,
+		CreatedAt:    time.Now(),
+	}
+
+	var secret string
+	if confidential {
+		random, err := randomToken()
+		if err != nil {
+			return model.OAuthClient{}, "", err
+		}
+		secret = ClientSecretPrefix + random
+		client.SecretHash = hashToken(secret)
+	}
+
+	if err := s.ClientRepo.Create(ctx, client); err != nil {
+		return model.OAuthClient{}, "", err
+	}
+	return client, secret, nil
+}
+
+// validRedirectURI accepts absolute https URIs, plain http only for
+// loopback (native apps), and custom schemes; never fragments.
+func validRedirectURI(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() || u.Fragment != "" || strings.ContainsAny(raw, " \t\n") {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return u.Host != ""
+	case "http":
+		host := u.Hostname()
+		return host == "localhost" || host == "127.0.0.1" || host == "::1"
+	case "javascript", "data", "file":
+		return false
+	default:
+		return true
+	}
+}
+
+func (s *Service) ListClients(ctx context.Context, ownerID string) ([]model.OAuthClient, error) {
+	ctx, span := tracing.Start(ctx, "oauth.ListClients")
+	defer span.End()
+
+	return s.ClientRepo.ListByOwner(ctx, ownerID)
+}
+
+// DeleteClient removes the client and its refresh tokens. Access tokens
+// already issued stay valid until they expire.
+func (s *Service) DeleteClient(ctx context.Context, ownerID, id string) error {
+	ctx, span := tracing.Start(ctx, "oauth.DeleteClient")
+	defer span.End()
+
+	ok, err := s.ClientRepo.Delete(ctx, ownerID, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrClientNotFound
+	}
+	return nil
+}
+
+// AuthenticateClient checks client credentials. Public clients identify
+// themselves by id alone.
+func (s *Service) AuthenticateClient(ctx context.Context, clientID, secret string) (model.OAuthClient, error) {
+	ctx, span := tracing.Start(ctx, "oauth.AuthenticateClient")
+	defer span.End()
+
+	if clientID == "" {
+		return model.OAuthClient{}, oauthError(CodeInvalidClient, "client authentication required")
+	}
+
+	client, found, err := s.ClientRepo.FindByID(ctx, clientID)
+	if err != nil {
+		return model.OAuthClient{}, err
+	}
+	if !found {
+		return model.OAuthClient{}, oauthError(CodeInvalidClient, "unknown client")
+	}
+
+	if client.Confidential() {
+		if secret == "" || subtle.ConstantTimeCompare(hashToken(secret), client.SecretHash) != 1 {
+			return model.OAuthClient{}, oauthError(CodeInvalidClient, "client authentication failed")
+		}
+	} else if secret != "" {
+		return model.OAuthClient{}, oauthError(CodeInvalidClient, "public clients have no secret")
+	}
+
+	return client, nil
+}
+
+// grantScopes resolves a requested scope string against what the client
+// (or an earlier grant) allows. An empty request means everything allowed.
+func grantScopes(requested string, allowed []string) ([]string, error) {
+	fields := strings.Fields(requested)
+	if len(fields) == 0 {
+		return allowed, nil
+	}
+
+	var scopes []string
+	for _, scope := range fields {
+		if !slices.Contains(allowed, scope) {
+			return nil, oauthError(CodeInvalidScope, "scope "+scope+" is not allowed")
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
@@ -0,0 +1,485 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"task-flow/internal/model"
+	"task-flow/internal/pkg/jwt"
+)
+
+// ============================================
+// MOCK REPOSITORIES
+// ============================================
+
+type mockClientRepo struct {
+	clients map[string]model.OAuthClient
+}
+
+func (m *mockClientRepo) Create(ctx context.Context, client model.OAuthClient) error {
+	m.clients[client.ID] = client
+	return nil
+}
+
+func (m *mockClientRepo) FindByID(ctx context.Context, id string) (model.OAuthClient, bool, error) {
+	c, ok := m.clients[id]
+	return c, ok, nil
+}
+
+func (m *mockClientRepo) ListByOwner(ctx context.Context, ownerID string) ([]model.OAuthClient, error) {
+	var res []model.OAuthClient
+	for _, c := range m.clients {
+		if c.OwnerID == ownerID {
+			res = append(res, c)
+		}
+	}
+	return res, nil
+}
+
+func (m *mockClientRepo) Delete(ctx context.Context, ownerID, id string) (bool, error) {
+	c, ok := m.clients[id]
+	if !ok || c.OwnerID != ownerID {
+		return false, nil
+	}
+	delete(m.clients, id)
+	return true, nil
+}
+
+type mockCodeRepo struct {
+	codes map[string]model.OAuthAuthorizationCode
+}
+
+func (m *mockCodeRepo) Insert(ctx context.Context, codeHash []byte, code model.OAuthAuthorizationCode) error {
+	m.codes[string(codeHash)] = code
+	return nil
+}
+
+func (m *mockCodeRepo) Consume(ctx context.Context, codeHash []byte) (model.OAuthAuthorizationCode, bool, error) {
+	c, ok := m.codes[string(codeHash)]
+	delete(m.codes, string(codeHash))
+	if !ok || time.Now().After(c.ExpiresAt) {
+		return model.OAuthAuthorizationCode{}, false, nil
+	}
+	return c, true, nil
+}
+
+type mockRefreshRepo struct {
+	mu      sync.Mutex
+	tokens  map[string]model.OAuthRefreshToken
+	revoked map[string]bool
+}
+
+func (m *mockRefreshRepo) Insert(ctx context.Context, tokenHash []byte, token model.OAuthRefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tokens[string(tokenHash)] = token
+	return nil
+}
+
+func (m *mockRefreshRepo) FindByHash(ctx context.Context, tokenHash []byte) (model.OAuthRefreshToken, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tokens[string(tokenHash)]
+	if !ok || m.revoked[string(tokenHash)] {
+		return model.OAuthRefreshToken{}, false, nil
+	}
+	return t, true, nil
+}
+
+func (m *mockRefreshRepo) Rotate(ctx context.Context, tokenHash []byte) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.tokens[string(tokenHash)]; !ok || m.revoked[string(tokenHash)] {
+		return false, nil
+	}
+	m.revoked[string(tokenHash)] = true
+	return true, nil
+}
+
+func (m *mockRefreshRepo) RevokeByHash(ctx context.Context, tokenHash []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.revoked[string(tokenHash)] = true
+	return nil
+}
+
+type mockDenylist struct {
+	tokens map[string]int64
+}
+
+func (m *mockDenylist) Revoke(ctx context.Context, jti string, expUnix int64) error {
+	m.tokens[jti] = expUnix
+	return nil
+}
+
+func (m *mockDenylist) RevokeUser(ctx context.Context, userID string, issuedThroughUnixMicro, expUnix int64) error {
+	return nil
+}
+
+func (m *mockDenylist) IsRevoked(ctx context.Context, jti, userID string, issuedAtUnixMicro int64) (bool, error) {
+	_, ok := m.tokens[jti]
+	return ok, nil
+}
+
+// ============================================
+// HELPER
+// ============================================
+
+const redirectURI = "https://app.example.com/callback"
+
+func newTestService() *Service {
+	return NewService(
+		&mockClientRepo{clients: make(map[string]model.OAuthClient)},
+		&mockCodeRepo{codes: make(map[string]model.OAuthAuthorizationCode)},
+		&mockRefreshRepo{tokens: make(map[string]model.OAuthRefreshToken), revoked: make(map[string]bool)},
+		&mockDenylist{tokens: make(map[string]int64)},
+		jwt.New([]byte("test-secret")),
+		15*time.Minute,
+		30*24*time.Hour,
+	)
+}
+
+func registerClient(t *testing.T, svc *Service, confidential bool) (model.OAuthClient, string) {
+	t.Helper()
+	client, secret, err := svc.RegisterClient(context.Background(), "owner-1", "Test App",
+		[]string{redirectURI}, []string{model.ScopeTasksRead, model.ScopeTasksWrite}, confidential)
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	return client, secret
+}
+
+func pkce() (verifier, challenge string) {
+	verifier = strings.Repeat("v", 43)
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// authorize approves a request as user-1 and returns the issued code.
+func authorize(t *testing.T, svc *Service, client model.OAuthClient, scope, challenge string) string {
+	t.Helper()
+	redirect, err := svc.Authorize(context.Background(), "user-1", AuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            client.ID,
+		RedirectURI:         redirectURI,
+		Scope:               scope,
+		State:               "xyz",
+		CodeChallenge:       challenge,
+		CodeChallengeMethod: "S256",
+	}, true)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+
+	u, _ := url.Parse(redirect)
+	if u.Query().Get("state") != "xyz" || u.Query().Get("code") == "" {
+		t.Fatalf("unexpected redirect %s", redirect)
+	}
+	return u.Query().Get("code")
+}
+
+func oauthCode(err error) string {
+	var oerr *Error
+	if errors.As(err, &oerr) {
+		return oerr.Code
+	}
+	return ""
+}
+
+// ============================================
+// TESTS
+// ============================================
+
+func TestRegisterClient_Validation(t *testing.T) {
+	svc := newTestService()
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		uris   []string
+		scopes []string
+		want   error
+	}{
+		{"plain http", []string{"http://app.example.com/cb"}, []string{model.ScopeTasksRead}, ErrInvalidRedirectURI},
+		{"fragment", []string{"https://app.example.com/cb#x"}, []string{model.ScopeTasksRead}, ErrInvalidRedirectURI},
+		{"relative", []string{"/cb"}, []string{model.ScopeTasksRead}, ErrInvalidRedirectURI},
+		{"account scope", []string{redirectURI}, []string{model.ScopeAccountManage}, ErrInvalidScope},
+		{"no scopes", []string{redirectURI}, nil, ErrInvalidScope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := svc.RegisterClient(ctx, "owner-1", "App", tt.uris, tt.scopes, false); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if _, _, err := svc.RegisterClient(ctx, "owner-1", "CLI", []string{"http://127.0.0.1:8765/cb"}, []string{model.ScopeTasksRead}, false); err != nil {
+		t.Errorf("expected loopback redirect to be allowed, got %v", err)
+	}
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	svc := newTestService()
+	ctx := context.Background()
+	client, secret := registerClient(t, svc, true)
+	verifier, challenge := pkce()
+
+	code := authorize(t, svc, client, model.ScopeTasksRead, challenge)
+
+	authed, err := svc.AuthenticateClient(ctx, client.ID, secret)
+	if err != nil {
+		t.Fatalf("client auth: %v", err)
+	}
+	res, err := svc.Token(ctx, authed, TokenRequest{
+		GrantType:    "authorization_code",
+		Code:         code,
+		RedirectURI:  redirectURI,
+		CodeVerifier: verifier,
+	})
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	if res.Scope != model.ScopeTasksRead || res.RefreshToken == "" || res.TokenType != "Bearer" {
+		t.Fatalf("unexpected response %+v", res)
+	}
+
+	claims, err := svc.JWT.Parse(res.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "user-1" || claims.ClientID != client.ID || claims.Scope != model.ScopeTasksRead {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+
+	// Codes are single-use
+	_, err = svc.Token(ctx, authed, TokenRequest{
+		GrantType:    "authorization_code",
+		Code:         code,
+		RedirectURI:  redirectURI,
+		CodeVerifier: verifier,
+	})
+	if oauthCode(err) != CodeInvalidGrant {
+		t.Errorf("expected invalid_grant on code reuse, got %v", err)
+	}
+}
+
+func TestAuthorizationCode_WrongVerifier(t *testing.T) {
+	svc := newTestService()
+	client, _ := registerClient(t, svc, false)
+	_, challenge := pkce()
+
+	code := authorize(t, svc, client, "", challenge)
+	_, err := svc.Token(context.Background(), client, TokenRequest{
+		GrantType:    "authorization_code",
+		Code:         code,
+		RedirectURI:  redirectURI,
+		CodeVerifier: strings.Repeat("w", 43),
+	})
+	if oauthCode(err) != CodeInvalidGrant {
+		t.Errorf("expected invalid_grant, got %v", err)
+	}
+}
+
+func TestAuthorize_Errors(t *testing.T) {
+	svc := newTestService()
+	ctx := context.Background()
+	client, _ := registerClient(t, svc, false)
+	_, challenge := pkce()
+
+	base := AuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            client.ID,
+		RedirectURI:         redirectURI,
+		State:               "xyz",
+		CodeChallenge:       challenge,
+		CodeChallengeMethod: "S256",
+	}
+
+	req := base
+	req.RedirectURI = "https://evil.example/cb"
+	if _, err := svc.Authorize(ctx, "user-1", req, true); !errors.Is(err, ErrRedirectURIMismatch) {
+		t.Errorf("expected ErrRedirectURIMismatch, got %v", err)
+	}
+
+	req = base
+	req.ClientID = "nope"
+	if _, err := svc.Authorize(ctx, "user-1", req, true); !errors.Is(err, ErrUnknownClient) {
+		t.Errorf("expected ErrUnknownClient, got %v", err)
+	}
+
+	// Redirectable errors go back to the client
+	req = base
+	req.CodeChallenge = ""
+	redirect, err := svc.Authorize(ctx, "user-1", req, true)
+	if err != nil || !strings.Contains(redirect, "error=invalid_request") {
+		t.Errorf("expected invalid_request redirect, got %q, %v", redirect, err)
+	}
+
+	req = base
+	req.Scope = model.ScopeUserRead
+	redirect, _ = svc.Authorize(ctx, "user-1", req, true)
+	if !strings.Contains(redirect, "error=invalid_scope") {
+		t.Errorf("expected invalid_scope redirect, got %q", redirect)
+	}
+
+	redirect, _ = svc.Authorize(ctx, "user-1", base, false)
+	if !strings.Contains(redirect, "error=access_denied") || !strings.Contains(redirect, "state=xyz") {
+		t.Errorf("expected access_denied redirect, got %q", redirect)
+	}
+}
+
+func TestRefreshGrant_RotatesAndNarrows(t *testing.T) {
+	svc := newTestService()
+	ctx := context.Background()
+	client, _ := registerClient(t, svc, false)
+	verifier, challenge := pkce()
+
+	code := authorize(t, svc, client, "", challenge)
+	first, err := svc.Token(ctx, client, TokenRequest{GrantType: "authorization_code", Code: code, RedirectURI: redirectURI, CodeVerifier: verifier})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svc.Token(ctx, client, TokenRequest{GrantType: "refresh_token", RefreshToken: first.RefreshToken, Scope: model.ScopeUserRead}); oauthCode(err) != CodeInvalidScope {
+		t.Errorf("expected invalid_scope when widening, got %v", err)
+	}
+
+	second, err := svc.Token(ctx, client, TokenRequest{GrantType: "refresh_token", RefreshToken: first.RefreshToken, Scope: model.ScopeTasksRead})
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if second.Scope != model.ScopeTasksRead || second.RefreshToken == first.RefreshToken {
+		t.Errorf("unexpected refresh response %+v", second)
+	}
+
+	if _, err := svc.Token(ctx, client, TokenRequest{GrantType: "refresh_token", RefreshToken: first.RefreshToken}); oauthCode(err) != CodeInvalidGrant {
+		t.Errorf("expected rotated token to be rejected, got %v", err)
+	}
+}
+
+func TestRefreshGrant_ConcurrentUseIssuesOnce(t *testing.T) {
+	svc := newTestService()
+	ctx := context.Background()
+	client, _ := registerClient(t, svc, false)
+	verifier, challenge := pkce()
+
+	code := authorize(t, svc, client, "", challenge)
+	first, err := svc.Token(ctx, client, TokenRequest{GrantType: "authorization_code", Code: code, RedirectURI: redirectURI, CodeVerifier: verifier})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = svc.Token(ctx, client, TokenRequest{GrantType: "refresh_token", RefreshToken: first.RefreshToken})
+		}()
+	}
+	wg.Wait()
+
+	granted := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			granted++
+		case oauthCode(err) != CodeInvalidGrant:
+			t.Errorf("expected invalid_grant, got %v", err)
+		}
+	}
+	if granted != 1 {
+		t.Errorf("expected exactly one new token pair, got %d", granted)
+	}
+}
+
+func TestClientCredentials(t *testing.T) {
+	svc := newTestService()
+	ctx := context.Background()
+
+	public, _ := registerClient(t, svc, false)
+	if _, err := svc.Token(ctx, public, TokenRequest{GrantType: "client_credentials"}); oauthCode(err) != CodeUnauthorizedClient {
+		t.Errorf("expected unauthorized_client for public client, got %v", err)
+	}
+
+	confidential, secret := registerClient(t, svc, true)
+	if _, err := svc.AuthenticateClient(ctx, confidential.ID, "wrong"); oauthCode(err) != CodeInvalidClient {
+		t.Errorf("expected invalid_client, got %v", err)
+	}
+	authed, err := svc.AuthenticateClient(ctx, confidential.ID, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := svc.Token(ctx, authed, TokenRequest{GrantType: "client_credentials"})
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	if res.RefreshToken != "" {
+		t.Error("expected no refresh token for client_credentials")
+	}
+	claims, _ := svc.JWT.Parse(res.AccessToken)
+	if claims.Subject != "owner-1" || !slices.Equal(strings.Fields(claims.Scope), confidential.Scopes) {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+}
+
+func TestIntrospectAndRevoke(t *testing.T) {
+	svc := newTestService()
+	ctx := context.Background()
+	client, _ := registerClient(t, svc, true)
+	other, _ := registerClient(t, svc, true)
+	verifier, challenge := pkce()
+
+	code := authorize(t, svc, client, "", challenge)
+	res, err := svc.Token(ctx, client, TokenRequest{GrantType: "authorization_code", Code: code, RedirectURI: redirectURI, CodeVerifier: verifier})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := svc.Introspect(ctx, client, res.AccessToken)
+	if err != nil || !info.Active || info.Subject != "user-1" || info.TokenType != "access_token" {
+		t.Fatalf("unexpected introspection %+v, %v", info, err)
+	}
+	if info, _ := svc.Introspect(ctx, other, res.AccessToken); info.Active {
+		t.Error("expected other clients to see the token as inactive")
+	}
+	if info, _ := svc.Introspect(ctx, client, res.RefreshToken); !info.Active || info.TokenType != "refresh_token" {
+		t.Errorf("expected active refresh token, got %+v", info)
+	}
+
+	// Another client can't revoke it either
+	if err := svc.Revoke(ctx, other, res.AccessToken); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := svc.Introspect(ctx, client, res.AccessToken); !info.Active {
+		t.Error("expected token to survive revocation by another client")
+	}
+
+	if err := svc.Revoke(ctx, client, res.AccessToken); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Revoke(ctx, client, res.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := svc.Introspect(ctx, client, res.AccessToken); info.Active {
+		t.Error("expected revoked access token to be inactive")
+	}
+	if info, _ := svc.Introspect(ctx, client, res.RefreshToken); info.Active {
+		t.Error("expected revoked refresh token to be inactive")
+	}
+
+	if err := svc.Revoke(ctx, client, "garbage"); err != nil {
+		t.Errorf("expected unknown tokens to be ignored, got %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"task-flow/internal/model"
+	"task-flow/internal/tracing"
+)
+
+// TokenRequest holds the token endpoint parameters after client
+// authentication.
+type TokenRequest struct {
+	GrantType    string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	RefreshToken string
+	Scope        string
+}
+
+// TokenResponse is the RFC 6749 section 5.1 success response.
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope"`
+}
+
+func (s *Service) Token(ctx context.Context, client model.OAuthClient, req TokenRequest) (TokenResponse, error) {
+	ctx, span := tracing.Start(ctx, "oauth.Token")
+	defer span.End()
+
+	switch req.GrantType {
+	case "authorization_code":
+		return s.exchangeCode(ctx, client, req)
+	case "refresh_token":
+		return s.refresh(ctx, client, req)
+	case "client_credentials":
+		return s.clientCredentials(ctx, client, req)
+	case "":
+		return TokenResponse{}, oauthError(CodeInvalidRequest, "grant_type is required")
+	default:
+		return TokenResponse{}, oauthError(CodeUnsupportedGrantType, "")
+	}
+}
+
+func (s *Service) exchangeCode(ctx context.Context, client model.OAuthClient, req TokenRequest) (TokenResponse, error) {
+	if req.Code == "" || req.CodeVerifier == "" {
+		return TokenResponse{}, oauthError(CodeInvalidRequest, "code and code_verifier are required")
+	}
+
+	code, found, err := s.CodeRepo.Consume(ctx, hashToken(req.Code))
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	if !found || code.ClientID != client.ID {
+		return TokenResponse{}, oauthError(CodeInvalidGrant, "invalid or expired code")
+	}
+	if req.RedirectURI != code.RedirectURI {
+		return TokenResponse{}, oauthError(CodeInvalidGrant, "redirect_uri does not match")
+	}
+
+	sum := sha256.Sum256([]byte(req.CodeVerifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	if subtle.ConstantTimeCompare([]byte(challenge), []byte(code.CodeChallenge)) != 1 {
+		return TokenResponse{}, oauthError(CodeInvalidGrant, "code_verifier does not match")
+	}
+
+	return s.issue(ctx, client.ID, code.UserID, code.Scopes, true)
+}
+
+func (s *Service) refresh(ctx context.Context, client model.OAuthClient, req TokenRequest) (TokenResponse, error) {
+	if req.RefreshToken == "" {
+		return TokenResponse{}, oauthError(CodeInvalidRequest, "refresh_token is required")
+	}
+
+	hash := hashToken(req.RefreshToken)
+	token, found, err := s.RefreshTokenRepo.FindByHash(ctx, hash)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	if !found || token.ClientID != client.ID {
+		return TokenResponse{}, oauthError(CodeInvalidGrant, "invalid or expired refresh token")
+	}
+
+	// A refresh may narrow the grant but never widen it
+	scopes, err := grantScopes(req.Scope, token.Scopes)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	// Token rotation: revoke old, issue new. Of concurrent requests with
+	// the same token only one gets through.
+	rotated, err := s.RefreshTokenRepo.Rotate(ctx, hash)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	if !rotated {
+		return TokenResponse{}, oauthError(CodeInvalidGrant, "invalid or expired refresh token")
+	}
+
+	return s.issue(ctx, client.ID, token.UserID, scopes, true)
+}
+
+// clientCredentials lets a confidential client act for the user who
+// registered it, within the client's scopes. No refresh token is issued;
+// the client can simply ask again.
+func (s *Service) clientCredentials(ctx context.Context, client model.OAuthClient, req TokenRequest) (TokenResponse, error) {
+	if !client.Confidential() {
+		return TokenResponse{}, oauthError(CodeUnauthorizedClient, "public clients cannot use client_credentials")
+	}
+
+	scopes, err := grantScopes(req.Scope, client.Scopes)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	return s.issue(ctx, client.ID, client.OwnerID, scopes, false)
+}
+
+func (s *Service) issue(ctx context.Context, clientID, userID string, scopes []string, withRefresh bool) (TokenResponse, error) {
+	access, err := s.JWT.SignDelegated(userID, clientID, scopes, s.AccessTTL)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	res := TokenResponse{
+		AccessToken: access,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.AccessTTL.Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}
+	if !withRefresh {
+		return res, nil
+	}
+
+	refresh, err := randomToken()
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	err = s.RefreshTokenRepo.Insert(ctx, hashToken(refresh), model.OAuthRefreshToken{
+		ClientID:  clientID,
+		UserID:    userID,
+		Scopes:    scopes,
+		ExpiresAt: time.Now().Add(s.RefreshTTL),
+	})
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	res.RefreshToken = refresh
+
+	return res, nil
+}
+
+// Introspection is the RFC 7662 response. Only Active is set for tokens
+// that are invalid or belong to another client.
+type Introspection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+}
+
+// Introspect reports on a token issued to client. Clients can't probe
+// tokens belonging to someone else.
+func (s *Service) Introspect(ctx context.Context, client model.OAuthClient, token string) (Introspection, error) {
+	ctx, span := tracing.Start(ctx, "oauth.Introspect")
+	defer span.End()
+
+	if claims, err := s.JWT.Parse(token); err == nil {
+		if claims.ClientID != client.ID || claims.Purpose != "" {
+			return Introspection{}, nil
+		}
+		revoked, err := s.Denylist.IsRevoked(ctx, claims.ID, claims.Subject, claims.IssuedAtUnixMicro())
+		if err != nil {
+			return Introspection{}, err
+		}
+		if revoked {
+			return Introspection{}, nil
+		}
+		return Introspection{
+			Active:    true,
+			Scope:     claims.Scope,
+			ClientID:  claims.ClientID,
+			Subject:   claims.Subject,
+			TokenType: "access_token",
+			IssuedAt:  claims.IssuedAt,
+			ExpiresAt: claims.ExpiresAt,
+		}, nil
+	}
+
+	rt, found, err := s.RefreshTokenRepo.FindByHash(ctx, hashToken(token))
+	if err != nil {
+		return Introspection{}, err
+	}
+	if !found || rt.ClientID != client.ID {
+		return Introspection{}, nil
+	}
+	return Introspection{
+		Active:    true,
+		Scope:     strings.Join(rt.Scopes, " "),
+		ClientID:  rt.ClientID,
+		Subject:   rt.UserID,
+		TokenType: "refresh_token",
+		ExpiresAt: rt.ExpiresAt.Unix(),
+	}, nil
+}
+
+// Revoke invalidates an access or refresh token of client. Unknown tokens
+// are ignored, as RFC 7009 requires.
+func (s *Service) Revoke(ctx context.Context, client model.OAuthClient, token string) error {
+	ctx, span := tracing.Start(ctx, "oauth.Revoke")
+	defer span.End()
+
+	if claims, err := s.JWT.Parse(token); err == nil {
+		if claims.ClientID != client.ID || claims.ID == "" {
+			return nil
+		}
+		return s.Denylist.Revoke(ctx, claims.ID, claims.ExpiresAt)
+	}
+
+	hash := hashToken(token)
+	rt, found, err := s.RefreshTokenRepo.FindByHash(ctx, hash)
+	if err != nil {
+		return err
+	}
+	if !found || rt.ClientID != client.ID {
+		return nil
+	}
+	return s.RefreshTokenRepo.RevokeByHash(ctx, hash)
+}
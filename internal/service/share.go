@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"task-flow/internal/apperr"
+	"task-flow/internal/model"
+	"task-flow/internal/repository"
+	"task-flow/internal/tracing"
+	"task-flow/internal/utils"
+)
+
+const maxCommentLength = 5000
+
+var (
+	ErrUserNotFound      = apperr.NotFound("user_not_found", "user not found")
+	ErrInvalidLevel      = apperr.Validation("invalid_level", "level must be view, comment or edit")
+	ErrShareNotFound     = apperr.NotFound("share_not_found", "share not found")
+	ErrLinkNotFound      = apperr.NotFound("link_not_found", "link not found")
+	ErrInvalidExpiry     = apperr.Validation("invalid_expiry", "expires_at must be in the future")
+	ErrInvalidComment    = apperr.Validation("invalid_comment", "comment must be 1-5000 characters")
+	ErrCannotShareToSelf = apperr.Conflict("share_to_self", "you already have access to this task")
+)
+
+// ListShared returns the tasks shared with userID across all workspaces.
+func (s *Service) ListShared(ctx context.Context, userID string) ([]model.SharedTask, error) {
+	ctx, span := tracing.Start(ctx, "task.ListShared")
+	defer span.End()
+
+	return s.ShareRepo.ListTasksForUser(ctx, userID)
+}
+
+// ShareTask grants the account with email access to a task loaded through
+// Authorize with ActionShare. Sharing again changes the level.
+func (s *Service) ShareTask(ctx context.Context, actorID string, task model.Task, email string, level model.AccessLevel) (model.Share, error) {
+	ctx, span := tracing.Start(ctx, "task.ShareTask")
+	defer span.End()
+
+	if !level.Valid() {
+		return model.Share{}, ErrInvalidLevel
+	}
+
+	user, found, err := s.UserRepo.FindByEmail(ctx, strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		return model.Share{}, err
+	}
+	if !found {
+		return model.Share{}, ErrUserNotFound
+	}
+	if user.ID == actorID {
+		return model.Share{}, ErrCannotShareToSelf
+	}
+
+	share := model.Share{
+		WorkspaceID: task.WorkspaceID,
+		Resource:    model.ShareTask,
+		ResourceID:  task.ID,
+		UserID:      user.ID,
+		Email:       user.Email,
+		Level:       level,
+		CreatedBy:   actorID,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.ShareRepo.Set(ctx, share); err != nil {
+		return model.Share{}, err
+	}
+	return share, nil
+}
+
+func (s *Service) ListTaskShares(ctx context.Context, task model.Task) ([]model.Share, error) {
+	ctx, span := tracing.Start(ctx, "task.ListTaskShares")
+	defer span.End()
+
+	return s.ShareRepo.List(ctx, model.ShareTask, task.ID)
+}
+
+func (s *Service) UnshareTask(ctx context.Context, task model.Task, userID string) error {
+	ctx, span := tracing.Start(ctx, "task.UnshareTask")
+	defer span.End()
+
+	deleted, err := s.ShareRepo.Delete(ctx, model.ShareTask, task.ID, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrShareNotFound
+	}
+	return nil
+}
+
+func newLinkToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashLinkToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// CreateLink makes a public read-only link to task. The token is returned
+// only here; expiresAt is optional.
+func (s *Service) CreateLink(ctx context.Context, actorID string, task model.Task, expiresAt *time.Time) (model.TaskLink, string, error) {
+	ctx, span := tracing.Start(ctx, "task.CreateLink")
+	defer span.End()
+
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return model.TaskLink{}, "", ErrInvalidExpiry
+	}
+
+	id, err := utils.GenerateID()
+	if err != nil {
+		return model.TaskLink{}, "", err
+	}
+	token, err := newLinkToken()
+	if err != nil {
+		return model.TaskLink{}, "", err
+	}
+
+	link := model.TaskLink{
+		ID:          id,
+		WorkspaceID: task.WorkspaceID,
+		TaskID:      task.ID,
+		TokenHash:   hashLinkToken(token),
+		CreatedBy:   actorID,
+		ExpiresAt:   expiresAt,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.LinkRepo.Create(ctx, link); err != nil {
+		return model.TaskLink{}, "", err
+	}
+	return link, token, nil
+}
+
+func (s *Service) ListLinks(ctx context.Context, task model.Task) ([]model.TaskLink, error) {
+	ctx, span := tracing.Start(ctx, "task.ListLinks")
+	defer span.End()
+
+	return s.LinkRepo.List(ctx, task.ID)
+}
+
+func (s *Service) RevokeLink(ctx context.Context, task model.Task, linkID string) error {
+	ctx, span := tracing.Start(ctx, "task.RevokeLink")
+	defer span.End()
+
+	revoked, err := s.LinkRepo.Revoke(ctx, task.ID, linkID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrLinkNotFound
+	}
+	return nil
+}
+
+// PublicTask resolves a public link. It needs no authenticated user: the
+// token is the credential.
+func (s *Service) PublicTask(ctx context.Context, token string) (model.Task, error) {
+	ctx, span := tracing.Start(ctx, "task.PublicTask")
+	defer span.End()
+
+	if token == "" {
+		return model.Task{}, ErrLinkNotFound
+	}
+
+	link, found, err := s.LinkRepo.FindByTokenHash(ctx, hashLinkToken(token))
+	if err != nil {
+		return model.Task{}, err
+	}
+	if !found {
+		return model.Task{}, ErrLinkNotFound
+	}
+
+	task, err := s.findTask(repository.WithWorkspace(ctx, link.WorkspaceID), link.TaskID)
+	if errors.Is(err, ErrTaskNotFound) {
+		return model.Task{}, ErrLinkNotFound
+	}
+	return task, err
+}
+
+func (s *Service) AddComment(ctx context.Context, userID string, task model.Task, body string) (model.TaskComment, error) {
+	ctx, span := tracing.Start(ctx, "task.AddComment")
+	defer span.End()
+
+	body = strings.TrimSpace(body)
+	if body == "" || utf8.RuneCountInString(body) > maxCommentLength {
+		return model.TaskComment{}, ErrInvalidComment
+	}
+
+	id, err := utils.GenerateID()
+	if err != nil {
+		return model.TaskComment{}, err
+	}
+
+	comment := model.TaskComment{
+		ID:          id,
+		WorkspaceID: task.WorkspaceID,
+		TaskID:      task.ID,
+		UserID:      userID,
+		Body:        body,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.CommentRepo.Add(ctx, comment); err != nil {
+		return model.TaskComment{}, err
+	}
+	return comment, nil
+}
+
+func (s *Service) ListComments(ctx context.Context, task model.Task) ([]model.TaskComment, error) {
+	ctx, span := tracing.Start(ctx, "task.ListComments")
+	defer span.End()
+
+	return s.CommentRepo.List(ctx, task.ID)
+}
@@ -2,23 +2,56 @@ package service
 
 import (
 	"context"
+	"time"
 
+	"task-flow/internal/apperr"
+	"task-flow/internal/metrics"
 	"task-flow/internal/model"
 	"task-flow/internal/repository"
+	"task-flow/internal/tracing"
 	"task-flow/internal/utils"
 )
 
+// ErrProjectNotFound is returned when a task names a project outside the
+// current workspace.
+var ErrProjectNotFound = apperr.Validation("project_not_found", "project not found")
+
+// Service works on the workspace in the context; see repository.WithWorkspace.
+// Callers check access with Authorize first.
 type Service struct {
-	TaskRepo repository.TaskRepo
+	TaskRepo    repository.TaskRepo
+	ProjectRepo repository.ProjectRepo
+	ShareRepo   repository.ShareRepo
+	LinkRepo    repository.TaskLinkRepo
+	CommentRepo repository.TaskCommentRepo
+	UserRepo    repository.UserRepo
 }
 
-func NewServiceTask(task repository.TaskRepo) *Service {
+func NewServiceTask(task repository.TaskRepo, project repository.ProjectRepo, shares repository.ShareRepo, links repository.TaskLinkRepo, comments repository.TaskCommentRepo, users repository.UserRepo) *Service {
 	return &Service{
-		TaskRepo: task,
+		TaskRepo:    task,
+		ProjectRepo: project,
+		ShareRepo:   shares,
+		LinkRepo:    links,
+		CommentRepo: comments,
+		UserRepo:    users,
 	}
 }
 
-func (s *Service) AddTask(ctx context.Context, title, description string) error {
+func (s *Service) AddTask(ctx context.Context, title, description string, projectID *string) error {
+	ctx, span := tracing.Start(ctx, "task.AddTask")
+	defer span.End()
+
+	if projectID != nil {
+		_, found, err := s.ProjectRepo.FindByID(ctx, *projectID)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ErrProjectNotFound
+		}
+	}
+
 	id, err := utils.GenerateID()
 	if err != nil {
 		return err
@@ -26,14 +59,22 @@ func (s *Service) AddTask(ctx context.Context, title, description string) error
 
 	task := model.Task{
 		ID:          id,
+		ProjectID:   projectID,
 		Title:       title,
 		Description: description,
 	}
 
-	return s.TaskRepo.AddTask(ctx, task)
+	if err := s.TaskRepo.AddTask(ctx, task); err != nil {
+		return err
+	}
+	metrics.TasksCreated.Inc()
+	return nil
 }
 
 func (s *Service) GetTasks(ctx context.Context) ([]model.Task, error) {
+	ctx, span := tracing.Start(ctx, "task.GetTasks")
+	defer span.End()
+
 	tasks, err := s.TaskRepo.GetTasks(ctx)
 	if err != nil {
 		return nil, err
@@ -43,10 +84,16 @@ func (s *Service) GetTasks(ctx context.Context) ([]model.Task, error) {
 }
 
 func (s *Service) DeleteTask(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "task.DeleteTask")
+	defer span.End()
+
 	return s.TaskRepo.DeleteTask(ctx, id)
 }
 
 func (s *Service) FindByID(ctx context.Context, id string) (model.Task, error) {
+	ctx, span := tracing.Start(ctx, "task.FindByID")
+	defer span.End()
+
 	task, err := s.TaskRepo.FindByID(ctx, id)
 	if err != nil {
 		return model.Task{}, err
@@ -54,3 +101,29 @@ func (s *Service) FindByID(ctx context.Context, id string) (model.Task, error) {
 
 	return task, nil
 }
+
+// UpdateTask changes the title, description and completion of a task
+// loaded through Authorize. A task that is already done keeps the time it
+// was completed.
+func (s *Service) UpdateTask(ctx context.Context, task model.Task, title, description string, completed bool) (model.Task, error) {
+	ctx, span := tracing.Start(ctx, "task.UpdateTask")
+	defer span.End()
+
+	completing := completed && task.CompletedAt == nil
+	task.Title = title
+	task.Description = description
+	switch {
+	case completing:
+		now := time.Now()
+		task.CompletedAt = &now
+	case !completed:
+		task.CompletedAt = nil
+	}
+	if err := s.TaskRepo.UpdateTask(ctx, task); err != nil {
+		return model.Task{}, err
+	}
+	if completing {
+		metrics.TasksCompleted.Inc()
+	}
+	return task, nil
+}
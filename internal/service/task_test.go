@@ -0,0 +1,495 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"task-flow/internal/metrics"
+	"task-flow/internal/model"
+	"task-flow/internal/repository"
+)
+
+// ============================================
+// MOCK REPOSITORIES
+// ============================================
+
+// mockTaskRepo, like the MySQL implementation, only ever touches the
+// workspace in the context.
+type mockTaskRepo struct {
+	tasks map[string]model.Task
+}
+
+func (m *mockTaskRepo) AddTask(ctx context.Context, task model.Task) error {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+	task.WorkspaceID = id
+	m.tasks[task.ID] = task
+	return nil
+}
+
+func (m *mockTaskRepo) GetTasks(ctx context.Context) ([]model.Task, error) {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var res []model.Task
+	for _, t := range m.tasks {
+		if t.WorkspaceID == id {
+			res = append(res, t)
+		}
+	}
+	return res, nil
+}
+
+func (m *mockTaskRepo) UpdateTask(ctx context.Context, task model.Task) error {
+	current, err := m.FindByID(ctx, task.ID)
+	if err != nil || current.ID == "" {
+		return err
+	}
+	current.Title, current.Description, current.CompletedAt = task.Title, task.Description, task.CompletedAt
+	m.tasks[task.ID] = current
+	return nil
+}
+
+func (m *mockTaskRepo) DeleteTask(ctx context.Context, taskID string) error {
+	current, err := m.FindByID(ctx, taskID)
+	if err != nil || current.ID == "" {
+		return err
+	}
+	delete(m.tasks, taskID)
+	return nil
+}
+
+func (m *mockTaskRepo) FindByID(ctx context.Context, taskID string) (model.Task, error) {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return model.Task{}, err
+	}
+	t, ok := m.tasks[taskID]
+	if !ok || t.WorkspaceID != id {
+		return model.Task{}, nil
+	}
+	return t, nil
+}
+
+// mockShareRepo only holds task shares; project shares reach tasks through
+// the same TaskLevel lookup in MySQL.
+type mockShareRepo struct {
+	shares map[string]model.Share // task/user -> share
+}
+
+func shareKey(taskID, userID string) string { return taskID + "/" + userID }
+
+func (m *mockShareRepo) Set(ctx context.Context, share model.Share) error {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+	share.WorkspaceID = id
+	m.shares[shareKey(share.ResourceID, share.UserID)] = share
+	return nil
+}
+
+func (m *mockShareRepo) List(ctx context.Context, resource model.ShareResource, resourceID string) ([]model.Share, error) {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var res []model.Share
+	for _, s := range m.shares {
+		if s.WorkspaceID == id && s.Resource == resource && s.ResourceID == resourceID {
+			res = append(res, s)
+		}
+	}
+	return res, nil
+}
+
+func (m *mockShareRepo) Delete(ctx context.Context, resource model.ShareResource, resourceID, userID string) (bool, error) {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return false, err
+	}
+	s, ok := m.shares[shareKey(resourceID, userID)]
+	if !ok || s.WorkspaceID != id {
+		return false, nil
+	}
+	delete(m.shares, shareKey(resourceID, userID))
+	return true, nil
+}
+
+func (m *mockShareRepo) TaskLevel(ctx context.Context, userID, taskID string) (model.AccessLevel, error) {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return model.AccessNone, err
+	}
+	s, ok := m.shares[shareKey(taskID, userID)]
+	if !ok || s.WorkspaceID != id {
+		return model.AccessNone, nil
+	}
+	return s.Level, nil
+}
+
+func (m *mockShareRepo) FindTaskGrant(ctx context.Context, userID, taskID string) (string, model.AccessLevel, bool, error) {
+	s, ok := m.shares[shareKey(taskID, userID)]
+	return s.WorkspaceID, s.Level, ok, nil
+}
+
+func (m *mockShareRepo) ListTasksForUser(ctx context.Context, userID string) ([]model.SharedTask, error) {
+	return nil, nil
+}
+
+type mockLinkRepo struct {
+	links map[string]model.TaskLink
+}
+
+func (m *mockLinkRepo) Create(ctx context.Context, link model.TaskLink) error {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+	link.WorkspaceID = id
+	m.links[link.ID] = link
+	return nil
+}
+
+func (m *mockLinkRepo) List(ctx context.Context, taskID string) ([]model.TaskLink, error) {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var res []model.TaskLink
+	for _, l := range m.links {
+		if l.WorkspaceID == id && l.TaskID == taskID {
+			res = append(res, l)
+		}
+	}
+	return res, nil
+}
+
+func (m *mockLinkRepo) Revoke(ctx context.Context, taskID, linkID string) (bool, error) {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return false, err
+	}
+	l, ok := m.links[linkID]
+	if !ok || l.WorkspaceID != id || l.TaskID != taskID {
+		return false, nil
+	}
+	delete(m.links, linkID)
+	return true, nil
+}
+
+func (m *mockLinkRepo) FindByTokenHash(ctx context.Context, hash []byte) (model.TaskLink, bool, error) {
+	for _, l := range m.links {
+		if bytes.Equal(l.TokenHash, hash) && (l.ExpiresAt == nil || l.ExpiresAt.After(time.Now())) {
+			return l, true, nil
+		}
+	}
+	return model.TaskLink{}, false, nil
+}
+
+type mockCommentRepo struct {
+	comments []model.TaskComment
+}
+
+func (m *mockCommentRepo) Add(ctx context.Context, comment model.TaskComment) error {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+	comment.WorkspaceID = id
+	m.comments = append(m.comments, comment)
+	return nil
+}
+
+func (m *mockCommentRepo) List(ctx context.Context, taskID string) ([]model.TaskComment, error) {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var res []model.TaskComment
+	for _, c := range m.comments {
+		if c.WorkspaceID == id && c.TaskID == taskID {
+			res = append(res, c)
+		}
+	}
+	return res, nil
+}
+
+func (m *mockCommentRepo) ListByUser(ctx context.Context, userID string) ([]model.TaskComment, error) {
+	var res []model.TaskComment
+	for _, c := range m.comments {
+		if c.UserID == userID {
+			res = append(res, c)
+		}
+	}
+	return res, nil
+}
+
+type mockUserRepo struct {
+	repository.UserRepo
+	users map[string]model.User // email -> user
+}
+
+func (m *mockUserRepo) FindByEmail(ctx context.Context, email string) (model.User, bool, error) {
+	u, ok := m.users[email]
+	return u, ok, nil
+}
+
+// ============================================
+// HELPER
+// ============================================
+
+type fixture struct {
+	svc   *Service
+	links *mockLinkRepo
+	// ws is scoped to workspace "ws-a", which holds task "task-a"
+	ws   context.Context
+	task model.Task
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	tasks := &mockTaskRepo{tasks: make(map[string]model.Task)}
+	links := &mockLinkRepo{links: make(map[string]model.TaskLink)}
+	users := &mockUserRepo{users: map[string]model.User{
+		"owner@example.com":  {ID: "owner", Email: "owner@example.com"},
+		"viewer@example.com": {ID: "viewer", Email: "viewer@example.com"},
+		"guest@example.com":  {ID: "guest", Email: "guest@example.com"},
+	}}
+	svc := NewServiceTask(tasks, nil, &mockShareRepo{shares: make(map[string]model.Share)}, links, &mockCommentRepo{}, users)
+
+	ws := repository.WithWorkspace(context.Background(), "ws-a")
+	task := model.Task{ID: "task-a", Title: "Write report"}
+	if err := tasks.AddTask(ws, task); err != nil {
+		t.Fatal(err)
+	}
+	other := repository.WithWorkspace(context.Background(), "ws-b")
+	if err := tasks.AddTask(other, model.Task{ID: "task-b", Title: "Other"}); err != nil {
+		t.Fatal(err)
+	}
+
+	task.WorkspaceID = "ws-a"
+	return &fixture{svc: svc, links: links, ws: ws, task: task}
+}
+
+var (
+	owner  = Actor{UserID: "owner", WorkspaceRole: model.WorkspaceRoleOwner}
+	viewer = Actor{UserID: "viewer", WorkspaceRole: model.WorkspaceRoleViewer}
+	guest  = Actor{UserID: "guest"}
+)
+
+func (f *fixture) share(t *testing.T, email string, level model.AccessLevel) {
+	t.Helper()
+	if _, err := f.svc.ShareTask(f.ws, owner.UserID, f.task, email, level); err != nil {
+		t.Fatalf("ShareTask(%s, %s): %v", email, level, err)
+	}
+}
+
+// ============================================
+// TESTS
+// ============================================
+
+func TestAuthorize_WorkspaceRoles(t *testing.T) {
+	f := newFixture(t)
+
+	tests := []struct {
+		actor  Actor
+		action Action
+		want   error
+	}{
+		{owner, ActionList, nil},
+		{owner, ActionCreate, nil},
+		{owner, ActionEdit, nil},
+		{owner, ActionDelete, nil},
+		{owner, ActionShare, nil},
+		{viewer, ActionList, nil},
+		{viewer, ActionView, nil},
+		{viewer, ActionCreate, ErrForbidden},
+		{viewer, ActionComment, ErrForbidden},
+		{viewer, ActionEdit, ErrForbidden},
+		{viewer, ActionDelete, ErrForbidden},
+		{viewer, ActionShare, ErrForbidden},
+	}
+	for _, tt := range tests {
+		_, _, err := f.svc.Authorize(f.ws, tt.actor, f.task.ID, tt.action)
+		if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+			t.Errorf("%s %s: err = %v, want %v", tt.actor.WorkspaceRole, tt.action, err, tt.want)
+		}
+	}
+}
+
+func TestAuthorize_OtherWorkspaceTaskIsNotFound(t *testing.T) {
+	f := newFixture(t)
+
+	for _, action := range []Action{ActionView, ActionEdit, ActionDelete, ActionShare} {
+		if _, _, err := f.svc.Authorize(f.ws, owner, "task-b", action); !errors.Is(err, ErrTaskNotFound) {
+			t.Errorf("%s on another workspace's task: err = %v, want ErrTaskNotFound", action, err)
+		}
+	}
+}
+
+func TestAuthorize_ShareRaisesWorkspaceRole(t *testing.T) {
+	f := newFixture(t)
+	f.share(t, "viewer@example.com", model.AccessEdit)
+
+	if _, _, err := f.svc.Authorize(f.ws, viewer, f.task.ID, ActionEdit); err != nil {
+		t.Fatalf("viewer with edit share: %v", err)
+	}
+	// Deleting and sharing still need the workspace role
+	if _, _, err := f.svc.Authorize(f.ws, viewer, f.task.ID, ActionDelete); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("viewer with edit share deleting: err = %v, want ErrForbidden", err)
+	}
+}
+
+func TestAuthorize_SharedWithOutsider(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	if _, _, err := f.svc.Authorize(ctx, guest, f.task.ID, ActionView); !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("unshared task: err = %v, want ErrTaskNotFound", err)
+	}
+
+	f.share(t, "guest@example.com", model.AccessComment)
+
+	scoped, task, err := f.svc.Authorize(ctx, guest, f.task.ID, ActionComment)
+	if err != nil {
+		t.Fatalf("comment with comment share: %v", err)
+	}
+	if task.ID != f.task.ID {
+		t.Fatalf("task = %+v", task)
+	}
+	if id, _ := repository.WorkspaceID(scoped); id != "ws-a" {
+		t.Fatalf("context scoped to %q, want ws-a", id)
+	}
+	if _, err := f.svc.AddComment(scoped, guest.UserID, task, "Looks good"); err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+
+	for _, action := range []Action{ActionEdit, ActionDelete, ActionShare, ActionList} {
+		if _, _, err := f.svc.Authorize(ctx, guest, f.task.ID, action); !errors.Is(err, ErrForbidden) {
+			t.Errorf("%s with comment share: err = %v, want ErrForbidden", action, err)
+		}
+	}
+
+	if err := f.svc.UnshareTask(f.ws, f.task, guest.UserID); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := f.svc.Authorize(ctx, guest, f.task.ID, ActionView); !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("after unshare: err = %v, want ErrTaskNotFound", err)
+	}
+}
+
+func TestShareTask_Validation(t *testing.T) {
+	f := newFixture(t)
+
+	if _, err := f.svc.ShareTask(f.ws, owner.UserID, f.task, "guest@example.com", "admin"); !errors.Is(err, ErrInvalidLevel) {
+		t.Fatalf("invalid level: err = %v", err)
+	}
+	if _, err := f.svc.ShareTask(f.ws, owner.UserID, f.task, "nobody@example.com", model.AccessView); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("unknown user: err = %v", err)
+	}
+	if _, err := f.svc.ShareTask(f.ws, owner.UserID, f.task, "owner@example.com", model.AccessView); !errors.Is(err, ErrCannotShareToSelf) {
+		t.Fatalf("self: err = %v", err)
+	}
+}
+
+func TestPublicLink(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	link, token, err := f.svc.CreateLink(f.ws, owner.UserID, f.task, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(token) < 43 {
+		t.Fatalf("token %q is too short", token)
+	}
+	if bytes.Contains(link.TokenHash, []byte(token)) {
+		t.Fatal("link stores the raw token")
+	}
+
+	task, err := f.svc.PublicTask(ctx, token)
+	if err != nil || task.ID != f.task.ID {
+		t.Fatalf("PublicTask = %+v, %v", task, err)
+	}
+	if _, err := f.svc.PublicTask(ctx, token+"x"); !errors.Is(err, ErrLinkNotFound) {
+		t.Fatalf("wrong token: err = %v", err)
+	}
+
+	if err := f.svc.RevokeLink(f.ws, f.task, link.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.svc.PublicTask(ctx, token); !errors.Is(err, ErrLinkNotFound) {
+		t.Fatalf("revoked link: err = %v", err)
+	}
+}
+
+func TestPublicLink_Expiry(t *testing.T) {
+	f := newFixture(t)
+
+	past := time.Now().Add(-time.Minute)
+	if _, _, err := f.svc.CreateLink(f.ws, owner.UserID, f.task, &past); !errors.Is(err, ErrInvalidExpiry) {
+		t.Fatalf("past expiry: err = %v", err)
+	}
+
+	future := time.Now().Add(time.Hour)
+	link, token, err := f.svc.CreateLink(f.ws, owner.UserID, f.task, &future)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	link.ExpiresAt = &past
+	f.links.links[link.ID] = link
+	if _, err := f.svc.PublicTask(context.Background(), token); !errors.Is(err, ErrLinkNotFound) {
+		t.Fatalf("expired link: err = %v", err)
+	}
+}
+
+func TestUpdateTask_Completion(t *testing.T) {
+	f := newFixture(t)
+	before := metrics.TasksCompleted.Value()
+
+	task, err := f.svc.UpdateTask(f.ws, f.task, f.task.Title, f.task.Description, true)
+	if err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if task.CompletedAt == nil {
+		t.Fatal("expected the task to be completed")
+	}
+	completedAt := *task.CompletedAt
+
+	// Saving a done task again neither moves its completion nor counts it
+	task, err = f.svc.UpdateTask(f.ws, task, "Write final report", task.Description, true)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if task.CompletedAt == nil || !task.CompletedAt.Equal(completedAt) {
+		t.Errorf("expected completion time to stay %v, got %v", completedAt, task.CompletedAt)
+	}
+	if got := metrics.TasksCompleted.Value() - before; got != 1 {
+		t.Errorf("expected one completion counted, got %v", got)
+	}
+
+	task, err = f.svc.UpdateTask(f.ws, task, task.Title, task.Description, false)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	stored, _ := f.svc.FindByID(f.ws, task.ID)
+	if stored.CompletedAt != nil {
+		t.Errorf("expected the reopened task to be stored as open, got %v", stored.CompletedAt)
+	}
+}
+
+func TestAddComment_Validation(t *testing.T) {
+	f := newFixture(t)
+
+	if _, err := f.svc.AddComment(f.ws, owner.UserID, f.task, "   "); !errors.Is(err, ErrInvalidComment) {
+		t.Fatalf("blank comment: err = %v", err)
+	}
+}
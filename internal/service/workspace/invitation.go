@@ -0,0 +1,288 @@
+package workspace
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"task-flow/internal/apperr"
+	"task-flow/internal/logging"
+	"task-flow/internal/model"
+	"task-flow/internal/pkg/mailer"
+	"task-flow/internal/repository"
+	"task-flow/internal/service/auth"
+	"task-flow/internal/tracing"
+	"task-flow/internal/utils"
+)
+
+// invitationPurpose marks invitation tokens. They are signed with the
+// access token key and carry the invitation ID as subject; the stored row
+// makes them single-use and revocable.
+const invitationPurpose = "workspace_invitation"
+
+const DefaultInvitationTTL = 7 * 24 * time.Hour
+
+// Registrar creates password accounts. It is implemented by the auth
+// service.
+type Registrar interface {
+	Register(ctx context.Context, email, password string) error
+}
+
+var (
+	ErrInvalidInvitation = apperr.Validation("invalid_invitation", "invitation is invalid or has expired")
+	ErrInvitationEmail   = apperr.Forbidden("invitation_email_mismatch", "invitation was sent to a different email address")
+	// ErrAccountExists means the invitee should log in and accept instead.
+	ErrAccountExists = apperr.Conflict("account_exists", "an account with this email already exists; log in to accept the invitation")
+	// ErrInvitationNotSent leaves no invitation behind; inviting again is safe.
+	ErrInvitationNotSent = apperr.Unavailable("invitation_not_sent", "the invitation email could not be sent, try again")
+)
+
+// Invitation is what an invitee sees before accepting.
+type Invitation struct {
+	model.WorkspaceInvitation
+	WorkspaceName string
+	AccountExists bool
+}
+
+// Invite emails a link to join the workspace with role. Inviting the same
+// address again replaces the earlier invitation.
+func (s *Service) Invite(ctx context.Context, actorID string, actor model.WorkspaceRole, email string, role model.WorkspaceRole) (model.WorkspaceInvitation, error) {
+	ctx, span := tracing.Start(ctx, "workspace.Invite")
+	defer span.End()
+
+	if err := checkGrant(actor, role); err != nil {
+		return model.WorkspaceInvitation{}, err
+	}
+
+	email, err := auth.NormalizeEmail(email)
+	if err != nil {
+		return model.WorkspaceInvitation{}, err
+	}
+
+	ws, err := s.WorkspaceRepo.Get(ctx)
+	if err != nil {
+		return model.WorkspaceInvitation{}, err
+	}
+
+	if user, found, err := s.UserRepo.FindByEmail(ctx, email); err != nil {
+		return model.WorkspaceInvitation{}, err
+	} else if found {
+		if _, member, err := s.WorkspaceRepo.FindMember(ctx, ws.ID, user.ID); err != nil {
+			return model.WorkspaceInvitation{}, err
+		} else if member {
+			return model.WorkspaceInvitation{}, ErrAlreadyMember
+		}
+	}
+
+	if err := s.InvitationRepo.RevokeForEmail(ctx, email); err != nil {
+		return model.WorkspaceInvitation{}, err
+	}
+
+	id, err := utils.GenerateID()
+	if err != nil {
+		return model.WorkspaceInvitation{}, err
+	}
+
+	ttl := s.InvitationTTL
+	if ttl <= 0 {
+		ttl = DefaultInvitationTTL
+	}
+	inv := model.WorkspaceInvitation{
+		ID:          id,
+		WorkspaceID: ws.ID,
+		Email:       email,
+		Role:        role,
+		InvitedBy:   actorID,
+		ExpiresAt:   time.Now().Add(ttl),
+		CreatedAt:   time.Now(),
+	}
+
+	token, err := s.JWT.SignPurpose(inv.ID, invitationPurpose, ttl)
+	if err != nil {
+		return model.WorkspaceInvitation{}, err
+	}
+
+	if err := s.InvitationRepo.Create(ctx, inv); err != nil {
+		return model.WorkspaceInvitation{}, err
+	}
+
+	inviter := "A teammate"
+	if u, found, err := s.UserRepo.FindByID(ctx, actorID); err == nil && found {
+		inviter = u.Email
+	}
+
+	link := s.AppBaseURL + "/invitations/accept?token=" + url.QueryEscape(token)
+	err = s.Mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "You're invited to " + ws.Name + " on Task Flow",
+		Body: inviter + " invited you to join the workspace \"" + ws.Name + "\" as " + string(role) + ".\n\n" +
+			"Open the link below to accept. It expires in " + ttl.String() + ".\n\n" +
+			link + "\n",
+	})
+	if err != nil {
+		// An invitation nobody received is only in the way of the retry
+		logging.FromContext(ctx).Error("send invitation failed", "invitation_id", inv.ID, "err", err)
+		if _, err := s.InvitationRepo.Revoke(context.WithoutCancel(ctx), inv.ID); err != nil {
+			logging.FromContext(ctx).Warn("revoke unsent invitation", "invitation_id", inv.ID, "err", err)
+		}
+		return model.WorkspaceInvitation{}, ErrInvitationNotSent
+	}
+	return inv, nil
+}
+
+func (s *Service) ListInvitations(ctx context.Context, actor model.WorkspaceRole) ([]model.WorkspaceInvitation, error) {
+	ctx, span := tracing.Start(ctx, "workspace.ListInvitations")
+	defer span.End()
+
+	if !actor.Can(model.PermissionMembersManage) {
+		return nil, ErrForbidden
+	}
+	return s.InvitationRepo.ListPending(ctx)
+}
+
+func (s *Service) RevokeInvitation(ctx context.Context, actor model.WorkspaceRole, id string) error {
+	ctx, span := tracing.Start(ctx, "workspace.RevokeInvitation")
+	defer span.End()
+
+	if !actor.Can(model.PermissionMembersManage) {
+		return ErrForbidden
+	}
+	revoked, err := s.InvitationRepo.Revoke(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrInvalidInvitation
+	}
+	return nil
+}
+
+// pendingInvitation verifies the token and loads the invitation it names.
+func (s *Service) pendingInvitation(ctx context.Context, token string) (model.WorkspaceInvitation, error) {
+	claims, err := s.JWT.Parse(token)
+	if err != nil || claims.Purpose != invitationPurpose {
+		return model.WorkspaceInvitation{}, ErrInvalidInvitation
+	}
+
+	inv, found, err := s.InvitationRepo.FindByID(ctx, claims.Subject)
+	if err != nil {
+		return model.WorkspaceInvitation{}, err
+	}
+	if !found || !inv.Pending(time.Now()) {
+		return model.WorkspaceInvitation{}, ErrInvalidInvitation
+	}
+	return inv, nil
+}
+
+// LookupInvitation describes a pending invitation so the frontend can
+// offer to log in or register.
+func (s *Service) LookupInvitation(ctx context.Context, token string) (Invitation, error) {
+	ctx, span := tracing.Start(ctx, "workspace.LookupInvitation")
+	defer span.End()
+
+	inv, err := s.pendingInvitation(ctx, token)
+	if err != nil {
+		return Invitation{}, err
+	}
+
+	ws, err := s.WorkspaceRepo.Get(repository.WithWorkspace(ctx, inv.WorkspaceID))
+	if err != nil {
+		return Invitation{}, err
+	}
+	_, exists, err := s.UserRepo.FindByEmail(ctx, inv.Email)
+	if err != nil {
+		return Invitation{}, err
+	}
+
+	return Invitation{WorkspaceInvitation: inv, WorkspaceName: ws.Name, AccountExists: exists}, nil
+}
+
+// AcceptInvitation adds the signed-in user to the workspace. The account
+// must use the invited email address.
+func (s *Service) AcceptInvitation(ctx context.Context, userID, token string) (model.WorkspaceMembership, error) {
+	ctx, span := tracing.Start(ctx, "workspace.AcceptInvitation")
+	defer span.End()
+
+	inv, err := s.pendingInvitation(ctx, token)
+	if err != nil {
+		return model.WorkspaceMembership{}, err
+	}
+
+	user, found, err := s.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return model.WorkspaceMembership{}, err
+	}
+	if !found || user.Email != inv.Email {
+		return model.WorkspaceMembership{}, ErrInvitationEmail
+	}
+
+	return s.join(ctx, inv, userID)
+}
+
+// RegisterWithInvitation creates an account for the invited email through
+// the regular registration (and its password policy) and joins the
+// workspace. Following the emailed link proves the address, so the account
+// starts out verified.
+func (s *Service) RegisterWithInvitation(ctx context.Context, token, password string) (model.WorkspaceMembership, error) {
+	ctx, span := tracing.Start(ctx, "workspace.RegisterWithInvitation")
+	defer span.End()
+
+	inv, err := s.pendingInvitation(ctx, token)
+	if err != nil {
+		return model.WorkspaceMembership{}, err
+	}
+
+	if _, exists, err := s.UserRepo.FindByEmail(ctx, inv.Email); err != nil {
+		return model.WorkspaceMembership{}, err
+	} else if exists {
+		return model.WorkspaceMembership{}, ErrAccountExists
+	}
+
+	if err := s.Registrar.Register(ctx, inv.Email, password); err != nil {
+		return model.WorkspaceMembership{}, err
+	}
+
+	user, found, err := s.UserRepo.FindByEmail(ctx, inv.Email)
+	if err != nil {
+		return model.WorkspaceMembership{}, err
+	}
+	if !found {
+		return model.WorkspaceMembership{}, ErrUserNotFound
+	}
+	if err := s.UserRepo.MarkVerified(ctx, user.ID); err != nil {
+		logging.FromContext(ctx).Warn("mark invited user verified failed", "user_id", user.ID, "err", err)
+	}
+
+	return s.join(ctx, inv, user.ID)
+}
+
+// join redeems the invitation and adds userID. Existing members keep their
+// current role.
+func (s *Service) join(ctx context.Context, inv model.WorkspaceInvitation, userID string) (model.WorkspaceMembership, error) {
+	accepted, err := s.InvitationRepo.Accept(ctx, inv.ID)
+	if err != nil {
+		return model.WorkspaceMembership{}, err
+	}
+	if !accepted {
+		return model.WorkspaceMembership{}, ErrInvalidInvitation
+	}
+
+	ctx = repository.WithWorkspace(ctx, inv.WorkspaceID)
+	ws, err := s.WorkspaceRepo.Get(ctx)
+	if err != nil {
+		return model.WorkspaceMembership{}, err
+	}
+
+	member, found, err := s.WorkspaceRepo.FindMember(ctx, inv.WorkspaceID, userID)
+	if err != nil {
+		return model.WorkspaceMembership{}, err
+	}
+	if found {
+		return model.WorkspaceMembership{Workspace: ws, Role: member.Role}, nil
+	}
+
+	if err := s.WorkspaceRepo.SetMember(ctx, userID, inv.Role); err != nil {
+		return model.WorkspaceMembership{}, err
+	}
+	return model.WorkspaceMembership{Workspace: ws, Role: inv.Role}, nil
+}
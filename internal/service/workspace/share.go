@@ -0,0 +1,90 @@
+package workspace
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"task-flow/internal/apperr"
+	"task-flow/internal/model"
+	"task-flow/internal/repository"
+	"task-flow/internal/tracing"
+)
+
+var (
+	ErrInvalidLevel  = apperr.Validation("invalid_level", "level must be view, comment or edit")
+	ErrShareNotFound = apperr.NotFound("share_not_found", "share not found")
+)
+
+// ShareProject grants the account with email access to every task in a
+// project. Sharing again changes the level.
+func (s *Service) ShareProject(ctx context.Context, actorID string, actor model.WorkspaceRole, projectID, email string, level model.AccessLevel) (model.Share, error) {
+	ctx, span := tracing.Start(ctx, "workspace.ShareProject")
+	defer span.End()
+
+	if !actor.Can(model.PermissionProjectsManage) {
+		return model.Share{}, ErrForbidden
+	}
+	if !level.Valid() {
+		return model.Share{}, ErrInvalidLevel
+	}
+
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return model.Share{}, err
+	}
+	if _, err := s.GetProject(ctx, projectID); err != nil {
+		return model.Share{}, err
+	}
+
+	user, found, err := s.UserRepo.FindByEmail(ctx, strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		return model.Share{}, err
+	}
+	if !found {
+		return model.Share{}, ErrUserNotFound
+	}
+
+	share := model.Share{
+		WorkspaceID: workspaceID,
+		Resource:    model.ShareProject,
+		ResourceID:  projectID,
+		UserID:      user.ID,
+		Email:       user.Email,
+		Level:       level,
+		CreatedBy:   actorID,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.ShareRepo.Set(ctx, share); err != nil {
+		return model.Share{}, err
+	}
+	return share, nil
+}
+
+func (s *Service) ListProjectShares(ctx context.Context, projectID string) ([]model.Share, error) {
+	ctx, span := tracing.Start(ctx, "workspace.ListProjectShares")
+	defer span.End()
+
+	if _, err := s.GetProject(ctx, projectID); err != nil {
+		return nil, err
+	}
+	return s.ShareRepo.List(ctx, model.ShareProject, projectID)
+}
+
+func (s *Service) UnshareProject(ctx context.Context, actor model.WorkspaceRole, projectID, userID string) error {
+	ctx, span := tracing.Start(ctx, "workspace.UnshareProject")
+	defer span.End()
+
+	if !actor.Can(model.PermissionProjectsManage) {
+		return ErrForbidden
+	}
+
+	deleted, err := s.ShareRepo.Delete(ctx, model.ShareProject, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrShareNotFound
+	}
+	return nil
+}
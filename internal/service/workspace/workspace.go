@@ -0,0 +1,321 @@
+package workspace
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"task-flow/internal/apperr"
+	"task-flow/internal/model"
+	"task-flow/internal/pkg/jwt"
+	"task-flow/internal/pkg/mailer"
+	"task-flow/internal/repository"
+	"task-flow/internal/tracing"
+	"task-flow/internal/utils"
+)
+
+// Service manages workspaces, their members and projects. Apart from
+// Create and List, every method acts on the workspace in the context (see
+// repository.WithWorkspace) and takes the acting member's role, which the
+// caller has already resolved for that workspace.
+type Service struct {
+	WorkspaceRepo repository.WorkspaceRepo
+	ProjectRepo   repository.ProjectRepo
+	UserRepo      repository.UserRepo
+	ShareRepo     repository.ShareRepo
+
+	// Invitations. InvitationTTL defaults to DefaultInvitationTTL.
+	InvitationRepo repository.InvitationRepo
+	Registrar      Registrar
+	Mailer         mailer.Mailer
+	JWT            *jwt.JWT
+	AppBaseURL     string
+	InvitationTTL  time.Duration
+}
+
+func NewService(workspaceRepo repository.WorkspaceRepo, projectRepo repository.ProjectRepo, userRepo repository.UserRepo) *Service {
+	return &Service{
+		WorkspaceRepo: workspaceRepo,
+		ProjectRepo:   projectRepo,
+		UserRepo:      userRepo,
+	}
+}
+
+const maxNameLength = 100
+
+var (
+	ErrForbidden       = apperr.Forbidden("permission_denied", "permission denied")
+	ErrInvalidName     = apperr.Validation("invalid_name", "name must be 1-100 characters")
+	ErrInvalidRole     = apperr.Validation("invalid_role", "invalid workspace role")
+	ErrUserNotFound    = apperr.NotFound("user_not_found", "user not found")
+	ErrMemberNotFound  = apperr.NotFound("member_not_found", "member not found")
+	ErrProjectNotFound = apperr.NotFound("project_not_found", "project not found")
+	ErrLastOwner       = apperr.Conflict("last_owner", "a workspace must keep at least one owner")
+	ErrOwnerRequired   = apperr.Forbidden("owner_required", "only owners can grant or change the owner role")
+	ErrAlreadyMember   = apperr.Conflict("already_member", "user is already a member")
+)
+
+func cleanName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxNameLength {
+		return "", ErrInvalidName
+	}
+	return name, nil
+}
+
+// Create starts a new workspace owned by userID.
+func (s *Service) Create(ctx context.Context, userID, name string) (model.Workspace, error) {
+	ctx, span := tracing.Start(ctx, "workspace.Create")
+	defer span.End()
+
+	name, err := cleanName(name)
+	if err != nil {
+		return model.Workspace{}, err
+	}
+
+	id, err := utils.GenerateID()
+	if err != nil {
+		return model.Workspace{}, err
+	}
+
+	ws := model.Workspace{ID: id, Name: name, CreatedBy: userID}
+	if err := s.WorkspaceRepo.Create(ctx, ws, userID); err != nil {
+		return model.Workspace{}, err
+	}
+	return ws, nil
+}
+
+func (s *Service) List(ctx context.Context, userID string) ([]model.WorkspaceMembership, error) {
+	ctx, span := tracing.Start(ctx, "workspace.List")
+	defer span.End()
+
+	return s.WorkspaceRepo.ListForUser(ctx, userID)
+}
+
+func (s *Service) Get(ctx context.Context) (model.Workspace, error) {
+	ctx, span := tracing.Start(ctx, "workspace.Get")
+	defer span.End()
+
+	return s.WorkspaceRepo.Get(ctx)
+}
+
+func (s *Service) Rename(ctx context.Context, actor model.WorkspaceRole, name string) error {
+	ctx, span := tracing.Start(ctx, "workspace.Rename")
+	defer span.End()
+
+	if !actor.Can(model.PermissionWorkspaceManage) {
+		return ErrForbidden
+	}
+	name, err := cleanName(name)
+	if err != nil {
+		return err
+	}
+	return s.WorkspaceRepo.Rename(ctx, name)
+}
+
+func (s *Service) ListMembers(ctx context.Context) ([]model.WorkspaceMember, error) {
+	ctx, span := tracing.Start(ctx, "workspace.ListMembers")
+	defer span.End()
+
+	return s.WorkspaceRepo.ListMembers(ctx)
+}
+
+// AddMember adds an existing account by email.
+func (s *Service) AddMember(ctx context.Context, actor model.WorkspaceRole, email string, role model.WorkspaceRole) (model.WorkspaceMember, error) {
+	ctx, span := tracing.Start(ctx, "workspace.AddMember")
+	defer span.End()
+
+	if err := checkGrant(actor, role); err != nil {
+		return model.WorkspaceMember{}, err
+	}
+
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return model.WorkspaceMember{}, err
+	}
+
+	user, found, err := s.UserRepo.FindByEmail(ctx, strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		return model.WorkspaceMember{}, err
+	}
+	if !found {
+		return model.WorkspaceMember{}, ErrUserNotFound
+	}
+
+	_, exists, err := s.WorkspaceRepo.FindMember(ctx, workspaceID, user.ID)
+	if err != nil {
+		return model.WorkspaceMember{}, err
+	}
+	if exists {
+		return model.WorkspaceMember{}, ErrAlreadyMember
+	}
+
+	if err := s.WorkspaceRepo.SetMember(ctx, user.ID, role); err != nil {
+		return model.WorkspaceMember{}, err
+	}
+	return model.WorkspaceMember{WorkspaceID: workspaceID, UserID: user.ID, Email: user.Email, Role: role}, nil
+}
+
+// ChangeRole updates a member's role. Demoting the last owner is refused.
+func (s *Service) ChangeRole(ctx context.Context, actor model.WorkspaceRole, userID string, role model.WorkspaceRole) error {
+	ctx, span := tracing.Start(ctx, "workspace.ChangeRole")
+	defer span.End()
+
+	if err := checkGrant(actor, role); err != nil {
+		return err
+	}
+
+	member, err := s.member(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if member.Role == model.WorkspaceRoleOwner && actor != model.WorkspaceRoleOwner {
+		return ErrOwnerRequired
+	}
+	if member.Role == model.WorkspaceRoleOwner && role != model.WorkspaceRoleOwner {
+		if err := s.keepOwner(ctx); err != nil {
+			return err
+		}
+	}
+
+	return s.WorkspaceRepo.SetMember(ctx, userID, role)
+}
+
+// RemoveMember removes userID from the workspace. Members may always
+// remove themselves (leave), except for the last owner.
+func (s *Service) RemoveMember(ctx context.Context, actorID string, actor model.WorkspaceRole, userID string) error {
+	ctx, span := tracing.Start(ctx, "workspace.RemoveMember")
+	defer span.End()
+
+	if actorID != userID && !actor.Can(model.PermissionMembersManage) {
+		return ErrForbidden
+	}
+
+	member, err := s.member(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if member.Role == model.WorkspaceRoleOwner {
+		if actorID != userID && actor != model.WorkspaceRoleOwner {
+			return ErrOwnerRequired
+		}
+		if err := s.keepOwner(ctx); err != nil {
+			return err
+		}
+	}
+
+	removed, err := s.WorkspaceRepo.RemoveMember(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrMemberNotFound
+	}
+	return nil
+}
+
+func checkGrant(actor, role model.WorkspaceRole) error {
+	if !actor.Can(model.PermissionMembersManage) {
+		return ErrForbidden
+	}
+	if !role.Valid() {
+		return ErrInvalidRole
+	}
+	if role == model.WorkspaceRoleOwner && actor != model.WorkspaceRoleOwner {
+		return ErrOwnerRequired
+	}
+	return nil
+}
+
+func (s *Service) member(ctx context.Context, userID string) (model.WorkspaceMember, error) {
+	workspaceID, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return model.WorkspaceMember{}, err
+	}
+
+	member, found, err := s.WorkspaceRepo.FindMember(ctx, workspaceID, userID)
+	if err != nil {
+		return model.WorkspaceMember{}, err
+	}
+	if !found {
+		return model.WorkspaceMember{}, ErrMemberNotFound
+	}
+	return member, nil
+}
+
+// keepOwner fails when removing one owner would leave none.
+func (s *Service) keepOwner(ctx context.Context) error {
+	owners, err := s.WorkspaceRepo.CountOwners(ctx)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+func (s *Service) CreateProject(ctx context.Context, actor model.WorkspaceRole, name, description string) (model.Project, error) {
+	ctx, span := tracing.Start(ctx, "workspace.CreateProject")
+	defer span.End()
+
+	if !actor.Can(model.PermissionProjectsManage) {
+		return model.Project{}, ErrForbidden
+	}
+	name, err := cleanName(name)
+	if err != nil {
+		return model.Project{}, err
+	}
+
+	id, err := utils.GenerateID()
+	if err != nil {
+		return model.Project{}, err
+	}
+
+	project := model.Project{ID: id, Name: name, Description: strings.TrimSpace(description)}
+	if err := s.ProjectRepo.Create(ctx, project); err != nil {
+		return model.Project{}, err
+	}
+	project.WorkspaceID, _ = repository.WorkspaceID(ctx)
+	return project, nil
+}
+
+func (s *Service) ListProjects(ctx context.Context) ([]model.Project, error) {
+	ctx, span := tracing.Start(ctx, "workspace.ListProjects")
+	defer span.End()
+
+	return s.ProjectRepo.List(ctx)
+}
+
+func (s *Service) GetProject(ctx context.Context, id string) (model.Project, error) {
+	ctx, span := tracing.Start(ctx, "workspace.GetProject")
+	defer span.End()
+
+	project, found, err := s.ProjectRepo.FindByID(ctx, id)
+	if err != nil {
+		return model.Project{}, err
+	}
+	if !found {
+		return model.Project{}, ErrProjectNotFound
+	}
+	return project, nil
+}
+
+// DeleteProject deletes the project together with its tasks.
+func (s *Service) DeleteProject(ctx context.Context, actor model.WorkspaceRole, id string) error {
+	ctx, span := tracing.Start(ctx, "workspace.DeleteProject")
+	defer span.End()
+
+	if !actor.Can(model.PermissionProjectsManage) {
+		return ErrForbidden
+	}
+	deleted, err := s.ProjectRepo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrProjectNotFound
+	}
+	return nil
+}
@@ -0,0 +1,636 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"task-flow/internal/model"
+	"task-flow/internal/pkg/jwt"
+	"task-flow/internal/pkg/mailer"
+	"task-flow/internal/repository"
+)
+
+// ============================================
+// MOCK REPOSITORIES
+// ============================================
+
+// mockWorkspaceRepo keeps members per workspace and, like the MySQL
+// implementation, only ever touches the workspace in the context.
+type mockWorkspaceRepo struct {
+	workspaces map[string]model.Workspace
+	members    map[string]map[string]model.WorkspaceRole // workspace -> user -> role
+}
+
+func newMockWorkspaceRepo() *mockWorkspaceRepo {
+	return &mockWorkspaceRepo{
+		workspaces: make(map[string]model.Workspace),
+		members:    make(map[string]map[string]model.WorkspaceRole),
+	}
+}
+
+func (m *mockWorkspaceRepo) Create(ctx context.Context, ws model.Workspace, ownerID string) error {
+	m.workspaces[ws.ID] = ws
+	m.members[ws.ID] = map[string]model.WorkspaceRole{ownerID: model.WorkspaceRoleOwner}
+	return nil
+}
+
+func (m *mockWorkspaceRepo) FindMember(ctx context.Context, workspaceID, userID string) (model.WorkspaceMember, bool, error) {
+	role, ok := m.members[workspaceID][userID]
+	return model.WorkspaceMember{WorkspaceID: workspaceID, UserID: userID, Role: role}, ok, nil
+}
+
+func (m *mockWorkspaceRepo) ListForUser(ctx context.Context, userID string) ([]model.WorkspaceMembership, error) {
+	var res []model.WorkspaceMembership
+	for id, members := range m.members {
+		if role, ok := members[userID]; ok {
+			res = append(res, model.WorkspaceMembership{Workspace: m.workspaces[id], Role: role})
+		}
+	}
+	return res, nil
+}
+
+func (m *mockWorkspaceRepo) Get(ctx context.Context) (model.Workspace, error) {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return model.Workspace{}, err
+	}
+	return m.workspaces[id], nil
+}
+
+func (m *mockWorkspaceRepo) Rename(ctx context.Context, name string) error {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+	ws := m.workspaces[id]
+	ws.Name = name
+	m.workspaces[id] = ws
+	return nil
+}
+
+func (m *mockWorkspaceRepo) ListMembers(ctx context.Context) ([]model.WorkspaceMember, error) {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var res []model.WorkspaceMember
+	for userID, role := range m.members[id] {
+		res = append(res, model.WorkspaceMember{WorkspaceID: id, UserID: userID, Role: role})
+	}
+	return res, nil
+}
+
+func (m *mockWorkspaceRepo) SetMember(ctx context.Context, userID string, role model.WorkspaceRole) error {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+	m.members[id][userID] = role
+	return nil
+}
+
+func (m *mockWorkspaceRepo) RemoveMember(ctx context.Context, userID string) (bool, error) {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return false, err
+	}
+	_, ok := m.members[id][userID]
+	delete(m.members[id], userID)
+	return ok, nil
+}
+
+func (m *mockWorkspaceRepo) CountOwners(ctx context.Context) (int, error) {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, role := range m.members[id] {
+		if role == model.WorkspaceRoleOwner {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *mockWorkspaceRepo) Delete(ctx context.Context) error {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+	delete(m.workspaces, id)
+	delete(m.members, id)
+	return nil
+}
+
+type mockProjectRepo struct {
+	projects map[string]model.Project
+}
+
+func (m *mockProjectRepo) Create(ctx context.Context, project model.Project) error {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+	project.WorkspaceID = id
+	m.projects[project.ID] = project
+	return nil
+}
+
+func (m *mockProjectRepo) List(ctx context.Context) ([]model.Project, error) {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var res []model.Project
+	for _, p := range m.projects {
+		if p.WorkspaceID == id {
+			res = append(res, p)
+		}
+	}
+	return res, nil
+}
+
+func (m *mockProjectRepo) FindByID(ctx context.Context, projectID string) (model.Project, bool, error) {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return model.Project{}, false, err
+	}
+	p, ok := m.projects[projectID]
+	if !ok || p.WorkspaceID != id {
+		return model.Project{}, false, nil
+	}
+	return p, true, nil
+}
+
+func (m *mockProjectRepo) Delete(ctx context.Context, projectID string) (bool, error) {
+	_, found, err := m.FindByID(ctx, projectID)
+	if err != nil || !found {
+		return false, err
+	}
+	delete(m.projects, projectID)
+	return true, nil
+}
+
+type mockUserRepo struct {
+	repository.UserRepo
+	users    map[string]model.User // email -> user
+	verified map[string]bool
+}
+
+func (m *mockUserRepo) FindByEmail(ctx context.Context, email string) (model.User, bool, error) {
+	u, ok := m.users[email]
+	return u, ok, nil
+}
+
+func (m *mockUserRepo) FindByID(ctx context.Context, id string) (model.User, bool, error) {
+	for _, u := range m.users {
+		if u.ID == id {
+			return u, true, nil
+		}
+	}
+	return model.User{}, false, nil
+}
+
+func (m *mockUserRepo) MarkVerified(ctx context.Context, id string) error {
+	m.verified[id] = true
+	return nil
+}
+
+type mockInvitationRepo struct {
+	invitations map[string]model.WorkspaceInvitation
+}
+
+func (m *mockInvitationRepo) Create(ctx context.Context, inv model.WorkspaceInvitation) error {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+	inv.WorkspaceID = id
+	m.invitations[inv.ID] = inv
+	return nil
+}
+
+func (m *mockInvitationRepo) ListPending(ctx context.Context) ([]model.WorkspaceInvitation, error) {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var res []model.WorkspaceInvitation
+	for _, inv := range m.invitations {
+		if inv.WorkspaceID == id && inv.Pending(time.Now()) {
+			res = append(res, inv)
+		}
+	}
+	return res, nil
+}
+
+func (m *mockInvitationRepo) Revoke(ctx context.Context, invID string) (bool, error) {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return false, err
+	}
+	inv, ok := m.invitations[invID]
+	if !ok || inv.WorkspaceID != id || inv.AcceptedAt != nil {
+		return false, nil
+	}
+	delete(m.invitations, invID)
+	return true, nil
+}
+
+func (m *mockInvitationRepo) RevokeForEmail(ctx context.Context, email string) error {
+	id, err := repository.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+	for invID, inv := range m.invitations {
+		if inv.WorkspaceID == id && inv.Email == email && inv.AcceptedAt == nil {
+			delete(m.invitations, invID)
+		}
+	}
+	return nil
+}
+
+func (m *mockInvitationRepo) FindByID(ctx context.Context, id string) (model.WorkspaceInvitation, bool, error) {
+	inv, ok := m.invitations[id]
+	return inv, ok, nil
+}
+
+func (m *mockInvitationRepo) Accept(ctx context.Context, id string) (bool, error) {
+	inv, ok := m.invitations[id]
+	if !ok || !inv.Pending(time.Now()) {
+		return false, nil
+	}
+	now := time.Now()
+	inv.AcceptedAt = &now
+	m.invitations[id] = inv
+	return true, nil
+}
+
+type mockMailer struct {
+	sent []mailer.Message
+	err  error
+}
+
+func (m *mockMailer) Send(ctx context.Context, msg mailer.Message) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// mockRegistrar stands in for auth.Service.Register.
+type mockRegistrar struct {
+	users *mockUserRepo
+}
+
+func (m *mockRegistrar) Register(ctx context.Context, email, password string) error {
+	if len(password) < 8 {
+		return errors.New("password too short")
+	}
+	m.users.users[email] = model.User{ID: "new-" + email, Email: email}
+	return nil
+}
+
+// ============================================
+// HELPER
+// ============================================
+
+func newTestService() *Service {
+	users := &mockUserRepo{
+		users: map[string]model.User{
+			"alice@example.com": {ID: "alice", Email: "alice@example.com"},
+			"bob@example.com":   {ID: "bob", Email: "bob@example.com"},
+			"carol@example.com": {ID: "carol", Email: "carol@example.com"},
+		},
+		verified: make(map[string]bool),
+	}
+	svc := NewService(
+		newMockWorkspaceRepo(),
+		&mockProjectRepo{projects: make(map[string]model.Project)},
+		users,
+	)
+	svc.InvitationRepo = &mockInvitationRepo{invitations: make(map[string]model.WorkspaceInvitation)}
+	svc.Registrar = &mockRegistrar{users: users}
+	svc.Mailer = &mockMailer{}
+	svc.JWT = jwt.New([]byte("test-secret"))
+	svc.AppBaseURL = "http://app.test"
+	return svc
+}
+
+// invite invites email to the workspace in ctx as alice and returns the
+// token from the emailed link.
+func invite(t *testing.T, svc *Service, ctx context.Context, email string, role model.WorkspaceRole) string {
+	t.Helper()
+	if _, err := svc.Invite(ctx, "alice", model.WorkspaceRoleOwner, email, role); err != nil {
+		t.Fatalf("Invite: %v", err)
+	}
+	sent := svc.Mailer.(*mockMailer).sent
+	body := sent[len(sent)-1].Body
+	_, rest, ok := strings.Cut(body, "token=")
+	if !ok {
+		t.Fatalf("no token in email: %q", body)
+	}
+	token, err := url.QueryUnescape(strings.Fields(rest)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// newWorkspace creates a workspace owned by "alice" and returns a context
+// scoped to it.
+func newWorkspace(t *testing.T, svc *Service) context.Context {
+	t.Helper()
+	ws, err := svc.Create(context.Background(), "alice", "Acme")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return repository.WithWorkspace(context.Background(), ws.ID)
+}
+
+// ============================================
+// TESTS
+// ============================================
+
+func TestCreate_OwnerIsMember(t *testing.T) {
+	svc := newTestService()
+	newWorkspace(t, svc)
+
+	list, err := svc.List(context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Role != model.WorkspaceRoleOwner || list[0].Name != "Acme" {
+		t.Fatalf("List = %+v, want Acme as owner", list)
+	}
+
+	if _, err := svc.Create(context.Background(), "alice", "  "); !errors.Is(err, ErrInvalidName) {
+		t.Fatalf("blank name: err = %v, want ErrInvalidName", err)
+	}
+}
+
+func TestAddMember(t *testing.T) {
+	svc := newTestService()
+	ctx := newWorkspace(t, svc)
+
+	if _, err := svc.AddMember(ctx, model.WorkspaceRoleMember, "bob@example.com", model.WorkspaceRoleMember); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("member adding members: err = %v, want ErrForbidden", err)
+	}
+	if _, err := svc.AddMember(ctx, model.WorkspaceRoleAdmin, "bob@example.com", model.WorkspaceRoleOwner); !errors.Is(err, ErrOwnerRequired) {
+		t.Fatalf("admin granting owner: err = %v, want ErrOwnerRequired", err)
+	}
+	if _, err := svc.AddMember(ctx, model.WorkspaceRoleAdmin, "nobody@example.com", model.WorkspaceRoleMember); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("unknown email: err = %v, want ErrUserNotFound", err)
+	}
+
+	member, err := svc.AddMember(ctx, model.WorkspaceRoleAdmin, " Bob@Example.com ", model.WorkspaceRoleViewer)
+	if err != nil {
+		t.Fatalf("AddMember: %v", err)
+	}
+	if member.UserID != "bob" || member.Role != model.WorkspaceRoleViewer {
+		t.Fatalf("member = %+v", member)
+	}
+
+	if _, err := svc.AddMember(ctx, model.WorkspaceRoleAdmin, "bob@example.com", model.WorkspaceRoleMember); !errors.Is(err, ErrAlreadyMember) {
+		t.Fatalf("duplicate: err = %v, want ErrAlreadyMember", err)
+	}
+}
+
+func TestChangeRole_LastOwner(t *testing.T) {
+	svc := newTestService()
+	ctx := newWorkspace(t, svc)
+
+	if err := svc.ChangeRole(ctx, model.WorkspaceRoleOwner, "alice", model.WorkspaceRoleAdmin); !errors.Is(err, ErrLastOwner) {
+		t.Fatalf("demoting last owner: err = %v, want ErrLastOwner", err)
+	}
+
+	if _, err := svc.AddMember(ctx, model.WorkspaceRoleOwner, "bob@example.com", model.WorkspaceRoleOwner); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.ChangeRole(ctx, model.WorkspaceRoleAdmin, "bob", model.WorkspaceRoleMember); !errors.Is(err, ErrOwnerRequired) {
+		t.Fatalf("admin demoting owner: err = %v, want ErrOwnerRequired", err)
+	}
+	if err := svc.ChangeRole(ctx, model.WorkspaceRoleOwner, "alice", model.WorkspaceRoleAdmin); err != nil {
+		t.Fatalf("demoting with a second owner: %v", err)
+	}
+}
+
+func TestRemoveMember(t *testing.T) {
+	svc := newTestService()
+	ctx := newWorkspace(t, svc)
+	if _, err := svc.AddMember(ctx, model.WorkspaceRoleOwner, "bob@example.com", model.WorkspaceRoleMember); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.AddMember(ctx, model.WorkspaceRoleOwner, "carol@example.com", model.WorkspaceRoleMember); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.RemoveMember(ctx, "bob", model.WorkspaceRoleMember, "carol"); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("member removing another: err = %v, want ErrForbidden", err)
+	}
+	if err := svc.RemoveMember(ctx, "bob", model.WorkspaceRoleMember, "bob"); err != nil {
+		t.Fatalf("leaving: %v", err)
+	}
+	if err := svc.RemoveMember(ctx, "alice", model.WorkspaceRoleOwner, "alice"); !errors.Is(err, ErrLastOwner) {
+		t.Fatalf("last owner leaving: err = %v, want ErrLastOwner", err)
+	}
+	if err := svc.RemoveMember(ctx, "alice", model.WorkspaceRoleOwner, "bob"); !errors.Is(err, ErrMemberNotFound) {
+		t.Fatalf("removing non-member: err = %v, want ErrMemberNotFound", err)
+	}
+}
+
+func TestProjects_ScopedToWorkspace(t *testing.T) {
+	svc := newTestService()
+	ctxA := newWorkspace(t, svc)
+	ctxB := newWorkspace(t, svc)
+
+	if _, err := svc.CreateProject(ctxA, model.WorkspaceRoleMember, "Roadmap", ""); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("member creating project: err = %v, want ErrForbidden", err)
+	}
+
+	project, err := svc.CreateProject(ctxA, model.WorkspaceRoleAdmin, "Roadmap", "Q3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svc.GetProject(ctxB, project.ID); !errors.Is(err, ErrProjectNotFound) {
+		t.Fatalf("reading across workspaces: err = %v, want ErrProjectNotFound", err)
+	}
+	if err := svc.DeleteProject(ctxB, model.WorkspaceRoleOwner, project.ID); !errors.Is(err, ErrProjectNotFound) {
+		t.Fatalf("deleting across workspaces: err = %v, want ErrProjectNotFound", err)
+	}
+	if list, _ := svc.ListProjects(ctxB); len(list) != 0 {
+		t.Fatalf("ListProjects in B = %+v, want none", list)
+	}
+
+	if err := svc.DeleteProject(ctxA, model.WorkspaceRoleAdmin, project.ID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNoWorkspaceInContext(t *testing.T) {
+	svc := newTestService()
+
+	if _, err := svc.ListProjects(context.Background()); !errors.Is(err, repository.ErrNoWorkspace) {
+		t.Fatalf("err = %v, want ErrNoWorkspace", err)
+	}
+	if _, err := svc.AddMember(context.Background(), model.WorkspaceRoleOwner, "bob@example.com", model.WorkspaceRoleMember); !errors.Is(err, repository.ErrNoWorkspace) {
+		t.Fatalf("err = %v, want ErrNoWorkspace", err)
+	}
+}
+
+func TestInvite_ExistingUserAccepts(t *testing.T) {
+	svc := newTestService()
+	ctx := newWorkspace(t, svc)
+	token := invite(t, svc, ctx, "Bob@Example.com", model.WorkspaceRoleViewer)
+
+	if _, err := svc.AcceptInvitation(context.Background(), "carol", token); !errors.Is(err, ErrInvitationEmail) {
+		t.Fatalf("other account accepting: err = %v, want ErrInvitationEmail", err)
+	}
+
+	membership, err := svc.AcceptInvitation(context.Background(), "bob", token)
+	if err != nil {
+		t.Fatalf("AcceptInvitation: %v", err)
+	}
+	if membership.Role != model.WorkspaceRoleViewer || membership.Name != "Acme" {
+		t.Fatalf("membership = %+v", membership)
+	}
+
+	// Single use
+	if _, err := svc.AcceptInvitation(context.Background(), "bob", token); !errors.Is(err, ErrInvalidInvitation) {
+		t.Fatalf("second accept: err = %v, want ErrInvalidInvitation", err)
+	}
+	if list, _ := svc.ListInvitations(ctx, model.WorkspaceRoleOwner); len(list) != 0 {
+		t.Fatalf("pending after accept = %+v", list)
+	}
+}
+
+func TestInvite_NewUserRegisters(t *testing.T) {
+	svc := newTestService()
+	ctx := newWorkspace(t, svc)
+	token := invite(t, svc, ctx, "dave@example.com", model.WorkspaceRoleMember)
+
+	info, err := svc.LookupInvitation(context.Background(), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.AccountExists || info.WorkspaceName != "Acme" || info.Email != "dave@example.com" {
+		t.Fatalf("lookup = %+v", info)
+	}
+
+	if _, err := svc.RegisterWithInvitation(context.Background(), token, "short"); err == nil {
+		t.Fatal("registration error not returned")
+	}
+	membership, err := svc.RegisterWithInvitation(context.Background(), token, "long enough password")
+	if err != nil {
+		t.Fatalf("RegisterWithInvitation: %v", err)
+	}
+	if membership.Role != model.WorkspaceRoleMember {
+		t.Fatalf("role = %s", membership.Role)
+	}
+
+	users := svc.UserRepo.(*mockUserRepo)
+	if !users.verified["new-dave@example.com"] {
+		t.Fatal("invited account not marked verified")
+	}
+	if _, found, _ := svc.WorkspaceRepo.FindMember(ctx, membership.ID, "new-dave@example.com"); !found {
+		t.Fatal("new account did not join the workspace")
+	}
+}
+
+func TestInvite_RegisterRejectsExistingAccount(t *testing.T) {
+	svc := newTestService()
+	ctx := newWorkspace(t, svc)
+	token := invite(t, svc, ctx, "bob@example.com", model.WorkspaceRoleMember)
+
+	if _, err := svc.RegisterWithInvitation(context.Background(), token, "long enough password"); !errors.Is(err, ErrAccountExists) {
+		t.Fatalf("err = %v, want ErrAccountExists", err)
+	}
+}
+
+func TestInvite_Permissions(t *testing.T) {
+	svc := newTestService()
+	ctx := newWorkspace(t, svc)
+
+	if _, err := svc.Invite(ctx, "bob", model.WorkspaceRoleMember, "dave@example.com", model.WorkspaceRoleMember); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("member inviting: err = %v, want ErrForbidden", err)
+	}
+	if _, err := svc.Invite(ctx, "bob", model.WorkspaceRoleAdmin, "dave@example.com", model.WorkspaceRoleOwner); !errors.Is(err, ErrOwnerRequired) {
+		t.Fatalf("admin inviting owner: err = %v, want ErrOwnerRequired", err)
+	}
+	if _, err := svc.Invite(ctx, "alice", model.WorkspaceRoleOwner, "alice@example.com", model.WorkspaceRoleMember); !errors.Is(err, ErrAlreadyMember) {
+		t.Fatalf("inviting a member: err = %v, want ErrAlreadyMember", err)
+	}
+}
+
+func TestInvite_RevokeAndReissue(t *testing.T) {
+	svc := newTestService()
+	ctx := newWorkspace(t, svc)
+
+	first := invite(t, svc, ctx, "bob@example.com", model.WorkspaceRoleMember)
+	second := invite(t, svc, ctx, "bob@example.com", model.WorkspaceRoleMember)
+	if _, err := svc.AcceptInvitation(context.Background(), "bob", first); !errors.Is(err, ErrInvalidInvitation) {
+		t.Fatalf("replaced invitation: err = %v, want ErrInvalidInvitation", err)
+	}
+
+	list, err := svc.ListInvitations(ctx, model.WorkspaceRoleAdmin)
+	if err != nil || len(list) != 1 {
+		t.Fatalf("ListInvitations = %+v, %v; want one", list, err)
+	}
+
+	// Another workspace can't revoke it
+	other := newWorkspace(t, svc)
+	if err := svc.RevokeInvitation(other, model.WorkspaceRoleOwner, list[0].ID); !errors.Is(err, ErrInvalidInvitation) {
+		t.Fatalf("revoking across workspaces: err = %v", err)
+	}
+
+	if err := svc.RevokeInvitation(ctx, model.WorkspaceRoleAdmin, list[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.AcceptInvitation(context.Background(), "bob", second); !errors.Is(err, ErrInvalidInvitation) {
+		t.Fatalf("revoked invitation: err = %v, want ErrInvalidInvitation", err)
+	}
+}
+
+func TestInvite_UnsentIsNotKept(t *testing.T) {
+	svc := newTestService()
+	ctx := newWorkspace(t, svc)
+	svc.Mailer.(*mockMailer).err = errors.New("smtp down")
+
+	if _, err := svc.Invite(ctx, "alice", model.WorkspaceRoleOwner, "bob@example.com", model.WorkspaceRoleMember); !errors.Is(err, ErrInvitationNotSent) {
+		t.Fatalf("Invite: err = %v, want ErrInvitationNotSent", err)
+	}
+	list, err := svc.ListInvitations(ctx, model.WorkspaceRoleAdmin)
+	if err != nil || len(list) != 0 {
+		t.Fatalf("ListInvitations = %+v, %v; want none", list, err)
+	}
+}
+
+func TestInvite_ExpiredOrForgedToken(t *testing.T) {
+	svc := newTestService()
+	ctx := newWorkspace(t, svc)
+	token := invite(t, svc, ctx, "bob@example.com", model.WorkspaceRoleMember)
+	invitations := svc.InvitationRepo.(*mockInvitationRepo).invitations
+	for id, inv := range invitations {
+		inv.ExpiresAt = time.Now().Add(-time.Minute)
+		invitations[id] = inv
+	}
+
+	if _, err := svc.AcceptInvitation(context.Background(), "bob", token); !errors.Is(err, ErrInvalidInvitation) {
+		t.Fatalf("expired: err = %v, want ErrInvalidInvitation", err)
+	}
+
+	// A regular access token for the invitation ID is not an invitation
+	invite(t, svc, ctx, "carol@example.com", model.WorkspaceRoleMember)
+	list, _ := svc.ListInvitations(ctx, model.WorkspaceRoleOwner)
+	forged, _ := svc.JWT.Sign(list[0].ID, time.Hour)
+	if _, err := svc.AcceptInvitation(context.Background(), "carol", forged); !errors.Is(err, ErrInvalidInvitation) {
+		t.Fatalf("wrong purpose: err = %v, want ErrInvalidInvitation", err)
+	}
+}
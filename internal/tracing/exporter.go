@@ -0,0 +1,54 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+)
+
+// Exporter receives every finished, sampled span. Export is called on the
+// request path and must not block.
+type Exporter interface {
+	Export(span SpanData)
+	// Shutdown sends what is still buffered and releases resources.
+	Shutdown(ctx context.Context) error
+}
+
+// NoopExporter drops spans. IDs are still generated and propagated, so
+// logs carry trace IDs even with export off.
+type NoopExporter struct{}
+
+func (NoopExporter) Export(SpanData)                {}
+func (NoopExporter) Shutdown(context.Context) error { return nil }
+
+// InMemoryExporter keeps spans for inspection, meant for tests.
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+func (e *InMemoryExporter) Export(span SpanData) {
+	e.mu.Lock()
+	e.spans = append(e.spans, span)
+	e.mu.Unlock()
+}
+
+func (e *InMemoryExporter) Shutdown(context.Context) error { return nil }
+
+// Spans returns the spans exported so far, in the order they ended.
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	out := make([]SpanData, len(e.spans))
+	copy(out, e.spans)
+	return out
+}
+
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	e.spans = nil
+	e.mu.Unlock()
+}
@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+)
+
+// TraceparentHeader carries the W3C Trace Context.
+const TraceparentHeader = "traceparent"
+
+// ParseTraceparent parses a version 00 traceparent value, such as
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01". Later
+// versions are read the same way, as the spec asks.
+func ParseTraceparent(v string) (SpanContext, bool) {
+	if len(v) < 55 || v[2] != '-' || v[35] != '-' || v[52] != '-' {
+		return SpanContext{}, false
+	}
+	version := v[:2]
+	if version == "ff" || !isLowerHex(version) || (version == "00" && len(v) != 55) || (len(v) > 55 && v[55] != '-') {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+	if !isLowerHex(v[3:35]) || !isLowerHex(v[36:52]) || !isLowerHex(v[53:55]) {
+		return SpanContext{}, false
+	}
+	_, _ = hex.Decode(sc.TraceID[:], []byte(v[3:35]))
+	_, _ = hex.Decode(sc.SpanID[:], []byte(v[36:52]))
+	flags, _ := strconv.ParseUint(v[53:55], 16, 8)
+	sc.Sampled = flags&1 == 1
+
+	if !sc.IsValid() {
+		return SpanContext{}, false
+	}
+	return sc, true
+}
+
+// Traceparent formats sc as a traceparent value.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+func isLowerHex(s string) bool {
+	for i := range len(s) {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Extract returns ctx with the caller's span from h as remote parent, or
+// ctx unchanged when h has no valid traceparent.
+func Extract(ctx context.Context, h http.Header) context.Context {
+	sc, ok := ParseTraceparent(h.Get(TraceparentHeader))
+	if !ok {
+		return ctx
+	}
+	return ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// Inject sets the traceparent of ctx's span on h.
+func Inject(ctx context.Context, h http.Header) {
+	if sc := SpanContextFromContext(ctx); sc.IsValid() {
+		h.Set(TraceparentHeader, sc.Traceparent())
+	}
+}
+
+// Transport wraps base, nil meaning http.DefaultTransport, so outgoing
+// requests get a client span and carry its traceparent.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return transport{base: base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := StartKind(req.Context(), KindClient, "HTTP "+req.Method,
+		String("http.request.method", req.Method),
+		String("server.address", req.URL.Host),
+		String("url.full", req.URL.Redacted()),
+	)
+	defer span.End()
+
+	req = req.Clone(ctx)
+	Inject(ctx, req.Header)
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	span.SetAttributes(Int("http.response.status_code", res.StatusCode))
+	if res.StatusCode >= 500 {
+		span.SetStatus(StatusError, "")
+	}
+	return res, nil
+}
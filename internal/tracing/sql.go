@@ -0,0 +1,201 @@
+package tracing
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"time"
+)
+
+// WrapConnector returns a connector whose connections record a client
+// span for every query and statement execution, as a child of the span in
+// the query's context. Use it with sql.OpenDB.
+func WrapConnector(c driver.Connector, system string) driver.Connector {
+	return connector{Connector: c, system: system}
+}
+
+type connector struct {
+	driver.Connector
+	system string
+}
+
+func (c connector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn, system: c.system}, nil
+}
+
+// traceQuery runs fn and records it as a span. database/sql retries a
+// query that returns driver.ErrSkip another way (the MySQL driver does so
+// for queries with arguments), so those attempts are not recorded. The
+// span is created afterwards but backdated to when fn started.
+func traceQuery(ctx context.Context, system, query string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	if errors.Is(err, driver.ErrSkip) {
+		return err
+	}
+
+	span := Default().start(ctx, KindClient, operation(query), start, []Attr{
+		String("db.system", system),
+		String("db.statement", query),
+	})
+	if err != nil && !errors.Is(err, driver.ErrBadConn) {
+		span.RecordError(err)
+	}
+	span.End()
+	return err
+}
+
+// operation is the span name: the statement's first keyword.
+func operation(query string) string {
+	op, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	if op == "" {
+		return "sql"
+	}
+	return strings.ToUpper(op)
+}
+
+type tracedConn struct {
+	driver.Conn
+	system string
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	var rows driver.Rows
+	err := traceQuery(ctx, c.system, query, func() (err error) {
+		rows, err = q.QueryContext(ctx, query, args)
+		return err
+	})
+	return rows, err
+}
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	var res driver.Result
+	err := traceQuery(ctx, c.system, query, func() (err error) {
+		res, err = e.ExecContext(ctx, query, args)
+		return err
+	})
+	return res, err
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tracedStmt{Stmt: stmt, conn: c, query: query}, nil
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+// CheckNamedValue keeps the driver's own argument conversions.
+func (c *tracedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if ch, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return ch.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type tracedStmt struct {
+	driver.Stmt
+	conn  *tracedConn
+	query string
+}
+
+func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	var rows driver.Rows
+	err := traceQuery(ctx, s.conn.system, s.query, func() (err error) {
+		if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+			rows, err = q.QueryContext(ctx, args)
+			return err
+		}
+		values, err := namedToValues(args)
+		if err != nil {
+			return err
+		}
+		rows, err = s.Stmt.Query(values)
+		return err
+	})
+	return rows, err
+}
+
+func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	var res driver.Result
+	err := traceQuery(ctx, s.conn.system, s.query, func() (err error) {
+		if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+			res, err = e.ExecContext(ctx, args)
+			return err
+		}
+		values, err := namedToValues(args)
+		if err != nil {
+			return err
+		}
+		res, err = s.Stmt.Exec(values)
+		return err
+	})
+	return res, err
+}
+
+func (s *tracedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if ch, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return ch.CheckNamedValue(nv)
+	}
+	// database/sql would skip the connection's checker once the
+	// statement has one
+	return s.conn.CheckNamedValue(nv)
+}
+
+func namedToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			return nil, errors.New("tracing: driver does not support named arguments")
+		}
+		values[i] = a.Value
+	}
+	return values, nil
+}
@@ -0,0 +1,91 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+)
+
+// ==========================================
+// FAKE DRIVER
+// ==========================================
+
+// fakeConn behaves like the MySQL driver: direct queries with arguments
+// are refused with ErrSkip, so database/sql prepares them instead.
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+func (fakeConn) QueryContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Rows, error) {
+	if len(args) > 0 {
+		return nil, driver.ErrSkip
+	}
+	return &fakeRows{}, nil
+}
+
+type fakeStmt struct{}
+
+func (fakeStmt) Close() error                                    { return nil }
+func (fakeStmt) NumInput() int                                   { return -1 }
+func (fakeStmt) Exec(args []driver.Value) (driver.Result, error) { return driver.RowsAffected(1), nil }
+func (fakeStmt) Query(args []driver.Value) (driver.Rows, error)  { return &fakeRows{}, nil }
+
+type fakeRows struct{ done bool }
+
+func (*fakeRows) Columns() []string { return []string{"n"} }
+func (*fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{}, nil }
+func (fakeConnector) Driver() driver.Driver                        { return nil }
+
+// ==========================================
+// TESTS
+// ==========================================
+
+func TestWrapConnector(t *testing.T) {
+	exp := useInMemory(t)
+	db := sql.OpenDB(WrapConnector(fakeConnector{}, "fake"))
+	defer db.Close()
+
+	ctx, root := Start(context.Background(), "request")
+	var n int
+	if err := db.QueryRowContext(ctx, "select 1").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRowContext(ctx, "SELECT n FROM t WHERE id = ?", 7).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE t SET n = ?", 2); err != nil {
+		t.Fatal(err)
+	}
+	root.End()
+
+	spans := exp.Spans()
+	// The skipped direct attempt of the second query is not recorded
+	want := []string{"SELECT", "SELECT", "UPDATE", "request"}
+	if len(spans) != len(want) {
+		t.Fatalf("spans = %+v", spans)
+	}
+	for i, s := range spans {
+		if s.Name != want[i] {
+			t.Errorf("span %d = %q, want %q", i, s.Name, want[i])
+		}
+		if i < 3 && (s.ParentID != root.SpanContext().SpanID || s.Kind != KindClient) {
+			t.Errorf("span %q is not a client span under the request", s.Name)
+		}
+	}
+}
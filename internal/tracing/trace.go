@@ -0,0 +1,268 @@
+// Package tracing records spans for HTTP requests, service calls and SQL
+// queries, propagates them with W3C Trace Context (traceparent) and hands
+// finished spans to an Exporter: a no-op one by default, OTLP over HTTP in
+// production and an in-memory one in tests. It follows the OpenTelemetry
+// data model closely enough for any OTLP collector to accept its spans.
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type TraceID [16]byte
+
+func (id TraceID) IsValid() bool  { return id != TraceID{} }
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+type SpanID [8]byte
+
+func (id SpanID) IsValid() bool  { return id != SpanID{} }
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// SpanContext identifies a span, local or received from a caller.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// SpanKind values match OTLP's.
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+// StatusCode values match OTLP's.
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = 0
+	StatusOK    StatusCode = 1
+	StatusError StatusCode = 2
+)
+
+// Attr is a span attribute. Value is a string, bool, int64 or float64.
+type Attr struct {
+	Key   string
+	Value any
+}
+
+func String(key, value string) Attr    { return Attr{Key: key, Value: value} }
+func Int(key string, value int) Attr   { return Attr{Key: key, Value: int64(value)} }
+func Bool(key string, value bool) Attr { return Attr{Key: key, Value: value} }
+
+// SpanData is a finished span as exporters see it.
+type SpanData struct {
+	Name          string
+	Kind          SpanKind
+	TraceID       TraceID
+	SpanID        SpanID
+	ParentID      SpanID
+	Start         time.Time
+	End           time.Time
+	Attrs         []Attr
+	Status        StatusCode
+	StatusMessage string
+}
+
+// Span is an operation in progress. All methods are safe on a nil *Span,
+// so callers never need to check whether tracing is on.
+type Span struct {
+	tracer  *Tracer
+	sampled bool
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return SpanContext{TraceID: s.data.TraceID, SpanID: s.data.SpanID, Sampled: s.sampled}
+}
+
+// SetName replaces the name given at Start, for names only known later
+// such as the route a request matched.
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Name = name
+	s.mu.Unlock()
+}
+
+func (s *Span) SetAttributes(attrs ...Attr) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Attrs = append(s.data.Attrs, attrs...)
+	s.mu.Unlock()
+}
+
+// SetStatus marks the span's outcome; msg only matters for StatusError.
+func (s *Span) SetStatus(code StatusCode, msg string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Status = code
+	s.data.StatusMessage = msg
+	s.mu.Unlock()
+}
+
+// RecordError marks the span failed with err; a nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if err != nil {
+		s.SetStatus(StatusError, err.Error())
+	}
+}
+
+// End finishes the span and exports it if sampled. Only the first call
+// counts.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	if s.sampled {
+		s.tracer.exporter.Export(data)
+	}
+}
+
+// Tracer creates spans and sends the finished ones to its exporter.
+type Tracer struct {
+	exporter Exporter
+}
+
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{exporter: exporter}
+}
+
+// Start begins an internal span as a child of the span in ctx, or of the
+// remote caller's span (see Extract), or as the root of a new trace.
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...Attr) (context.Context, *Span) {
+	return t.StartKind(ctx, KindInternal, name, attrs...)
+}
+
+func (t *Tracer) StartKind(ctx context.Context, kind SpanKind, name string, attrs ...Attr) (context.Context, *Span) {
+	span := t.start(ctx, kind, name, time.Now(), attrs)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (t *Tracer) start(ctx context.Context, kind SpanKind, name string, at time.Time, attrs []Attr) *Span {
+	parent := SpanContextFromContext(ctx)
+
+	s := &Span{tracer: t, sampled: true}
+	s.data = SpanData{Name: name, Kind: kind, Start: at, Attrs: attrs, SpanID: newSpanID()}
+	if parent.IsValid() {
+		s.data.TraceID = parent.TraceID
+		s.data.ParentID = parent.SpanID
+		s.sampled = parent.Sampled
+	} else {
+		s.data.TraceID = newTraceID()
+	}
+	return s
+}
+
+var defaultTracer atomic.Pointer[Tracer]
+
+func init() {
+	defaultTracer.Store(NewTracer(NoopExporter{}))
+}
+
+// SetDefault makes t the tracer used by the package-level Start.
+func SetDefault(t *Tracer) {
+	defaultTracer.Store(t)
+}
+
+func Default() *Tracer {
+	return defaultTracer.Load()
+}
+
+// Start begins a span with the default tracer:
+//
+//	ctx, span := tracing.Start(ctx, "task.AddTask")
+//	defer span.End()
+func Start(ctx context.Context, name string, attrs ...Attr) (context.Context, *Span) {
+	return Default().Start(ctx, name, attrs...)
+}
+
+// StartKind is Start for server and client spans.
+func StartKind(ctx context.Context, kind SpanKind, name string, attrs ...Attr) (context.Context, *Span) {
+	return Default().StartKind(ctx, kind, name, attrs...)
+}
+
+type (
+	spanKey   struct{}
+	remoteKey struct{}
+)
+
+// SpanFromContext returns the current span, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// SpanContextFromContext returns the current span's context, falling back
+// to a remote parent stored by Extract.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if s := SpanFromContext(ctx); s != nil {
+		return s.SpanContext()
+	}
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
+
+// ContextWithRemoteSpanContext records a caller's span as the parent of
+// spans started from the returned context.
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		putUint64(id[:8], rand.Uint64())
+		putUint64(id[8:], rand.Uint64())
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		putUint64(id[:], rand.Uint64())
+	}
+	return id
+}
+
+func putUint64(b []byte, v uint64) {
+	for i := range 8 {
+		b[i] = byte(v >> (56 - 8*i))
+	}
+}
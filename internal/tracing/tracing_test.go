@@ -0,0 +1,195 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// ==========================================
+// HELPER
+// ==========================================
+
+// useInMemory installs a tracer exporting to memory for the test.
+func useInMemory(t *testing.T) *InMemoryExporter {
+	t.Helper()
+	exp := NewInMemoryExporter()
+	prev := Default()
+	SetDefault(NewTracer(exp))
+	t.Cleanup(func() { SetDefault(prev) })
+	return exp
+}
+
+// ==========================================
+// TESTS
+// ==========================================
+
+func TestParseTraceparent(t *testing.T) {
+	sc, ok := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if !ok || !sc.Sampled {
+		t.Fatalf("sc = %+v, ok = %v", sc, ok)
+	}
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" {
+		t.Errorf("ids = %s %s", sc.TraceID, sc.SpanID)
+	}
+	if got := sc.Traceparent(); got != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Errorf("Traceparent() = %q", got)
+	}
+
+	invalid := []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7_01",
+	}
+	for _, v := range invalid {
+		if _, ok := ParseTraceparent(v); ok {
+			t.Errorf("accepted %q", v)
+		}
+	}
+
+	// Future versions may append fields
+	if _, ok := ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"); !ok {
+		t.Error("rejected a future version")
+	}
+}
+
+func TestStart_Nesting(t *testing.T) {
+	exp := useInMemory(t)
+
+	ctx, root := Start(context.Background(), "root")
+	_, child := Start(ctx, "child", String("k", "v"))
+	child.RecordError(errors.New("boom"))
+	child.End()
+	root.End()
+	root.End()
+
+	spans := exp.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("spans = %d", len(spans))
+	}
+	c, r := spans[0], spans[1]
+	if c.TraceID != r.TraceID || c.ParentID != r.SpanID || r.ParentID.IsValid() {
+		t.Errorf("child %+v is not under root %+v", c, r)
+	}
+	if c.Status != StatusError || c.StatusMessage != "boom" || len(c.Attrs) != 1 {
+		t.Errorf("child = %+v", c)
+	}
+}
+
+func TestExtract_RemoteParent(t *testing.T) {
+	exp := useInMemory(t)
+
+	h := http.Header{}
+	h.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	_, span := Start(Extract(context.Background(), h), "server")
+	span.End()
+
+	s := exp.Spans()[0]
+	if s.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || s.ParentID.String() != "00f067aa0ba902b7" {
+		t.Errorf("span = %+v", s)
+	}
+}
+
+func TestExtract_NotSampled(t *testing.T) {
+	exp := useInMemory(t)
+
+	h := http.Header{}
+	h.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	_, span := Start(Extract(context.Background(), h), "server")
+	span.End()
+
+	if len(exp.Spans()) != 0 {
+		t.Error("exported a span the caller did not sample")
+	}
+	if !span.SpanContext().IsValid() {
+		t.Error("unsampled spans still need IDs for logs")
+	}
+}
+
+func TestNilSpan(t *testing.T) {
+	var s *Span
+	s.SetName("x")
+	s.SetAttributes(String("k", "v"))
+	s.RecordError(errors.New("x"))
+	s.End()
+	if s.SpanContext().IsValid() {
+		t.Error("nil span has a valid context")
+	}
+}
+
+func TestTransport_Propagates(t *testing.T) {
+	exp := useInMemory(t)
+
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(TraceparentHeader)
+	}))
+	defer srv.Close()
+
+	ctx, root := Start(context.Background(), "root")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	res, err := (&http.Client{Transport: Transport(nil)}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	root.End()
+
+	client := exp.Spans()[0]
+	if client.Kind != KindClient || client.ParentID != root.SpanContext().SpanID {
+		t.Errorf("client span = %+v", client)
+	}
+	sc, ok := ParseTraceparent(got)
+	if !ok || sc.SpanID != client.SpanID {
+		t.Errorf("traceparent = %q, want the client span", got)
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	bodies := make(chan map[string]any, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Authorization") != "Bearer abc" {
+			t.Errorf("request %s with %v", r.URL.Path, r.Header)
+		}
+		raw, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		if err := json.Unmarshal(raw, &body); err != nil {
+			t.Errorf("body %s: %v", raw, err)
+		}
+		bodies <- body
+	}))
+	defer srv.Close()
+
+	exp := NewOTLPExporter(OTLPConfig{
+		Endpoint:      srv.URL,
+		Headers:       ParseHeaders("Authorization=Bearer%20abc"),
+		ServiceName:   "task-flow",
+		FlushInterval: time.Hour,
+	})
+	_, span := NewTracer(exp).Start(context.Background(), "GET /tasks", Int("http.response.status_code", 200))
+	span.End()
+	if err := exp.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	body := <-bodies
+	rs := body["resourceSpans"].([]any)[0].(map[string]any)
+	spans := rs["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any)
+	s := spans[0].(map[string]any)
+	if s["name"] != "GET /tasks" || s["traceId"] != span.SpanContext().TraceID.String() || s["kind"] != float64(KindInternal) {
+		t.Errorf("span = %v", s)
+	}
+	attr := s["attributes"].([]any)[0].(map[string]any)
+	if attr["value"].(map[string]any)["intValue"] != "200" {
+		t.Errorf("attribute = %v", attr)
+	}
+}
@@ -0,0 +1,128 @@
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Rule checks one value against its tag parameter and returns what is
+// wrong, phrased to follow the field name ("must be ..."), or "" when the
+// value is fine.
+type Rule func(v reflect.Value, param string) string
+
+var (
+	mu    sync.RWMutex
+	rules = map[string]Rule{
+		"min":      minRule,
+		"max":      maxRule,
+		"email":    emailRule,
+		"url":      urlRule,
+		"oneof":    oneOfRule,
+		"notblank": notBlankRule,
+	}
+)
+
+// Register adds a custom rule usable in validate tags. It is meant to be
+// called from init functions; registering a name twice replaces the rule.
+func Register(name string, rule Rule) {
+	if name == "required" {
+		panic("validate: required is built in")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	rules[name] = rule
+}
+
+func lookup(name string) (Rule, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	r, ok := rules[name]
+	return r, ok
+}
+
+// size is what min and max compare: characters for strings, elements for
+// collections and the value itself for numbers.
+func size(v reflect.Value) (n float64, unit string, ok bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), " characters", true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), " items", true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), "", true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), "", true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), "", true
+	}
+	return 0, "", false
+}
+
+func bound(v reflect.Value, param string) (n, limit float64, unit string) {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("validate: bad limit %q", param))
+	}
+	n, unit, ok := size(v)
+	if !ok {
+		panic(fmt.Sprintf("validate: min/max on %s", v.Kind()))
+	}
+	return n, limit, unit
+}
+
+func minRule(v reflect.Value, param string) string {
+	if n, limit, unit := bound(v, param); n < limit {
+		return "must be at least " + param + unit
+	}
+	return ""
+}
+
+func maxRule(v reflect.Value, param string) string {
+	if n, limit, unit := bound(v, param); n > limit {
+		return "must be at most " + param + unit
+	}
+	return ""
+}
+
+func emailRule(v reflect.Value, _ string) string {
+	s := strings.TrimSpace(v.String())
+	addr, err := mail.ParseAddress(s)
+	// Reject display-name forms like "Ann <ann@example.com>"
+	if err != nil || addr.Address != s {
+		return "must be a valid email address"
+	}
+	return ""
+}
+
+func urlRule(v reflect.Value, _ string) string {
+	u, err := url.Parse(v.String())
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "must be an http or https URL"
+	}
+	return ""
+}
+
+// notBlankRule is "required" for optional fields: it only runs on values
+// that were sent, so a PATCH may omit the field but not blank it.
+func notBlankRule(v reflect.Value, _ string) string {
+	if isBlank(v) {
+		return "must not be blank"
+	}
+	return ""
+}
+
+// oneOfRule takes the allowed values separated by spaces.
+func oneOfRule(v reflect.Value, param string) string {
+	allowed := strings.Fields(param)
+	if !slices.Contains(allowed, v.String()) {
+		return "must be one of " + strings.Join(allowed, ", ")
+	}
+	return ""
+}
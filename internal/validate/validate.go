@@ -0,0 +1,237 @@
+// Package validate checks request DTOs against rules declared in struct
+// tags and reports every violation at once, each located by a JSON pointer
+// built from the json tags:
+//
+//	type taskRequest struct {
+//		Title string `json:"title" validate:"required,max=255"`
+//	}
+//
+// Rules are comma separated; parameters follow "=". Only "required" looks
+// at zero values: an optional field that was left out is not checked
+// further. A non-nil pointer counts as sent, so its target is always
+// checked, which is what PATCH bodies need.
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"task-flow/internal/apperr"
+)
+
+// Violation is one broken rule. Pointer is an RFC 6901 JSON pointer into the
+// request body.
+type Violation struct {
+	Pointer string `json:"pointer"`
+	Rule    string `json:"rule"`
+	Detail  string `json:"detail"`
+}
+
+// Violations collects the problems found in one value.
+type Violations []Violation
+
+// Add records a violation. field is a JSON pointer relative to the value
+// being checked, such as "/expires_at".
+func (v *Violations) Add(field, rule, detail string) {
+	*v = append(*v, Violation{Pointer: field, Rule: rule, Detail: detail})
+}
+
+// Checker is implemented by DTOs with rules that tags can't express, such
+// as ones spanning several fields. Check runs after the tag rules.
+type Checker interface {
+	Check(v *Violations)
+}
+
+var errInvalid = apperr.Validation("invalid_request", "request validation failed")
+
+// Error lists every violation of a request. It unwraps to a validation
+// apperr.Error carrying the violations as "errors".
+type Error struct {
+	Violations Violations
+}
+
+func (e *Error) Error() string {
+	details := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		details[i] = v.Detail
+	}
+	return strings.Join(details, "; ")
+}
+
+func (e *Error) Unwrap() error {
+	return errInvalid.With("errors", e.Violations)
+}
+
+// Struct checks v, a struct or a pointer to one, and returns an *Error when
+// any rule is broken.
+func Struct(v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var vs Violations
+	checkStruct(rv, "", &vs)
+	if len(vs) == 0 {
+		return nil
+	}
+	return &Error{Violations: vs}
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+func checkStruct(rv reflect.Value, prefix string, vs *Violations) {
+	rt := rv.Type()
+	for i := range rt.NumField() {
+		f := rt.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, skip := jsonName(f)
+		if skip {
+			continue
+		}
+
+		fv := rv.Field(i)
+		if f.Anonymous && name == "" {
+			// Embedded structs are flattened by encoding/json too
+			if fv.Kind() == reflect.Struct {
+				checkStruct(fv, prefix, vs)
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		checkField(fv, f.Tag.Get("validate"), prefix+"/"+escape(name), name, vs)
+	}
+
+	if rv.CanAddr() {
+		if c, ok := rv.Addr().Interface().(Checker); ok {
+			var own Violations
+			c.Check(&own)
+			for _, v := range own {
+				v.Pointer = prefix + v.Pointer
+				*vs = append(*vs, v)
+			}
+		}
+	}
+}
+
+func checkField(fv reflect.Value, tag, pointer, name string, vs *Violations) {
+	sent := true
+	if fv.Kind() == reflect.Pointer {
+		if fv.IsNil() {
+			sent = false
+		} else {
+			fv = fv.Elem()
+		}
+	} else {
+		sent = !fv.IsZero()
+	}
+
+	for _, rule := range parseTag(tag) {
+		if rule.name == "required" {
+			if !sent || isBlank(fv) {
+				vs.Add(pointer, "required", name+" is required")
+				// Nothing else is worth saying about a missing value
+				return
+			}
+			continue
+		}
+		if !sent {
+			continue
+		}
+		check, ok := lookup(rule.name)
+		if !ok {
+			panic(fmt.Sprintf("validate: unknown rule %q on %s", rule.name, pointer))
+		}
+		if msg := check(fv, rule.param); msg != "" {
+			vs.Add(pointer, rule.name, name+" "+msg)
+		}
+	}
+
+	if !sent && fv.Kind() != reflect.Struct {
+		return
+	}
+	switch {
+	case fv.Kind() == reflect.Struct && fv.Type() != timeType:
+		checkStruct(fv, pointer, vs)
+	case fv.Kind() == reflect.Slice:
+		for i := range fv.Len() {
+			elem := fv.Index(i)
+			for elem.Kind() == reflect.Pointer && !elem.IsNil() {
+				elem = elem.Elem()
+			}
+			if elem.Kind() == reflect.Struct && elem.Type() != timeType {
+				checkStruct(elem, fmt.Sprintf("%s/%d", pointer, i), vs)
+			}
+		}
+	}
+}
+
+// isBlank treats whitespace-only strings and empty collections as missing.
+func isBlank(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(v.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return false
+}
+
+type tagRule struct {
+	name  string
+	param string
+}
+
+func parseTag(tag string) []tagRule {
+	if tag == "" {
+		return nil
+	}
+	parts := strings.Split(tag, ",")
+	rules := make([]tagRule, 0, len(parts))
+	for _, p := range parts {
+		name, param, _ := strings.Cut(strings.TrimSpace(p), "=")
+		if name != "" {
+			rules = append(rules, tagRule{name: name, param: param})
+		}
+	}
+	return rules
+}
+
+func jsonName(f reflect.StructField) (name string, skip bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	name, _, _ = strings.Cut(tag, ",")
+	return name, false
+}
+
+// escape encodes a JSON pointer reference token (RFC 6901, section 3).
+func escape(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// Pointer turns a dotted field path, as reported by encoding/json, into a
+// JSON pointer.
+func Pointer(path string) string {
+	if path == "" {
+		return ""
+	}
+	parts := strings.Split(path, ".")
+	for i, p := range parts {
+		parts[i] = escape(p)
+	}
+	return "/" + strings.Join(parts, "/")
+}
@@ -0,0 +1,209 @@
+package validate
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"task-flow/internal/apperr"
+)
+
+// ==========================================
+// HELPER
+// ==========================================
+
+func violations(t *testing.T, v any) Violations {
+	t.Helper()
+	err := Struct(v)
+	if err == nil {
+		return nil
+	}
+	var verr *Error
+	if !errors.As(err, &verr) {
+		t.Fatalf("Struct returned %T, want *Error", err)
+	}
+	return verr.Violations
+}
+
+func pointers(vs Violations) []string {
+	out := make([]string, len(vs))
+	for i, v := range vs {
+		out[i] = v.Pointer + " " + v.Rule
+	}
+	return out
+}
+
+// ==========================================
+// TESTS
+// ==========================================
+
+type signup struct {
+	Email    string   `json:"email" validate:"required,email,max=30"`
+	Name     string   `json:"name" validate:"max=5"`
+	Role     string   `json:"role" validate:"oneof=admin member"`
+	Tags     []string `json:"tags" validate:"max=2"`
+	Age      int      `json:"age" validate:"min=18"`
+	Homepage string   `json:"homepage" validate:"url"`
+}
+
+func TestStruct_Valid(t *testing.T) {
+	err := Struct(&signup{Email: "ann@example.com", Role: "admin", Age: 20, Homepage: "https://example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestStruct_ReportsEveryViolation(t *testing.T) {
+	vs := violations(t, &signup{
+		Email:    "Ann <ann@example.com>",
+		Name:     "Annabel",
+		Role:     "owner",
+		Tags:     []string{"a", "b", "c"},
+		Age:      12,
+		Homepage: "ftp://example.com",
+	})
+
+	want := []string{"/email email", "/name max", "/role oneof", "/tags max", "/age min", "/homepage url"}
+	if got := pointers(vs); !reflect.DeepEqual(got, want) {
+		t.Errorf("violations = %v, want %v", got, want)
+	}
+	if vs[1].Detail != "name must be at most 5 characters" {
+		t.Errorf("detail = %q", vs[1].Detail)
+	}
+}
+
+func TestStruct_RequiredStopsFurtherRules(t *testing.T) {
+	vs := violations(t, &signup{Email: "   "})
+	if got := pointers(vs); !reflect.DeepEqual(got, []string{"/email required"}) {
+		t.Errorf("violations = %v", got)
+	}
+}
+
+func TestStruct_MinCountsCharacters(t *testing.T) {
+	type req struct {
+		Name string `json:"name" validate:"max=3"`
+	}
+	if err := Struct(&req{Name: "ñóü"}); err != nil {
+		t.Errorf("three runes rejected: %v", err)
+	}
+}
+
+type patch struct {
+	Title       *string `json:"title" validate:"notblank,max=5"`
+	Description *string `json:"description" validate:"max=5"`
+}
+
+func TestStruct_PointerSemantics(t *testing.T) {
+	if err := Struct(&patch{}); err != nil {
+		t.Errorf("omitted fields rejected: %v", err)
+	}
+
+	blank, long := "", "toolong"
+	vs := violations(t, &patch{Title: &blank, Description: &long})
+	want := []string{"/title notblank", "/description max"}
+	if got := pointers(vs); !reflect.DeepEqual(got, want) {
+		t.Errorf("violations = %v, want %v", got, want)
+	}
+}
+
+type address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type person struct {
+	Home      address    `json:"home"`
+	Work      *address   `json:"work"`
+	Previous  []address  `json:"previous"`
+	Nicknames []*address `json:"nicknames"`
+}
+
+func TestStruct_Nested(t *testing.T) {
+	vs := violations(t, &person{
+		Work:     &address{},
+		Previous: []address{{City: "Bandung"}, {}},
+	})
+	want := []string{"/home/city required", "/work/city required", "/previous/1/city required"}
+	if got := pointers(vs); !reflect.DeepEqual(got, want) {
+		t.Errorf("violations = %v, want %v", got, want)
+	}
+}
+
+type dateRange struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+func (d *dateRange) Check(v *Violations) {
+	if d.To < d.From {
+		v.Add("/to", "after_from", "to must not be before from")
+	}
+}
+
+func TestStruct_Checker(t *testing.T) {
+	type wrapper struct {
+		Range dateRange `json:"range"`
+	}
+	vs := violations(t, &wrapper{Range: dateRange{From: 5, To: 1}})
+	if got := pointers(vs); !reflect.DeepEqual(got, []string{"/range/to after_from"}) {
+		t.Errorf("violations = %v", got)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("even", func(v reflect.Value, _ string) string {
+		if v.Int()%2 != 0 {
+			return "must be even"
+		}
+		return ""
+	})
+	type req struct {
+		N int `json:"n" validate:"even"`
+	}
+
+	vs := violations(t, &req{N: 3})
+	if len(vs) != 1 || vs[0].Detail != "n must be even" {
+		t.Errorf("violations = %v", vs)
+	}
+}
+
+func TestStruct_UnknownRulePanics(t *testing.T) {
+	type req struct {
+		N string `json:"n" validate:"nosuchrule"`
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	_ = Struct(&req{N: "x"})
+}
+
+func TestError_UnwrapsToValidation(t *testing.T) {
+	err := Struct(&signup{})
+	e, ok := apperr.As(err)
+	if !ok || e.Kind != apperr.KindValidation || e.Code != "invalid_request" {
+		t.Fatalf("apperr = %+v, %v", e, ok)
+	}
+	if _, ok := e.Details["errors"].(Violations); !ok {
+		t.Errorf("details = %v", e.Details)
+	}
+	if !strings.Contains(err.Error(), "email is required") {
+		t.Errorf("message = %q", err.Error())
+	}
+}
+
+func TestPointer(t *testing.T) {
+	cases := map[string]string{
+		"":              "",
+		"title":         "/title",
+		"range.to":      "/range/to",
+		"a/b.c~d":       "/a~1b/c~0d",
+		"previous.1.id": "/previous/1/id",
+	}
+	for in, want := range cases {
+		if got := Pointer(in); got != want {
+			t.Errorf("Pointer(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
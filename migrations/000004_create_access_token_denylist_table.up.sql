@@ -0,0 +1,5 @@
+CREATE TABLE access_token_denylist (
+    jti VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL,
+    INDEX idx_access_token_denylist_expires_at (expires_at)
+);
@@ -0,0 +1 @@
+DROP TABLE user_access_revocations;
\ No newline at end of file
@@ -0,0 +1,6 @@
+CREATE TABLE user_access_revocations (
+    user_id VARCHAR(36) PRIMARY KEY,
+    revoked_before TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
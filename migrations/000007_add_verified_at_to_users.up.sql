@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN verified_at TIMESTAMP NULL DEFAULT NULL;
+
+-- Accounts created before verification existed are trusted as-is
+UPDATE users SET verified_at = created_at;
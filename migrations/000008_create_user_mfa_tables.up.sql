@@ -0,0 +1,18 @@
+CREATE TABLE user_mfa (
+    user_id VARCHAR(36) PRIMARY KEY,
+    secret VARBINARY(64) NOT NULL,
+    enabled_at TIMESTAMP NULL DEFAULT NULL,
+    last_counter BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE TABLE mfa_recovery_codes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    code_hash VARBINARY(32) NOT NULL,
+    used_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_mfa_recovery_codes_user (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
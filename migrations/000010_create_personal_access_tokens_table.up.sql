@@ -0,0 +1,14 @@
+CREATE TABLE personal_access_tokens (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    token_hash VARBINARY(32) NOT NULL,
+    scopes VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP NULL DEFAULT NULL,
+    last_used_at TIMESTAMP NULL DEFAULT NULL,
+    revoked_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_personal_access_tokens_hash (token_hash),
+    INDEX idx_personal_access_tokens_user (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
@@ -0,0 +1,2 @@
+DROP TABLE user_identities;
+DROP TABLE oidc_login_states;
\ No newline at end of file
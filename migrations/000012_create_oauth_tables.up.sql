@@ -0,0 +1,37 @@
+CREATE TABLE oauth_clients (
+    id VARCHAR(36) PRIMARY KEY,
+    owner_id VARCHAR(36) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    secret_hash VARBINARY(32) NULL DEFAULT NULL,
+    redirect_uris TEXT NOT NULL,
+    scopes VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_oauth_clients_owner (owner_id),
+    FOREIGN KEY (owner_id) REFERENCES users(id)
+);
+
+CREATE TABLE oauth_authorization_codes (
+    code_hash VARBINARY(32) PRIMARY KEY,
+    client_id VARCHAR(36) NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    redirect_uri VARCHAR(2048) NOT NULL,
+    scopes VARCHAR(255) NOT NULL,
+    code_challenge VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL DEFAULT NULL,
+    FOREIGN KEY (client_id) REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE TABLE oauth_refresh_tokens (
+    token_hash VARBINARY(32) PRIMARY KEY,
+    client_id VARCHAR(36) NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    scopes VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_oauth_refresh_tokens_user (user_id),
+    FOREIGN KEY (client_id) REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
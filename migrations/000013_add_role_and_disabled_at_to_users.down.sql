@@ -0,0 +1,4 @@
+ALTER TABLE users
+    DROP INDEX idx_users_role,
+    DROP COLUMN disabled_at,
+    DROP COLUMN role;
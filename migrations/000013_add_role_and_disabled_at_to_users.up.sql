@@ -0,0 +1,4 @@
+ALTER TABLE users
+    ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'member',
+    ADD COLUMN disabled_at TIMESTAMP NULL DEFAULT NULL,
+    ADD INDEX idx_users_role (role);
@@ -0,0 +1,10 @@
+ALTER TABLE tasks
+    DROP FOREIGN KEY fk_tasks_project,
+    DROP FOREIGN KEY fk_tasks_workspace,
+    DROP INDEX idx_tasks_workspace,
+    DROP COLUMN project_id,
+    DROP COLUMN workspace_id;
+
+DROP TABLE projects;
+DROP TABLE workspace_members;
+DROP TABLE workspaces;
@@ -0,0 +1,46 @@
+CREATE TABLE workspaces (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    created_by VARCHAR(36) NULL DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE workspace_members (
+    workspace_id VARCHAR(36) NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    role VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (workspace_id, user_id),
+    INDEX idx_workspace_members_user (user_id),
+    FOREIGN KEY (workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE projects (
+    id VARCHAR(36) PRIMARY KEY,
+    workspace_id VARCHAR(36) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_projects_workspace_id (workspace_id, id),
+    FOREIGN KEY (workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE
+);
+
+-- Tasks used to be global. Keep them visible to everyone by moving them
+-- into a shared workspace that every existing account joins.
+INSERT INTO workspaces (id, name) VALUES ('default', 'Default');
+
+INSERT INTO workspace_members (workspace_id, user_id, role)
+SELECT 'default', id, CASE role WHEN 'admin' THEN 'owner' WHEN 'viewer' THEN 'viewer' ELSE 'member' END
+FROM users;
+
+ALTER TABLE tasks
+    ADD COLUMN workspace_id VARCHAR(36) NOT NULL DEFAULT 'default' AFTER id,
+    ADD COLUMN project_id VARCHAR(36) NULL DEFAULT NULL AFTER workspace_id;
+
+ALTER TABLE tasks
+    ALTER COLUMN workspace_id DROP DEFAULT,
+    ADD INDEX idx_tasks_workspace (workspace_id, created_at),
+    ADD CONSTRAINT fk_tasks_workspace FOREIGN KEY (workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE,
+    -- A task's project must live in the task's own workspace
+    ADD CONSTRAINT fk_tasks_project FOREIGN KEY (workspace_id, project_id) REFERENCES projects(workspace_id, id) ON DELETE CASCADE;
@@ -0,0 +1,5 @@
+DROP TABLE task_comments;
+DROP TABLE task_links;
+DROP TABLE shares;
+
+ALTER TABLE tasks DROP INDEX uq_tasks_workspace_id;
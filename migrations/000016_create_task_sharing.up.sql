@@ -0,0 +1,46 @@
+-- Lets shares, links and comments reference a task together with its
+-- workspace, so they can never point into another tenant.
+ALTER TABLE tasks ADD UNIQUE KEY uq_tasks_workspace_id (workspace_id, id);
+
+-- Exactly one of task_id and project_id is set.
+CREATE TABLE shares (
+    workspace_id VARCHAR(36) NOT NULL,
+    task_id VARCHAR(36) NULL DEFAULT NULL,
+    project_id VARCHAR(36) NULL DEFAULT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    level VARCHAR(20) NOT NULL,
+    created_by VARCHAR(36) NULL DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_shares_task_user (task_id, user_id),
+    UNIQUE KEY uq_shares_project_user (project_id, user_id),
+    INDEX idx_shares_user (user_id),
+    FOREIGN KEY (workspace_id, task_id) REFERENCES tasks(workspace_id, id) ON DELETE CASCADE,
+    FOREIGN KEY (workspace_id, project_id) REFERENCES projects(workspace_id, id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE TABLE task_links (
+    id VARCHAR(36) PRIMARY KEY,
+    workspace_id VARCHAR(36) NOT NULL,
+    task_id VARCHAR(36) NOT NULL,
+    token_hash VARBINARY(32) NOT NULL,
+    created_by VARCHAR(36) NULL DEFAULT NULL,
+    expires_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_task_links_token_hash (token_hash),
+    FOREIGN KEY (workspace_id, task_id) REFERENCES tasks(workspace_id, id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE TABLE task_comments (
+    id VARCHAR(36) PRIMARY KEY,
+    workspace_id VARCHAR(36) NOT NULL,
+    task_id VARCHAR(36) NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_task_comments_task (workspace_id, task_id, created_at),
+    FOREIGN KEY (workspace_id, task_id) REFERENCES tasks(workspace_id, id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,7 @@
+ALTER TABLE users
+    DROP COLUMN deleted_at,
+    DROP COLUMN pending_email,
+    DROP COLUMN locale,
+    DROP COLUMN timezone,
+    DROP COLUMN avatar_url,
+    DROP COLUMN display_name;
@@ -0,0 +1,8 @@
+ALTER TABLE users
+    ADD COLUMN display_name VARCHAR(100) NOT NULL DEFAULT '',
+    ADD COLUMN avatar_url VARCHAR(500) NOT NULL DEFAULT '',
+    ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    ADD COLUMN locale VARCHAR(35) NOT NULL DEFAULT 'en',
+    -- Address waiting for confirmation through an email_change token
+    ADD COLUMN pending_email VARCHAR(255) NULL DEFAULT NULL,
+    ADD COLUMN deleted_at TIMESTAMP NULL DEFAULT NULL;
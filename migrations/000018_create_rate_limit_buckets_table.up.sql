@@ -0,0 +1,7 @@
+CREATE TABLE rate_limit_buckets (
+    bucket_key VARCHAR(255) PRIMARY KEY,
+    tokens DOUBLE NOT NULL,
+    updated_at TIMESTAMP(6) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    INDEX idx_rate_limit_buckets_expires_at (expires_at)
+);
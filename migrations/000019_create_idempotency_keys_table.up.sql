@@ -0,0 +1,13 @@
+CREATE TABLE idempotency_keys (
+    user_id VARCHAR(36) NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    fingerprint VARBINARY(32) NOT NULL,
+    status_code SMALLINT NULL DEFAULT NULL,
+    response_headers TEXT NULL,
+    response_body MEDIUMBLOB NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, idempotency_key),
+    INDEX idx_idempotency_keys_expires_at (expires_at),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
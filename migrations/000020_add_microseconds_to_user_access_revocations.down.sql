@@ -0,0 +1 @@
+ALTER TABLE user_access_revocations MODIFY revoked_before TIMESTAMP NOT NULL;
@@ -0,0 +1,2 @@
+-- Tokens carry their issue time to the microsecond; so does the cutoff
+ALTER TABLE user_access_revocations MODIFY revoked_before TIMESTAMP(6) NOT NULL;
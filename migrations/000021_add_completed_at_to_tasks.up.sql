@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN completed_at TIMESTAMP NULL DEFAULT NULL;
@@ -0,0 +1,3 @@
+-- Set when a token is exchanged for a new one. Presenting it again means
+-- it was copied, as opposed to one revoked by logging out.
+ALTER TABLE refresh_tokens ADD COLUMN rotated_at TIMESTAMP NULL DEFAULT NULL;
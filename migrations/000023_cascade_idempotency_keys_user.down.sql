@@ -0,0 +1,4 @@
+ALTER TABLE idempotency_keys DROP FOREIGN KEY fk_idempotency_keys_user;
+ALTER TABLE idempotency_keys
+    ADD CONSTRAINT idempotency_keys_ibfk_1
+        FOREIGN KEY (user_id) REFERENCES users(id);
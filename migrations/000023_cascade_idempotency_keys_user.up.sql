@@ -0,0 +1,6 @@
+-- Deleting a user takes its stored responses along. 000019 left the key
+-- unnamed, so it carries MySQL's generated name.
+ALTER TABLE idempotency_keys DROP FOREIGN KEY idempotency_keys_ibfk_1;
+ALTER TABLE idempotency_keys
+    ADD CONSTRAINT fk_idempotency_keys_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
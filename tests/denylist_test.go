@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"task-flow/internal/model"
+	"task-flow/internal/repository/mysql"
+)
+
+// ============================================
+// TESTS
+// ============================================
+
+// TestAccessTokenDenylist_RevokeUser_SameSecond checks that revoking a
+// user's tokens spares those issued after the cutoff, within the same second.
+func TestAccessTokenDenylist_RevokeUser_SameSecond(t *testing.T) {
+	db := openDB(t)
+	ctx := context.Background()
+
+	user := model.User{ID: newID(t), PassHash: []byte{}, Role: model.RoleMember}
+	user.Email = "denylist-" + user.ID + "@example.test"
+	if err := mysql.NewUserRepo(db).Create(ctx, user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	t.Cleanup(func() {
+		_, _ = db.Exec("DELETE FROM user_access_revocations WHERE user_id = ?", user.ID)
+		_, _ = db.Exec("DELETE FROM users WHERE id = ?", user.ID)
+	})
+
+	d := mysql.NewAccessTokenDenylist(db)
+	cutoff := time.Now().Truncate(time.Second).Add(500 * time.Millisecond)
+	if err := d.RevokeUser(ctx, user.ID, cutoff.UnixMicro(), time.Now().Add(time.Hour).Unix()); err != nil {
+		t.Fatalf("revoke user: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		issuedAt time.Time
+		want     bool
+	}{
+		{"earlier in the second", cutoff.Add(-time.Microsecond), true},
+		{"at the cutoff", cutoff, true},
+		{"later in the same second", cutoff.Add(time.Microsecond), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revoked, err := d.IsRevoked(ctx, "jti-"+user.ID, user.ID, tt.issuedAt.UnixMicro())
+			if err != nil {
+				t.Fatalf("is revoked: %v", err)
+			}
+			if revoked != tt.want {
+				t.Errorf("expected revoked=%v, got %v", tt.want, revoked)
+			}
+		})
+	}
+}